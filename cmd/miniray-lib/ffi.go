@@ -0,0 +1,36 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/HugoDaniel/miniray/internal/service"
+)
+
+// Contexts are handed to C as numbers rather than pointers, so an unknown
+// or freed handle is an error rather than a crash.
+var (
+	contexts    sync.Map // uintptr -> *service.Context
+	lastContext atomic.Uintptr
+)
+
+// registerContext returns a new handle for c; handles are never 0.
+func registerContext(c *service.Context) uintptr {
+	h := lastContext.Add(1)
+	contexts.Store(h, c)
+	return h
+}
+
+// lookupContext returns the context of handle h, or nil.
+func lookupContext(h uintptr) *service.Context {
+	if c, ok := contexts.Load(h); ok {
+		return c.(*service.Context)
+	}
+	return nil
+}
+
+// releaseContext forgets handle h, reporting whether it was known.
+func releaseContext(h uintptr) bool {
+	_, ok := contexts.LoadAndDelete(h)
+	return ok
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/service"
+)
+
+func TestContextHandles(t *testing.T) {
+	ctx, err := service.NewContext("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := registerContext(ctx)
+	if h == 0 || lookupContext(h) != ctx {
+		t.Fatalf("handle %d does not find its context", h)
+	}
+	if other := registerContext(ctx); other == h {
+		t.Errorf("handle %d was handed out twice", h)
+	} else {
+		releaseContext(other)
+	}
+
+	if !releaseContext(h) || lookupContext(h) != nil {
+		t.Error("freed handle still finds its context")
+	}
+	if releaseContext(h) || lookupContext(0) != nil {
+		t.Error("freed and zero handles must be unknown")
+	}
+}
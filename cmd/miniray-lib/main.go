@@ -13,66 +13,95 @@
 //	miniray_minify(source, source_len, options_json, options_len, out_code, out_code_len, out_json, out_json_len) -> error_code
 //	miniray_reflect(source, source_len, out_json, out_len) -> error_code
 //	miniray_minify_and_reflect(source, source_len, options_json, options_len, out_code, out_code_len, out_json, out_json_len) -> error_code
+//	miniray_minify_and_validate(source, source_len, options_json, options_len, out_code, out_code_len, out_json, out_json_len) -> error_code
 //	miniray_validate(source, source_len, options_json, options_len, out_json, out_json_len) -> error_code
+//	miniray_context_new(options_json, options_len, out_ctx) -> error_code
+//	miniray_context_minify(ctx, source, source_len, out_code, out_code_len, out_json, out_json_len) -> error_code
+//	miniray_context_minify_and_validate(ctx, source, source_len, out_code, out_code_len, out_json, out_json_len) -> error_code
+//	miniray_context_validate(ctx, source, source_len, out_json, out_json_len) -> error_code
+//	miniray_context_free(ctx) -> void
 //	miniray_free(ptr) -> void
 //	miniray_version() -> *char
+//	miniray_abi_version() -> int
+//
+// The generated libminiray.h declares these functions along with the
+// MINIRAY_ABI_VERSION and error code macros of the preamble below, so the
+// header always matches the library.
 package main
 
 /*
 #include <stdlib.h>
+#include <stdint.h>
+
+// ABI version of this header. Compare it with miniray_abi_version() to
+// check that the library matches the header it was built with. It changes
+// whenever a function signature, option or result field changes
+// incompatibly.
+#define MINIRAY_ABI_VERSION 1
+
+// Error codes
+#define MINIRAY_OK 0
+#define MINIRAY_ERR_JSON_ENCODE 1
+#define MINIRAY_ERR_NULL_INPUT 2
+#define MINIRAY_ERR_JSON_DECODE 3
+#define MINIRAY_ERR_INVALID_CONTEXT 4
+
+// Opaque handle of a context created by miniray_context_new. 0 is never a
+// valid handle.
+typedef uintptr_t miniray_context;
 */
 import "C"
 import (
 	"encoding/json"
 	"unsafe"
 
-	"github.com/HugoDaniel/miniray/internal/diagnostic"
-	"github.com/HugoDaniel/miniray/internal/minifier"
-	"github.com/HugoDaniel/miniray/internal/parser"
 	"github.com/HugoDaniel/miniray/internal/reflect"
-	"github.com/HugoDaniel/miniray/internal/validator"
+	"github.com/HugoDaniel/miniray/internal/service"
 )
 
 // Version should match the release version
 const version = "0.3.1"
 
-// Error codes
+// Error codes, defined in the header
 const (
-	MINIRAY_OK              = 0
-	MINIRAY_ERR_JSON_ENCODE = 1
-	MINIRAY_ERR_NULL_INPUT  = 2
-	MINIRAY_ERR_JSON_DECODE = 3
+	MINIRAY_OK                  = C.MINIRAY_OK
+	MINIRAY_ERR_JSON_ENCODE     = C.MINIRAY_ERR_JSON_ENCODE
+	MINIRAY_ERR_NULL_INPUT      = C.MINIRAY_ERR_NULL_INPUT
+	MINIRAY_ERR_JSON_DECODE     = C.MINIRAY_ERR_JSON_DECODE
+	MINIRAY_ERR_INVALID_CONTEXT = C.MINIRAY_ERR_INVALID_CONTEXT
 )
 
-// MinifyOptions mirrors the Go API options for JSON parsing
-type MinifyOptions struct {
-	MinifyWhitespace       bool     `json:"minifyWhitespace"`
-	MinifyIdentifiers      bool     `json:"minifyIdentifiers"`
-	MinifySyntax           bool     `json:"minifySyntax"`
-	MangleExternalBindings bool     `json:"mangleExternalBindings"`
-	TreeShaking            bool     `json:"treeShaking"`
-	KeepNames              []string `json:"keepNames"`
-}
+// versionString is allocated once, so miniray_version can return it
+// without the caller freeing it.
+var versionString = C.CString(version)
 
-// MinifyResult is the JSON result structure for minification
-type MinifyResult struct {
-	Code         string   `json:"code"`
-	Errors       []string `json:"errors,omitempty"`
-	OriginalSize int      `json:"originalSize"`
-	MinifiedSize int      `json:"minifiedSize"`
+// setJSON encodes v into a string the caller frees with miniray_free.
+func setJSON(v interface{}, out_json **C.char, out_len *C.int) C.int {
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return MINIRAY_ERR_JSON_ENCODE
+	}
+	*out_json = C.CString(string(jsonBytes))
+	*out_len = C.int(len(jsonBytes))
+	return MINIRAY_OK
 }
 
-// MinifyAndReflectResult combines minification and reflection results
-type MinifyAndReflectResult struct {
-	Code         string                `json:"code"`
-	Errors       []string              `json:"errors,omitempty"`
-	OriginalSize int                   `json:"originalSize"`
-	MinifiedSize int                   `json:"minifiedSize"`
-	Reflect      reflect.ReflectResult `json:"reflect"`
+// goOptions returns the options JSON, or "" for NULL.
+func goOptions(options_json *C.char, options_len C.int) string {
+	if options_json == nil || options_len <= 0 {
+		return ""
+	}
+	return C.GoStringN(options_json, options_len)
 }
 
 // miniray_minify minifies WGSL source code.
 //
+// The options JSON has the schema of the config file (miniray.json), where
+// omitted options take their defaults, plus "defines", "overrides",
+// "sourceMap", "sourceMapSources", "sourceMapScopes", "sourceName" and
+// "file" (see service.MinifyOptions). The JSON result has the code, positioned
+// "diagnostics", size "stats", the content "hash" and the "sourceMap".
+//
 // Parameters:
 //   - source: pointer to WGSL source code (UTF-8)
 //   - source_len: length of source in bytes
@@ -98,27 +127,11 @@ func miniray_minify(
 		return MINIRAY_ERR_NULL_INPUT
 	}
 
-	goSource := C.GoStringN(source, source_len)
-
-	// Parse options or use defaults
-	opts := minifier.DefaultOptions()
-	if options_json != nil && options_len > 0 {
-		var jsonOpts MinifyOptions
-		optStr := C.GoStringN(options_json, options_len)
-		if err := json.Unmarshal([]byte(optStr), &jsonOpts); err != nil {
-			return MINIRAY_ERR_JSON_DECODE
-		}
-		opts.MinifyWhitespace = jsonOpts.MinifyWhitespace
-		opts.MinifyIdentifiers = jsonOpts.MinifyIdentifiers
-		opts.MinifySyntax = jsonOpts.MinifySyntax
-		opts.MangleExternalBindings = jsonOpts.MangleExternalBindings
-		opts.TreeShaking = jsonOpts.TreeShaking
-		opts.KeepNames = jsonOpts.KeepNames
+	opts, err := service.ParseMinifyOptions(goOptions(options_json, options_len))
+	if err != nil {
+		return MINIRAY_ERR_JSON_DECODE
 	}
-
-	// Run minification
-	m := minifier.New(opts)
-	result := m.Minify(goSource)
+	result := service.Minify(C.GoStringN(source, source_len), opts)
 
 	// Set output code
 	*out_code = C.CString(result.Code)
@@ -126,22 +139,7 @@ func miniray_minify(
 
 	// Build JSON result if requested
 	if out_json != nil && out_json_len != nil {
-		errors := make([]string, len(result.Errors))
-		for i, e := range result.Errors {
-			errors[i] = e.Message
-		}
-		jsonResult := MinifyResult{
-			Code:         result.Code,
-			Errors:       errors,
-			OriginalSize: result.Stats.OriginalSize,
-			MinifiedSize: result.Stats.MinifiedSize,
-		}
-		jsonBytes, err := json.Marshal(jsonResult)
-		if err != nil {
-			return MINIRAY_ERR_JSON_ENCODE
-		}
-		*out_json = C.CString(string(jsonBytes))
-		*out_json_len = C.int(len(jsonBytes))
+		return setJSON(result, out_json, out_json_len)
 	}
 
 	return MINIRAY_OK
@@ -168,15 +166,7 @@ func miniray_reflect(source *C.char, source_len C.int, out_json **C.char, out_le
 	goSource := C.GoStringN(source, source_len)
 	result := reflect.Reflect(goSource)
 
-	jsonBytes, err := json.Marshal(result)
-	if err != nil {
-		return MINIRAY_ERR_JSON_ENCODE
-	}
-
-	*out_json = C.CString(string(jsonBytes))
-	*out_len = C.int(len(jsonBytes))
-
-	return MINIRAY_OK
+	return setJSON(result, out_json, out_len)
 }
 
 // miniray_minify_and_reflect minifies WGSL and returns reflection with mapped names.
@@ -210,52 +200,64 @@ func miniray_minify_and_reflect(
 		return MINIRAY_ERR_NULL_INPUT
 	}
 
-	goSource := C.GoStringN(source, source_len)
-
-	// Parse options or use defaults
-	opts := minifier.DefaultOptions()
-	if options_json != nil && options_len > 0 {
-		var jsonOpts MinifyOptions
-		optStr := C.GoStringN(options_json, options_len)
-		if err := json.Unmarshal([]byte(optStr), &jsonOpts); err != nil {
-			return MINIRAY_ERR_JSON_DECODE
-		}
-		opts.MinifyWhitespace = jsonOpts.MinifyWhitespace
-		opts.MinifyIdentifiers = jsonOpts.MinifyIdentifiers
-		opts.MinifySyntax = jsonOpts.MinifySyntax
-		opts.MangleExternalBindings = jsonOpts.MangleExternalBindings
-		opts.TreeShaking = jsonOpts.TreeShaking
-		opts.KeepNames = jsonOpts.KeepNames
+	opts, err := service.ParseMinifyOptions(goOptions(options_json, options_len))
+	if err != nil {
+		return MINIRAY_ERR_JSON_DECODE
 	}
 
 	// Run combined minify + reflect
-	m := minifier.New(opts)
-	result := m.MinifyAndReflect(goSource)
+	result := service.MinifyAndReflect(C.GoStringN(source, source_len), opts)
 
 	// Set output code
 	*out_code = C.CString(result.Code)
 	*out_code_len = C.int(len(result.Code))
 
-	// Build JSON result
-	errors := make([]string, len(result.Errors))
-	for i, e := range result.Errors {
-		errors[i] = e.Message
-	}
-	jsonResult := MinifyAndReflectResult{
-		Code:         result.Code,
-		Errors:       errors,
-		OriginalSize: result.Stats.OriginalSize,
-		MinifiedSize: result.Stats.MinifiedSize,
-		Reflect:      result.Reflect,
+	return setJSON(result, out_json, out_json_len)
+}
+
+// miniray_minify_and_validate minifies WGSL and validates the source.
+//
+// The options JSON is the one of miniray_minify. Its "validate" object
+// holds the options of miniray_validate; "lint" severities and "defines"
+// apply to validation too. Minification and validation both always run,
+// so the JSON result has the fields of miniray_minify plus "validation",
+// the result of miniray_validate.
+//
+// Parameters:
+//   - source: pointer to WGSL source code (UTF-8)
+//   - source_len: length of source in bytes
+//   - options_json: pointer to JSON options (can be NULL for defaults)
+//   - options_len: length of options JSON
+//   - out_code: pointer to receive minified code (caller must free with miniray_free)
+//   - out_code_len: pointer to receive code length
+//   - out_json: pointer to receive JSON result with diagnostics (caller must free with miniray_free)
+//   - out_json_len: pointer to receive JSON length
+//
+// Returns:
+//   - 0 on success
+//   - non-zero error code on failure
+//
+//export miniray_minify_and_validate
+func miniray_minify_and_validate(
+	source *C.char, source_len C.int,
+	options_json *C.char, options_len C.int,
+	out_code **C.char, out_code_len *C.int,
+	out_json **C.char, out_json_len *C.int,
+) C.int {
+	if source == nil || out_code == nil || out_code_len == nil || out_json == nil || out_json_len == nil {
+		return MINIRAY_ERR_NULL_INPUT
 	}
-	jsonBytes, err := json.Marshal(jsonResult)
+
+	opts, err := service.ParseMinifyOptions(goOptions(options_json, options_len))
 	if err != nil {
-		return MINIRAY_ERR_JSON_ENCODE
+		return MINIRAY_ERR_JSON_DECODE
 	}
-	*out_json = C.CString(string(jsonBytes))
-	*out_json_len = C.int(len(jsonBytes))
+	result := service.MinifyAndValidate(C.GoStringN(source, source_len), opts)
 
-	return MINIRAY_OK
+	*out_code = C.CString(result.Code)
+	*out_code_len = C.int(len(result.Code))
+
+	return setJSON(result, out_json, out_json_len)
 }
 
 // miniray_free frees memory allocated by miniray functions.
@@ -275,37 +277,23 @@ func miniray_free(ptr *C.char) {
 //
 //export miniray_version
 func miniray_version() *C.char {
-	return C.CString(version)
+	return versionString
 }
 
-// ValidateOptions mirrors the Go API validation options for JSON parsing
-type ValidateOptions struct {
-	StrictMode        bool              `json:"strictMode"`
-	DiagnosticFilters map[string]string `json:"diagnosticFilters"`
-}
-
-// DiagnosticInfo is a single validation diagnostic
-type DiagnosticInfo struct {
-	Severity  string `json:"severity"`
-	Code      string `json:"code,omitempty"`
-	Message   string `json:"message"`
-	Line      int    `json:"line"`
-	Column    int    `json:"column"`
-	EndLine   int    `json:"endLine,omitempty"`
-	EndColumn int    `json:"endColumn,omitempty"`
-	SpecRef   string `json:"specRef,omitempty"`
-}
-
-// ValidateResult is the JSON result structure for validation
-type ValidateResult struct {
-	Valid        bool             `json:"valid"`
-	Diagnostics  []DiagnosticInfo `json:"diagnostics"`
-	ErrorCount   int              `json:"errorCount"`
-	WarningCount int              `json:"warningCount"`
+// miniray_abi_version returns the ABI version the library was built with,
+// MINIRAY_ABI_VERSION in its header.
+//
+//export miniray_abi_version
+func miniray_abi_version() C.int {
+	return C.MINIRAY_ABI_VERSION
 }
 
 // miniray_validate validates WGSL source code.
 //
+// The options JSON has "strictMode", "diagnosticFilters", "lint" and
+// "defines"; diagnostics are positioned in the source even when defines
+// select a variant.
+//
 // Parameters:
 //   - source: pointer to WGSL source code (UTF-8)
 //   - source_len: length of source in bytes
@@ -328,110 +316,145 @@ func miniray_validate(
 		return MINIRAY_ERR_NULL_INPUT
 	}
 
-	goSource := C.GoStringN(source, source_len)
+	opts, err := service.ParseValidateOptions(goOptions(options_json, options_len))
+	if err != nil {
+		return MINIRAY_ERR_JSON_DECODE
+	}
+
+	return setJSON(service.Validate(C.GoStringN(source, source_len), opts), out_json, out_json_len)
+}
 
-	// Parse options
-	var opts ValidateOptions
-	if options_json != nil && options_len > 0 {
-		optStr := C.GoStringN(options_json, options_len)
-		if err := json.Unmarshal([]byte(optStr), &opts); err != nil {
-			return MINIRAY_ERR_JSON_DECODE
-		}
+// miniray_context_new creates a context that minifies and validates with
+// fixed options, for callers that process many shaders or the same shader
+// many times, such as an editor validating on every keystroke.
+//
+// The options JSON is parsed once; it is the one of
+// miniray_minify_and_validate. The context computes the reserved names
+// once and caches minified results and parsed modules of recent sources by
+// content hash. A context can be used from several threads at once.
+//
+// Parameters:
+//   - options_json: pointer to JSON options (can be NULL for defaults)
+//   - options_len: length of options JSON
+//   - out_ctx: pointer to receive the context (free with miniray_context_free)
+//
+// Returns:
+//   - 0 on success
+//   - non-zero error code on failure
+//
+//export miniray_context_new
+func miniray_context_new(options_json *C.char, options_len C.int, out_ctx *C.miniray_context) C.int {
+	if out_ctx == nil {
+		return MINIRAY_ERR_NULL_INPUT
 	}
 
-	// Parse the source
-	p := parser.New(goSource)
-	module, parseErrors := p.Parse()
-
-	// Convert diagnostic filters
-	var filters *diagnostic.DiagnosticFilter
-	if len(opts.DiagnosticFilters) > 0 {
-		filters = diagnostic.NewDiagnosticFilter()
-		for rule, severity := range opts.DiagnosticFilters {
-			switch severity {
-			case "off":
-				filters.DisableRule(rule)
-			case "error":
-				filters.SetRule(rule, diagnostic.Error)
-			case "warning":
-				filters.SetRule(rule, diagnostic.Warning)
-			case "info":
-				filters.SetRule(rule, diagnostic.Info)
-			}
-		}
+	ctx, err := service.NewContext(goOptions(options_json, options_len))
+	if err != nil {
+		return MINIRAY_ERR_JSON_DECODE
 	}
+	*out_ctx = C.miniray_context(registerContext(ctx))
+	return MINIRAY_OK
+}
 
-	// Initialize result
-	result := ValidateResult{
-		Valid:       true,
-		Diagnostics: make([]DiagnosticInfo, 0),
+// miniray_context_minify minifies WGSL source code with the options of the
+// context. The outputs are the ones of miniray_minify.
+//
+// Returns:
+//   - 0 on success
+//   - MINIRAY_ERR_INVALID_CONTEXT for an unknown or freed context
+//   - another non-zero error code on failure
+//
+//export miniray_context_minify
+func miniray_context_minify(
+	ctx C.miniray_context,
+	source *C.char, source_len C.int,
+	out_code **C.char, out_code_len *C.int,
+	out_json **C.char, out_json_len *C.int,
+) C.int {
+	if source == nil || out_code == nil || out_code_len == nil {
+		return MINIRAY_ERR_NULL_INPUT
 	}
+	c := lookupContext(uintptr(ctx))
+	if c == nil {
+		return MINIRAY_ERR_INVALID_CONTEXT
+	}
+
+	result := c.Minify(C.GoStringN(source, source_len))
+
+	*out_code = C.CString(result.Code)
+	*out_code_len = C.int(len(result.Code))
 
-	// Add parse errors
-	for _, e := range parseErrors {
-		result.Diagnostics = append(result.Diagnostics, DiagnosticInfo{
-			Severity: "error",
-			Code:     "E0001",
-			Message:  e.Message,
-			Line:     e.Line,
-			Column:   e.Column,
-		})
-		result.ErrorCount++
-		result.Valid = false
+	if out_json != nil && out_json_len != nil {
+		return setJSON(result, out_json, out_json_len)
 	}
+	return MINIRAY_OK
+}
 
-	// If parsing succeeded, run semantic validation
-	if len(parseErrors) == 0 {
-		validatorResult := validator.Validate(module, validator.Options{
-			StrictMode:        opts.StrictMode,
-			DiagnosticFilters: filters,
-		})
-
-		// Convert diagnostics
-		for _, d := range validatorResult.Diagnostics.Diagnostics() {
-			severity := "error"
-			switch d.Severity {
-			case diagnostic.Error:
-				severity = "error"
-				result.ErrorCount++
-			case diagnostic.Warning:
-				severity = "warning"
-				result.WarningCount++
-			case diagnostic.Info:
-				severity = "info"
-			case diagnostic.Note:
-				severity = "note"
-			}
-
-			diag := DiagnosticInfo{
-				Severity: severity,
-				Code:     d.Code,
-				Message:  d.Message,
-				Line:     d.Range.Start.Line,
-				Column:   d.Range.Start.Column,
-			}
-			if d.Range.End.Line > 0 {
-				diag.EndLine = d.Range.End.Line
-				diag.EndColumn = d.Range.End.Column
-			}
-			if d.SpecRef != "" {
-				diag.SpecRef = d.SpecRef
-			}
-			result.Diagnostics = append(result.Diagnostics, diag)
-		}
-
-		result.Valid = !validatorResult.Diagnostics.HasErrors()
+// miniray_context_minify_and_validate minifies WGSL and validates the
+// source with the options of the context. The outputs are the ones of
+// miniray_minify_and_validate.
+//
+// Returns:
+//   - 0 on success
+//   - MINIRAY_ERR_INVALID_CONTEXT for an unknown or freed context
+//   - another non-zero error code on failure
+//
+//export miniray_context_minify_and_validate
+func miniray_context_minify_and_validate(
+	ctx C.miniray_context,
+	source *C.char, source_len C.int,
+	out_code **C.char, out_code_len *C.int,
+	out_json **C.char, out_json_len *C.int,
+) C.int {
+	if source == nil || out_code == nil || out_code_len == nil || out_json == nil || out_json_len == nil {
+		return MINIRAY_ERR_NULL_INPUT
+	}
+	c := lookupContext(uintptr(ctx))
+	if c == nil {
+		return MINIRAY_ERR_INVALID_CONTEXT
 	}
 
-	// Encode result as JSON
-	jsonBytes, err := json.Marshal(result)
-	if err != nil {
-		return MINIRAY_ERR_JSON_ENCODE
+	result := c.MinifyAndValidate(C.GoStringN(source, source_len))
+
+	*out_code = C.CString(result.Code)
+	*out_code_len = C.int(len(result.Code))
+
+	return setJSON(result, out_json, out_json_len)
+}
+
+// miniray_context_validate validates WGSL source code with the "validate"
+// options, "lint" severities and "defines" of the context. The result JSON
+// is the one of miniray_validate.
+//
+// Returns:
+//   - 0 on success
+//   - MINIRAY_ERR_INVALID_CONTEXT for an unknown or freed context
+//   - another non-zero error code on failure
+//
+//export miniray_context_validate
+func miniray_context_validate(
+	ctx C.miniray_context,
+	source *C.char, source_len C.int,
+	out_json **C.char, out_json_len *C.int,
+) C.int {
+	if source == nil || out_json == nil || out_json_len == nil {
+		return MINIRAY_ERR_NULL_INPUT
+	}
+	c := lookupContext(uintptr(ctx))
+	if c == nil {
+		return MINIRAY_ERR_INVALID_CONTEXT
 	}
-	*out_json = C.CString(string(jsonBytes))
-	*out_json_len = C.int(len(jsonBytes))
 
-	return MINIRAY_OK
+	return setJSON(c.Validate(C.GoStringN(source, source_len)), out_json, out_json_len)
+}
+
+// miniray_context_free frees a context. Calls still running on other
+// threads finish normally; freeing an unknown or freed context does
+// nothing.
+//
+//export miniray_context_free
+func miniray_context_free(ctx C.miniray_context) {
+	releaseContext(uintptr(ctx))
 }
 
 // Required for c-archive build mode
@@ -0,0 +1,74 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/HugoDaniel/miniray/internal/compiler"
+	"github.com/HugoDaniel/miniray/pkg/api"
+)
+
+// createCompilerJS is the JavaScript-callable createCompiler function. The
+// options are the ones of minify, parsed once; the compiler computes the
+// reserved names once, caches the results and parsed modules of recent
+// sources, so repeated calls with unchanged sources are cheap, and
+// validates incrementally.
+// Signature: __miniray.createCompiler(options?: object) => {
+//
+//	minify(source: string) => object,
+//	validate(source: string, options?: object) => object,
+//	free() => void,
+//
+// }
+func createCompilerJS(this js.Value, args []js.Value) interface{} {
+	var options js.Value
+	if len(args) > 0 {
+		options = args[0]
+	}
+	c := compiler.New(minifierOptions(options), 0)
+
+	var minify, validate, free js.Func
+	minify = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) < 1 {
+			return makeError("minify requires 1 argument (source)")
+		}
+		return minifyResultJS(c.Minify(args[0].String()))
+	})
+	validate = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if len(args) < 1 {
+			return makeValidateError("validate requires at least 1 argument (source)")
+		}
+		var opts jsValidateOptions
+		if len(args) > 1 && !args[1].IsUndefined() && !args[1].IsNull() {
+			jsonStr := js.Global().Get("JSON").Call("stringify", args[1]).String()
+			json.Unmarshal([]byte(jsonStr), &opts)
+		}
+		// A compiler object validates the versions of one document
+		result := c.ValidateDocument("", args[0].String(), api.ValidateOptions{
+			StrictMode:        opts.StrictMode != nil && *opts.StrictMode,
+			DiagnosticFilters: opts.DiagnosticFilters,
+			Lint:              opts.Lint != nil && *opts.Lint,
+			Stats:             opts.Stats != nil && *opts.Stats,
+		})
+		data, err := json.Marshal(result)
+		if err != nil {
+			return makeValidateError(err.Error())
+		}
+		return js.Global().Get("JSON").Call("parse", string(data))
+	})
+	// free releases the functions; the compiler is unusable afterwards
+	free = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		minify.Release()
+		validate.Release()
+		free.Release()
+		return nil
+	})
+
+	return map[string]interface{}{
+		"minify":   minify,
+		"validate": validate,
+		"free":     free,
+	}
+}
@@ -28,15 +28,17 @@ type jsOptions struct {
 	KeepNames                  []string `json:"keepNames"`
 	SourceMap                  *bool    `json:"sourceMap"`
 	SourceMapSources           *bool    `json:"sourceMapSources"`
+	SourceMapScopes            *bool    `json:"sourceMapScopes"`
 }
 
 func main() {
 	// Export functions to JavaScript
 	js.Global().Set("__miniray", js.ValueOf(map[string]interface{}{
-		"minify":   js.FuncOf(minifyJS),
-		"reflect":  js.FuncOf(reflectJS),
-		"validate": js.FuncOf(validateJS),
-		"version":  version,
+		"minify":         js.FuncOf(minifyJS),
+		"reflect":        js.FuncOf(reflectJS),
+		"validate":       js.FuncOf(validateJS),
+		"createCompiler": js.FuncOf(createCompilerJS),
+		"version":        version,
 	}))
 
 	// Keep the Go runtime alive
@@ -51,8 +53,19 @@ func minifyJS(this js.Value, args []js.Value) interface{} {
 	}
 
 	source := args[0].String()
+	var options js.Value
+	if len(args) > 1 {
+		options = args[1]
+	}
+
+	// Run minification
+	m := minifier.New(minifierOptions(options))
+	return minifyResultJS(m.Minify(source))
+}
 
-	// Parse options (default to full minification)
+// minifierOptions converts a JS options object, which may be undefined,
+// to minifier options. Omitted options default to full minification.
+func minifierOptions(jsVal js.Value) minifier.Options {
 	opts := minifier.Options{
 		MinifyWhitespace:       true,
 		MinifyIdentifiers:      true,
@@ -61,41 +74,45 @@ func minifyJS(this js.Value, args []js.Value) interface{} {
 		TreeShaking:            true,
 	}
 
-	if len(args) > 1 && !args[1].IsUndefined() && !args[1].IsNull() {
-		jsOpts := parseOptions(args[1])
-		if jsOpts.MinifyWhitespace != nil {
-			opts.MinifyWhitespace = *jsOpts.MinifyWhitespace
-		}
-		if jsOpts.MinifyIdentifiers != nil {
-			opts.MinifyIdentifiers = *jsOpts.MinifyIdentifiers
-		}
-		if jsOpts.MinifySyntax != nil {
-			opts.MinifySyntax = *jsOpts.MinifySyntax
-		}
-		if jsOpts.MangleExternalBindings != nil {
-			opts.MangleExternalBindings = *jsOpts.MangleExternalBindings
-		}
-		if jsOpts.TreeShaking != nil {
-			opts.TreeShaking = *jsOpts.TreeShaking
-		}
-		if jsOpts.PreserveUniformStructTypes != nil {
-			opts.PreserveUniformStructTypes = *jsOpts.PreserveUniformStructTypes
-		}
-		if jsOpts.KeepNames != nil {
-			opts.KeepNames = jsOpts.KeepNames
-		}
-		if jsOpts.SourceMap != nil {
-			opts.GenerateSourceMap = *jsOpts.SourceMap
-		}
-		if jsOpts.SourceMapSources != nil {
-			opts.SourceMapOptions.IncludeSource = *jsOpts.SourceMapSources
-		}
+	if jsVal.IsUndefined() || jsVal.IsNull() {
+		return opts
 	}
+	jsOpts := parseOptions(jsVal)
+	if jsOpts.MinifyWhitespace != nil {
+		opts.MinifyWhitespace = *jsOpts.MinifyWhitespace
+	}
+	if jsOpts.MinifyIdentifiers != nil {
+		opts.MinifyIdentifiers = *jsOpts.MinifyIdentifiers
+	}
+	if jsOpts.MinifySyntax != nil {
+		opts.MinifySyntax = *jsOpts.MinifySyntax
+	}
+	if jsOpts.MangleExternalBindings != nil {
+		opts.MangleExternalBindings = *jsOpts.MangleExternalBindings
+	}
+	if jsOpts.TreeShaking != nil {
+		opts.TreeShaking = *jsOpts.TreeShaking
+	}
+	if jsOpts.PreserveUniformStructTypes != nil {
+		opts.PreserveUniformStructTypes = *jsOpts.PreserveUniformStructTypes
+	}
+	if jsOpts.KeepNames != nil {
+		opts.KeepNames = jsOpts.KeepNames
+	}
+	if jsOpts.SourceMap != nil {
+		opts.GenerateSourceMap = *jsOpts.SourceMap
+	}
+	if jsOpts.SourceMapSources != nil {
+		opts.SourceMapOptions.IncludeSource = *jsOpts.SourceMapSources
+	}
+	if jsOpts.SourceMapScopes != nil {
+		opts.SourceMapOptions.Scopes = *jsOpts.SourceMapScopes
+	}
+	return opts
+}
 
-	// Run minification
-	m := minifier.New(opts)
-	result := m.Minify(source)
-
+// minifyResultJS converts a minification result to a JS object.
+func minifyResultJS(result minifier.Result) interface{} {
 	// Convert errors to JS array
 	errors := make([]interface{}, len(result.Errors))
 	for i, e := range result.Errors {
@@ -172,6 +189,10 @@ func parseOptions(jsVal js.Value) jsOptions {
 		b := v.Bool()
 		opts.SourceMapSources = &b
 	}
+	if v := jsVal.Get("sourceMapScopes"); !v.IsUndefined() {
+		b := v.Bool()
+		opts.SourceMapScopes = &b
+	}
 
 	return opts
 }
@@ -320,6 +341,11 @@ func convertEntryPointsToJS(entryPoints []reflect.EntryPointInfo) []interface{}
 type jsValidateOptions struct {
 	StrictMode        *bool             `json:"strictMode"`
 	DiagnosticFilters map[string]string `json:"diagnosticFilters"`
+
+	// Lint and Stats are only supported by compiler objects (see
+	// createCompilerJS)
+	Lint  *bool `json:"lint"`
+	Stats *bool `json:"stats"`
 }
 
 // validateJS is the JavaScript-callable validate function.
@@ -369,11 +395,13 @@ func validateJS(this js.Value, args []js.Value) interface{} {
 	// Add parse errors
 	for _, e := range parseErrors {
 		diagnostics = append(diagnostics, map[string]interface{}{
-			"severity": "error",
-			"code":     "E0001",
-			"message":  e.Message,
-			"line":     e.Line,
-			"column":   e.Column,
+			"severity":  "error",
+			"code":      "E0001",
+			"message":   e.Message,
+			"line":      e.Line,
+			"column":    e.Column,
+			"endLine":   e.EndLine,
+			"endColumn": e.EndColumn,
 		})
 		errorCount++
 		valid = false
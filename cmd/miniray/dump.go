@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/dump"
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/preprocess"
+	"github.com/HugoDaniel/miniray/internal/validator"
+)
+
+// runAST handles the "ast" subcommand.
+func runAST(args []string) error {
+	return runDump("ast", args, func(source string, defines map[string]string) (interface{ Outline() string }, error) {
+		pre, module, errs, err := parseForDump(source, defines)
+		if err != nil {
+			return nil, err
+		}
+		return dump.Module(module, errs, pre), nil
+	})
+}
+
+// runTypes handles the "types" subcommand.
+func runTypes(args []string) error {
+	return runDump("types", args, func(source string, defines map[string]string) (interface{ Outline() string }, error) {
+		pre, module, errs, err := parseForDump(source, defines)
+		if err != nil {
+			return nil, err
+		}
+		if len(errs) > 0 {
+			for _, e := range errs {
+				line, col := pre.Position(e.Pos)
+				fmt.Fprintf(os.Stderr, "%d:%d: error: %s\n", line, col, e.Message)
+			}
+			return nil, fmt.Errorf("parsing failed with %d error(s)", len(errs))
+		}
+		return dump.TypeInfo(module, validator.Validate(module, validator.Options{}), pre), nil
+	})
+}
+
+// parseForDump preprocesses and parses source. Preprocessor errors are
+// printed and returned as an error.
+func parseForDump(source string, defines map[string]string) (*preprocess.Result, *ast.Module, []parser.ParseError, error) {
+	pre := preprocess.Process(source, defines)
+	if len(pre.Errors) > 0 {
+		for _, e := range pre.Errors {
+			fmt.Fprintf(os.Stderr, "%d:%d: error: %s\n", e.Line, e.Column, e.Message)
+		}
+		return nil, nil, nil, fmt.Errorf("preprocessing failed with %d error(s)", len(pre.Errors))
+	}
+	module, errs := parser.New(pre.Code).Parse()
+	return pre, module, errs, nil
+}
+
+// runDump reads the input of a dump subcommand and writes the document
+// that convert makes of it, as JSON or as an outline.
+func runDump(name string, args []string, convert func(source string, defines map[string]string) (interface{ Outline() string }, error)) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	var (
+		outputFile  string
+		asJSON      bool
+		compact     bool
+		showHelp    bool
+		showVersion bool
+	)
+	defines := make(defineFlags)
+
+	fs.StringVar(&outputFile, "o", "", "Write output to `file` (default: stdout)")
+	fs.BoolVar(&asJSON, "json", false, "Output JSON (default: an indented outline)")
+	fs.BoolVar(&compact, "compact", false, "Output compact JSON (default: pretty-printed)")
+	fs.Var(defines, "D", "Define preprocessor `NAME[=VALUE]` for #ifdef/#if (repeatable)")
+	fs.BoolVar(&showHelp, "help", false, "Print help and exit")
+	fs.BoolVar(&showVersion, "version", false, "Print version and exit")
+
+	fs.Usage = func() {
+		if name == "ast" {
+			fmt.Fprintf(os.Stderr, "miniray ast - WGSL Syntax Tree Dump v%s\n\n", version)
+			fmt.Fprintf(os.Stderr, "Print the parsed module: directives, declarations, statements, expressions,\n")
+			fmt.Fprintf(os.Stderr, "attributes, the symbol table and the scope tree.\n\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "miniray types - WGSL Type Dump v%s\n\n", version)
+			fmt.Fprintf(os.Stderr, "Print the type the validator resolved for each expression and declared\n")
+			fmt.Fprintf(os.Stderr, "symbol, by source range, with the validation diagnostics.\n\n")
+		}
+		fmt.Fprintf(os.Stderr, "Usage: miniray %s [options] <input.wgsl>\n", name)
+		fmt.Fprintf(os.Stderr, "       cat input.wgsl | miniray %s [options]\n\n", name)
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nThe JSON schema (version %d) is described in docs/json-dump.md.\n", dump.Version)
+		fmt.Fprintf(os.Stderr, "Ranges are positions in the input before preprocessing.\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  miniray %s shader.wgsl\n", name)
+		fmt.Fprintf(os.Stderr, "  miniray %s --json shader.wgsl -o %s.json\n", name, name)
+		fmt.Fprintf(os.Stderr, "  miniray %s --json --compact -D SHADOWS shader.wgsl | jq .version\n", name)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if showHelp {
+		fs.Usage()
+		return nil
+	}
+
+	if showVersion {
+		fmt.Printf("miniray %s v%s (%s)\n", name, version, commit)
+		return nil
+	}
+
+	// Read input
+	var source []byte
+	var err error
+
+	if fs.NArg() > 0 {
+		source, err = os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("reading input: %w", err)
+		}
+	} else {
+		// Check if stdin is a pipe
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeCharDevice) != 0 {
+			fs.Usage()
+			return fmt.Errorf("no input file specified")
+		}
+		source, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+	}
+
+	doc, err := convert(string(source), defines)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	switch {
+	case !asJSON:
+		out = []byte(doc.Outline())
+	case compact:
+		out, err = json.Marshal(doc)
+		out = append(out, '\n')
+	default:
+		out, err = json.MarshalIndent(doc, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, out, 0644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		return nil
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+	"github.com/HugoDaniel/miniray/internal/reflect"
+	"github.com/HugoDaniel/miniray/pkg/api"
+)
+
+var entryPlaceholder = regexp.MustCompile(`\[[^\]]*\]`)
+
+// expandEntryName fills in an --entry-names template such as
+// "[name].[hash].wgsl". [name] is the input file name without its
+// extension and [hash] the content hash of the minified code.
+func expandEntryName(template, name, hash string) (string, error) {
+	var err error
+	expanded := entryPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case "[name]":
+			return name
+		case "[hash]":
+			return hash
+		}
+		if err == nil {
+			err = fmt.Errorf("unknown placeholder %s in --entry-names %q (use [name] and [hash])", placeholder, template)
+		}
+		return placeholder
+	})
+	if err != nil {
+		return "", err
+	}
+	if filepath.IsAbs(expanded) || strings.HasPrefix(filepath.Clean(expanded), "..") {
+		return "", fmt.Errorf("--entry-names %q must stay inside the output directory", template)
+	}
+	return expanded, nil
+}
+
+// manifest is the format of the --manifest file:
+//
+//	{
+//	    "version": 1,
+//	    "entries": {
+//	        "src/shader.wgsl": {
+//	            "output": "shader.1a2b3c4d.wgsl",
+//	            "hash": "1a2b3c4d",
+//	            "originalSize": 2048,
+//	            "minifiedSize": 812,
+//	            "sourceMap": "shader.1a2b3c4d.wgsl.map",
+//	            "reflect": {"entryPoints": [...], "bindings": [...]}
+//	        }
+//	    }
+//	}
+//
+// Entries are keyed by input path, as given on the command line. Output
+// and source map paths are relative to the manifest's directory. All paths
+// use forward slashes, so the manifest is the same on every machine.
+type manifest struct {
+	Version int                       `json:"version"`
+	Entries map[string]*manifestEntry `json:"entries"`
+
+	path string
+}
+
+type manifestEntry struct {
+	Output       string             `json:"output"`
+	Hash         string             `json:"hash"`
+	OriginalSize int                `json:"originalSize"`
+	MinifiedSize int                `json:"minifiedSize"`
+	SourceMap    string             `json:"sourceMap,omitempty"`
+	Reflect      manifestReflection `json:"reflect"`
+}
+
+// manifestReflection summarizes the reflection of an output; the full
+// layouts are available from "miniray reflect".
+type manifestReflection struct {
+	EntryPoints []reflect.EntryPointInfo `json:"entryPoints"`
+	Bindings    []manifestBinding        `json:"bindings"`
+	Overrides   []string                 `json:"overrides,omitempty"`
+}
+
+type manifestBinding struct {
+	Group        int    `json:"group"`
+	Binding      int    `json:"binding"`
+	Name         string `json:"name"`
+	NameMapped   string `json:"nameMapped"`
+	AddressSpace string `json:"addressSpace"`
+	Type         string `json:"type"`
+}
+
+func newManifest(path string) *manifest {
+	return &manifest{Version: 1, Entries: make(map[string]*manifestEntry), path: path}
+}
+
+// add records the output of input. mapFile is empty without a source map.
+func (m *manifest) add(input, output, mapFile, hash string, stats minifier.Stats, info reflect.ReflectResult) {
+	entry := &manifestEntry{
+		Output:       m.relative(output),
+		Hash:         hash,
+		OriginalSize: stats.OriginalSize,
+		MinifiedSize: stats.MinifiedSize,
+		Reflect: manifestReflection{
+			EntryPoints: info.EntryPoints,
+			Bindings:    []manifestBinding{},
+		},
+	}
+	if entry.Reflect.EntryPoints == nil {
+		entry.Reflect.EntryPoints = []reflect.EntryPointInfo{}
+	}
+	if mapFile != "" {
+		entry.SourceMap = m.relative(mapFile)
+	}
+	for _, b := range info.Bindings {
+		entry.Reflect.Bindings = append(entry.Reflect.Bindings, manifestBinding{
+			Group:        b.Group,
+			Binding:      b.Binding,
+			Name:         b.Name,
+			NameMapped:   b.NameMapped,
+			AddressSpace: b.AddressSpace,
+			Type:         b.Type,
+		})
+	}
+	for _, o := range info.Overrides {
+		if !o.Specialized {
+			entry.Reflect.Overrides = append(entry.Reflect.Overrides, o.Name)
+		}
+	}
+	m.Entries[filepath.ToSlash(filepath.Clean(input))] = entry
+}
+
+// relative returns path relative to the manifest's directory.
+func (m *manifest) relative(path string) string {
+	if path == "" {
+		return path
+	}
+	dir, errDir := filepath.Abs(filepath.Dir(m.path))
+	abs, errPath := filepath.Abs(path)
+	if errDir == nil && errPath == nil {
+		if rel, err := filepath.Rel(dir, abs); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+func (m *manifest) write() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("creating manifest directory: %w", err)
+	}
+	if err := os.WriteFile(m.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	return nil
+}
+
+// runEntries minifies every input into outDir, naming each output after
+// the --entry-names template, and records them in the manifest if one is
+// given. Source maps are written next to their output.
+func runEntries(opts minifier.Options, inputs []string, template, outDir string, inlineMap bool, mf *manifest) error {
+	if outDir == "" {
+		outDir = "."
+	}
+
+	written := make(map[string]string)
+	failed := 0
+	for _, input := range inputs {
+		source, err := os.ReadFile(input)
+		if err != nil {
+			return fmt.Errorf("reading input: %w", err)
+		}
+
+		inputOpts := opts
+		if inputOpts.GenerateSourceMap {
+			inputOpts.SourceMapOptions.SourceName = filepath.Base(input)
+		}
+		result := minifier.New(inputOpts).MinifyAndReflect(string(source))
+		if len(result.Errors) > 0 {
+			for _, e := range result.Errors {
+				fmt.Fprintf(os.Stderr, "error: %s: %s\n", input, formatError(e))
+			}
+			failed++
+			continue
+		}
+
+		hash := api.ContentHash(result.Code)
+		name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+		rel, err := expandEntryName(template, name, hash)
+		if err != nil {
+			return err
+		}
+		codeFile := filepath.Join(outDir, rel)
+		if other, ok := written[codeFile]; ok {
+			return fmt.Errorf("%s and %s both write %s (add [hash] or a directory to --entry-names)", other, input, codeFile)
+		}
+		written[codeFile] = input
+		if err := os.MkdirAll(filepath.Dir(codeFile), 0755); err != nil {
+			return fmt.Errorf("creating output directory: %w", err)
+		}
+
+		code := result.Code
+		mapFile := ""
+		if result.SourceMap != nil {
+			// The name is only known once the code is hashed
+			result.SourceMap.File = filepath.Base(codeFile)
+			if inlineMap {
+				code += "\n//# sourceMappingURL=" + result.SourceMap.ToDataURI()
+			} else {
+				mapFile = codeFile + ".map"
+				if err := os.WriteFile(mapFile, []byte(result.SourceMap.ToJSON()), 0644); err != nil {
+					return fmt.Errorf("writing source map: %w", err)
+				}
+				code += "\n//# sourceMappingURL=" + filepath.Base(mapFile)
+			}
+		}
+		if err := os.WriteFile(codeFile, []byte(code), 0644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d -> %d bytes\n", codeFile, result.Stats.OriginalSize, result.Stats.MinifiedSize)
+
+		if mf != nil {
+			mf.add(input, codeFile, mapFile, hash, result.Stats, result.Reflect)
+		}
+	}
+
+	if mf != nil {
+		if err := mf.write(); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d input(s) failed", failed, len(inputs))
+	}
+	return nil
+}
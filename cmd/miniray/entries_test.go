@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandEntryName(t *testing.T) {
+	tests := []struct {
+		template string
+		expected string
+		err      string
+	}{
+		{template: "[name].[hash].wgsl", expected: "shader.1a2b3c4d.wgsl"},
+		{template: "[name].wgsl", expected: "shader.wgsl"},
+		{template: "gpu/[hash]/[name].wgsl", expected: "gpu/1a2b3c4d/shader.wgsl"},
+		{template: "a/../[name].wgsl", expected: "a/../shader.wgsl"},
+		{template: "[name].[ext]", err: "unknown placeholder [ext]"},
+		{template: "[Name].wgsl", err: "unknown placeholder [Name]"},
+		{template: "../[name].wgsl", err: "must stay inside the output directory"},
+		{template: "a/../../[name].wgsl", err: "must stay inside the output directory"},
+		{template: "/tmp/[name].wgsl", err: "must stay inside the output directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			got, err := expandEntryName(tt.template, "shader", "1a2b3c4d")
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %q, %v", tt.err, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestManifestRelative(t *testing.T) {
+	dir := t.TempDir()
+	m := newManifest(filepath.Join(dir, "build", "manifest.json"))
+
+	tests := map[string]string{
+		"":                                    "",
+		filepath.Join(dir, "build", "a.wgsl"): "a.wgsl",
+		filepath.Join(dir, "build", "gpu", "b.wgsl"): "gpu/b.wgsl",
+		filepath.Join(dir, "dist", "c.wgsl"):         "../dist/c.wgsl",
+	}
+	for path, expected := range tests {
+		if got := m.relative(path); got != expected {
+			t.Errorf("relative(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+func TestManifestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out", "manifest.json")
+	m := newManifest(path)
+	m.Entries["src/shader.wgsl"] = &manifestEntry{
+		Output:    "shader.1a2b3c4d.wgsl",
+		Hash:      "1a2b3c4d",
+		SourceMap: "shader.1a2b3c4d.wgsl.map",
+	}
+	if err := m.write(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "}\n") {
+		t.Errorf("manifest should end with a newline: %q", data)
+	}
+	var got manifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v", err)
+	}
+	if got.Version != 1 {
+		t.Errorf("expected version 1, got %d", got.Version)
+	}
+	entry := got.Entries["src/shader.wgsl"]
+	if entry == nil || entry.Output != "shader.1a2b3c4d.wgsl" || entry.SourceMap != "shader.1a2b3c4d.wgsl.map" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if strings.Contains(string(data), `"path"`) {
+		t.Errorf("the manifest's own path should not be written: %s", data)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/config"
+	"github.com/HugoDaniel/miniray/internal/gogen"
+	"github.com/HugoDaniel/miniray/internal/minifier"
+)
+
+// runGogen handles the "gogen" subcommand.
+func runGogen(args []string) error {
+	fs := flag.NewFlagSet("gogen", flag.ExitOnError)
+
+	var (
+		pkg         string
+		outputFile  string
+		configFile  string
+		noConfig    bool
+		showHelp    bool
+		showVersion bool
+	)
+	defines := make(defineFlags)
+
+	fs.StringVar(&pkg, "pkg", os.Getenv("GOPACKAGE"), "Go `package` name (default: $GOPACKAGE, set by go generate)")
+	fs.StringVar(&outputFile, "o", "", "Write Go source to `file` (default: stdout)")
+	fs.StringVar(&configFile, "config", "", "Use specific config `file` for minification options")
+	fs.BoolVar(&noConfig, "no-config", false, "Ignore config files")
+	fs.Var(defines, "D", "Define preprocessor `NAME[=VALUE]` for #ifdef/#if (repeatable)")
+	fs.BoolVar(&showHelp, "help", false, "Print help and exit")
+	fs.BoolVar(&showVersion, "version", false, "Print version and exit")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "miniray gogen - Generate Go Source for WGSL Shaders v%s\n\n", version)
+		fmt.Fprintf(os.Stderr, "Write a Go file with each minified shader as a string constant, Go structs\n")
+		fmt.Fprintf(os.Stderr, "matching its uniform and storage buffer layouts, binding index constants\n")
+		fmt.Fprintf(os.Stderr, "and entry point name constants.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: miniray gogen [options] <input.wgsl>...\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nNames are prefixed with the input file name: blur.wgsl gives BlurWGSL,\n")
+		fmt.Fprintf(os.Stderr, "BlurEntryMain, BlurParamsGroup, BlurParamsBinding and struct BlurParams.\n")
+		fmt.Fprintf(os.Stderr, "Struct fields are padded to WGSL offsets; vectors and matrices are arrays.\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  miniray gogen -pkg shaders -o shaders_gen.go *.wgsl\n")
+		fmt.Fprintf(os.Stderr, "  //go:generate miniray gogen -o shaders_gen.go blur.wgsl sky.wgsl\n")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if showHelp {
+		fs.Usage()
+		return nil
+	}
+
+	if showVersion {
+		fmt.Printf("miniray gogen v%s (%s)\n", version, commit)
+		return nil
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no input files specified")
+	}
+	if pkg == "" {
+		return fmt.Errorf("no package name specified (use -pkg)")
+	}
+
+	// Load config file
+	opts := minifier.DefaultOptions()
+	if !noConfig {
+		var cfg *config.Config
+		var err error
+		if configFile != "" {
+			cfg, err = config.LoadFile(configFile)
+			if err != nil {
+				return fmt.Errorf("loading config file %s: %w", configFile, err)
+			}
+		} else {
+			cfg, _, err = config.Load(filepath.Dir(fs.Arg(0)))
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+		}
+		if cfg != nil {
+			opts = cfg.ToOptions()
+		}
+	}
+	if len(defines) > 0 {
+		opts.Defines = defines
+	}
+
+	var shaders []gogen.Shader
+	for _, input := range fs.Args() {
+		source, err := os.ReadFile(input)
+		if err != nil {
+			return fmt.Errorf("reading input: %w", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+		shaders = append(shaders, gogen.Shader{
+			Name:   gogen.Identifier(name),
+			File:   filepath.ToSlash(input),
+			Source: string(source),
+		})
+	}
+
+	code, errs := gogen.Generate(gogen.Options{Package: pkg, Minify: opts}, shaders)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+		return fmt.Errorf("code generation failed with %d error(s)", len(errs))
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, code, 0644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		return nil
+	}
+	_, err := os.Stdout.Write(code)
+	return err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/HugoDaniel/miniray/internal/depgraph"
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/preprocess"
+)
+
+// runGraph handles the "graph" subcommand.
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+
+	var (
+		outputFile  string
+		format      string
+		showHelp    bool
+		showVersion bool
+	)
+	defines := make(defineFlags)
+
+	fs.StringVar(&outputFile, "o", "", "Write output to `file` (default: stdout)")
+	fs.StringVar(&format, "format", "json", "Output `format`: json, dot or mermaid")
+	fs.Var(defines, "D", "Define preprocessor `NAME[=VALUE]` for #ifdef/#if (repeatable)")
+	fs.BoolVar(&showHelp, "help", false, "Print help and exit")
+	fs.BoolVar(&showVersion, "version", false, "Print version and exit")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "miniray graph - WGSL Dependency Graph v%s\n\n", version)
+		fmt.Fprintf(os.Stderr, "Print the call graph and type dependency graph of the module-scope\n")
+		fmt.Fprintf(os.Stderr, "declarations, with entry points, dead declarations, dependency cycles\n")
+		fmt.Fprintf(os.Stderr, "and the declarations each entry point reaches.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: miniray graph [options] <input.wgsl>\n")
+		fmt.Fprintf(os.Stderr, "       cat input.wgsl | miniray graph [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nIn dot and mermaid output, calls are solid edges and uses of types,\n")
+		fmt.Fprintf(os.Stderr, "constants and variables dashed. Dead declarations are gray and dashed,\n")
+		fmt.Fprintf(os.Stderr, "cycles red. Without entry points no declaration is dead.\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  miniray graph shader.wgsl | jq '.entryPoints'\n")
+		fmt.Fprintf(os.Stderr, "  miniray graph --format=dot shader.wgsl | dot -Tsvg -o graph.svg\n")
+		fmt.Fprintf(os.Stderr, "  miniray graph --format=mermaid lib.wgsl\n")
+	}
+
+	inputs, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if showHelp {
+		fs.Usage()
+		return nil
+	}
+
+	if showVersion {
+		fmt.Printf("miniray graph v%s (%s)\n", version, commit)
+		return nil
+	}
+
+	if format != "json" && format != "dot" && format != "mermaid" {
+		return fmt.Errorf("unknown format %q (use json, dot or mermaid)", format)
+	}
+	if len(inputs) > 1 {
+		return fmt.Errorf("expected one input file, got %d", len(inputs))
+	}
+
+	// Read input
+	var source []byte
+	if len(inputs) > 0 {
+		source, err = os.ReadFile(inputs[0])
+		if err != nil {
+			return fmt.Errorf("reading input: %w", err)
+		}
+	} else {
+		// Check if stdin is a pipe
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeCharDevice) != 0 {
+			fs.Usage()
+			return fmt.Errorf("no input file specified")
+		}
+		source, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+	}
+
+	pre := preprocess.Process(string(source), defines)
+	if len(pre.Errors) > 0 {
+		for _, e := range pre.Errors {
+			fmt.Fprintf(os.Stderr, "%d:%d: error: %s\n", e.Line, e.Column, e.Message)
+		}
+		return fmt.Errorf("preprocessing failed with %d error(s)", len(pre.Errors))
+	}
+	module, errs := parser.New(pre.Code).Parse()
+	if len(errs) > 0 {
+		for _, e := range errs {
+			line, col := pre.Position(e.Pos)
+			fmt.Fprintf(os.Stderr, "%d:%d: error: %s\n", line, col, e.Message)
+		}
+		return fmt.Errorf("parsing failed with %d error(s)", len(errs))
+	}
+
+	// Preprocessing keeps lines where they are, so node lines hold for
+	// the input
+	graph := depgraph.Build(module)
+
+	var out []byte
+	switch format {
+	case "dot":
+		out = []byte(graph.DOT())
+	case "mermaid":
+		out = []byte(graph.Mermaid())
+	default:
+		out, err = json.MarshalIndent(graph, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding JSON: %w", err)
+		}
+		out = append(out, '\n')
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, out, 0644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		return nil
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// parseInterspersed parses flags that may follow the positional arguments,
+// as in "miniray graph shader.wgsl --format=dot", and returns the
+// positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
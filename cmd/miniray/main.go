@@ -21,6 +21,18 @@
 //	--source-map               Generate source map file (.map)
 //	--source-map-inline        Embed source map as inline data URI
 //	--source-map-sources       Include original source in source map
+//	--source-map-scopes        Describe scopes and declared names in the
+//	                           source map (x_miniray_scopes)
+//	-D NAME[=VALUE]            Define a preprocessor name (repeatable)
+//	--override NAME=VALUE      Bake an override (by name or @id) into a
+//	                           constant (repeatable)
+//	--permutations <file>      Build every variant listed in a JSON file
+//	                           into the directory given by -o
+//	--entry-names <template>   Name outputs in the -o directory after a
+//	                           template such as "[name].[hash].wgsl"
+//	                           (accepts several input files)
+//	--manifest <file>          Record output paths, content hashes, sizes,
+//	                           source maps and reflection summaries as JSON
 //	--version                  Print version and exit
 //	--help                     Print help and exit
 //
@@ -29,6 +41,67 @@
 //	miniray reflect [options] <input.wgsl>
 //	  -o <file>     Write JSON output to file (default: stdout)
 //	  --compact     Output compact JSON (default: pretty-printed)
+//	  --override NAME=VALUE  Reflect with the override specialized (repeatable)
+//
+// Validate subcommand:
+//
+//	miniray validate [options] <input.wgsl> [fragment.wgsl]
+//	  -o <file>          Write output to file (default: stdout)
+//	  --format <fmt>     Output format: text, json, or sarif
+//	  --strict           Treat warnings as errors
+//	  --lint             Run lint rules (unused code, style and correctness)
+//	  --config <file>    Config file with lint rule severities
+//	  --no-config        Ignore config files
+//	  --link <vs:fs>     Check that the vertex outputs of vs match the
+//	                     fragment inputs of fs (one or two modules)
+//
+// Symbolicate subcommand:
+//
+//	miniray symbolicate --map <shader.min.wgsl.map> [errors.txt]
+//	  --map <file>       Source map of the minified shader
+//	  --generated <file> Minified shader (default: the map's "file")
+//	  -o <file>          Write output to file (default: stdout)
+//
+// Gogen subcommand:
+//
+//	miniray gogen [options] <input.wgsl>...
+//	  -pkg <name>        Go package name (default: $GOPACKAGE)
+//	  -o <file>          Write Go source to file (default: stdout)
+//	  --config <file>    Config file with minification options
+//	  --no-config        Ignore config files
+//	  -D NAME[=VALUE]    Define a preprocessor name (repeatable)
+//
+// Ast and types subcommands:
+//
+//	miniray ast [options] <input.wgsl>
+//	miniray types [options] <input.wgsl>
+//	  -o <file>          Write output to file (default: stdout)
+//	  --json             Output JSON (default: an indented outline)
+//	  --compact          Output compact JSON (default: pretty-printed)
+//	  -D NAME[=VALUE]    Define a preprocessor name (repeatable)
+//
+//	ast prints the syntax tree, symbols and scopes; types prints the
+//	resolved type of each expression and symbol. The JSON schemas are
+//	described in docs/json-dump.md.
+//
+// Graph subcommand:
+//
+//	miniray graph [options] <input.wgsl>
+//	  -o <file>          Write output to file (default: stdout)
+//	  --format <fmt>     Output format: json, dot or mermaid
+//	  -D NAME[=VALUE]    Define a preprocessor name (repeatable)
+//
+//	Prints the call graph and type dependency graph with entry points,
+//	dead declarations, cycles and per-entry-point reachable sets.
+//
+// Serve subcommand:
+//
+//	miniray serve --socket <path> | --port <port>
+//	  --socket <path>    Listen on a Unix socket
+//	  --port <port>      Listen on a localhost port (0 picks a free port)
+//
+//	Answers JSON-RPC 2.0 requests posted over HTTP: minify, validate,
+//	reflect and format, with the options JSON of the C API.
 //
 // Config file:
 //
@@ -56,6 +129,7 @@ import (
 	"strings"
 
 	"github.com/HugoDaniel/miniray/internal/config"
+	"github.com/HugoDaniel/miniray/internal/lint"
 	"github.com/HugoDaniel/miniray/internal/minifier"
 	"github.com/HugoDaniel/miniray/internal/reflect"
 	"github.com/HugoDaniel/miniray/pkg/api"
@@ -82,6 +156,42 @@ func main() {
 				os.Exit(1)
 			}
 			return
+		case "symbolicate":
+			if err := runSymbolicate(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "gogen":
+			if err := runGogen(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "ast":
+			if err := runAST(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "types":
+			if err := runTypes(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "graph":
+			if err := runGraph(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "serve":
+			if err := runServe(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+			return
 		}
 	}
 
@@ -109,9 +219,15 @@ func run() error {
 		sourceMap                  bool
 		sourceMapInline            bool
 		sourceMapSources           bool
+		sourceMapScopes            bool
+		permutationsFile           string
+		entryNames                 string
+		manifestFile               string
 		showVersion                bool
 		showHelp                   bool
 	)
+	defines := make(defineFlags)
+	overrides := make(overrideFlags)
 
 	flag.StringVar(&outputFile, "o", "", "Write output to `file`")
 	flag.StringVar(&configFile, "config", "", "Use specific config `file`")
@@ -128,6 +244,12 @@ func run() error {
 	flag.BoolVar(&sourceMap, "source-map", false, "Generate source map file (.map)")
 	flag.BoolVar(&sourceMapInline, "source-map-inline", false, "Embed source map as inline data URI")
 	flag.BoolVar(&sourceMapSources, "source-map-sources", false, "Include original source in source map")
+	flag.BoolVar(&sourceMapScopes, "source-map-scopes", false, "Describe scopes and declared names in the source map (x_miniray_scopes)")
+	flag.Var(defines, "D", "Define preprocessor `NAME[=VALUE]` for #ifdef/#if (repeatable)")
+	flag.Var(overrides, "override", "Specialize override `NAME=VALUE` (name or @id) into a constant (repeatable)")
+	flag.StringVar(&permutationsFile, "permutations", "", "Minify and reflect every variant in JSON `file` into the -o directory")
+	flag.StringVar(&entryNames, "entry-names", "", "Write outputs into the -o directory named after `template` ([name], [hash])")
+	flag.StringVar(&manifestFile, "manifest", "", "Write a JSON manifest of outputs, hashes, sizes and reflection to `file`")
 	flag.BoolVar(&showVersion, "version", false, "Print version and exit")
 	flag.BoolVar(&showHelp, "help", false, "Print help and exit")
 
@@ -141,6 +263,15 @@ func run() error {
 		fmt.Fprintf(os.Stderr, "\nSubcommands:\n")
 		fmt.Fprintf(os.Stderr, "  reflect    Extract bindings, struct layouts, and entry points as JSON\n")
 		fmt.Fprintf(os.Stderr, "             Run 'miniray reflect --help' for details\n")
+		fmt.Fprintf(os.Stderr, "  symbolicate  Map GPU compiler errors on minified code back to the source\n")
+		fmt.Fprintf(os.Stderr, "             Run 'miniray symbolicate --help' for details\n")
+		fmt.Fprintf(os.Stderr, "  gogen      Generate Go constants and layout structs for shaders\n")
+		fmt.Fprintf(os.Stderr, "             Run 'miniray gogen --help' for details\n")
+		fmt.Fprintf(os.Stderr, "  ast        Print the syntax tree, symbols and scopes (--json for tools)\n")
+		fmt.Fprintf(os.Stderr, "  types      Print the resolved type of each expression and symbol\n")
+		fmt.Fprintf(os.Stderr, "  graph      Print the call and type dependency graph (json, dot, mermaid)\n")
+		fmt.Fprintf(os.Stderr, "  serve      Answer JSON-RPC requests on a Unix socket or localhost port\n")
+		fmt.Fprintf(os.Stderr, "             Run 'miniray serve --help' for details\n")
 		fmt.Fprintf(os.Stderr, "\nConfig file:\n")
 		fmt.Fprintf(os.Stderr, "  Searches for miniray.json or .minirayrc in current and parent directories.\n")
 		fmt.Fprintf(os.Stderr, "  CLI flags override config file settings.\n")
@@ -149,6 +280,10 @@ func run() error {
 		fmt.Fprintf(os.Stderr, "  miniray reflect shader.wgsl -o info.json\n")
 		fmt.Fprintf(os.Stderr, "  cat shader.wgsl | miniray > shader.min.wgsl\n")
 		fmt.Fprintf(os.Stderr, "  miniray --no-mangle shader.wgsl\n")
+		fmt.Fprintf(os.Stderr, "  miniray -D SHADOWS -D MSAA=4 shader.wgsl\n")
+		fmt.Fprintf(os.Stderr, "  miniray --override blockSize=64 --override 0=true shader.wgsl\n")
+		fmt.Fprintf(os.Stderr, "  miniray --permutations variants.json -o build/ shader.wgsl\n")
+		fmt.Fprintf(os.Stderr, "  miniray --entry-names \"[name].[hash].wgsl\" --manifest dist/manifest.json -o dist/ *.wgsl\n")
 	}
 
 	flag.Parse()
@@ -167,7 +302,15 @@ func run() error {
 	var source []byte
 	var err error
 
-	if flag.NArg() > 0 {
+	if entryNames != "" {
+		// Every input is read by runEntries
+		if flag.NArg() == 0 {
+			return fmt.Errorf("--entry-names needs input files")
+		}
+		if permutationsFile != "" {
+			return fmt.Errorf("--entry-names cannot be combined with --permutations")
+		}
+	} else if flag.NArg() > 0 {
 		// Read from file
 		source, err = os.ReadFile(flag.Arg(0))
 		if err != nil {
@@ -292,29 +435,69 @@ func run() error {
 		}
 	}
 
+	if len(defines) > 0 {
+		opts.Defines = defines
+	}
+	if len(overrides) > 0 {
+		opts.Overrides = overrides
+	}
+
 	// Configure source map options
 	generateSourceMap := sourceMap || sourceMapInline
 	if generateSourceMap {
 		opts.GenerateSourceMap = true
 		opts.SourceMapOptions.IncludeSource = sourceMapSources
+		opts.SourceMapOptions.Scopes = sourceMapScopes
 
 		// Determine source and output file names for source map
 		if flag.NArg() > 0 {
 			opts.SourceMapOptions.SourceName = filepath.Base(flag.Arg(0))
 		}
-		if outputFile != "" {
+		if outputFile != "" && permutationsFile == "" {
 			opts.SourceMapOptions.File = filepath.Base(outputFile)
 		}
 	}
 
-	// Minify
+	var mf *manifest
+	if manifestFile != "" {
+		if permutationsFile != "" {
+			return fmt.Errorf("--manifest cannot be combined with --permutations")
+		}
+		if entryNames == "" && outputFile == "" {
+			return fmt.Errorf("--manifest needs an output file (-o) or --entry-names")
+		}
+		mf = newManifest(manifestFile)
+	}
+
+	// Name outputs after their content
+	if entryNames != "" {
+		return runEntries(opts, flag.Args(), entryNames, outputFile, sourceMapInline, mf)
+	}
+
+	// Build every permutation
+	if permutationsFile != "" {
+		inputPath := ""
+		if flag.NArg() > 0 {
+			inputPath = flag.Arg(0)
+		}
+		return runPermutations(opts, string(source), inputPath, permutationsFile, outputFile)
+	}
+
+	// Minify, reflecting too when the manifest needs a summary
 	m := minifier.New(opts)
-	result := m.Minify(string(source))
+	var result minifier.Result
+	var info reflect.ReflectResult
+	if mf != nil {
+		reflected := m.MinifyAndReflect(string(source))
+		result, info = reflected.Result, reflected.Reflect
+	} else {
+		result = m.Minify(string(source))
+	}
 
 	// Check for errors
 	if len(result.Errors) > 0 {
 		for _, e := range result.Errors {
-			fmt.Fprintf(os.Stderr, "error: %s\n", e.Message)
+			fmt.Fprintf(os.Stderr, "error: %s\n", formatError(e))
 		}
 		return fmt.Errorf("minification failed with %d error(s)", len(result.Errors))
 	}
@@ -344,14 +527,26 @@ func run() error {
 	}
 
 	// Write external source map file
+	mapFile := ""
 	if sourceMap && !sourceMapInline && result.SourceMap != nil && outputFile != "" {
-		mapFile := outputFile + ".map"
+		mapFile = outputFile + ".map"
 		if err := os.WriteFile(mapFile, []byte(result.SourceMap.ToJSON()), 0644); err != nil {
 			return fmt.Errorf("writing source map: %w", err)
 		}
 		fmt.Fprintf(os.Stderr, "Source map: %s\n", mapFile)
 	}
 
+	if mf != nil {
+		input := "<stdin>"
+		if flag.NArg() > 0 {
+			input = flag.Arg(0)
+		}
+		mf.add(input, outputFile, mapFile, api.ContentHash(result.Code), result.Stats, info)
+		if err := mf.write(); err != nil {
+			return err
+		}
+	}
+
 	// Print stats to stderr if output is to file
 	if outputFile != "" {
 		ratio := float64(result.Stats.MinifiedSize) / float64(result.Stats.OriginalSize) * 100
@@ -372,9 +567,11 @@ func runReflect(args []string) error {
 		showHelp    bool
 		showVersion bool
 	)
+	overrides := make(overrideFlags)
 
 	fs.StringVar(&outputFile, "o", "", "Write JSON output to `file`")
 	fs.BoolVar(&compact, "compact", false, "Output compact JSON (default: pretty-printed)")
+	fs.Var(overrides, "override", "Specialize override `NAME=VALUE` (name or @id) into a constant (repeatable)")
 	fs.BoolVar(&showHelp, "help", false, "Print help and exit")
 	fs.BoolVar(&showVersion, "version", false, "Print version and exit")
 
@@ -388,10 +585,12 @@ func runReflect(args []string) error {
 		fmt.Fprintf(os.Stderr, "\nOutput:\n")
 		fmt.Fprintf(os.Stderr, "  JSON object with bindings, structs, entryPoints, and errors.\n")
 		fmt.Fprintf(os.Stderr, "  Memory layouts follow WGSL specification (vec3 align=16, size=12, etc).\n")
+		fmt.Fprintf(os.Stderr, "  With --override, overrides baked into constants are marked specialized.\n")
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  miniray reflect shader.wgsl\n")
 		fmt.Fprintf(os.Stderr, "  miniray reflect shader.wgsl -o info.json\n")
 		fmt.Fprintf(os.Stderr, "  miniray reflect --compact shader.wgsl | jq '.bindings'\n")
+		fmt.Fprintf(os.Stderr, "  miniray reflect --override USE_FOG=false shader.wgsl\n")
 	}
 
 	if err := fs.Parse(args); err != nil {
@@ -430,8 +629,21 @@ func runReflect(args []string) error {
 		}
 	}
 
-	// Run reflection
-	result := reflect.Reflect(string(source))
+	// Run reflection, on the specialized module when overrides are given
+	var result reflect.ReflectResult
+	if len(overrides) > 0 {
+		m := minifier.New(minifier.Options{Overrides: overrides})
+		reflected := m.MinifyAndReflect(string(source))
+		result = reflected.Reflect
+		if len(reflected.Errors) > 0 {
+			result.Errors = nil
+			for _, e := range reflected.Errors {
+				result.Errors = append(result.Errors, e.Message)
+			}
+		}
+	} else {
+		result = reflect.Reflect(string(source))
+	}
 
 	// Convert to JSON
 	var jsonBytes []byte
@@ -476,6 +688,11 @@ func runValidate(args []string) error {
 		outputFile  string
 		format      string
 		strict      bool
+		link        string
+		runLint     bool
+		configFile  string
+		noConfig    bool
+		timings     bool
 		showHelp    bool
 		showVersion bool
 	)
@@ -483,6 +700,11 @@ func runValidate(args []string) error {
 	fs.StringVar(&outputFile, "o", "", "Write output to `file`")
 	fs.StringVar(&format, "format", "text", "Output format: text, json, or sarif")
 	fs.BoolVar(&strict, "strict", false, "Treat warnings as errors")
+	fs.BoolVar(&runLint, "lint", false, "Run lint rules (unused code, style and correctness checks)")
+	fs.StringVar(&configFile, "config", "", "Use specific config `file` for lint rule severities")
+	fs.BoolVar(&noConfig, "no-config", false, "Ignore config files")
+	fs.StringVar(&link, "link", "", "Check the vertex/fragment interface of `vs:fs` entry points")
+	fs.BoolVar(&timings, "timings", false, "Print the time spent in each validation phase to stderr")
 	fs.BoolVar(&showHelp, "help", false, "Print help and exit")
 	fs.BoolVar(&showVersion, "version", false, "Print version and exit")
 
@@ -490,6 +712,7 @@ func runValidate(args []string) error {
 		fmt.Fprintf(os.Stderr, "miniray validate - WGSL Shader Validation v%s\n\n", version)
 		fmt.Fprintf(os.Stderr, "Validate WGSL source code for semantic correctness.\n\n")
 		fmt.Fprintf(os.Stderr, "Usage: miniray validate [options] <input.wgsl>\n")
+		fmt.Fprintf(os.Stderr, "       miniray validate --link vs:fs <shader.wgsl> [fragment.wgsl]\n")
 		fmt.Fprintf(os.Stderr, "       cat input.wgsl | miniray validate [options]\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		fs.PrintDefaults()
@@ -497,10 +720,19 @@ func runValidate(args []string) error {
 		fmt.Fprintf(os.Stderr, "  text   Human-readable output (default)\n")
 		fmt.Fprintf(os.Stderr, "  json   JSON array of diagnostics\n")
 		fmt.Fprintf(os.Stderr, "  sarif  SARIF format for IDE integration\n")
+		fmt.Fprintf(os.Stderr, "\nLint rules (--lint):\n")
+		for _, rule := range lint.Rules {
+			fmt.Fprintf(os.Stderr, "  %-24s %s\n", rule.Name, rule.Description)
+		}
+		fmt.Fprintf(os.Stderr, "  Set severities in miniray.json: {\"lint\": {\"float_equality\": \"error\"}}\n")
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  miniray validate shader.wgsl\n")
 		fmt.Fprintf(os.Stderr, "  miniray validate --strict shader.wgsl\n")
 		fmt.Fprintf(os.Stderr, "  miniray validate --format json shader.wgsl\n")
+		fmt.Fprintf(os.Stderr, "  miniray validate --lint shader.wgsl\n")
+		fmt.Fprintf(os.Stderr, "  miniray validate --timings shader.wgsl\n")
+		fmt.Fprintf(os.Stderr, "  miniray validate --link vs_main:fs_main shader.wgsl\n")
+		fmt.Fprintf(os.Stderr, "  miniray validate --link vs_main:fs_main vert.wgsl frag.wgsl\n")
 	}
 
 	if err := fs.Parse(args); err != nil {
@@ -543,9 +775,57 @@ func runValidate(args []string) error {
 	}
 
 	// Run validation
-	result := api.ValidateWithOptions(string(source), api.ValidateOptions{
+	validateOpts := api.ValidateOptions{
 		StrictMode: strict,
-	})
+		Lint:       runLint,
+		Stats:      timings,
+	}
+	if runLint && !noConfig {
+		var cfg *config.Config
+		if configFile != "" {
+			cfg, err = config.LoadFile(configFile)
+			if err != nil {
+				return fmt.Errorf("loading config file %s: %w", configFile, err)
+			}
+		} else {
+			startDir, _ := os.Getwd()
+			if fs.NArg() > 0 {
+				startDir = filepath.Dir(fs.Arg(0))
+			}
+			cfg, _, err = config.Load(startDir)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+		}
+		if cfg != nil {
+			validateOpts.DiagnosticFilters = cfg.Lint
+		}
+	}
+	var result api.ValidateResult
+	var files map[string]string
+	if link != "" {
+		vertexEntry, fragmentEntry, ok := strings.Cut(link, ":")
+		if !ok || vertexEntry == "" || fragmentEntry == "" {
+			return fmt.Errorf("--link expects vertex:fragment entry point names, got %q", link)
+		}
+		opts := api.InterfaceOptions{
+			ValidateOptions: validateOpts,
+			VertexEntry:     vertexEntry,
+			FragmentEntry:   fragmentEntry,
+		}
+		if fs.NArg() > 1 {
+			fragmentFile := fs.Arg(1)
+			fragmentSource, err := os.ReadFile(fragmentFile)
+			if err != nil {
+				return fmt.Errorf("reading input: %w", err)
+			}
+			opts.FragmentSource = string(fragmentSource)
+			files = map[string]string{"vertex": inputFile, "fragment": fragmentFile}
+		}
+		result = api.ValidateInterface(string(source), opts)
+	} else {
+		result = api.ValidateWithOptions(string(source), validateOpts)
+	}
 
 	// Prepare output
 	var output io.Writer = os.Stdout
@@ -568,7 +848,7 @@ func runValidate(args []string) error {
 		fmt.Fprintln(output, string(jsonBytes))
 
 	case "sarif":
-		sarif := formatSARIF(inputFile, result)
+		sarif := formatSARIF(inputFile, files, result)
 		jsonBytes, err := json.MarshalIndent(sarif, "", "  ")
 		if err != nil {
 			return fmt.Errorf("encoding SARIF: %w", err)
@@ -578,7 +858,10 @@ func runValidate(args []string) error {
 	case "text":
 		fallthrough
 	default:
-		formatTextDiagnostics(output, inputFile, result)
+		formatTextDiagnostics(output, inputFile, files, result)
+	}
+	if result.Stats != nil {
+		printValidateStats(result.Stats)
 	}
 
 	// Return error if validation failed
@@ -589,8 +872,37 @@ func runValidate(args []string) error {
 	return nil
 }
 
+// printValidateStats prints the time spent in each validation phase.
+func printValidateStats(stats *api.ValidateStats) {
+	t := stats.Timings
+	fmt.Fprintf(os.Stderr, "Checked %d declaration(s) in %.2fms\n", stats.Rechecked, t.Total)
+	for _, phase := range []struct {
+		name string
+		ms   float64
+	}{
+		{"parse", t.Parse},
+		{"type declarations", t.TypeDeclarations},
+		{"struct layouts", t.StructLayouts},
+		{"declarations", t.Declarations},
+		{"functions", t.Functions},
+		{"uniformity", t.Uniformity},
+		{"lint", t.Lint},
+	} {
+		fmt.Fprintf(os.Stderr, "  %-18s %8.3fms\n", phase.name, phase.ms)
+	}
+}
+
+// diagnosticFile returns the file a diagnostic belongs to. files maps
+// diagnostic stages to file names when several modules were validated.
+func diagnosticFile(file string, files map[string]string, d api.DiagnosticInfo) string {
+	if f, ok := files[d.Stage]; ok {
+		return f
+	}
+	return file
+}
+
 // formatTextDiagnostics formats diagnostics as human-readable text.
-func formatTextDiagnostics(w io.Writer, file string, result api.ValidateResult) {
+func formatTextDiagnostics(w io.Writer, file string, files map[string]string, result api.ValidateResult) {
 	if result.Valid && len(result.Diagnostics) == 0 {
 		fmt.Fprintf(w, "%s: valid\n", file)
 		return
@@ -599,7 +911,7 @@ func formatTextDiagnostics(w io.Writer, file string, result api.ValidateResult)
 	for _, d := range result.Diagnostics {
 		// Format: file:line:col: severity: message [code]
 		fmt.Fprintf(w, "%s:%d:%d: %s: %s",
-			file, d.Line, d.Column, d.Severity, d.Message)
+			diagnosticFile(file, files, d), d.Line, d.Column, d.Severity, d.Message)
 		if d.Code != "" {
 			fmt.Fprintf(w, " [%s]", d.Code)
 		}
@@ -614,7 +926,7 @@ func formatTextDiagnostics(w io.Writer, file string, result api.ValidateResult)
 }
 
 // formatSARIF formats diagnostics in SARIF format for IDE integration.
-func formatSARIF(file string, result api.ValidateResult) map[string]interface{} {
+func formatSARIF(file string, files map[string]string, result api.ValidateResult) map[string]interface{} {
 	results := make([]map[string]interface{}, 0, len(result.Diagnostics))
 
 	for _, d := range result.Diagnostics {
@@ -633,7 +945,7 @@ func formatSARIF(file string, result api.ValidateResult) map[string]interface{}
 			"locations": []map[string]interface{}{
 				{
 					"physicalLocation": map[string]interface{}{
-						"artifactLocation": map[string]string{"uri": file},
+						"artifactLocation": map[string]string{"uri": diagnosticFile(file, files, d)},
 						"region": map[string]int{
 							"startLine":   d.Line,
 							"startColumn": d.Column,
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+)
+
+// defineFlags collects repeated -D NAME[=VALUE] flags.
+type defineFlags map[string]string
+
+func (d defineFlags) String() string {
+	names := make([]string, 0, len(d))
+	for name, value := range d {
+		names = append(names, name+"="+value)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Set defines NAME as VALUE, or as 1 when no value is given.
+func (d defineFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		value = "1"
+	}
+	if name == "" {
+		return fmt.Errorf("missing name in define %q", s)
+	}
+	d[name] = value
+	return nil
+}
+
+// overrideFlags collects repeated --override NAME=VALUE flags.
+type overrideFlags map[string]float64
+
+func (o overrideFlags) String() string {
+	names := make([]string, 0, len(o))
+	for name, value := range o {
+		names = append(names, name+"="+strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Set records the value for an override, given by name or @id. Booleans
+// may be written as true or false.
+func (o overrideFlags) Set(s string) error {
+	name, text, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected NAME=VALUE in override %q", s)
+	}
+	switch text {
+	case "true":
+		o[name] = 1
+	case "false":
+		o[name] = 0
+	default:
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("invalid value in override %q", s)
+		}
+		o[name] = value
+	}
+	return nil
+}
+
+// permutationFile is the format of the --permutations file:
+//
+//	{
+//	    "permutations": [
+//	        {"name": "base"},
+//	        {"name": "shadows", "defines": {"SHADOWS": "1"}}
+//	    ]
+//	}
+type permutationFile struct {
+	Permutations []permutation `json:"permutations"`
+}
+
+type permutation struct {
+	Name    string            `json:"name"`
+	Defines map[string]string `json:"defines"`
+}
+
+func loadPermutations(path string) ([]permutation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file permutationFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	seen := make(map[string]bool)
+	for _, p := range file.Permutations {
+		if p.Name == "" || strings.ContainsAny(p.Name, `/\`) {
+			return nil, fmt.Errorf("%s: invalid permutation name %q", path, p.Name)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("%s: duplicate permutation %q", path, p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return file.Permutations, nil
+}
+
+// runPermutations writes every permutation of source to outDir as
+// <base>.<name>.wgsl with its reflection in <base>.<name>.json and,
+// when enabled, a source map pointing to the unpreprocessed input.
+func runPermutations(opts minifier.Options, source, inputPath, permPath, outDir string) error {
+	perms, err := loadPermutations(permPath)
+	if err != nil {
+		return err
+	}
+
+	base := "shader"
+	if inputPath != "" {
+		base = strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	}
+	if outDir == "" {
+		outDir = "."
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+
+	failed := 0
+	for _, perm := range perms {
+		permOpts := opts
+		permOpts.Defines = make(map[string]string)
+		for name, value := range opts.Defines {
+			permOpts.Defines[name] = value
+		}
+		for name, value := range perm.Defines {
+			permOpts.Defines[name] = value
+		}
+
+		codeFile := filepath.Join(outDir, base+"."+perm.Name+".wgsl")
+		if permOpts.GenerateSourceMap {
+			permOpts.SourceMapOptions.File = filepath.Base(codeFile)
+		}
+
+		result := minifier.New(permOpts).MinifyAndReflect(source)
+		if len(result.Errors) > 0 {
+			for _, e := range result.Errors {
+				fmt.Fprintf(os.Stderr, "error: %s: %s\n", perm.Name, formatError(e))
+			}
+			failed++
+			continue
+		}
+
+		reflectJSON, err := json.MarshalIndent(result.Reflect, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding reflection: %w", err)
+		}
+		code := result.Code
+		if result.SourceMap != nil {
+			mapFile := codeFile + ".map"
+			if err := os.WriteFile(mapFile, []byte(result.SourceMap.ToJSON()), 0644); err != nil {
+				return fmt.Errorf("writing source map: %w", err)
+			}
+			code += "\n//# sourceMappingURL=" + filepath.Base(mapFile)
+		}
+		if err := os.WriteFile(codeFile, []byte(code), 0644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		reflectFile := filepath.Join(outDir, base+"."+perm.Name+".json")
+		if err := os.WriteFile(reflectFile, append(reflectJSON, '\n'), 0644); err != nil {
+			return fmt.Errorf("writing reflection: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d -> %d bytes\n", codeFile, result.Stats.OriginalSize, result.Stats.MinifiedSize)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d permutation(s) failed", failed, len(perms))
+	}
+	return nil
+}
+
+// formatError prefixes a minification error with its position, if known.
+func formatError(e minifier.Error) string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+	}
+	return e.Message
+}
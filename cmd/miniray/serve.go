@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/HugoDaniel/miniray/internal/service"
+)
+
+// runServe handles the "serve" subcommand.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+
+	var (
+		socket      string
+		port        int
+		showHelp    bool
+		showVersion bool
+	)
+
+	fs.StringVar(&socket, "socket", "", "Listen on the Unix socket at `path`")
+	fs.IntVar(&port, "port", -1, "Listen on localhost `port` (0 picks a free port)")
+	fs.BoolVar(&showHelp, "help", false, "Print help and exit")
+	fs.BoolVar(&showVersion, "version", false, "Print version and exit")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "miniray serve - JSON-RPC Server v%s\n\n", version)
+		fmt.Fprintf(os.Stderr, "Answer JSON-RPC 2.0 requests posted over HTTP, keeping caches warm\n")
+		fmt.Fprintf(os.Stderr, "between requests. Methods take {\"source\": ..., \"options\": ...}:\n\n")
+		fmt.Fprintf(os.Stderr, "  minify     options of miniray_minify (the miniray.json schema)\n")
+		fmt.Fprintf(os.Stderr, "  validate   options of miniray_validate; \"document\": name validates\n")
+		fmt.Fprintf(os.Stderr, "             the versions of a document incrementally\n")
+		fmt.Fprintf(os.Stderr, "  reflect    no options\n")
+		fmt.Fprintf(os.Stderr, "  format     no options\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: miniray serve --socket <path>\n")
+		fmt.Fprintf(os.Stderr, "       miniray serve --port <port>\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nThe server stops on SIGINT or SIGTERM.\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  miniray serve --socket /tmp/miniray.sock\n")
+		fmt.Fprintf(os.Stderr, "  curl --unix-socket /tmp/miniray.sock http://miniray/ \\\n")
+		fmt.Fprintf(os.Stderr, "    -d '{\"jsonrpc\": \"2.0\", \"id\": 1, \"method\": \"minify\", \"params\": {\"source\": \"const a = 1;\"}}'\n")
+		fmt.Fprintf(os.Stderr, "  miniray serve --port 7777\n")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if showHelp {
+		fs.Usage()
+		return nil
+	}
+
+	if showVersion {
+		fmt.Printf("miniray serve v%s (%s)\n", version, commit)
+		return nil
+	}
+
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if (socket == "") == (port < 0) {
+		fs.Usage()
+		return fmt.Errorf("specify either --socket or --port")
+	}
+
+	var listener net.Listener
+	var err error
+	if socket != "" {
+		listener, err = listenUnix(socket)
+	} else {
+		// Only this machine may connect
+		listener, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	}
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           service.NewServer(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(listener) }()
+	fmt.Fprintf(os.Stderr, "miniray serve: listening on %s\n", listener.Addr())
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Let running requests finish; closing the listener removes the socket
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdown)
+}
+
+// listenUnix listens on the Unix socket at path, replacing a socket file
+// left behind by a server that did not shut down.
+func listenUnix(path string) (net.Listener, error) {
+	listener, err := net.Listen("unix", path)
+	if err == nil || !errors.Is(err, syscall.EADDRINUSE) {
+		return listener, err
+	}
+	if info, statErr := os.Stat(path); statErr != nil || info.Mode()&os.ModeSocket == 0 {
+		return nil, err
+	}
+	if conn, dialErr := net.Dial("unix", path); dialErr == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another server is listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
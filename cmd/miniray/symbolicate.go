@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/HugoDaniel/miniray/internal/sourcemap"
+)
+
+// runSymbolicate handles the "symbolicate" subcommand.
+func runSymbolicate(args []string) error {
+	fs := flag.NewFlagSet("symbolicate", flag.ExitOnError)
+
+	var (
+		mapFile       string
+		generatedFile string
+		outputFile    string
+		showHelp      bool
+		showVersion   bool
+	)
+
+	fs.StringVar(&mapFile, "map", "", "Source map `file` of the minified shader")
+	fs.StringVar(&generatedFile, "generated", "", "Minified shader `file` (default: the map's \"file\", next to the map)")
+	fs.StringVar(&outputFile, "o", "", "Write output to `file`")
+	fs.BoolVar(&showHelp, "help", false, "Print help and exit")
+	fs.BoolVar(&showVersion, "version", false, "Print version and exit")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "miniray symbolicate - Map Compiler Errors to Original Source v%s\n\n", version)
+		fmt.Fprintf(os.Stderr, "Rewrite line:col positions and renamed identifiers in GPU compiler messages\n")
+		fmt.Fprintf(os.Stderr, "about a minified shader back to the original source.\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: miniray symbolicate --map <shader.min.wgsl.map> [errors.txt]\n")
+		fmt.Fprintf(os.Stderr, "       miniray symbolicate --map <shader.min.wgsl.map> < errors.txt\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nIdentifiers are only restored when the minified shader can be read.\n")
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  miniray symbolicate --map shader.min.wgsl.map < errors.txt\n")
+		fmt.Fprintf(os.Stderr, "  miniray symbolicate --map out.map --generated out.wgsl errors.txt\n")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if showHelp {
+		fs.Usage()
+		return nil
+	}
+
+	if showVersion {
+		fmt.Printf("miniray symbolicate v%s (%s)\n", version, commit)
+		return nil
+	}
+
+	if mapFile == "" {
+		fs.Usage()
+		return fmt.Errorf("no source map specified (use --map)")
+	}
+
+	data, err := os.ReadFile(mapFile)
+	if err != nil {
+		return fmt.Errorf("reading source map: %w", err)
+	}
+	consumer, err := sourcemap.NewConsumer(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", mapFile, err)
+	}
+
+	// The generated code is optional: without it only positions change
+	var generated []byte
+	if generatedFile != "" {
+		generated, err = os.ReadFile(generatedFile)
+		if err != nil {
+			return fmt.Errorf("reading generated file: %w", err)
+		}
+	} else if consumer.Map.File != "" {
+		generated, _ = os.ReadFile(filepath.Join(filepath.Dir(mapFile), consumer.Map.File))
+	}
+
+	// Read messages
+	var messages []byte
+	if fs.NArg() > 0 {
+		messages, err = os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("reading input: %w", err)
+		}
+	} else {
+		// Check if stdin is a pipe
+		stat, _ := os.Stdin.Stat()
+		if (stat.Mode() & os.ModeCharDevice) != 0 {
+			fs.Usage()
+			return fmt.Errorf("no input file specified")
+		}
+		messages, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading stdin: %w", err)
+		}
+	}
+
+	symbolicator := sourcemap.NewSymbolicator(consumer, string(generated))
+	output := symbolicator.Symbolicate(string(messages))
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		return nil
+	}
+	_, err = io.WriteString(os.Stdout, output)
+	return err
+}
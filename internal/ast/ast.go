@@ -53,6 +53,7 @@ func (f ExprFlags) Has(flag ExprFlags) bool {
 // Loc represents a location in source code.
 type Loc struct {
 	Start int32 // Byte offset of start
+	End   int32 // Byte offset just past the end (exclusive)
 }
 
 // Range represents a range in source code.
@@ -192,6 +193,10 @@ type Module struct {
 
 	// Module-level scope
 	Scope *Scope
+
+	// Tokens is the full token stream with trivia attached. It is only
+	// set when the module was parsed in lossless mode.
+	Tokens []lexer.Token
 }
 
 // ----------------------------------------------------------------------------
@@ -639,6 +644,15 @@ type ParenExpr struct {
 
 func (*ParenExpr) isExpr() {}
 
+// ErrorExpr stands in for an expression that failed to parse. The parser
+// inserts it so that later passes can keep walking a module with syntax
+// errors.
+type ErrorExpr struct {
+	Loc Loc
+}
+
+func (*ErrorExpr) isExpr() {}
+
 // ----------------------------------------------------------------------------
 // Statements
 // ----------------------------------------------------------------------------
@@ -794,11 +808,20 @@ func (*CallStmt) isStmt() {}
 
 // DeclStmt wraps a declaration as a statement (for local const/let/var).
 type DeclStmt struct {
+	Loc  Loc
 	Decl Decl
 }
 
 func (*DeclStmt) isStmt() {}
 
+// ErrorStmt stands in for a statement that failed to parse. The parser
+// skips to the next statement boundary after inserting it.
+type ErrorStmt struct {
+	Loc Loc
+}
+
+func (*ErrorStmt) isStmt() {}
+
 // ----------------------------------------------------------------------------
 // Scope
 // ----------------------------------------------------------------------------
@@ -0,0 +1,405 @@
+package ast
+
+// Node is any node of the syntax tree: *Module, a Directive, Decl, Stmt,
+// Expr or Type, or one of the non-interface parts *Parameter,
+// *StructMember, *SwitchCase and *Attribute.
+type Node interface{}
+
+// Visitor is called by Walk for every node. If Visit returns a non-nil
+// visitor w, Walk visits the children of node with w and then calls
+// w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first source order.
+// Nil children are skipped.
+func Walk(v Visitor, node Node) {
+	if isNilNode(node) {
+		return
+	}
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Module:
+		for _, d := range n.Directives {
+			Walk(v, d)
+		}
+		for _, d := range n.Declarations {
+			Walk(v, d)
+		}
+
+	// Directives have no children
+	case *EnableDirective, *RequiresDirective, *DiagnosticDirective:
+
+	// Declarations
+	case *ConstDecl:
+		walkType(v, n.Type)
+		walkExpr(v, n.Initializer)
+	case *OverrideDecl:
+		walkAttributes(v, n.Attributes)
+		walkType(v, n.Type)
+		walkExpr(v, n.Initializer)
+	case *VarDecl:
+		walkAttributes(v, n.Attributes)
+		walkType(v, n.Type)
+		walkExpr(v, n.Initializer)
+	case *LetDecl:
+		walkType(v, n.Type)
+		walkExpr(v, n.Initializer)
+	case *FunctionDecl:
+		walkAttributes(v, n.Attributes)
+		for i := range n.Parameters {
+			Walk(v, &n.Parameters[i])
+		}
+		walkAttributes(v, n.ReturnAttr)
+		walkType(v, n.ReturnType)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *Parameter:
+		walkAttributes(v, n.Attributes)
+		walkType(v, n.Type)
+	case *StructDecl:
+		for i := range n.Members {
+			Walk(v, &n.Members[i])
+		}
+	case *StructMember:
+		walkAttributes(v, n.Attributes)
+		walkType(v, n.Type)
+	case *AliasDecl:
+		walkType(v, n.Type)
+	case *ConstAssertDecl:
+		walkExpr(v, n.Expr)
+	case *Attribute:
+		for _, arg := range n.Args {
+			walkExpr(v, arg)
+		}
+
+	// Types
+	case *IdentType, *SamplerType:
+	case *VecType:
+		walkType(v, n.ElemType)
+	case *MatType:
+		walkType(v, n.ElemType)
+	case *ArrayType:
+		walkType(v, n.ElemType)
+		walkExpr(v, n.Size)
+	case *PtrType:
+		walkType(v, n.ElemType)
+	case *AtomicType:
+		walkType(v, n.ElemType)
+	case *TextureType:
+		walkType(v, n.SampledType)
+
+	// Expressions
+	case *IdentExpr, *LiteralExpr, *ErrorExpr:
+	case *BinaryExpr:
+		walkExpr(v, n.Left)
+		walkExpr(v, n.Right)
+	case *UnaryExpr:
+		walkExpr(v, n.Operand)
+	case *CallExpr:
+		walkExpr(v, n.Func)
+		walkType(v, n.TemplateType)
+		for _, arg := range n.Args {
+			walkExpr(v, arg)
+		}
+	case *IndexExpr:
+		walkExpr(v, n.Base)
+		walkExpr(v, n.Index)
+	case *MemberExpr:
+		walkExpr(v, n.Base)
+	case *ParenExpr:
+		walkExpr(v, n.Expr)
+
+	// Statements
+	case *CompoundStmt:
+		for _, s := range n.Stmts {
+			walkStmt(v, s)
+		}
+	case *ReturnStmt:
+		walkExpr(v, n.Value)
+	case *IfStmt:
+		walkExpr(v, n.Condition)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+		walkStmt(v, n.Else)
+	case *SwitchStmt:
+		walkExpr(v, n.Expr)
+		for i := range n.Cases {
+			Walk(v, &n.Cases[i])
+		}
+	case *SwitchCase:
+		for _, sel := range n.Selectors {
+			walkExpr(v, sel)
+		}
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *ForStmt:
+		walkStmt(v, n.Init)
+		walkExpr(v, n.Condition)
+		walkStmt(v, n.Update)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *WhileStmt:
+		walkExpr(v, n.Condition)
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+	case *LoopStmt:
+		if n.Body != nil {
+			Walk(v, n.Body)
+		}
+		if n.Continuing != nil {
+			Walk(v, n.Continuing)
+		}
+	case *BreakStmt, *ContinueStmt, *DiscardStmt, *ErrorStmt:
+	case *BreakIfStmt:
+		walkExpr(v, n.Condition)
+	case *AssignStmt:
+		walkExpr(v, n.Left)
+		walkExpr(v, n.Right)
+	case *IncrDecrStmt:
+		walkExpr(v, n.Expr)
+	case *CallStmt:
+		if n.Call != nil {
+			Walk(v, n.Call)
+		}
+	case *DeclStmt:
+		if n.Decl != nil {
+			Walk(v, n.Decl)
+		}
+	}
+
+	v.Visit(nil)
+}
+
+func walkExpr(v Visitor, e Expr) {
+	if e != nil {
+		Walk(v, e)
+	}
+}
+
+func walkStmt(v Visitor, s Stmt) {
+	if s != nil {
+		Walk(v, s)
+	}
+}
+
+func walkType(v Visitor, t Type) {
+	if t != nil {
+		Walk(v, t)
+	}
+}
+
+func walkAttributes(v Visitor, attrs []Attribute) {
+	for i := range attrs {
+		Walk(v, &attrs[i])
+	}
+}
+
+// isNilNode reports whether node is nil or a typed nil pointer to one of
+// the node structs that may be stored in an optional field.
+func isNilNode(node Node) bool {
+	switch n := node.(type) {
+	case nil:
+		return true
+	case *Module:
+		return n == nil
+	case *CompoundStmt:
+		return n == nil
+	case *CallExpr:
+		return n == nil
+	}
+	return false
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node, calling f for every node and
+// then f(nil) after a node's children. The children of a node are skipped
+// when f returns false.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// RewriteExprs replaces every expression below node with the result of
+// f. Children are rewritten before their parent, so f sees an expression
+// whose operands have already been replaced. Returning the argument
+// unchanged keeps the expression.
+func RewriteExprs(node Node, f func(Expr) Expr) {
+	rewrite := func(e *Expr) {
+		if *e != nil {
+			RewriteExprs(*e, f)
+			*e = f(*e)
+		}
+	}
+	rewriteType := func(t Type) {
+		if t != nil {
+			RewriteExprs(t, f)
+		}
+	}
+	rewriteAttrs := func(attrs []Attribute) {
+		for i := range attrs {
+			for j := range attrs[i].Args {
+				rewrite(&attrs[i].Args[j])
+			}
+		}
+	}
+	rewriteBlock := func(b *CompoundStmt) {
+		if b != nil {
+			RewriteExprs(b, f)
+		}
+	}
+
+	switch n := node.(type) {
+	case *Module:
+		if n == nil {
+			return
+		}
+		for _, d := range n.Declarations {
+			RewriteExprs(d, f)
+		}
+
+	case *ConstDecl:
+		rewriteType(n.Type)
+		rewrite(&n.Initializer)
+	case *OverrideDecl:
+		rewriteAttrs(n.Attributes)
+		rewriteType(n.Type)
+		rewrite(&n.Initializer)
+	case *VarDecl:
+		rewriteAttrs(n.Attributes)
+		rewriteType(n.Type)
+		rewrite(&n.Initializer)
+	case *LetDecl:
+		rewriteType(n.Type)
+		rewrite(&n.Initializer)
+	case *FunctionDecl:
+		rewriteAttrs(n.Attributes)
+		for i := range n.Parameters {
+			rewriteAttrs(n.Parameters[i].Attributes)
+			rewriteType(n.Parameters[i].Type)
+		}
+		rewriteAttrs(n.ReturnAttr)
+		rewriteType(n.ReturnType)
+		rewriteBlock(n.Body)
+	case *StructDecl:
+		for i := range n.Members {
+			rewriteAttrs(n.Members[i].Attributes)
+			rewriteType(n.Members[i].Type)
+		}
+	case *AliasDecl:
+		rewriteType(n.Type)
+	case *ConstAssertDecl:
+		rewrite(&n.Expr)
+
+	case *VecType:
+		rewriteType(n.ElemType)
+	case *MatType:
+		rewriteType(n.ElemType)
+	case *ArrayType:
+		rewriteType(n.ElemType)
+		rewrite(&n.Size)
+	case *PtrType:
+		rewriteType(n.ElemType)
+	case *AtomicType:
+		rewriteType(n.ElemType)
+	case *TextureType:
+		rewriteType(n.SampledType)
+
+	case *BinaryExpr:
+		rewrite(&n.Left)
+		rewrite(&n.Right)
+	case *UnaryExpr:
+		rewrite(&n.Operand)
+	case *CallExpr:
+		if n == nil {
+			return
+		}
+		rewrite(&n.Func)
+		rewriteType(n.TemplateType)
+		for i := range n.Args {
+			rewrite(&n.Args[i])
+		}
+	case *IndexExpr:
+		rewrite(&n.Base)
+		rewrite(&n.Index)
+	case *MemberExpr:
+		rewrite(&n.Base)
+	case *ParenExpr:
+		rewrite(&n.Expr)
+
+	case *CompoundStmt:
+		if n == nil {
+			return
+		}
+		for _, s := range n.Stmts {
+			RewriteExprs(s, f)
+		}
+	case *ReturnStmt:
+		rewrite(&n.Value)
+	case *IfStmt:
+		rewrite(&n.Condition)
+		rewriteBlock(n.Body)
+		if n.Else != nil {
+			RewriteExprs(n.Else, f)
+		}
+	case *SwitchStmt:
+		rewrite(&n.Expr)
+		for i := range n.Cases {
+			for j := range n.Cases[i].Selectors {
+				rewrite(&n.Cases[i].Selectors[j])
+			}
+			rewriteBlock(n.Cases[i].Body)
+		}
+	case *ForStmt:
+		if n.Init != nil {
+			RewriteExprs(n.Init, f)
+		}
+		rewrite(&n.Condition)
+		if n.Update != nil {
+			RewriteExprs(n.Update, f)
+		}
+		rewriteBlock(n.Body)
+	case *WhileStmt:
+		rewrite(&n.Condition)
+		rewriteBlock(n.Body)
+	case *LoopStmt:
+		rewriteBlock(n.Body)
+		rewriteBlock(n.Continuing)
+	case *BreakIfStmt:
+		rewrite(&n.Condition)
+	case *AssignStmt:
+		rewrite(&n.Left)
+		rewrite(&n.Right)
+	case *IncrDecrStmt:
+		rewrite(&n.Expr)
+	case *CallStmt:
+		// The statement must stay a call; a replacement that is not a
+		// call expression is ignored.
+		if n.Call != nil {
+			RewriteExprs(n.Call, f)
+			if call, ok := f(n.Call).(*CallExpr); ok {
+				n.Call = call
+			}
+		}
+	case *DeclStmt:
+		if n.Decl != nil {
+			RewriteExprs(n.Decl, f)
+		}
+	}
+}
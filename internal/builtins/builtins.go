@@ -1050,7 +1050,7 @@ func registerTexture() {
 		Stage:      StageRuntime,
 		Uniformity: RequiresUniformFlow,
 		Overloads: []Overload{
-			{Matcher: matchTextureSample},
+			{Matcher: textureMatcher("textureSample")},
 		},
 	})
 
@@ -1061,7 +1061,7 @@ func registerTexture() {
 		Stage:      StageRuntime,
 		Uniformity: RequiresUniformFlow,
 		Overloads: []Overload{
-			{Matcher: matchTextureSampleBias},
+			{Matcher: textureMatcher("textureSampleBias")},
 		},
 	})
 
@@ -1072,7 +1072,7 @@ func registerTexture() {
 		Stage:      StageRuntime,
 		Uniformity: RequiresUniformFlow,
 		Overloads: []Overload{
-			{Matcher: matchTextureSampleCompare},
+			{Matcher: textureMatcher("textureSampleCompare")},
 		},
 	})
 
@@ -1082,7 +1082,7 @@ func registerTexture() {
 		Kind:  BuiltinTexture,
 		Stage: StageRuntime,
 		Overloads: []Overload{
-			{Matcher: matchTextureSampleCompareLevel},
+			{Matcher: textureMatcher("textureSampleCompareLevel")},
 		},
 	})
 
@@ -1092,7 +1092,7 @@ func registerTexture() {
 		Kind:  BuiltinTexture,
 		Stage: StageRuntime,
 		Overloads: []Overload{
-			{Matcher: matchTextureSampleLevel},
+			{Matcher: textureMatcher("textureSampleLevel")},
 		},
 	})
 
@@ -1102,7 +1102,7 @@ func registerTexture() {
 		Kind:  BuiltinTexture,
 		Stage: StageRuntime,
 		Overloads: []Overload{
-			{Matcher: matchTextureSampleGrad},
+			{Matcher: textureMatcher("textureSampleGrad")},
 		},
 	})
 
@@ -1112,7 +1112,7 @@ func registerTexture() {
 		Kind:  BuiltinTexture,
 		Stage: StageRuntime,
 		Overloads: []Overload{
-			{Matcher: matchTextureLoad},
+			{Matcher: textureMatcher("textureLoad")},
 		},
 	})
 
@@ -1122,7 +1122,7 @@ func registerTexture() {
 		Kind:  BuiltinTexture,
 		Stage: StageRuntime,
 		Overloads: []Overload{
-			{Matcher: matchTextureStore},
+			{Matcher: textureMatcher("textureStore")},
 		},
 	})
 
@@ -1132,7 +1132,7 @@ func registerTexture() {
 		Kind:  BuiltinTexture,
 		Stage: StageRuntime,
 		Overloads: []Overload{
-			{Matcher: matchTextureDimensions},
+			{Matcher: textureMatcher("textureDimensions")},
 		},
 	})
 
@@ -1142,7 +1142,7 @@ func registerTexture() {
 		Kind:  BuiltinTexture,
 		Stage: StageRuntime,
 		Overloads: []Overload{
-			{Matcher: matchTextureNumLayers},
+			{Matcher: textureMatcher("textureNumLayers")},
 		},
 	})
 
@@ -1152,7 +1152,7 @@ func registerTexture() {
 		Kind:  BuiltinTexture,
 		Stage: StageRuntime,
 		Overloads: []Overload{
-			{Matcher: matchTextureNumLevels},
+			{Matcher: textureMatcher("textureNumLevels")},
 		},
 	})
 
@@ -1162,7 +1162,7 @@ func registerTexture() {
 		Kind:  BuiltinTexture,
 		Stage: StageRuntime,
 		Overloads: []Overload{
-			{Matcher: matchTextureNumSamples},
+			{Matcher: textureMatcher("textureNumSamples")},
 		},
 	})
 
@@ -1173,7 +1173,7 @@ func registerTexture() {
 		Stage:      StageRuntime,
 		Uniformity: RequiresUniformFlow,
 		Overloads: []Overload{
-			{Matcher: matchTextureGather},
+			{Matcher: textureMatcher("textureGather")},
 		},
 	})
 
@@ -1183,115 +1183,19 @@ func registerTexture() {
 		Stage:      StageRuntime,
 		Uniformity: RequiresUniformFlow,
 		Overloads: []Overload{
-			{Matcher: matchTextureGatherCompare},
+			{Matcher: textureMatcher("textureGatherCompare")},
 		},
 	})
-}
-
-// Simplified texture matchers - full implementation would check texture types more precisely
-func matchTextureSample(args []types.Type) (types.Type, bool) {
-	if len(args) < 2 {
-		return nil, false
-	}
-	if _, ok := args[0].(*types.Texture); !ok {
-		return nil, false
-	}
-	if _, ok := args[1].(*types.Sampler); !ok {
-		return nil, false
-	}
-	return types.Vec(4, types.F32), true
-}
-
-func matchTextureSampleBias(args []types.Type) (types.Type, bool) {
-	if len(args) < 3 {
-		return nil, false
-	}
-	return types.Vec(4, types.F32), true
-}
-
-func matchTextureSampleCompare(args []types.Type) (types.Type, bool) {
-	if len(args) < 3 {
-		return nil, false
-	}
-	return types.F32, true
-}
-
-func matchTextureSampleCompareLevel(args []types.Type) (types.Type, bool) {
-	if len(args) < 4 {
-		return nil, false
-	}
-	return types.F32, true
-}
-
-func matchTextureSampleLevel(args []types.Type) (types.Type, bool) {
-	if len(args) < 3 {
-		return nil, false
-	}
-	return types.Vec(4, types.F32), true
-}
-
-func matchTextureSampleGrad(args []types.Type) (types.Type, bool) {
-	if len(args) < 4 {
-		return nil, false
-	}
-	return types.Vec(4, types.F32), true
-}
-
-func matchTextureLoad(args []types.Type) (types.Type, bool) {
-	if len(args) < 2 {
-		return nil, false
-	}
-	return types.Vec(4, types.F32), true
-}
-
-func matchTextureStore(args []types.Type) (types.Type, bool) {
-	if len(args) < 3 {
-		return nil, false
-	}
-	return nil, true // void return
-}
-
-func matchTextureDimensions(args []types.Type) (types.Type, bool) {
-	if len(args) < 1 {
-		return nil, false
-	}
-	// Returns u32 for 1D, vec2<u32> for 2D, vec3<u32> for 3D
-	return types.Vec(2, types.U32), true // Simplified
-}
-
-func matchTextureNumLayers(args []types.Type) (types.Type, bool) {
-	if len(args) != 1 {
-		return nil, false
-	}
-	return types.U32, true
-}
-
-func matchTextureNumLevels(args []types.Type) (types.Type, bool) {
-	if len(args) != 1 {
-		return nil, false
-	}
-	return types.U32, true
-}
 
-func matchTextureNumSamples(args []types.Type) (types.Type, bool) {
-	if len(args) != 1 {
-		return nil, false
-	}
-	return types.U32, true
-}
-
-func matchTextureGather(args []types.Type) (types.Type, bool) {
-	if len(args) < 3 {
-		return nil, false
-	}
-	return types.Vec(4, types.F32), true
-}
-
-func matchTextureGatherCompare(args []types.Type) (types.Type, bool) {
-	if len(args) < 4 {
-		return nil, false
-	}
-	return types.Vec(4, types.F32), true
+	// textureSampleBaseClampToEdge - does NOT require uniform control flow
+	register(&Builtin{
+		Name:  "textureSampleBaseClampToEdge",
+		Kind:  BuiltinTexture,
+		Stage: StageRuntime,
+		Overloads: []Overload{
+			{Matcher: textureMatcher("textureSampleBaseClampToEdge")},
+		},
+	})
 }
 
 // ----------------------------------------------------------------------------
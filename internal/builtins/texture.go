@@ -0,0 +1,451 @@
+package builtins
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/types"
+)
+
+// ----------------------------------------------------------------------------
+// Texture Overload Tables (Section 17.7)
+// ----------------------------------------------------------------------------
+
+// texParam describes one argument of a texture builtin. The expected type of
+// most parameters depends on the texture the call is made with.
+type texParam uint8
+
+const (
+	texParamSampler           texParam = iota // sampler
+	texParamSamplerComparison                 // sampler_comparison
+	texParamCoords                            // f32 coordinates, one component per dimension
+	texParamTexelCoords                       // i32/u32 texel coordinates, one component per dimension
+	texParamArrayIndex                        // i32 or u32
+	texParamLevel                             // i32 or u32 mip level
+	texParamLevelF32                          // f32 mip level
+	texParamSampleIndex                       // i32 or u32
+	texParamComponent                         // i32 or u32 channel index for textureGather
+	texParamBias                              // f32
+	texParamDepthRef                          // f32
+	texParamGradient                          // f32 derivative, one component per dimension
+	texParamOffset                            // i32 texel offset, one component per dimension
+	texParamTexel                             // vec4 of the texel format's channel type
+)
+
+// textureOverload is a single signature of a texture builtin, for one
+// texture dimension and a set of texture kinds.
+type textureOverload struct {
+	// Kinds lists the texture kinds this signature accepts.
+	Kinds []types.TextureKind
+	// Dim is the texture dimension this signature accepts.
+	Dim types.TextureDimension
+	// FloatOnly requires sampled textures to have an f32 sampled type.
+	FloatOnly bool
+	// Access is the storage texture access a call needs: read or write.
+	// read_write storage textures satisfy both.
+	Access types.AccessMode
+	// Leading are the parameters that come before the texture.
+	Leading []texParam
+	// Params are the parameters that come after the texture.
+	Params []texParam
+	// Optional is the number of trailing Params that may be omitted.
+	Optional int
+	// Result computes the return type; nil means void.
+	Result func(t *types.Texture) types.Type
+}
+
+var (
+	sampledKinds        = []types.TextureKind{types.TextureSampled}
+	depthKinds          = []types.TextureKind{types.TextureDepth}
+	sampledOrDepthKinds = []types.TextureKind{types.TextureSampled, types.TextureDepth}
+	storageKinds        = []types.TextureKind{types.TextureStorage}
+
+	allDims       = []types.TextureDimension{types.Texture1D, types.Texture2D, types.Texture2DArray, types.Texture3D, types.TextureCube, types.TextureCubeArray}
+	filterDims    = []types.TextureDimension{types.Texture2D, types.Texture2DArray, types.Texture3D, types.TextureCube, types.TextureCubeArray}
+	depthDims     = []types.TextureDimension{types.Texture2D, types.Texture2DArray, types.TextureCube, types.TextureCubeArray}
+	loadDims      = []types.TextureDimension{types.Texture1D, types.Texture2D, types.Texture2DArray, types.Texture3D}
+	depthLoadDims = []types.TextureDimension{types.Texture2D, types.Texture2DArray}
+	arrayDims     = []types.TextureDimension{types.Texture2DArray, types.TextureCubeArray}
+)
+
+// textureOverloads maps each texture builtin to all of its signatures.
+var textureOverloads = map[string][]textureOverload{}
+
+func init() {
+	add := func(name string, overloads ...[]textureOverload) {
+		for _, o := range overloads {
+			textureOverloads[name] = append(textureOverloads[name], o...)
+		}
+	}
+	sampler := []texParam{texParamSampler, texParamCoords}
+	comparison := []texParam{texParamSamplerComparison, texParamCoords}
+
+	add("textureDimensions",
+		expandDims(textureOverload{Kinds: sampledOrDepthKinds, Params: []texParam{texParamLevel}, Optional: 1, Result: dimensionsResult}, allDims, false),
+		expandDims(textureOverload{Kinds: []types.TextureKind{types.TextureMultisampled, types.TextureDepthMultisampled, types.TextureExternal}, Result: dimensionsResult}, []types.TextureDimension{types.Texture2D}, false),
+		expandDims(textureOverload{Kinds: storageKinds, Result: dimensionsResult}, loadDims, false),
+	)
+	add("textureGather",
+		expandDims(textureOverload{Kinds: sampledKinds, Leading: []texParam{texParamComponent}, Params: sampler, Result: gatherResult}, depthDims, true),
+		expandDims(textureOverload{Kinds: depthKinds, Params: sampler, Result: gatherResult}, depthDims, true),
+	)
+	add("textureGatherCompare",
+		expandDims(textureOverload{Kinds: depthKinds, Params: append(comparison, texParamDepthRef), Result: gatherResult}, depthDims, true),
+	)
+	add("textureLoad",
+		expandDims(textureOverload{Kinds: sampledKinds, Params: []texParam{texParamTexelCoords, texParamLevel}, Result: loadResult}, loadDims, false),
+		expandDims(textureOverload{Kinds: depthKinds, Params: []texParam{texParamTexelCoords, texParamLevel}, Result: loadResult}, depthLoadDims, false),
+		expandDims(textureOverload{Kinds: []types.TextureKind{types.TextureMultisampled, types.TextureDepthMultisampled}, Params: []texParam{texParamTexelCoords, texParamSampleIndex}, Result: loadResult}, []types.TextureDimension{types.Texture2D}, false),
+		expandDims(textureOverload{Kinds: []types.TextureKind{types.TextureExternal}, Params: []texParam{texParamTexelCoords}, Result: loadResult}, []types.TextureDimension{types.Texture2D}, false),
+		expandDims(textureOverload{Kinds: storageKinds, Access: types.AccessModeRead, Params: []texParam{texParamTexelCoords}, Result: loadResult}, loadDims, false),
+	)
+	add("textureNumLayers",
+		expandDims(textureOverload{Kinds: sampledOrDepthKinds, Result: u32Result}, arrayDims, false),
+		expandDims(textureOverload{Kinds: storageKinds, Result: u32Result}, []types.TextureDimension{types.Texture2DArray}, false),
+	)
+	add("textureNumLevels",
+		expandDims(textureOverload{Kinds: sampledKinds, Result: u32Result}, allDims, false),
+		expandDims(textureOverload{Kinds: depthKinds, Result: u32Result}, depthDims, false),
+	)
+	add("textureNumSamples",
+		expandDims(textureOverload{Kinds: []types.TextureKind{types.TextureMultisampled, types.TextureDepthMultisampled}, Result: u32Result}, []types.TextureDimension{types.Texture2D}, false),
+	)
+	add("textureSample",
+		expandDims(textureOverload{Kinds: sampledKinds, FloatOnly: true, Params: sampler, Result: sampleResult}, allDims, true),
+		expandDims(textureOverload{Kinds: depthKinds, Params: sampler, Result: sampleResult}, depthDims, true),
+	)
+	add("textureSampleBias",
+		expandDims(textureOverload{Kinds: sampledKinds, FloatOnly: true, Params: append(sampler, texParamBias), Result: sampleResult}, filterDims, true),
+	)
+	add("textureSampleCompare",
+		expandDims(textureOverload{Kinds: depthKinds, Params: append(comparison, texParamDepthRef), Result: sampleResult}, depthDims, true),
+	)
+	add("textureSampleCompareLevel",
+		expandDims(textureOverload{Kinds: depthKinds, Params: append(comparison, texParamDepthRef), Result: sampleResult}, depthDims, true),
+	)
+	add("textureSampleGrad",
+		expandDims(textureOverload{Kinds: sampledKinds, FloatOnly: true, Params: append(sampler, texParamGradient, texParamGradient), Result: sampleResult}, filterDims, true),
+	)
+	add("textureSampleLevel",
+		expandDims(textureOverload{Kinds: sampledKinds, FloatOnly: true, Params: append(sampler, texParamLevelF32), Result: sampleResult}, filterDims, true),
+		expandDims(textureOverload{Kinds: depthKinds, Params: append(sampler, texParamLevel), Result: sampleResult}, depthDims, true),
+	)
+	add("textureSampleBaseClampToEdge",
+		expandDims(textureOverload{Kinds: []types.TextureKind{types.TextureSampled, types.TextureExternal}, FloatOnly: true, Params: sampler, Result: vec4F32Result}, []types.TextureDimension{types.Texture2D}, false),
+	)
+	add("textureStore",
+		expandDims(textureOverload{Kinds: storageKinds, Access: types.AccessModeWrite, Params: []texParam{texParamTexelCoords, texParamTexel}}, loadDims, false),
+	)
+}
+
+// expandDims instantiates an overload template for each dimension. Arrayed
+// dimensions get an array index right after the coordinates, and when
+// offset is true the dimensions that support it get an optional offset.
+func expandDims(o textureOverload, dims []types.TextureDimension, offset bool) []textureOverload {
+	result := make([]textureOverload, 0, len(dims))
+	for _, dim := range dims {
+		ov := o
+		ov.Dim = dim
+		ov.Params = nil
+		for _, p := range o.Params {
+			ov.Params = append(ov.Params, p)
+			if (p == texParamCoords || p == texParamTexelCoords) && isArrayedDim(dim) {
+				ov.Params = append(ov.Params, texParamArrayIndex)
+			}
+		}
+		if offset && (dim == types.Texture2D || dim == types.Texture2DArray || dim == types.Texture3D) {
+			ov.Params = append(ov.Params, texParamOffset)
+			ov.Optional++
+		}
+		result = append(result, ov)
+	}
+	return result
+}
+
+func isArrayedDim(dim types.TextureDimension) bool {
+	return dim == types.Texture2DArray || dim == types.TextureCubeArray
+}
+
+// coordWidth returns the number of coordinate components for a dimension.
+func coordWidth(dim types.TextureDimension, sampled bool) int {
+	switch dim {
+	case types.Texture1D:
+		return 1
+	case types.Texture3D:
+		return 3
+	case types.TextureCube, types.TextureCubeArray:
+		if sampled {
+			return 3
+		}
+	}
+	return 2
+}
+
+// ----------------------------------------------------------------------------
+// Result Types
+// ----------------------------------------------------------------------------
+
+func dimensionsResult(t *types.Texture) types.Type {
+	switch t.Dimension {
+	case types.Texture1D:
+		return types.U32
+	case types.Texture3D:
+		return types.Vec(3, types.U32)
+	}
+	return types.Vec(2, types.U32)
+}
+
+func sampleResult(t *types.Texture) types.Type {
+	if isDepthTexture(t) {
+		return types.F32
+	}
+	return types.Vec(4, types.F32)
+}
+
+func gatherResult(t *types.Texture) types.Type {
+	if isDepthTexture(t) || t.SampledType == nil {
+		return types.Vec(4, types.F32)
+	}
+	return types.Vec(4, t.SampledType)
+}
+
+func loadResult(t *types.Texture) types.Type {
+	switch t.Kind {
+	case types.TextureDepth, types.TextureDepthMultisampled:
+		return types.F32
+	case types.TextureExternal:
+		return types.Vec(4, types.F32)
+	case types.TextureStorage:
+		if channel := TexelFormatChannelType(t.TexelFormat); channel != nil {
+			return types.Vec(4, channel)
+		}
+		return types.Vec(4, types.F32)
+	}
+	if t.SampledType == nil {
+		return types.Vec(4, types.F32)
+	}
+	return types.Vec(4, t.SampledType)
+}
+
+func u32Result(t *types.Texture) types.Type {
+	return types.U32
+}
+
+func vec4F32Result(t *types.Texture) types.Type {
+	return types.Vec(4, types.F32)
+}
+
+func isDepthTexture(t *types.Texture) bool {
+	return t.Kind == types.TextureDepth || t.Kind == types.TextureDepthMultisampled
+}
+
+// ----------------------------------------------------------------------------
+// Texel Formats
+// ----------------------------------------------------------------------------
+
+// texelFormats maps each storage texel format to its channel type.
+var texelFormats = map[string]*types.Scalar{
+	"rgba8unorm":  types.F32,
+	"rgba8snorm":  types.F32,
+	"rgba8uint":   types.U32,
+	"rgba8sint":   types.I32,
+	"rgba16uint":  types.U32,
+	"rgba16sint":  types.I32,
+	"rgba16float": types.F32,
+	"r32uint":     types.U32,
+	"r32sint":     types.I32,
+	"r32float":    types.F32,
+	"rg32uint":    types.U32,
+	"rg32sint":    types.I32,
+	"rg32float":   types.F32,
+	"rgba32uint":  types.U32,
+	"rgba32sint":  types.I32,
+	"rgba32float": types.F32,
+	"bgra8unorm":  types.F32,
+}
+
+// TexelFormatChannelType returns the channel type of a storage texel format,
+// or nil if the format is unknown.
+func TexelFormatChannelType(format string) *types.Scalar {
+	return texelFormats[format]
+}
+
+// ----------------------------------------------------------------------------
+// Resolution
+// ----------------------------------------------------------------------------
+
+// TextureCallError explains why a texture builtin call matched no overload.
+type TextureCallError struct {
+	// Arg is the index of the offending argument, or -1 when the number of
+	// arguments is wrong.
+	Arg     int
+	Message string
+}
+
+// ResolveTextureCall resolves a call to a texture builtin against the kind,
+// dimension, sampled type and texel format of its texture argument.
+// Returns the result type (nil for textureStore) or an error describing the
+// first mismatching argument. Calls whose texture argument has an unknown
+// type resolve to (nil, nil) so that earlier errors do not cascade.
+func ResolveTextureCall(name string, args []types.Type) (types.Type, *TextureCallError) {
+	overloads := textureOverloads[name]
+	if len(overloads) == 0 {
+		return nil, &TextureCallError{Arg: -1, Message: fmt.Sprintf("'%s' is not a texture builtin", name)}
+	}
+
+	// Find the texture argument; textureGather takes a component first for
+	// sampled textures.
+	texIndex := 0
+	if name == "textureGather" && len(args) > 0 && args[0] != nil && !types.IsTexture(args[0]) {
+		texIndex = 1
+	}
+	if texIndex >= len(args) {
+		return nil, &TextureCallError{Arg: -1, Message: fmt.Sprintf("'%s' expects a texture argument", name)}
+	}
+	if args[texIndex] == nil {
+		return nil, nil
+	}
+	tex, ok := args[texIndex].(*types.Texture)
+	if !ok {
+		return nil, &TextureCallError{Arg: texIndex, Message: fmt.Sprintf(
+			"argument %d of '%s' must be a texture, got '%s'", texIndex+1, name, args[texIndex].String())}
+	}
+
+	// Narrow down to the signatures for this texture
+	var candidates []textureOverload
+	for _, o := range overloads {
+		if o.Dim == tex.Dimension && len(o.Leading) == texIndex && hasKind(o.Kinds, tex.Kind) {
+			candidates = append(candidates, o)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, &TextureCallError{Arg: texIndex, Message: fmt.Sprintf(
+			"'%s' is not defined for texture type '%s'", name, tex.String())}
+	}
+
+	var firstErr *TextureCallError
+	for _, o := range candidates {
+		ret, err := matchTextureOverload(name, o, tex, args)
+		if err == nil {
+			return ret, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+func hasKind(kinds []types.TextureKind, kind types.TextureKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func matchTextureOverload(name string, o textureOverload, tex *types.Texture, args []types.Type) (types.Type, *TextureCallError) {
+	texIndex := len(o.Leading)
+
+	if o.FloatOnly && tex.Kind == types.TextureSampled && tex.SampledType != nil && tex.SampledType.Kind != types.ScalarF32 {
+		return nil, &TextureCallError{Arg: texIndex, Message: fmt.Sprintf(
+			"'%s' requires a texture with sampled type 'f32', got '%s'", name, tex.String())}
+	}
+	if o.Access != types.AccessModeNone && tex.AccessMode != types.AccessModeReadWrite && tex.AccessMode != o.Access {
+		return nil, &TextureCallError{Arg: texIndex, Message: fmt.Sprintf(
+			"'%s' requires a storage texture with '%s' or 'read_write' access, got '%s'", name, o.Access, tex.String())}
+	}
+
+	maxArgs := texIndex + 1 + len(o.Params)
+	minArgs := maxArgs - o.Optional
+	if len(args) < minArgs || len(args) > maxArgs {
+		expected := fmt.Sprintf("%d", maxArgs)
+		if minArgs != maxArgs {
+			expected = fmt.Sprintf("%d or %d", minArgs, maxArgs)
+		}
+		return nil, &TextureCallError{Arg: -1, Message: fmt.Sprintf(
+			"'%s' with '%s' expects %s arguments, got %d", name, tex.String(), expected, len(args))}
+	}
+
+	check := func(i int, p texParam) *TextureCallError {
+		if args[i] == nil {
+			return nil
+		}
+		if ok, want := matchTexParam(p, tex, args[i]); !ok {
+			return &TextureCallError{Arg: i, Message: fmt.Sprintf(
+				"argument %d of '%s' must be %s, got '%s'", i+1, name, want, args[i].String())}
+		}
+		return nil
+	}
+	for i, p := range o.Leading {
+		if err := check(i, p); err != nil {
+			return nil, err
+		}
+	}
+	for i := texIndex + 1; i < len(args); i++ {
+		if err := check(i, o.Params[i-texIndex-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.Result == nil {
+		return nil, nil
+	}
+	return o.Result(tex), nil
+}
+
+// matchTexParam reports whether arg is valid for parameter p of a call made
+// with texture tex, and otherwise describes the expected type.
+func matchTexParam(p texParam, tex *types.Texture, arg types.Type) (bool, string) {
+	switch p {
+	case texParamSampler:
+		s, ok := arg.(*types.Sampler)
+		return ok && !s.Comparison, "'sampler'"
+	case texParamSamplerComparison:
+		s, ok := arg.(*types.Sampler)
+		return ok && s.Comparison, "'sampler_comparison'"
+	case texParamCoords, texParamGradient:
+		return matchVecOf(arg, coordWidth(tex.Dimension, true), types.F32)
+	case texParamTexelCoords:
+		return matchIntVec(arg, coordWidth(tex.Dimension, false))
+	case texParamOffset:
+		return matchVecOf(arg, coordWidth(tex.Dimension, true), types.I32)
+	case texParamArrayIndex, texParamLevel, texParamSampleIndex, texParamComponent:
+		return matchIntVec(arg, 1)
+	case texParamLevelF32, texParamBias, texParamDepthRef:
+		return types.CanConvertTo(arg, types.F32), "'f32'"
+	case texParamTexel:
+		channel := TexelFormatChannelType(tex.TexelFormat)
+		if channel == nil {
+			return true, ""
+		}
+		want := types.Vec(4, channel)
+		return types.CanConvertTo(arg, want), fmt.Sprintf("'%s' for texel format '%s'", want.String(), tex.TexelFormat)
+	}
+	return false, "?"
+}
+
+// matchVecOf matches a scalar (width 1) or vector of the given element type.
+func matchVecOf(arg types.Type, width int, elem *types.Scalar) (bool, string) {
+	var want types.Type = elem
+	if width > 1 {
+		want = types.Vec(width, elem)
+	}
+	return types.CanConvertTo(arg, want), "'" + want.String() + "'"
+}
+
+// matchIntVec matches an i32 or u32 scalar (width 1) or vector.
+func matchIntVec(arg types.Type, width int) (bool, string) {
+	okI, wantI := matchVecOf(arg, width, types.I32)
+	okU, wantU := matchVecOf(arg, width, types.U32)
+	return okI || okU, strings.Join([]string{wantI, wantU}, " or ")
+}
+
+// textureMatcher adapts ResolveTextureCall to the Overload matcher interface.
+func textureMatcher(name string) func(args []types.Type) (types.Type, bool) {
+	return func(args []types.Type) (types.Type, bool) {
+		ret, err := ResolveTextureCall(name, args)
+		return ret, err == nil
+	}
+}
@@ -0,0 +1,157 @@
+// Package compiler keeps the state that repeated minification and
+// validation with the same options can share.
+//
+// A Compiler holds one minifier, so the reserved names are computed once,
+// and remembers recent results by the SHA-256 of their source: minified
+// results, since minification consumes its AST, and parsed modules for
+// validation, which only reads them. Validation of a named document is
+// incremental for each set of options: only the declarations that changed
+// since the previous version of the document validated with the same
+// options are checked again. An editor that validates on every keystroke
+// pays for parsing only when the text changed, and for checking only what
+// the edit touched.
+package compiler
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+	"github.com/HugoDaniel/miniray/pkg/api"
+)
+
+// DefaultCacheSize is the number of sources remembered for minification and,
+// separately, for validation.
+const DefaultCacheSize = 64
+
+// Compiler minifies and validates with fixed minification options. It is
+// safe for concurrent use by multiple goroutines.
+type Compiler struct {
+	minifier *minifier.Minifier
+	results  *cache[minifier.Result]
+	modules  *cache[*api.Module]
+
+	// validators holds an incremental validator per document and set of
+	// options
+	validators *cache[*api.IncrementalValidator]
+}
+
+// CacheStats counts cache lookups.
+type CacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// New creates a compiler for the given options. Options.Plugins must be
+// safe for concurrent use. cacheSize <= 0 selects DefaultCacheSize.
+func New(options minifier.Options, cacheSize int) *Compiler {
+	if cacheSize <= 0 {
+		cacheSize = DefaultCacheSize
+	}
+	return &Compiler{
+		minifier: minifier.New(options),
+		results:  newCache[minifier.Result](cacheSize),
+		modules:  newCache[*api.Module](cacheSize),
+
+		validators: newCache[*api.IncrementalValidator](cacheSize),
+	}
+}
+
+// Minify minifies source. The result is shared with other callers that
+// minify the same source and must not be modified.
+func (c *Compiler) Minify(source string) minifier.Result {
+	return c.results.get(source, func() minifier.Result {
+		return c.minifier.Minify(source)
+	})
+}
+
+// Parse parses source for validation. The module is shared with other
+// callers that parse the same source.
+func (c *Compiler) Parse(source string) *api.Module {
+	return c.modules.get(source, func() *api.Module {
+		return api.Parse(source)
+	})
+}
+
+// Validate validates source, reusing its parsed module when it was seen
+// before.
+func (c *Compiler) Validate(source string, opts api.ValidateOptions) api.ValidateResult {
+	return c.Parse(source).Validate(opts)
+}
+
+// ValidateDocument validates source as the new version of document,
+// reusing the results of the declarations it shares with the previous
+// version validated with the same options. Documents are only names; the
+// least recently validated ones are forgotten.
+func (c *Compiler) ValidateDocument(document, source string, opts api.ValidateOptions) api.ValidateResult {
+	// fmt prints maps sorted by key, so equal options print the same
+	key := fmt.Sprintf("%q %+v", document, opts)
+	validator := c.validators.get(key, func() *api.IncrementalValidator {
+		return api.NewIncrementalValidator(opts)
+	})
+	return validator.Validate(c.Parse(source))
+}
+
+// Stats returns the lookups of the minification and validation caches.
+func (c *Compiler) Stats() (minify, validate CacheStats) {
+	return c.results.stats(), c.modules.stats()
+}
+
+// cache is a least recently used cache of values computed from sources,
+// or other strings.
+type cache[V any] struct {
+	mu      sync.Mutex
+	size    int
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List // of *entry[V], most recently used first
+	counts  CacheStats
+}
+
+type entry[V any] struct {
+	key   [sha256.Size]byte
+	once  sync.Once
+	value V
+}
+
+func newCache[V any](size int) *cache[V] {
+	return &cache[V]{
+		size:    size,
+		entries: make(map[[sha256.Size]byte]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the value for source, computing it when it is not cached.
+// Concurrent lookups of the same source compute it once.
+func (c *cache[V]) get(source string, compute func() V) V {
+	key := sha256.Sum256([]byte(source))
+
+	c.mu.Lock()
+	var e *entry[V]
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		e = el.Value.(*entry[V])
+		c.counts.Hits++
+	} else {
+		e = &entry[V]{key: key}
+		c.entries[key] = c.order.PushFront(e)
+		c.counts.Misses++
+		if c.order.Len() > c.size {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*entry[V]).key)
+		}
+	}
+	c.mu.Unlock()
+
+	e.once.Do(func() { e.value = compute() })
+	return e.value
+}
+
+func (c *cache[V]) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts
+}
@@ -0,0 +1,133 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+	"github.com/HugoDaniel/miniray/pkg/api"
+)
+
+const shader = `struct Uniforms { color: vec4f }
+@group(0) @binding(0) var<uniform> uniforms: Uniforms;
+
+fn unused() -> f32 { return 1.0; }
+
+@fragment fn main() -> @location(0) vec4f {
+    let tint = uniforms.color;
+    return tint;
+}
+`
+
+func TestMinifyMatchesMinifier(t *testing.T) {
+	opts := minifier.DefaultOptions()
+	opts.KeepNames = []string{"tint"}
+	c := New(opts, 0)
+
+	want := minifier.New(opts).Minify(shader).Code
+	for i := 0; i < 2; i++ {
+		if got := c.Minify(shader).Code; got != want {
+			t.Errorf("run %d: code = %q, want %q", i, got, want)
+		}
+	}
+	if stats, _ := c.Stats(); stats != (CacheStats{Hits: 1, Misses: 1}) {
+		t.Errorf("minify cache stats = %+v, want one hit and one miss", stats)
+	}
+}
+
+func TestValidateReusesModule(t *testing.T) {
+	c := New(minifier.DefaultOptions(), 0)
+	if c.Parse(shader) != c.Parse(shader) {
+		t.Error("unchanged source was parsed again")
+	}
+
+	// The cached module is validated with each call's options
+	if r := c.Validate(shader, api.ValidateOptions{}); !r.Valid || r.WarningCount != 0 {
+		t.Errorf("unexpected diagnostics: %+v", r)
+	}
+	if r := c.Validate(shader, api.ValidateOptions{Lint: true}); r.WarningCount == 0 {
+		t.Error("expected a lint warning for the unused function")
+	}
+
+	broken := "fn f() -> i32 { return 1.5; }"
+	if r := c.Validate(broken, api.ValidateOptions{}); r.Valid {
+		t.Error("expected an error for the changed source")
+	}
+	if _, stats := c.Stats(); stats != (CacheStats{Hits: 3, Misses: 2}) {
+		t.Errorf("validate cache stats = %+v, want 3 hits and 2 misses", stats)
+	}
+}
+
+func TestValidateIncremental(t *testing.T) {
+	c := New(minifier.DefaultOptions(), 0)
+	opts := api.ValidateOptions{Stats: true}
+	if r := c.ValidateDocument("a.wgsl", shader, opts); r.Stats == nil || r.Stats.Rechecked != 4 {
+		t.Fatalf("first validation stats = %+v, want all 4 declarations checked", r.Stats)
+	}
+
+	// Only the edited function is checked again
+	edited := strings.Replace(shader, "return 1.0;", "return 2.0;", 1)
+	if r := c.ValidateDocument("a.wgsl", edited, opts); !r.Valid || r.Stats.Rechecked != 1 {
+		t.Errorf("edited validation = %+v, want 1 declaration checked", r.Stats)
+	}
+
+	// Other options and other documents validate on their own
+	if r := c.ValidateDocument("a.wgsl", edited, api.ValidateOptions{Stats: true, Lint: true}); r.Stats.Rechecked != 4 || r.WarningCount == 0 {
+		t.Errorf("lint validation = %+v, want all declarations checked", r)
+	}
+	if r := c.ValidateDocument("b.wgsl", edited, opts); r.Stats.Rechecked != 4 {
+		t.Errorf("other document stats = %+v, want all declarations checked", r.Stats)
+	}
+	if r := c.Validate(edited, opts); r.Stats.Rechecked != 4 {
+		t.Errorf("stateless validation stats = %+v, want all declarations checked", r.Stats)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := New(minifier.DefaultOptions(), 2)
+	a, b, d := "const a = 1;", "const b = 2;", "const d = 3;"
+	c.Parse(a)
+	c.Parse(b)
+	c.Parse(a) // a is now more recently used than b
+	c.Parse(d) // evicts b
+	c.Parse(a)
+	c.Parse(b)
+	if _, stats := c.Stats(); stats != (CacheStats{Hits: 2, Misses: 4}) {
+		t.Errorf("stats = %+v, want b evicted and a kept", stats)
+	}
+}
+
+func TestConcurrentUse(t *testing.T) {
+	c := New(minifier.DefaultOptions(), 4)
+	want := minifier.New(minifier.DefaultOptions()).Minify(shader).Code
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 8; j++ {
+				// Mix shared and distinct sources so that entries are
+				// computed, reused and evicted concurrently
+				source := shader
+				if j%2 == 1 {
+					source = fmt.Sprintf("%s\nconst c%d: u32 = %du;", shader, i, j)
+				}
+				if got := c.Minify(shader).Code; got != want {
+					errs <- fmt.Errorf("goroutine %d: code = %q", i, got)
+				}
+				if r := c.Validate(source, api.ValidateOptions{Lint: j%4 == 0}); !r.Valid {
+					errs <- fmt.Errorf("goroutine %d: %+v", i, r.Diagnostics)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -1,6 +1,7 @@
 // Package config handles loading minifier configuration from files.
 //
-// Configuration can be specified in a JSON file named wgslmin.json or .wgslminrc.
+// Configuration can be specified in a JSON file named miniray.json or .minirayrc
+// (wgslmin.json and .wgslminrc are still recognized).
 // The config file is searched for in the current directory and parent directories.
 package config
 
@@ -40,10 +41,17 @@ type Config struct {
 
 	// KeepNames lists identifier names that should not be renamed
 	KeepNames []string `json:"keepNames,omitempty"`
+
+	// Lint sets the severity of lint rules used by validate --lint.
+	// Keys are rule names (e.g., "float_equality"), values are "error",
+	// "warning", "info" or "off".
+	Lint map[string]string `json:"lint,omitempty"`
 }
 
 // ConfigFileNames are the names searched for config files, in order of preference.
 var ConfigFileNames = []string{
+	"miniray.json",
+	".minirayrc",
 	"wgslmin.json",
 	".wgslminrc",
 	".wgslminrc.json",
@@ -362,3 +362,22 @@ func TestToOptionsEmptyKeepNames(t *testing.T) {
 		t.Errorf("MinifyIdentifiers: got %v, want true (default)", opts.MinifyIdentifiers)
 	}
 }
+
+func TestLoadMinirayJSONWithLintRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `{"lint": {"float_equality": "error", "prefer_let": "off"}}`
+	if err := os.WriteFile(filepath.Join(tmpDir, "miniray.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, path, err := Load(tmpDir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg == nil || filepath.Base(path) != "miniray.json" {
+		t.Fatalf("expected miniray.json to be found, got %q", path)
+	}
+	if cfg.Lint["float_equality"] != "error" || cfg.Lint["prefer_let"] != "off" {
+		t.Errorf("Lint: got %v", cfg.Lint)
+	}
+}
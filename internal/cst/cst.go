@@ -0,0 +1,225 @@
+// Package cst provides a lossless concrete syntax tree for WGSL source.
+//
+// A Tree pairs the AST, whose nodes carry full byte ranges, with the token
+// stream in which every token keeps the whitespace and comments around it
+// as trivia. Printing an unmodified tree gives back the source byte for
+// byte. Edits replace, insert or delete whole tokens, so everything outside
+// the edited nodes keeps its original formatting; this is the basis for
+// codemods, refactorings and the formatter.
+//
+// Trivia attachment follows the usual convention: a token owns the trivia
+// after it up to the end of its line (trailing), and the next token owns
+// everything from the line break on (leading). Comments on the lines above
+// a declaration therefore belong to its first token.
+package cst
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/lexer"
+	"github.com/HugoDaniel/miniray/internal/parser"
+)
+
+// Tree is a parsed module together with its lossless token stream.
+type Tree struct {
+	Source string
+	Module *ast.Module
+	Tokens []lexer.Token
+
+	edits []edit
+}
+
+// edit rewrites the tokens first..last (inclusive).
+type edit struct {
+	first, last int
+	kind        editKind
+	text        string
+}
+
+type editKind uint8
+
+const (
+	editReplace editKind = iota
+	editDelete
+	editInsertBefore
+	editInsertAfter
+)
+
+// Parse parses source in lossless mode. The tree is usable even when
+// there are parse errors.
+func Parse(source string) (*Tree, []parser.ParseError) {
+	module, errs := parser.NewWithOptions(source, parser.Options{Lossless: true}).Parse()
+	return &Tree{
+		Source: source,
+		Module: module,
+		Tokens: module.Tokens,
+	}, errs
+}
+
+// Text returns the source text covered by loc.
+func (t *Tree) Text(loc ast.Loc) string {
+	if loc.Start < 0 || loc.End < loc.Start || int(loc.End) > len(t.Source) {
+		return ""
+	}
+	return t.Source[loc.Start:loc.End]
+}
+
+// TokenRange returns the indices of the first and last tokens inside loc.
+// ok is false when loc covers no token.
+func (t *Tree) TokenRange(loc ast.Loc) (first, last int, ok bool) {
+	first = sort.Search(len(t.Tokens), func(i int) bool {
+		return t.Tokens[i].Start >= int(loc.Start)
+	})
+	last = sort.Search(len(t.Tokens), func(i int) bool {
+		return t.Tokens[i].End > int(loc.End)
+	}) - 1
+	// The EOF token is never part of a node
+	for last >= first && t.Tokens[last].Kind == lexer.TokEOF {
+		last--
+	}
+	return first, last, first <= last
+}
+
+// LeadingComments returns the text of the comments in the leading trivia
+// of the node's first token, such as doc comments above a declaration.
+func (t *Tree) LeadingComments(loc ast.Loc) []string {
+	first, _, ok := t.TokenRange(loc)
+	if !ok {
+		return nil
+	}
+	var comments []string
+	for _, tr := range t.Tokens[first].Leading {
+		if tr.Kind == lexer.TriviaLineComment || tr.Kind == lexer.TriviaBlockComment {
+			comments = append(comments, tr.Text(t.Source))
+		}
+	}
+	return comments
+}
+
+// FullLoc extends loc over the leading trivia of its first token and the
+// trailing trivia of its last token: the text that Delete removes.
+func (t *Tree) FullLoc(loc ast.Loc) ast.Loc {
+	first, last, ok := t.TokenRange(loc)
+	if !ok {
+		return loc
+	}
+	full := ast.Loc{Start: int32(t.Tokens[first].Start), End: int32(t.Tokens[last].End)}
+	if leading := t.Tokens[first].Leading; len(leading) > 0 {
+		full.Start = int32(leading[0].Start)
+	}
+	if trailing := t.Tokens[last].Trailing; len(trailing) > 0 {
+		full.End = int32(trailing[len(trailing)-1].End)
+	}
+	return full
+}
+
+// Replace replaces the tokens of a node with text. The node's leading and
+// trailing trivia are kept.
+func (t *Tree) Replace(loc ast.Loc, text string) error {
+	return t.addEdit(loc, editReplace, text)
+}
+
+// Delete removes a node together with its leading and trailing trivia, so
+// deleting a statement also removes its line and attached comments.
+func (t *Tree) Delete(loc ast.Loc) error {
+	return t.addEdit(loc, editDelete, "")
+}
+
+// InsertBefore inserts text between a node's leading trivia and its first
+// token.
+func (t *Tree) InsertBefore(loc ast.Loc, text string) error {
+	return t.addEdit(loc, editInsertBefore, text)
+}
+
+// InsertAfter inserts text between a node's last token and its trailing
+// trivia.
+func (t *Tree) InsertAfter(loc ast.Loc, text string) error {
+	return t.addEdit(loc, editInsertAfter, text)
+}
+
+func (t *Tree) addEdit(loc ast.Loc, kind editKind, text string) error {
+	first, last, ok := t.TokenRange(loc)
+	if !ok {
+		return fmt.Errorf("range %d-%d contains no tokens", loc.Start, loc.End)
+	}
+	e := edit{first: first, last: last, kind: kind, text: text}
+	for _, other := range t.edits {
+		if e.overlaps(other) {
+			return fmt.Errorf("edit of range %d-%d overlaps an earlier edit", loc.Start, loc.End)
+		}
+	}
+	t.edits = append(t.edits, e)
+	return nil
+}
+
+// overlaps reports whether two edits touch the same tokens. Insertions
+// only conflict with replacements and deletions that swallow their anchor.
+func (e edit) overlaps(other edit) bool {
+	if e.last < other.first || other.last < e.first {
+		return false
+	}
+	isInsert := func(k editKind) bool { return k == editInsertBefore || k == editInsertAfter }
+	return !(isInsert(e.kind) && isInsert(other.kind))
+}
+
+// Print returns the source of the tree with all edits applied. Without
+// edits it reproduces the parsed source exactly.
+func (t *Tree) Print() string {
+	before := make(map[int][]string)
+	after := make(map[int][]string)
+	spans := make(map[int]edit)
+	for _, e := range t.edits {
+		switch e.kind {
+		case editInsertBefore:
+			before[e.first] = append(before[e.first], e.text)
+		case editInsertAfter:
+			after[e.last] = append(after[e.last], e.text)
+		default:
+			spans[e.first] = e
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(t.Source))
+	writeTrivia := func(trivia []lexer.Trivia) {
+		for _, tr := range trivia {
+			b.WriteString(tr.Text(t.Source))
+		}
+	}
+
+	for i := 0; i < len(t.Tokens); i++ {
+		tok := t.Tokens[i]
+		if e, ok := spans[i]; ok {
+			if e.kind == editReplace {
+				writeTrivia(tok.Leading)
+				b.WriteString(e.text)
+				writeTrivia(t.Tokens[e.last].Trailing)
+			}
+			i = e.last
+			continue
+		}
+
+		writeTrivia(tok.Leading)
+		for _, text := range before[i] {
+			b.WriteString(text)
+		}
+		b.WriteString(tok.Text(t.Source))
+		for _, text := range after[i] {
+			b.WriteString(text)
+		}
+		writeTrivia(tok.Trailing)
+	}
+
+	// The lexer stops at the first invalid token; keep whatever follows
+	end := 0
+	if n := len(t.Tokens); n > 0 {
+		end = t.Tokens[n-1].End
+	}
+	if end < len(t.Source) {
+		b.WriteString(t.Source[end:])
+	}
+	return b.String()
+}
@@ -0,0 +1,170 @@
+package cst
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+)
+
+func TestRoundTrip(t *testing.T) {
+	sources := []string{
+		"",
+		"   \n\t",
+		"// only a comment",
+		"const a = 1;",
+		"const a = 1; // trailing\n",
+		"/* header */\n\n// doc\nfn f() -> f32 {\r\n    return 1.0;   \r\n}\r\n",
+		"/* nested /* block */ comment */ var<private> x: array<vec4<f32>, 4>;",
+		"fn f() { let a = b >> 1u; let c = array<i32, 2>(1, 2); }",
+		"fn f() {\n  let a = 1 +;\n  foo bar;\n}\n",
+		"const a = 1;\nconst b = 2 $ rest of the file\n",
+		"fn f() { /* unterminated",
+	}
+	for _, source := range sources {
+		tree, _ := Parse(source)
+		if got := tree.Print(); got != source {
+			t.Errorf("round trip mismatch\ninput:  %q\noutput: %q", source, got)
+		}
+	}
+}
+
+func TestRoundTripTestdata(t *testing.T) {
+	var files []string
+	err := filepath.Walk("../../testdata", func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.HasSuffix(path, ".wgsl") {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no testdata shaders found")
+	}
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, _ := Parse(string(source))
+		if got := tree.Print(); got != string(source) {
+			t.Errorf("%s: printing the unmodified tree changed the source", file)
+		}
+	}
+}
+
+const shader = `// Scale factor
+const scale = 2.0;
+
+/// Doubles a value.
+fn double(x: f32) -> f32 {
+    let y = x * scale; // multiply
+    return y;
+}
+`
+
+func TestNodeRanges(t *testing.T) {
+	tree, errs := Parse(shader)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	constDecl := tree.Module.Declarations[0].(*ast.ConstDecl)
+	if got := tree.Text(constDecl.Loc); got != "const scale = 2.0;" {
+		t.Errorf("const range: got %q", got)
+	}
+	if got := tree.Text(constDecl.Initializer.(*ast.LiteralExpr).Loc); got != "2.0" {
+		t.Errorf("literal range: got %q", got)
+	}
+
+	fn := tree.Module.Declarations[1].(*ast.FunctionDecl)
+	if got := tree.Text(fn.Loc); !strings.HasPrefix(got, "fn double") || !strings.HasSuffix(got, "}") {
+		t.Errorf("function range: got %q", got)
+	}
+	if got := tree.Text(fn.Parameters[0].Loc); got != "x: f32" {
+		t.Errorf("parameter range: got %q", got)
+	}
+	if got := tree.Text(fn.ReturnType.(*ast.IdentType).Loc); got != "f32" {
+		t.Errorf("return type range: got %q", got)
+	}
+
+	let := fn.Body.Stmts[0].(*ast.DeclStmt)
+	if got := tree.Text(let.Loc); got != "let y = x * scale;" {
+		t.Errorf("let statement range: got %q", got)
+	}
+	init := let.Decl.(*ast.LetDecl).Initializer.(*ast.BinaryExpr)
+	if got := tree.Text(init.Loc); got != "x * scale" {
+		t.Errorf("binary expression range: got %q", got)
+	}
+
+	ret := fn.Body.Stmts[1].(*ast.ReturnStmt)
+	if got := tree.Text(ret.Loc); got != "return y;" {
+		t.Errorf("return range: got %q", got)
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	tree, _ := Parse(shader)
+
+	fn := tree.Module.Declarations[1].(*ast.FunctionDecl)
+	comments := tree.LeadingComments(fn.Loc)
+	if len(comments) != 1 || comments[0] != "/// Doubles a value." {
+		t.Errorf("expected doc comment on function, got %q", comments)
+	}
+
+	let := fn.Body.Stmts[0].(*ast.DeclStmt)
+	if got := tree.Text(tree.FullLoc(let.Loc)); got != "\n    let y = x * scale; // multiply" {
+		t.Errorf("full range: got %q", got)
+	}
+}
+
+func TestEdits(t *testing.T) {
+	tree, _ := Parse(shader)
+	fn := tree.Module.Declarations[1].(*ast.FunctionDecl)
+	let := fn.Body.Stmts[0].(*ast.DeclStmt)
+	init := let.Decl.(*ast.LetDecl).Initializer
+	ret := fn.Body.Stmts[1].(*ast.ReturnStmt)
+
+	if err := tree.Replace(init.(*ast.BinaryExpr).Loc, "x + x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.InsertBefore(ret.Loc, "// done\n    "); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Replace(let.Loc, "let z = 0.0;"); err == nil {
+		t.Error("expected overlapping edit to fail")
+	}
+
+	expected := strings.Replace(shader, "x * scale", "x + x", 1)
+	expected = strings.Replace(expected, "    return y;", "    // done\n    return y;", 1)
+	if got := tree.Print(); got != expected {
+		t.Errorf("\nexpected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestDeleteRemovesLineAndComments(t *testing.T) {
+	tree, _ := Parse(shader)
+	fn := tree.Module.Declarations[1].(*ast.FunctionDecl)
+	if err := tree.Delete(fn.Body.Stmts[0].(*ast.DeclStmt).Loc); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete(tree.Module.Declarations[0].(*ast.ConstDecl).Loc); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+
+/// Doubles a value.
+fn double(x: f32) -> f32 {
+    return y;
+}
+`
+	if got := tree.Print(); got != expected {
+		t.Errorf("\nexpected:\n%q\ngot:\n%q", expected, got)
+	}
+}
@@ -0,0 +1,240 @@
+package cst
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/lexer"
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/printer"
+)
+
+// alignWindow is how far ahead Format looks for a token the printer
+// dropped or added, such as a trailing comma.
+const alignWindow = 8
+
+// Format prints source with the indentation and spacing of the printer's
+// readable mode, keeping its comments. Source with syntax errors is not
+// formatted; its errors are returned instead.
+//
+// The printer drops comments, so they are put back next to the printed
+// token that owned them in the source: comments on their own line go on a
+// line of their own above the printed line of their token, block comments
+// after a token stay after it, and line comments after a token go to the
+// end of its printed line.
+func Format(source string) (string, []parser.ParseError) {
+	tree, errs := Parse(source)
+	if len(errs) > 0 {
+		return "", errs
+	}
+	printed := printer.New(printer.Options{}, tree.Module.Symbols).Print(tree.Module)
+	out, errs := Parse(printed)
+	if len(errs) > 0 {
+		// Should not happen; keep the printer's output rather than fail
+		return printed, nil
+	}
+
+	f := &formatter{source: source, printed: printed, lineEnds: make(map[int]bool)}
+	f.align(tree.Tokens, out.Tokens)
+	return f.String(), nil
+}
+
+type formatter struct {
+	source  string
+	printed string
+	inserts []insert
+	// lineEnds marks the offsets where a line comment was put
+	lineEnds map[int]bool
+}
+
+// insert is text to add at an offset of the printed code. Inserts at the
+// same offset keep their order.
+type insert struct {
+	offset int
+	text   string
+}
+
+// align walks the source and printed tokens together and moves the
+// comments of each source token to its printed counterpart.
+func (f *formatter) align(src, dst []lexer.Token) {
+	var eof []lexer.Trivia
+	if n := len(src); n > 0 && src[n-1].Kind == lexer.TokEOF {
+		eof = comments(src[n-1].Leading)
+	}
+	src, dst = withoutEOF(src), withoutEOF(dst)
+	var pending []lexer.Trivia // leading comments of dropped tokens
+	last := -1                 // last matched printed token
+
+	i, j := 0, 0
+	for i < len(src) && j < len(dst) {
+		if k := f.skip(src[i:], dst[j:]); k > 0 {
+			// The printer dropped src[i:i+k]; their comments move to the
+			// neighbouring printed tokens
+			for _, tok := range src[i : i+k] {
+				pending = append(pending, comments(tok.Leading)...)
+				if last >= 0 {
+					f.trailing(dst[last], comments(tok.Trailing))
+				} else {
+					pending = append(pending, comments(tok.Trailing)...)
+				}
+			}
+			i += k
+			continue
+		}
+		if k := f.skip(dst[j:], src[i:]); k > 0 {
+			// The printer added dst[j:j+k]
+			j += k
+			continue
+		}
+		f.leading(dst[j], append(pending, comments(src[i].Leading)...))
+		f.trailing(dst[j], comments(src[i].Trailing))
+		pending = nil
+		last = j
+		i++
+		j++
+	}
+
+	// Comments after the last printed token, including those at the end
+	// of the file, go at the end
+	for _, tok := range src[i:] {
+		pending = append(pending, comments(tok.Leading)...)
+		pending = append(pending, comments(tok.Trailing)...)
+	}
+	pending = append(pending, eof...)
+	if len(pending) == 0 {
+		return
+	}
+	var tail strings.Builder
+	if f.printed != "" && !strings.HasSuffix(f.printed, "\n") {
+		tail.WriteByte('\n')
+	}
+	for _, c := range pending {
+		tail.WriteString(c.Text(f.source) + "\n")
+	}
+	f.add(len(f.printed), tail.String())
+}
+
+// skip returns how many tokens at the start of a are missing from b: the
+// smallest k for which a[k:] and b start with the same two tokens. It is
+// 0 when the first tokens match or no such k exists.
+func (f *formatter) skip(a, b []lexer.Token) int {
+	if f.same(a, b, 0, 0) {
+		return 0
+	}
+	for k := 1; k <= alignWindow && k < len(a); k++ {
+		if f.same(a, b, k, 0) && (k+1 >= len(a) || len(b) < 2 || f.same(a, b, k+1, 1)) {
+			return k
+		}
+	}
+	return 0
+}
+
+// same reports whether a[i] and b[j] are the same token. Either may come
+// from the source or the printed code, so tokens are compared by value
+// rather than by their text at an offset.
+func (f *formatter) same(a, b []lexer.Token, i, j int) bool {
+	return i < len(a) && j < len(b) && a[i].Kind == b[j].Kind && a[i].Value == b[j].Value
+}
+
+// leading puts comments on lines of their own above the printed line of
+// tok, at its indentation.
+func (f *formatter) leading(tok lexer.Token, cs []lexer.Trivia) {
+	if len(cs) == 0 {
+		return
+	}
+	start := strings.LastIndexByte(f.printed[:tok.Start], '\n') + 1
+	for _, c := range cs {
+		f.add(start, f.indent(tok.Start)+c.Text(f.source)+"\n")
+	}
+}
+
+// trailing puts block comments right after tok and line comments at the
+// end of its printed line. Comments that would follow a line comment go on
+// a line of their own below it.
+func (f *formatter) trailing(tok lexer.Token, cs []lexer.Trivia) {
+	for _, c := range cs {
+		offset := tok.End
+		if c.Kind == lexer.TriviaLineComment {
+			if end := strings.IndexByte(f.printed[tok.End:], '\n'); end >= 0 {
+				offset += end
+			} else {
+				offset = len(f.printed)
+			}
+		}
+		if !f.lineEnds[offset] {
+			f.add(offset, " "+c.Text(f.source))
+			if c.Kind == lexer.TriviaLineComment {
+				f.lineEnds[offset] = true
+			}
+		} else if next := f.nextLine(offset); next < len(f.printed) {
+			// Where formatting again would put it: above the next line
+			f.add(next, f.indent(next)+c.Text(f.source)+"\n")
+		} else {
+			f.add(offset, "\n"+c.Text(f.source))
+		}
+	}
+}
+
+// nextLine returns the start of the first non-blank printed line after
+// the one containing offset, or the end of the code.
+func (f *formatter) nextLine(offset int) int {
+	for {
+		end := strings.IndexByte(f.printed[offset:], '\n')
+		if end < 0 {
+			return len(f.printed)
+		}
+		offset += end + 1
+		line := f.printed[offset:]
+		if end := strings.IndexByte(line, '\n'); end >= 0 {
+			line = line[:end]
+		}
+		if strings.TrimSpace(line) != "" {
+			return offset
+		}
+	}
+}
+
+// indent returns the indentation of the printed line containing offset.
+func (f *formatter) indent(offset int) string {
+	start := strings.LastIndexByte(f.printed[:offset], '\n') + 1
+	end := start
+	for end < len(f.printed) && (f.printed[end] == ' ' || f.printed[end] == '\t') {
+		end++
+	}
+	return f.printed[start:end]
+}
+
+func (f *formatter) add(offset int, text string) {
+	f.inserts = append(f.inserts, insert{offset, text})
+}
+
+// String returns the printed code with the comments inserted.
+func (f *formatter) String() string {
+	sort.SliceStable(f.inserts, func(a, b int) bool { return f.inserts[a].offset < f.inserts[b].offset })
+	var b strings.Builder
+	prev := 0
+	for _, in := range f.inserts {
+		b.WriteString(f.printed[prev:in.offset])
+		b.WriteString(in.text)
+		prev = in.offset
+	}
+	b.WriteString(f.printed[prev:])
+	return b.String()
+}
+
+func comments(trivia []lexer.Trivia) []lexer.Trivia {
+	var cs []lexer.Trivia
+	for _, tr := range trivia {
+		if tr.Kind == lexer.TriviaLineComment || tr.Kind == lexer.TriviaBlockComment {
+			cs = append(cs, tr)
+		}
+	}
+	return cs
+}
+
+func withoutEOF(tokens []lexer.Token) []lexer.Token {
+	for len(tokens) > 0 && tokens[len(tokens)-1].Kind == lexer.TokEOF {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
@@ -0,0 +1,96 @@
+package cst
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "comments above and inside a function",
+			input:    "// keep me\nfn f() -> f32 { /* why */ return 1.0; }",
+			expected: "// keep me\nfn f() -> f32 { /* why */\n    return 1.0;\n}\n",
+		},
+		{
+			name:     "comments on dropped tokens",
+			input:    "struct S {\n  a: f32, // first\n  b: f32, // last\n};\n// end\n",
+			expected: "struct S {\n    a: f32, // first\n    b: f32 // last\n}\n// end\n",
+		},
+		{
+			name:     "line comments of joined lines",
+			input:    "fn f() -> f32 {\n    return max(1.0, // one\n        2.0); // two\n}\n",
+			expected: "fn f() -> f32 {\n    return max(1.0, 2.0); // one\n// two\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := Format(tt.input)
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			if got != tt.expected {
+				t.Errorf("\nexpected:\n%q\ngot:\n%q", tt.expected, got)
+			}
+		})
+	}
+
+	if _, errs := Format("fn f( {}"); len(errs) == 0 {
+		t.Error("expected syntax errors")
+	}
+}
+
+// TestFormatTestdata formats the test shaders, which must keep all their
+// comments, parse, and not change when formatted again.
+func TestFormatTestdata(t *testing.T) {
+	var files []string
+	err := filepath.Walk("../../testdata", func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && strings.HasSuffix(path, ".wgsl") {
+			files = append(files, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, errs := Format(string(source))
+		if len(errs) > 0 {
+			continue
+		}
+		if _, errs := Parse(formatted); len(errs) > 0 {
+			t.Errorf("%s: formatted code does not parse: %s", file, errs[0].Message)
+			continue
+		}
+		if want, got := commentTexts(string(source)), commentTexts(formatted); !slices.Equal(want, got) {
+			t.Errorf("%s: formatting kept %d of %d comments", file, len(got), len(want))
+		}
+		if again, _ := Format(formatted); again != formatted {
+			t.Errorf("%s: formatting again changed the code", file)
+		}
+	}
+}
+
+func commentTexts(source string) []string {
+	tree, _ := Parse(source)
+	var texts []string
+	for _, tok := range tree.Tokens {
+		for _, c := range append(comments(tok.Leading), comments(tok.Trailing)...) {
+			texts = append(texts, c.Text(source))
+		}
+	}
+	slices.Sort(texts)
+	return texts
+}
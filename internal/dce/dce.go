@@ -19,24 +19,20 @@ func Mark(module *ast.Module) int {
 		return 0
 	}
 
-	// Build dependency graph: for each symbol, which other symbols does it reference?
-	deps := buildDependencyGraph(module)
-
-	// Find entry points
-	entryPoints := findEntryPoints(module)
+	live := Reachable(module)
 
 	// If no entry points found, mark everything as live (conservative)
-	if len(entryPoints) == 0 {
+	if live == nil {
 		for i := range module.Symbols {
 			module.Symbols[i].Flags |= ast.IsLive
 		}
 		return 0
 	}
 
-	// Mark reachable symbols starting from entry points
-	visited := make(map[uint32]bool)
-	for _, ep := range entryPoints {
-		markLive(ep, module.Symbols, deps, visited)
+	for idx := range live {
+		if int(idx) < len(module.Symbols) {
+			module.Symbols[idx].Flags |= ast.IsLive
+		}
 	}
 
 	// Count dead symbols
@@ -50,6 +46,42 @@ func Mark(module *ast.Module) int {
 	return deadCount
 }
 
+// Reachable returns the symbols reachable from entry points, as Mark
+// would mark them, without changing the module, so it can run while other
+// goroutines read the module. It returns nil when the
+// module has no entry points, in which case every symbol is live.
+func Reachable(module *ast.Module) map[uint32]bool {
+	if module == nil {
+		return nil
+	}
+
+	// Build dependency graph: for each symbol, which other symbols does it reference?
+	deps := buildDependencyGraph(module)
+
+	// Find entry points
+	entryPoints := findEntryPoints(module)
+	if len(entryPoints) == 0 {
+		return nil
+	}
+
+	// Collect reachable symbols starting from entry points
+	visited := make(map[uint32]bool)
+	for _, ep := range entryPoints {
+		markLive(ep, nil, deps, visited)
+	}
+	return visited
+}
+
+// DependencyGraph returns, for each declared symbol, the symbols its
+// declaration references, one entry per reference. For functions this is
+// the call graph together with the types, constants and variables used.
+func DependencyGraph(module *ast.Module) map[uint32][]uint32 {
+	if module == nil {
+		return nil
+	}
+	return buildDependencyGraph(module)
+}
+
 // buildDependencyGraph builds a map from symbol index to the symbols it references.
 func buildDependencyGraph(module *ast.Module) map[uint32][]uint32 {
 	deps := make(map[uint32][]uint32)
@@ -78,6 +110,7 @@ func collectDeclDeps(decl ast.Decl, deps map[uint32][]uint32) {
 				refs = collectExprRefs(d.Initializer)
 			}
 			refs = append(refs, collectTypeRefs(d.Type)...)
+			refs = append(refs, collectAttributeRefs(d.Attributes)...)
 			deps[d.Name.InnerIndex] = refs
 		}
 
@@ -88,6 +121,7 @@ func collectDeclDeps(decl ast.Decl, deps map[uint32][]uint32) {
 				refs = collectExprRefs(d.Initializer)
 			}
 			refs = append(refs, collectTypeRefs(d.Type)...)
+			refs = append(refs, collectAttributeRefs(d.Attributes)...)
 			deps[d.Name.InnerIndex] = refs
 		}
 
@@ -100,12 +134,15 @@ func collectDeclDeps(decl ast.Decl, deps map[uint32][]uint32) {
 
 	case *ast.FunctionDecl:
 		if d.Name.IsValid() {
-			var refs []uint32
+			// Collect from attributes such as @workgroup_size(N)
+			refs := collectAttributeRefs(d.Attributes)
 			// Collect from parameters
 			for _, param := range d.Parameters {
+				refs = append(refs, collectAttributeRefs(param.Attributes)...)
 				refs = append(refs, collectTypeRefs(param.Type)...)
 			}
 			// Collect from return type
+			refs = append(refs, collectAttributeRefs(d.ReturnAttr)...)
 			refs = append(refs, collectTypeRefs(d.ReturnType)...)
 			// Collect from body
 			if d.Body != nil {
@@ -118,6 +155,7 @@ func collectDeclDeps(decl ast.Decl, deps map[uint32][]uint32) {
 		if d.Name.IsValid() {
 			var refs []uint32
 			for _, member := range d.Members {
+				refs = append(refs, collectAttributeRefs(member.Attributes)...)
 				refs = append(refs, collectTypeRefs(member.Type)...)
 			}
 			deps[d.Name.InnerIndex] = refs
@@ -131,6 +169,17 @@ func collectDeclDeps(decl ast.Decl, deps map[uint32][]uint32) {
 	}
 }
 
+// collectAttributeRefs collects symbol references from attribute arguments.
+func collectAttributeRefs(attrs []ast.Attribute) []uint32 {
+	var refs []uint32
+	for _, attr := range attrs {
+		for _, arg := range attr.Args {
+			refs = append(refs, collectExprRefs(arg)...)
+		}
+	}
+	return refs
+}
+
 // collectExprRefs collects symbol references from an expression.
 func collectExprRefs(expr ast.Expr) []uint32 {
 	if expr == nil {
@@ -252,7 +301,9 @@ func collectStmtRefs(stmt ast.Stmt) []uint32 {
 
 	case *ast.LoopStmt:
 		refs = append(refs, collectStmtRefs(s.Body)...)
-		refs = append(refs, collectStmtRefs(s.Continuing)...)
+		if s.Continuing != nil {
+			refs = append(refs, collectStmtRefs(s.Continuing)...)
+		}
 
 	case *ast.BreakIfStmt:
 		refs = append(refs, collectExprRefs(s.Condition)...)
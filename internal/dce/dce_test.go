@@ -136,6 +136,56 @@ const unused = 42;
 	}
 }
 
+func TestMark_AttributeDependencies(t *testing.T) {
+	// Constants named in @workgroup_size must stay live
+	source := `
+override blockSize: u32 = 64u;
+const height = 4u;
+@compute @workgroup_size(blockSize, height) fn main() {}
+`
+	p := parser.New(source)
+	module, errs := p.Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	Mark(module)
+
+	for _, sym := range module.Symbols {
+		if !sym.Flags.Has(ast.IsLive) {
+			t.Errorf("symbol '%s' should be marked as live", sym.OriginalName)
+		}
+	}
+}
+
+func TestMark_IOAttributeDependencies(t *testing.T) {
+	// Constants named in @location on parameters, return values and struct
+	// members must stay live
+	source := `
+const inSlot: u32 = 1u;
+const outSlot: u32 = 0u;
+const memberSlot: u32 = 2u;
+struct In { @location(memberSlot) uv: vec2f }
+@fragment fn main(@location(inSlot) c: vec4f, i: In) -> @location(outSlot) vec4f { return c; }
+`
+	p := parser.New(source)
+	module, errs := p.Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	Mark(module)
+
+	for _, sym := range module.Symbols {
+		switch sym.OriginalName {
+		case "inSlot", "outSlot", "memberSlot", "In":
+			if !sym.Flags.Has(ast.IsLive) {
+				t.Errorf("symbol '%s' should be marked as live", sym.OriginalName)
+			}
+		}
+	}
+}
+
 // ----------------------------------------------------------------------------
 // collectDeclDeps Tests
 // ----------------------------------------------------------------------------
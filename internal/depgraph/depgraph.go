@@ -0,0 +1,274 @@
+// Package depgraph reports the dependencies between the module-scope
+// declarations of a WGSL module: the call graph of its functions and the
+// types, constants and variables each declaration uses. It is the graph
+// tree shaking works on (dce.DependencyGraph), with entry points, dead
+// declarations and dependency cycles marked.
+//
+// The graph is available as JSON (Graph), Graphviz DOT and Mermaid.
+package depgraph
+
+import (
+	"sort"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/dce"
+	"github.com/HugoDaniel/miniray/internal/sourcemap"
+)
+
+// Version is the schema version of the JSON graph. It changes when a field
+// is removed or changes meaning.
+const Version = 1
+
+// Edge kinds, by what the dependency is.
+const (
+	EdgeCall = "call" // a function calls a function
+	EdgeType = "type" // a declaration uses a struct or alias
+	EdgeUse  = "use"  // a declaration uses a const, override or var
+)
+
+// Graph is the dependency graph of a module.
+type Graph struct {
+	Version int `json:"version"`
+	// Nodes are the module-scope declarations in source order.
+	Nodes []Node `json:"nodes"`
+	// Edges are sorted by the order of their nodes; each pair of nodes
+	// has at most one edge.
+	Edges []Edge `json:"edges"`
+	// EntryPoints lists the entry points in source order. Without entry
+	// points, as in a shared library, no declaration is dead.
+	EntryPoints []EntryPoint `json:"entryPoints"`
+	// Cycles lists the groups of declarations that depend on each other,
+	// such as mutually recursive functions, which WGSL rejects.
+	Cycles [][]string `json:"cycles"`
+}
+
+// Node is a module-scope declaration. Names are unique in a module, so
+// edges and lists refer to nodes by name.
+type Node struct {
+	Name string `json:"name"`
+	// Kind is function, struct, alias, const, override or var.
+	Kind string `json:"kind"`
+	// Line is the 1-based line of the declaration.
+	Line int `json:"line"`
+	// Stage is the stage of an entry point: vertex, fragment or compute.
+	Stage string `json:"stage,omitempty"`
+	// Dead marks declarations no entry point reaches, which tree shaking
+	// removes.
+	Dead bool `json:"dead"`
+	// Recursive marks declarations that are part of a cycle.
+	Recursive bool `json:"recursive"`
+}
+
+// Edge is a dependency of From on To.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// EntryPoint is an entry point with the declarations it reaches, directly
+// or indirectly, in source order.
+type EntryPoint struct {
+	Name      string   `json:"name"`
+	Stage     string   `json:"stage"`
+	Reachable []string `json:"reachable"`
+}
+
+// Build computes the dependency graph of a parsed module.
+func Build(module *ast.Module) *Graph {
+	g := &Graph{
+		Version:     Version,
+		Nodes:       []Node{},
+		Edges:       []Edge{},
+		EntryPoints: []EntryPoint{},
+		Cycles:      [][]string{},
+	}
+	lines := sourcemap.NewLineIndex(module.Source)
+
+	// Nodes, indexed by symbol
+	index := make(map[uint32]int)
+	var refs []uint32
+	for _, decl := range module.Declarations {
+		ref, loc, kind, stage := declInfo(decl)
+		if !ref.IsValid() || int(ref.InnerIndex) >= len(module.Symbols) {
+			continue
+		}
+		if _, ok := index[ref.InnerIndex]; ok {
+			continue
+		}
+		line, _ := lines.ByteOffsetToLineColumn(int(loc.Start))
+		index[ref.InnerIndex] = len(g.Nodes)
+		refs = append(refs, ref.InnerIndex)
+		g.Nodes = append(g.Nodes, Node{
+			Name:  module.Symbols[ref.InnerIndex].OriginalName,
+			Kind:  kind,
+			Line:  line + 1,
+			Stage: stage,
+		})
+	}
+
+	// Edges between declarations; references to locals, parameters and
+	// builtins are not part of the graph
+	deps := dce.DependencyGraph(module)
+	adjacency := make([][]int, len(g.Nodes))
+	for from, ref := range refs {
+		seen := make(map[int]bool)
+		for _, dep := range deps[ref] {
+			to, ok := index[dep]
+			if !ok || seen[to] {
+				continue
+			}
+			seen[to] = true
+			adjacency[from] = append(adjacency[from], to)
+		}
+		sort.Ints(adjacency[from])
+		for _, to := range adjacency[from] {
+			g.Edges = append(g.Edges, Edge{From: g.Nodes[from].Name, To: g.Nodes[to].Name, Kind: edgeKind(g.Nodes[to].Kind)})
+		}
+	}
+
+	// Entry points and what they reach
+	reached := make([]bool, len(g.Nodes))
+	for i, n := range g.Nodes {
+		if n.Stage == "" {
+			continue
+		}
+		visited := make([]bool, len(g.Nodes))
+		visit(i, adjacency, visited)
+		ep := EntryPoint{Name: n.Name, Stage: n.Stage, Reachable: []string{}}
+		for j, ok := range visited {
+			if ok {
+				reached[j] = true
+				if j != i {
+					ep.Reachable = append(ep.Reachable, g.Nodes[j].Name)
+				}
+			}
+		}
+		g.EntryPoints = append(g.EntryPoints, ep)
+	}
+	if len(g.EntryPoints) > 0 {
+		for i := range g.Nodes {
+			g.Nodes[i].Dead = !reached[i]
+		}
+	}
+
+	for _, cycle := range cycles(adjacency) {
+		names := make([]string, 0, len(cycle))
+		for _, i := range cycle {
+			g.Nodes[i].Recursive = true
+			names = append(names, g.Nodes[i].Name)
+		}
+		g.Cycles = append(g.Cycles, names)
+	}
+	return g
+}
+
+// declInfo returns the symbol, location and node kind of a declaration,
+// and the stage of an entry point.
+func declInfo(decl ast.Decl) (ref ast.Ref, loc ast.Loc, kind, stage string) {
+	switch d := decl.(type) {
+	case *ast.FunctionDecl:
+		for _, attr := range d.Attributes {
+			switch attr.Name {
+			case "vertex", "fragment", "compute":
+				stage = attr.Name
+			}
+		}
+		return d.Name, d.Loc, "function", stage
+	case *ast.StructDecl:
+		return d.Name, d.Loc, "struct", ""
+	case *ast.AliasDecl:
+		return d.Name, d.Loc, "alias", ""
+	case *ast.ConstDecl:
+		return d.Name, d.Loc, "const", ""
+	case *ast.OverrideDecl:
+		return d.Name, d.Loc, "override", ""
+	case *ast.VarDecl:
+		return d.Name, d.Loc, "var", ""
+	}
+	return ast.InvalidRef(), ast.Loc{}, "", ""
+}
+
+func edgeKind(target string) string {
+	switch target {
+	case "function":
+		return EdgeCall
+	case "struct", "alias":
+		return EdgeType
+	default:
+		return EdgeUse
+	}
+}
+
+func visit(i int, adjacency [][]int, visited []bool) {
+	if visited[i] {
+		return
+	}
+	visited[i] = true
+	for _, j := range adjacency[i] {
+		visit(j, adjacency, visited)
+	}
+}
+
+// cycles returns the strongly connected components of the graph that form
+// cycles: those with more than one node, or one node depending on itself.
+// Components are ordered by their first node, and nodes in order.
+func cycles(adjacency [][]int) [][]int {
+	// Tarjan's algorithm
+	n := len(adjacency)
+	order := make([]int, n)
+	low := make([]int, n)
+	onStack := make([]bool, n)
+	var stack []int
+	var result [][]int
+	counter := 1
+
+	var connect func(v int)
+	connect = func(v int) {
+		order[v], low[v] = counter, counter
+		counter++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range adjacency[v] {
+			if order[w] == 0 {
+				connect(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], order[w])
+			}
+		}
+		if low[v] != order[v] {
+			return
+		}
+		var component []int
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop(v, adjacency) {
+			sort.Ints(component)
+			result = append(result, component)
+		}
+	}
+	for v := 0; v < n; v++ {
+		if order[v] == 0 {
+			connect(v)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i][0] < result[j][0] })
+	return result
+}
+
+func selfLoop(v int, adjacency [][]int) bool {
+	for _, w := range adjacency[v] {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
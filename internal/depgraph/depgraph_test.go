@@ -0,0 +1,153 @@
+package depgraph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/parser"
+)
+
+const testShader = `struct Light { color: vec3f }
+struct Unused { x: f32 }
+@group(0) @binding(0) var<uniform> light: Light;
+const scale = 2.0;
+fn shade(c: vec3f) -> vec3f { return c * scale; }
+fn helper() -> f32 { return 1.0; }
+fn ping(x: i32) -> i32 { return pong(x); }
+fn pong(x: i32) -> i32 { return ping(x); }
+@fragment fn main() -> @location(0) vec4f { return vec4f(shade(light.color), 1.0); }
+@vertex fn vs() -> @builtin(position) vec4f { return vec4f(helper()); }
+`
+
+func build(t *testing.T, source string) *Graph {
+	t.Helper()
+	module, errs := parser.New(source).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return Build(module)
+}
+
+func node(t *testing.T, g *Graph, name string) Node {
+	t.Helper()
+	for _, n := range g.Nodes {
+		if n.Name == name {
+			return n
+		}
+	}
+	t.Fatalf("no node %q in %+v", name, g.Nodes)
+	return Node{}
+}
+
+func TestBuild(t *testing.T) {
+	g := build(t, testShader)
+
+	if len(g.Nodes) != 10 || g.Nodes[0].Name != "Light" || g.Nodes[9].Name != "vs" {
+		t.Fatalf("nodes = %+v", g.Nodes)
+	}
+	if n := node(t, g, "main"); n.Stage != "fragment" || n.Line != 9 || n.Dead {
+		t.Errorf("main = %+v", n)
+	}
+	for _, name := range []string{"Unused", "ping", "pong"} {
+		if !node(t, g, name).Dead {
+			t.Errorf("%s should be dead", name)
+		}
+	}
+	for _, name := range []string{"Light", "light", "scale", "shade", "helper"} {
+		if node(t, g, name).Dead {
+			t.Errorf("%s should be live", name)
+		}
+	}
+
+	wantEdges := []Edge{
+		{"light", "Light", EdgeType},
+		{"shade", "scale", EdgeUse},
+		{"ping", "pong", EdgeCall},
+		{"pong", "ping", EdgeCall},
+		{"main", "light", EdgeUse},
+		{"main", "shade", EdgeCall},
+		{"vs", "helper", EdgeCall},
+	}
+	if !reflect.DeepEqual(g.Edges, wantEdges) {
+		t.Errorf("edges = %+v\nwant %+v", g.Edges, wantEdges)
+	}
+}
+
+func TestEntryPointReachability(t *testing.T) {
+	g := build(t, testShader)
+
+	want := []EntryPoint{
+		{Name: "main", Stage: "fragment", Reachable: []string{"Light", "light", "scale", "shade"}},
+		{Name: "vs", Stage: "vertex", Reachable: []string{"helper"}},
+	}
+	if !reflect.DeepEqual(g.EntryPoints, want) {
+		t.Errorf("entry points = %+v\nwant %+v", g.EntryPoints, want)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	g := build(t, testShader+"struct Node { next: array<Node, 2> }\nfn self_call() { self_call(); }\n")
+
+	want := [][]string{{"ping", "pong"}, {"Node"}, {"self_call"}}
+	if !reflect.DeepEqual(g.Cycles, want) {
+		t.Errorf("cycles = %v, want %v", g.Cycles, want)
+	}
+	if !node(t, g, "ping").Recursive || node(t, g, "shade").Recursive {
+		t.Error("only declarations in cycles are recursive")
+	}
+}
+
+func TestLibraryWithoutEntryPoints(t *testing.T) {
+	g := build(t, "fn a() -> f32 { return b(); }\nfn b() -> f32 { return 1.0; }\n")
+
+	if len(g.EntryPoints) != 0 {
+		t.Errorf("entry points = %+v", g.EntryPoints)
+	}
+	for _, n := range g.Nodes {
+		if n.Dead {
+			t.Errorf("%s is dead in a module without entry points", n.Name)
+		}
+	}
+}
+
+func TestLocalsAreNotNodes(t *testing.T) {
+	g := build(t, "const k = 1.0;\n@compute @workgroup_size(1) fn main() { let x = k; var y = x; y += 1.0; }\n")
+
+	if len(g.Nodes) != 2 || len(g.Edges) != 1 || g.Edges[0] != (Edge{"main", "k", EdgeUse}) {
+		t.Errorf("nodes %+v, edges %+v", g.Nodes, g.Edges)
+	}
+}
+
+func TestDOT(t *testing.T) {
+	dot := build(t, testShader).DOT()
+	for _, want := range []string{
+		"digraph shader {",
+		`"main" [label="@fragment\nmain", shape=box, style=bold];`,
+		`"Unused" [label="struct Unused", shape=ellipse, style=dashed, color=gray, fontcolor=gray];`,
+		`"main" -> "shade";`,
+		`"main" -> "light" [style=dashed];`,
+		`"ping" -> "pong" [color=red];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output lacks %s:\n%s", want, dot)
+		}
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	mermaid := build(t, testShader).Mermaid()
+	for _, want := range []string{
+		"flowchart LR\n",
+		`n8(["@fragment main"])`,
+		`n0("struct Light")`,
+		"n8 --> n4\n",
+		"n2 -.-> n0\n",
+		"class n1,n6,n7 dead\n",
+		"class n6,n7 recursive\n",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output lacks %q:\n%s", want, mermaid)
+		}
+	}
+}
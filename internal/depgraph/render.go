@@ -0,0 +1,126 @@
+package depgraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DOT renders the graph in the Graphviz DOT language. Functions are boxes
+// and entry points bold boxes; other declarations are ellipses. Dead
+// declarations are gray and dashed, and edges within a cycle are red.
+// Type and use edges are dashed, calls solid.
+func (g *Graph) DOT() string {
+	var sb strings.Builder
+	sb.WriteString("digraph shader {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [fontname=\"monospace\"];\n")
+
+	recursive := g.recursive()
+	for _, n := range g.Nodes {
+		var attrs []string
+		label := n.Name
+		if n.Stage != "" {
+			label = "@" + n.Stage + "\\n" + n.Name
+		} else if n.Kind != "function" {
+			label = n.Kind + " " + n.Name
+		}
+		attrs = append(attrs, "label="+quoteDOT(label))
+		if n.Kind == "function" {
+			attrs = append(attrs, "shape=box")
+		} else {
+			attrs = append(attrs, "shape=ellipse")
+		}
+		switch {
+		case n.Stage != "":
+			attrs = append(attrs, "style=bold")
+		case n.Dead:
+			attrs = append(attrs, "style=dashed", "color=gray", "fontcolor=gray")
+		}
+		fmt.Fprintf(&sb, "  %s [%s];\n", quoteDOT(n.Name), strings.Join(attrs, ", "))
+	}
+
+	for _, e := range g.Edges {
+		var attrs []string
+		if e.Kind != EdgeCall {
+			attrs = append(attrs, "style=dashed")
+		}
+		if recursive[e.From] != 0 && recursive[e.From] == recursive[e.To] {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(&sb, "  %s -> %s", quoteDOT(e.From), quoteDOT(e.To))
+		if len(attrs) > 0 {
+			fmt.Fprintf(&sb, " [%s]", strings.Join(attrs, ", "))
+		}
+		sb.WriteString(";\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, with the same
+// conventions as DOT: entry points are stadiums, other functions
+// rectangles and other declarations rounded; dead declarations and cycles
+// get the "dead" and "recursive" classes.
+func (g *Graph) Mermaid() string {
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+
+	// Node names are WGSL identifiers, but Mermaid reserves some words
+	// such as "end", so nodes get numbered IDs
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.Name] = id
+		switch {
+		case n.Stage != "":
+			fmt.Fprintf(&sb, "  %s([\"@%s %s\"])\n", id, n.Stage, n.Name)
+		case n.Kind == "function":
+			fmt.Fprintf(&sb, "  %s[\"%s\"]\n", id, n.Name)
+		default:
+			fmt.Fprintf(&sb, "  %s(\"%s %s\")\n", id, n.Kind, n.Name)
+		}
+	}
+
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Kind != EdgeCall {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&sb, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+	}
+
+	var dead, recursive []string
+	for _, n := range g.Nodes {
+		if n.Dead {
+			dead = append(dead, ids[n.Name])
+		}
+		if n.Recursive {
+			recursive = append(recursive, ids[n.Name])
+		}
+	}
+	if len(dead) > 0 {
+		sb.WriteString("  classDef dead stroke-dasharray:4,color:#888\n")
+		fmt.Fprintf(&sb, "  class %s dead\n", strings.Join(dead, ","))
+	}
+	if len(recursive) > 0 {
+		sb.WriteString("  classDef recursive stroke:#d00\n")
+		fmt.Fprintf(&sb, "  class %s recursive\n", strings.Join(recursive, ","))
+	}
+	return sb.String()
+}
+
+// recursive numbers the cycles from 1 and maps each node in a cycle to
+// its number.
+func (g *Graph) recursive() map[string]int {
+	m := make(map[string]int)
+	for i, cycle := range g.Cycles {
+		for _, name := range cycle {
+			m[name] = i + 1
+		}
+	}
+	return m
+}
+
+func quoteDOT(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
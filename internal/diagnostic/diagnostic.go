@@ -302,6 +302,7 @@ const (
 	CodeMissingReturn      DiagnosticCode = "E0208"
 	CodeInvalidConversion  DiagnosticCode = "E0209"
 	CodeInvalidAssignment  DiagnosticCode = "E0210"
+	CodeInvalidTexelFormat DiagnosticCode = "E0211"
 
 	// Declaration errors (E03xx)
 	CodeMissingInitializer DiagnosticCode = "E0300"
@@ -328,6 +329,11 @@ const (
 	CodeInvalidEntryPoint  DiagnosticCode = "E0600"
 	CodeMissingEntryPoint  DiagnosticCode = "E0601"
 	CodeInvalidShaderIO    DiagnosticCode = "E0602"
+	CodeDuplicateLocation  DiagnosticCode = "E0603"
+	CodeDuplicateBuiltin   DiagnosticCode = "E0604"
+	CodeInterfaceTypeMismatch     DiagnosticCode = "E0605"
+	CodeInterfaceMissingLocation  DiagnosticCode = "E0606"
+	CodeInterpolationMismatch     DiagnosticCode = "E0607"
 
 	// Uniformity errors (E07xx)
 	CodeNonUniformDerivative DiagnosticCode = "E0700"
@@ -340,6 +346,10 @@ const (
 	CodeInvalidStorageVar   DiagnosticCode = "E0801"
 	CodeInvalidUniformVar   DiagnosticCode = "E0802"
 	CodeMissingBinding      DiagnosticCode = "E0803"
+	CodeInvalidAtomicAddressSpace DiagnosticCode = "E0804"
+	CodeAtomicDirectAccess        DiagnosticCode = "E0805"
+	CodeAtomicReadOnly            DiagnosticCode = "E0806"
+	CodeInvalidAtomicType         DiagnosticCode = "E0807"
 )
 
 // DiagnosticFilter controls which diagnostics are reported.
@@ -392,3 +402,14 @@ const (
 	RuleDerivativeUniformity = "derivative_uniformity"
 	RuleSubgroupUniformity   = "subgroup_uniformity"
 )
+
+// Lint rules (reported by validate --lint, warnings by default).
+const (
+	RuleUnusedFunction  = "unused_function"
+	RuleUnusedStruct    = "unused_struct"
+	RuleUnusedBinding   = "unused_binding"
+	RuleUnusedConstant  = "unused_constant"
+	RuleUnusedParameter = "unused_parameter"
+	RuleUnusedVariable  = "unused_variable"
+	RuleDiscardedResult = "discarded_result"
+)
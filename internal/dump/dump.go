@@ -0,0 +1,581 @@
+// Package dump serializes parsed modules and the validator's resolved
+// types to JSON, for tools that use miniray's analysis without linking Go.
+//
+// The schemas are documented in docs/json-dump.md. Version changes when a
+// field is removed or changes meaning; fields and node kinds may be added
+// without a version change.
+package dump
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/lexer"
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/preprocess"
+	"github.com/HugoDaniel/miniray/internal/sourcemap"
+)
+
+// Version is the schema version of the AST and types JSON.
+const Version = 1
+
+// Range is a source range. Offsets are bytes, End is exclusive; lines and
+// columns are 1-based, with columns in bytes as in diagnostics.
+type Range struct {
+	Start     int `json:"start"`
+	End       int `json:"end"`
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"endLine"`
+	EndColumn int `json:"endColumn"`
+}
+
+// Error is a parse error; Range covers the offending token.
+type Error struct {
+	Message string `json:"message"`
+	Range   Range  `json:"range"`
+}
+
+// Symbol is an entry of the symbol table. Nodes refer to symbols by ID,
+// their index in the table.
+type Symbol struct {
+	ID    int      `json:"id"`
+	Name  string   `json:"name"`
+	Kind  string   `json:"kind"`
+	Flags []string `json:"flags,omitempty"`
+	Range *Range   `json:"range,omitempty"`
+}
+
+// Scope is a lexical scope: the symbols declared directly in it, by name,
+// and its nested scopes.
+type Scope struct {
+	Members  []ScopeMember `json:"members"`
+	Children []*Scope      `json:"children,omitempty"`
+}
+
+// ScopeMember is a name declared in a scope.
+type ScopeMember struct {
+	Name   string `json:"name"`
+	Symbol int    `json:"symbol"`
+}
+
+// AST is the JSON document of a module. Directives, Declarations and the
+// nodes below them are objects with a "kind" and a "range" followed by
+// the fields of that kind of node.
+type AST struct {
+	Version      int              `json:"version"`
+	Directives   []json.Marshaler `json:"directives"`
+	Declarations []json.Marshaler `json:"declarations"`
+	Symbols      []Symbol         `json:"symbols"`
+	Scope        *Scope           `json:"scope"`
+	Errors       []Error          `json:"errors"`
+}
+
+// Module converts a parsed module and its parse errors. When the module
+// was parsed from the code of pre, ranges are positions in the source
+// before preprocessing; pre is nil otherwise.
+func Module(module *ast.Module, errs []parser.ParseError, pre *preprocess.Result) *AST {
+	e := newEncoder(module, pre)
+	doc := &AST{
+		Version:      Version,
+		Directives:   make([]json.Marshaler, 0, len(module.Directives)),
+		Declarations: make([]json.Marshaler, 0, len(module.Declarations)),
+		Symbols:      make([]Symbol, 0, len(module.Symbols)),
+		Scope:        e.scope(module.Scope),
+		Errors:       make([]Error, 0, len(errs)),
+	}
+	for _, err := range errs {
+		doc.Errors = append(doc.Errors, Error{
+			Message: err.Message,
+			Range:   e.rangeOf(ast.Loc{Start: int32(err.Pos), End: int32(err.End)}),
+		})
+	}
+	for _, d := range module.Directives {
+		doc.Directives = append(doc.Directives, e.directive(d))
+	}
+	for _, d := range module.Declarations {
+		doc.Declarations = append(doc.Declarations, e.decl(d))
+	}
+	for i, s := range module.Symbols {
+		sym := Symbol{ID: i, Name: s.OriginalName, Kind: symbolKinds[s.Kind], Flags: symbolFlags(s.Flags)}
+		// Builtins and unbound names are declared nowhere in the source
+		if s.Kind != ast.SymbolBuiltin && s.Kind != ast.SymbolUnbound {
+			r := e.symbolRange(s)
+			sym.Range = &r
+		}
+		doc.Symbols = append(doc.Symbols, sym)
+	}
+	return doc
+}
+
+var symbolKinds = [...]string{
+	ast.SymbolUnbound:   "unbound",
+	ast.SymbolConst:     "const",
+	ast.SymbolOverride:  "override",
+	ast.SymbolLet:       "let",
+	ast.SymbolVar:       "var",
+	ast.SymbolFunction:  "function",
+	ast.SymbolStruct:    "struct",
+	ast.SymbolAlias:     "alias",
+	ast.SymbolParameter: "parameter",
+	ast.SymbolBuiltin:   "builtin",
+	ast.SymbolMember:    "member",
+}
+
+func symbolFlags(flags ast.SymbolFlags) []string {
+	var names []string
+	for _, f := range []struct {
+		flag ast.SymbolFlags
+		name string
+	}{
+		{ast.MustNotBeRenamed, "mustNotBeRenamed"},
+		{ast.IsEntryPoint, "entryPoint"},
+		{ast.IsAPIFacing, "apiFacing"},
+		{ast.IsBuiltin, "builtin"},
+		{ast.IsExternalBinding, "externalBinding"},
+		{ast.IsLive, "live"},
+	} {
+		if flags.Has(f.flag) {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// encoder converts nodes, resolving offsets to the source before
+// preprocessing and to lines and columns.
+type encoder struct {
+	module *ast.Module
+	pre    *preprocess.Result
+	lines  *sourcemap.LineIndex
+}
+
+func newEncoder(module *ast.Module, pre *preprocess.Result) *encoder {
+	if pre == nil {
+		pre = preprocess.Identity(module.Source)
+	}
+	return &encoder{module: module, pre: pre, lines: sourcemap.NewLineIndex(pre.Source)}
+}
+
+func (e *encoder) rangeOf(loc ast.Loc) Range {
+	start, end := e.pre.OriginalOffset(int(loc.Start)), e.pre.OriginalOffset(int(loc.End))
+	if end < start {
+		end = start
+	}
+	line, col := e.lines.ByteOffsetToLineColumn(start)
+	endLine, endCol := e.lines.ByteOffsetToLineColumn(end)
+	return Range{
+		Start:     start,
+		End:       end,
+		Line:      line + 1,
+		Column:    col + 1,
+		EndLine:   endLine + 1,
+		EndColumn: endCol + 1,
+	}
+}
+
+// symbolRange returns the range of the name in a symbol's declaration.
+func (e *encoder) symbolRange(s ast.Symbol) Range {
+	loc := s.Loc
+	if loc.End <= loc.Start {
+		loc.End = loc.Start + int32(len(s.OriginalName))
+	}
+	return e.rangeOf(loc)
+}
+
+// ref returns the symbol ID of ref, or nil when it is unresolved.
+func (e *encoder) ref(ref ast.Ref) interface{} {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(e.module.Symbols) {
+		return nil
+	}
+	return int(ref.InnerIndex)
+}
+
+func (e *encoder) scope(s *ast.Scope) *Scope {
+	if s == nil {
+		return nil
+	}
+	out := &Scope{Members: make([]ScopeMember, 0, len(s.Members))}
+	for name, m := range s.Members {
+		out.Members = append(out.Members, ScopeMember{Name: name, Symbol: int(m.Ref.InnerIndex)})
+	}
+	sort.Slice(out.Members, func(i, j int) bool { return out.Members[i].Name < out.Members[j].Name })
+	for _, child := range s.Children {
+		out.Children = append(out.Children, e.scope(child))
+	}
+	return out
+}
+
+// node is a JSON object whose fields keep their order, so that "kind" and
+// "range" come first.
+type node []field
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+func (n node) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range n {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (e *encoder) node(kind string, loc ast.Loc, fields ...field) node {
+	return append(node{{"kind", kind}, {"range", e.rangeOf(loc)}}, fields...)
+}
+
+// ----------------------------------------------------------------------------
+// Directives and declarations
+// ----------------------------------------------------------------------------
+
+func (e *encoder) directive(d ast.Directive) json.Marshaler {
+	switch d := d.(type) {
+	case *ast.EnableDirective:
+		return e.node("enable", d.Loc, field{"features", d.Features})
+	case *ast.RequiresDirective:
+		return e.node("requires", d.Loc, field{"features", d.Features})
+	case *ast.DiagnosticDirective:
+		return e.node("diagnostic", d.Loc, field{"severity", d.Severity}, field{"rule", d.Rule})
+	}
+	return nil
+}
+
+func (e *encoder) decl(d ast.Decl) json.Marshaler {
+	switch d := d.(type) {
+	case *ast.ConstDecl:
+		return e.node("const", d.Loc,
+			field{"name", e.ref(d.Name)},
+			field{"type", e.typ(d.Type)},
+			field{"initializer", e.expr(d.Initializer)})
+	case *ast.OverrideDecl:
+		return e.node("override", d.Loc,
+			field{"attributes", e.attributes(d.Attributes)},
+			field{"name", e.ref(d.Name)},
+			field{"type", e.typ(d.Type)},
+			field{"initializer", e.expr(d.Initializer)})
+	case *ast.VarDecl:
+		return e.node("var", d.Loc,
+			field{"attributes", e.attributes(d.Attributes)},
+			field{"addressSpace", d.AddressSpace.String()},
+			field{"accessMode", d.AccessMode.String()},
+			field{"name", e.ref(d.Name)},
+			field{"type", e.typ(d.Type)},
+			field{"initializer", e.expr(d.Initializer)})
+	case *ast.LetDecl:
+		return e.node("let", d.Loc,
+			field{"name", e.ref(d.Name)},
+			field{"type", e.typ(d.Type)},
+			field{"initializer", e.expr(d.Initializer)})
+	case *ast.FunctionDecl:
+		params := make([]json.Marshaler, 0, len(d.Parameters))
+		for _, p := range d.Parameters {
+			params = append(params, e.node("parameter", p.Loc,
+				field{"attributes", e.attributes(p.Attributes)},
+				field{"name", e.ref(p.Name)},
+				field{"type", e.typ(p.Type)}))
+		}
+		return e.node("function", d.Loc,
+			field{"attributes", e.attributes(d.Attributes)},
+			field{"name", e.ref(d.Name)},
+			field{"parameters", params},
+			field{"returnAttributes", e.attributes(d.ReturnAttr)},
+			field{"returnType", e.typ(d.ReturnType)},
+			field{"body", e.block(d.Body)})
+	case *ast.StructDecl:
+		members := make([]json.Marshaler, 0, len(d.Members))
+		for _, m := range d.Members {
+			members = append(members, e.node("member", m.Loc,
+				field{"attributes", e.attributes(m.Attributes)},
+				field{"name", e.ref(m.Name)},
+				field{"type", e.typ(m.Type)}))
+		}
+		return e.node("struct", d.Loc, field{"name", e.ref(d.Name)}, field{"members", members})
+	case *ast.AliasDecl:
+		return e.node("alias", d.Loc, field{"name", e.ref(d.Name)}, field{"type", e.typ(d.Type)})
+	case *ast.ConstAssertDecl:
+		return e.node("const_assert", d.Loc, field{"expr", e.expr(d.Expr)})
+	}
+	return nil
+}
+
+func (e *encoder) attributes(attrs []ast.Attribute) []json.Marshaler {
+	out := make([]json.Marshaler, 0, len(attrs))
+	for _, a := range attrs {
+		out = append(out, e.node("attribute", a.Loc, field{"name", a.Name}, field{"args", e.exprs(a.Args)}))
+	}
+	return out
+}
+
+// ----------------------------------------------------------------------------
+// Types
+// ----------------------------------------------------------------------------
+
+func (e *encoder) typ(t ast.Type) json.Marshaler {
+	switch t := t.(type) {
+	case *ast.IdentType:
+		return e.node("ident_type", t.Loc, field{"name", t.Name}, field{"symbol", e.ref(t.Ref)})
+	case *ast.VecType:
+		return e.node("vec_type", t.Loc,
+			field{"size", t.Size},
+			field{"elemType", e.typ(t.ElemType)},
+			field{"shorthand", t.Shorthand})
+	case *ast.MatType:
+		return e.node("mat_type", t.Loc,
+			field{"cols", t.Cols},
+			field{"rows", t.Rows},
+			field{"elemType", e.typ(t.ElemType)},
+			field{"shorthand", t.Shorthand})
+	case *ast.ArrayType:
+		return e.node("array_type", t.Loc, field{"elemType", e.typ(t.ElemType)}, field{"size", e.expr(t.Size)})
+	case *ast.PtrType:
+		return e.node("ptr_type", t.Loc,
+			field{"addressSpace", t.AddressSpace.String()},
+			field{"elemType", e.typ(t.ElemType)},
+			field{"accessMode", t.AccessMode.String()})
+	case *ast.AtomicType:
+		return e.node("atomic_type", t.Loc, field{"elemType", e.typ(t.ElemType)})
+	case *ast.SamplerType:
+		return e.node("sampler_type", t.Loc, field{"comparison", t.Comparison})
+	case *ast.TextureType:
+		return e.node("texture_type", t.Loc,
+			field{"textureKind", textureKinds[t.Kind]},
+			field{"dimension", textureDimensions[t.Dimension]},
+			field{"sampledType", e.typ(t.SampledType)},
+			field{"texelFormat", t.TexelFormat},
+			field{"accessMode", t.AccessMode.String()})
+	}
+	return nil
+}
+
+var textureKinds = [...]string{
+	ast.TextureSampled:           "sampled",
+	ast.TextureMultisampled:      "multisampled",
+	ast.TextureStorage:           "storage",
+	ast.TextureDepth:             "depth",
+	ast.TextureDepthMultisampled: "depth_multisampled",
+	ast.TextureExternal:          "external",
+}
+
+var textureDimensions = [...]string{
+	ast.Texture1D:        "1d",
+	ast.Texture2D:        "2d",
+	ast.Texture2DArray:   "2d_array",
+	ast.Texture3D:        "3d",
+	ast.TextureCube:      "cube",
+	ast.TextureCubeArray: "cube_array",
+}
+
+// ----------------------------------------------------------------------------
+// Expressions
+// ----------------------------------------------------------------------------
+
+// ExprKind is the "kind" of an expression node.
+func ExprKind(expr ast.Expr) string {
+	switch expr.(type) {
+	case *ast.IdentExpr:
+		return "ident"
+	case *ast.LiteralExpr:
+		return "literal"
+	case *ast.BinaryExpr:
+		return "binary"
+	case *ast.UnaryExpr:
+		return "unary"
+	case *ast.CallExpr:
+		return "call"
+	case *ast.IndexExpr:
+		return "index"
+	case *ast.MemberExpr:
+		return "member_access"
+	case *ast.ParenExpr:
+		return "paren"
+	case *ast.ErrorExpr:
+		return "error"
+	}
+	return ""
+}
+
+func (e *encoder) exprs(exprs []ast.Expr) []json.Marshaler {
+	out := make([]json.Marshaler, 0, len(exprs))
+	for _, x := range exprs {
+		out = append(out, e.expr(x))
+	}
+	return out
+}
+
+func (e *encoder) expr(expr ast.Expr) json.Marshaler {
+	kind := ExprKind(expr)
+	switch x := expr.(type) {
+	case *ast.IdentExpr:
+		return e.node(kind, x.Loc, field{"name", x.Name}, field{"symbol", e.ref(x.Ref)})
+	case *ast.LiteralExpr:
+		return e.node(kind, x.Loc, field{"literalKind", literalKind(x.Kind)}, field{"value", x.Value})
+	case *ast.BinaryExpr:
+		return e.node(kind, x.Loc,
+			field{"op", binaryOps[x.Op]},
+			field{"left", e.expr(x.Left)},
+			field{"right", e.expr(x.Right)})
+	case *ast.UnaryExpr:
+		return e.node(kind, x.Loc, field{"op", unaryOps[x.Op]}, field{"operand", e.expr(x.Operand)})
+	case *ast.CallExpr:
+		return e.node(kind, x.Loc,
+			field{"func", e.expr(x.Func)},
+			field{"templateType", e.typ(x.TemplateType)},
+			field{"args", e.exprs(x.Args)})
+	case *ast.IndexExpr:
+		return e.node(kind, x.Loc, field{"base", e.expr(x.Base)}, field{"index", e.expr(x.Index)})
+	case *ast.MemberExpr:
+		return e.node(kind, x.Loc, field{"base", e.expr(x.Base)}, field{"member", x.Member})
+	case *ast.ParenExpr:
+		return e.node(kind, x.Loc, field{"expr", e.expr(x.Expr)})
+	case *ast.ErrorExpr:
+		return e.node(kind, x.Loc)
+	}
+	return nil
+}
+
+func literalKind(kind lexer.TokenKind) string {
+	switch kind {
+	case lexer.TokIntLiteral:
+		return "int"
+	case lexer.TokFloatLiteral:
+		return "float"
+	default:
+		return "bool"
+	}
+}
+
+var binaryOps = [...]string{
+	ast.BinOpAdd:        "+",
+	ast.BinOpSub:        "-",
+	ast.BinOpMul:        "*",
+	ast.BinOpDiv:        "/",
+	ast.BinOpMod:        "%",
+	ast.BinOpAnd:        "&",
+	ast.BinOpOr:         "|",
+	ast.BinOpXor:        "^",
+	ast.BinOpShl:        "<<",
+	ast.BinOpShr:        ">>",
+	ast.BinOpLogicalAnd: "&&",
+	ast.BinOpLogicalOr:  "||",
+	ast.BinOpEq:         "==",
+	ast.BinOpNe:         "!=",
+	ast.BinOpLt:         "<",
+	ast.BinOpLe:         "<=",
+	ast.BinOpGt:         ">",
+	ast.BinOpGe:         ">=",
+}
+
+var unaryOps = [...]string{
+	ast.UnaryOpNeg:    "-",
+	ast.UnaryOpNot:    "!",
+	ast.UnaryOpBitNot: "~",
+	ast.UnaryOpDeref:  "*",
+	ast.UnaryOpAddr:   "&",
+}
+
+var assignOps = [...]string{
+	ast.AssignOpSimple: "=",
+	ast.AssignOpAdd:    "+=",
+	ast.AssignOpSub:    "-=",
+	ast.AssignOpMul:    "*=",
+	ast.AssignOpDiv:    "/=",
+	ast.AssignOpMod:    "%=",
+	ast.AssignOpAnd:    "&=",
+	ast.AssignOpOr:     "|=",
+	ast.AssignOpXor:    "^=",
+	ast.AssignOpShl:    "<<=",
+	ast.AssignOpShr:    ">>=",
+}
+
+// ----------------------------------------------------------------------------
+// Statements
+// ----------------------------------------------------------------------------
+
+func (e *encoder) block(b *ast.CompoundStmt) json.Marshaler {
+	if b == nil {
+		return nil
+	}
+	return e.stmt(b)
+}
+
+func (e *encoder) stmt(stmt ast.Stmt) json.Marshaler {
+	switch s := stmt.(type) {
+	case *ast.CompoundStmt:
+		stmts := make([]json.Marshaler, 0, len(s.Stmts))
+		for _, inner := range s.Stmts {
+			stmts = append(stmts, e.stmt(inner))
+		}
+		return e.node("compound", s.Loc, field{"stmts", stmts})
+	case *ast.ReturnStmt:
+		return e.node("return", s.Loc, field{"value", e.expr(s.Value)})
+	case *ast.IfStmt:
+		return e.node("if", s.Loc,
+			field{"condition", e.expr(s.Condition)},
+			field{"body", e.block(s.Body)},
+			field{"else", e.stmt(s.Else)})
+	case *ast.SwitchStmt:
+		cases := make([]json.Marshaler, 0, len(s.Cases))
+		for _, c := range s.Cases {
+			// A nil selector is the default selector
+			selectors := make([]json.Marshaler, 0, len(c.Selectors))
+			for _, sel := range c.Selectors {
+				selectors = append(selectors, e.expr(sel))
+			}
+			cases = append(cases, e.node("case", c.Loc, field{"selectors", selectors}, field{"body", e.block(c.Body)}))
+		}
+		return e.node("switch", s.Loc, field{"expr", e.expr(s.Expr)}, field{"cases", cases})
+	case *ast.ForStmt:
+		return e.node("for", s.Loc,
+			field{"init", e.stmt(s.Init)},
+			field{"condition", e.expr(s.Condition)},
+			field{"update", e.stmt(s.Update)},
+			field{"body", e.block(s.Body)})
+	case *ast.WhileStmt:
+		return e.node("while", s.Loc, field{"condition", e.expr(s.Condition)}, field{"body", e.block(s.Body)})
+	case *ast.LoopStmt:
+		return e.node("loop", s.Loc, field{"body", e.block(s.Body)}, field{"continuing", e.block(s.Continuing)})
+	case *ast.BreakStmt:
+		return e.node("break", s.Loc)
+	case *ast.BreakIfStmt:
+		return e.node("break_if", s.Loc, field{"condition", e.expr(s.Condition)})
+	case *ast.ContinueStmt:
+		return e.node("continue", s.Loc)
+	case *ast.DiscardStmt:
+		return e.node("discard", s.Loc)
+	case *ast.AssignStmt:
+		return e.node("assign", s.Loc,
+			field{"op", assignOps[s.Op]},
+			field{"left", e.expr(s.Left)},
+			field{"right", e.expr(s.Right)})
+	case *ast.IncrDecrStmt:
+		return e.node("incr_decr", s.Loc, field{"increment", s.Increment}, field{"expr", e.expr(s.Expr)})
+	case *ast.CallStmt:
+		var call json.Marshaler
+		if s.Call != nil {
+			call = e.expr(s.Call)
+		}
+		return e.node("call_stmt", s.Loc, field{"call", call})
+	case *ast.DeclStmt:
+		return e.node("decl_stmt", s.Loc, field{"decl", e.decl(s.Decl)})
+	case *ast.ErrorStmt:
+		return e.node("error_stmt", s.Loc)
+	}
+	return nil
+}
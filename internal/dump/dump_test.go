@@ -0,0 +1,185 @@
+package dump
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/preprocess"
+	"github.com/HugoDaniel/miniray/internal/validator"
+)
+
+const testShader = `struct Light { color: vec3f }
+@group(0) @binding(0) var<uniform> light: Light;
+
+@fragment fn main() -> @location(0) vec4f {
+    let c = light.color * 2.0;
+    return vec4f(c, 1.0);
+}
+`
+
+// decode marshals v and decodes the JSON generically, as a tool would.
+func decode(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("%v: %s", err, data)
+	}
+	return doc
+}
+
+func TestModule(t *testing.T) {
+	module, errs := parser.New(testShader).Parse()
+	doc := decode(t, Module(module, errs, nil))
+
+	if doc["version"] != float64(Version) || len(doc["errors"].([]interface{})) != 0 {
+		t.Fatalf("version %v, errors %v", doc["version"], doc["errors"])
+	}
+	decls := doc["declarations"].([]interface{})
+	if len(decls) != 3 {
+		t.Fatalf("%d declarations, want 3", len(decls))
+	}
+
+	symbols := doc["symbols"].([]interface{})
+	name := func(node map[string]interface{}, key string) string {
+		id, ok := node[key].(float64)
+		if !ok {
+			t.Fatalf("%s of %v is not a symbol", key, node["kind"])
+		}
+		return symbols[int(id)].(map[string]interface{})["name"].(string)
+	}
+
+	v := decls[1].(map[string]interface{})
+	if v["kind"] != "var" || v["addressSpace"] != "uniform" || name(v, "name") != "light" {
+		t.Errorf("var declaration = %v", v)
+	}
+	if typ := v["type"].(map[string]interface{}); typ["kind"] != "ident_type" || name(typ, "symbol") != "Light" {
+		t.Errorf("var type = %v", typ)
+	}
+	if attrs := v["attributes"].([]interface{}); len(attrs) != 2 || attrs[1].(map[string]interface{})["name"] != "binding" {
+		t.Errorf("var attributes = %v", attrs)
+	}
+
+	fn := decls[2].(map[string]interface{})
+	stmts := fn["body"].(map[string]interface{})["stmts"].([]interface{})
+	let := stmts[0].(map[string]interface{})["decl"].(map[string]interface{})
+	init := let["initializer"].(map[string]interface{})
+	if let["kind"] != "let" || init["kind"] != "binary" || init["op"] != "*" {
+		t.Errorf("let declaration = %v", let)
+	}
+	member := init["left"].(map[string]interface{})
+	if member["member"] != "color" || name(member["base"].(map[string]interface{}), "symbol") != "light" {
+		t.Errorf("member access = %v", member)
+	}
+	r := init["range"].(map[string]interface{})
+	if got := testShader[int(r["start"].(float64)):int(r["end"].(float64))]; got != "light.color * 2.0" {
+		t.Errorf("binary range covers %q", got)
+	}
+	if r["line"] != float64(5) || r["column"] != float64(13) {
+		t.Errorf("binary position = %v", r)
+	}
+
+	// The function body is a scope of the module scope
+	scope := doc["scope"].(map[string]interface{})
+	if children, ok := scope["children"].([]interface{}); !ok || len(children) == 0 {
+		t.Errorf("module scope has no nested scopes: %v", scope)
+	}
+}
+
+func TestModuleFieldOrder(t *testing.T) {
+	module, errs := parser.New("const a = 1;").Parse()
+	data, err := json.Marshal(Module(module, errs, nil).Declarations[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), `{"kind":"const","range":{"start":0,"end":12,`) {
+		t.Errorf("declaration JSON = %s", data)
+	}
+}
+
+func TestModuleErrors(t *testing.T) {
+	source := "fn f() {\n  let x = ;\n}"
+	module, errs := parser.New(source).Parse()
+	doc := Module(module, errs, nil)
+	if len(doc.Errors) == 0 || doc.Errors[0].Range.Line != 2 {
+		t.Fatalf("errors = %+v", doc.Errors)
+	}
+	// Recovery keeps the function in the tree
+	if len(doc.Declarations) != 1 {
+		t.Errorf("%d declarations, want 1", len(doc.Declarations))
+	}
+}
+
+func TestPreprocessedRanges(t *testing.T) {
+	source := "#ifdef A\nconst a = 1;\n#endif\nconst b = 2;\n"
+	pre := preprocess.Process(source, map[string]string{"A": ""})
+	module, errs := parser.New(pre.Code).Parse()
+	doc := Module(module, errs, pre)
+
+	for _, s := range doc.Symbols {
+		if s.Name == "b" && (s.Range == nil || s.Range.Line != 4 || source[s.Range.Start:s.Range.End] != "b") {
+			t.Errorf("symbol b range = %+v", s.Range)
+		}
+	}
+}
+
+func TestTypeInfo(t *testing.T) {
+	module, _ := parser.New(testShader).Parse()
+	doc := TypeInfo(module, validator.Validate(module, validator.Options{}), nil)
+
+	if doc.Version != Version || len(doc.Diagnostics) != 0 {
+		t.Fatalf("version %d, diagnostics %+v", doc.Version, doc.Diagnostics)
+	}
+
+	types := make(map[string]string)
+	for _, e := range doc.Exprs {
+		types[testShader[e.Range.Start:e.Range.End]] = e.Type
+	}
+	for expr, want := range map[string]string{
+		"light.color * 2.0": "vec3<f32>",
+		"light.color":       "vec3<f32>",
+		"light":             "Light",
+		"vec4f(c, 1.0)":     "vec4<f32>",
+	} {
+		if types[expr] != want {
+			t.Errorf("type of %q = %q, want %q", expr, types[expr], want)
+		}
+	}
+
+	// Enclosing expressions come first
+	for i := 1; i < len(doc.Exprs); i++ {
+		a, b := doc.Exprs[i-1].Range, doc.Exprs[i].Range
+		if a.Start > b.Start || (a.Start == b.Start && a.End < b.End) {
+			t.Errorf("expressions out of order: %+v before %+v", a, b)
+		}
+	}
+
+	found := false
+	for _, s := range doc.Symbols {
+		if s.Name == "c" {
+			found = s.Kind == "let" && s.Type == "vec3<f32>" && testShader[s.Range.Start:s.Range.End] == "c"
+		}
+	}
+	if !found {
+		t.Errorf("let c missing from symbols: %+v", doc.Symbols)
+	}
+}
+
+func TestOutline(t *testing.T) {
+	module, errs := parser.New("fn f() -> i32 {\n  return 1;\n}").Parse()
+	got := Module(module, errs, nil).Outline()
+	want := `function 1:1-3:2 name=f
+  returnType: ident_type 1:11-1:14 name="i32"
+  body: compound 1:15-3:2
+    stmts: return 2:3-2:12
+      value: literal 2:10-2:11 literalKind="int" value="1"
+`
+	if got != want {
+		t.Errorf("outline:\n%s\nwant:\n%s", got, want)
+	}
+}
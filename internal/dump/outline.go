@@ -0,0 +1,106 @@
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Outline prints the document as an indented tree, one node per line,
+// for reading rather than for tools:
+//
+//	function 1:1-3:2 name=f
+//	  returnType: ident_type 1:11-1:14 name="i32"
+//	  body: compound 1:15-3:2
+//	    stmts: return 2:3-2:12
+//	      value: literal 2:10-2:11 literalKind="int" value="1"
+func (d *AST) Outline() string {
+	var sb strings.Builder
+	for _, e := range d.Errors {
+		fmt.Fprintf(&sb, "error %s %s\n", e.Range, e.Message)
+	}
+	o := &outliner{sb: &sb, symbols: d.Symbols}
+	for _, n := range d.Directives {
+		o.value("", n, 0)
+	}
+	for _, n := range d.Declarations {
+		o.value("", n, 0)
+	}
+	return sb.String()
+}
+
+// Outline prints the types one per line, expressions first:
+//
+//	2:10-2:11 literal abstract-int
+//	symbol 1:4-1:5 x: i32
+func (t *Types) Outline() string {
+	var sb strings.Builder
+	for _, d := range t.Diagnostics {
+		fmt.Fprintf(&sb, "%s %s %s\n", d.Severity, d.Range, d.Message)
+	}
+	for _, e := range t.Exprs {
+		fmt.Fprintf(&sb, "%s %s %s\n", e.Range, e.Kind, e.Type)
+	}
+	for _, s := range t.Symbols {
+		fmt.Fprintf(&sb, "symbol %s %s: %s\n", s.Range, s.Name, s.Type)
+	}
+	return sb.String()
+}
+
+// String formats the range as line:column-endLine:endColumn.
+func (r Range) String() string {
+	return fmt.Sprintf("%d:%d-%d:%d", r.Line, r.Column, r.EndLine, r.EndColumn)
+}
+
+type outliner struct {
+	sb      *strings.Builder
+	symbols []Symbol
+}
+
+// value prints a node, or a list of nodes, under label at depth.
+func (o *outliner) value(label string, v interface{}, depth int) {
+	switch v := v.(type) {
+	case node:
+		o.node(label, v, depth)
+	case []json.Marshaler:
+		for _, item := range v {
+			o.value(label, item, depth)
+		}
+	}
+}
+
+func (o *outliner) node(label string, n node, depth int) {
+	o.sb.WriteString(strings.Repeat("  ", depth))
+	if label != "" {
+		o.sb.WriteString(label + ": ")
+	}
+	var children []field
+	for _, f := range n {
+		switch v := f.value.(type) {
+		case nil:
+		case node, []json.Marshaler:
+			children = append(children, f)
+		case Range:
+			o.sb.WriteString(" " + v.String())
+		case string:
+			if f.key == "kind" {
+				o.sb.WriteString(v)
+			} else if v != "" {
+				fmt.Fprintf(o.sb, " %s=%q", f.key, v)
+			}
+		case int:
+			// Symbol references print as the name they refer to
+			if (f.key == "name" || f.key == "symbol") && v < len(o.symbols) {
+				fmt.Fprintf(o.sb, " %s=%s", f.key, o.symbols[v].Name)
+			} else {
+				fmt.Fprintf(o.sb, " %s=%d", f.key, v)
+			}
+		default:
+			fmt.Fprintf(o.sb, " %s=%v", f.key, v)
+		}
+	}
+	o.sb.WriteByte('\n')
+	for _, f := range children {
+		o.value(f.key, f.value, depth+1)
+	}
+}
@@ -0,0 +1,146 @@
+package dump
+
+import (
+	"sort"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/preprocess"
+	"github.com/HugoDaniel/miniray/internal/validator"
+)
+
+// Types is the JSON document of the types the validator resolved for a
+// module.
+type Types struct {
+	Version     int          `json:"version"`
+	Exprs       []ExprType   `json:"exprs"`
+	Symbols     []SymbolType `json:"symbols"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// ExprType is the type of the expression at Range. Nested expressions
+// may start at the same offset, such as a + b and a; each has its own
+// entry.
+type ExprType struct {
+	Range Range  `json:"range"`
+	Kind  string `json:"kind"`
+	Type  string `json:"type"`
+}
+
+// SymbolType is the type of a declared symbol. Range is the range of its
+// name in its declaration; Symbol is its ID in the AST document.
+type SymbolType struct {
+	Symbol int    `json:"symbol"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`
+	Range  Range  `json:"range"`
+	Type   string `json:"type"`
+}
+
+// Diagnostic is a validation error or warning. Types may be missing
+// around errors.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code,omitempty"`
+	Message  string `json:"message"`
+	Range    Range  `json:"range"`
+}
+
+// TypeInfo converts the result of validating module, with ranges as in
+// Module. Expressions are ordered by start offset, enclosing expressions
+// first, and symbols by ID.
+func TypeInfo(module *ast.Module, result *validator.Result, pre *preprocess.Result) *Types {
+	e := newEncoder(module, pre)
+	doc := &Types{
+		Version:     Version,
+		Exprs:       []ExprType{},
+		Symbols:     []SymbolType{},
+		Diagnostics: []Diagnostic{},
+	}
+
+	if info := result.TypeInfo; info != nil {
+		type entry struct {
+			loc  ast.Loc
+			expr ast.Expr
+		}
+		entries := make([]entry, 0, len(info.Exprs))
+		for expr, t := range info.Exprs {
+			if t == nil {
+				continue
+			}
+			if loc, ok := exprLoc(expr); ok {
+				entries = append(entries, entry{loc, expr})
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			a, b := entries[i].loc, entries[j].loc
+			if a.Start != b.Start {
+				return a.Start < b.Start
+			}
+			if a.End != b.End {
+				return a.End > b.End
+			}
+			// Parentheses enclose the expression they wrap
+			pa, pb := ExprKind(entries[i].expr) == "paren", ExprKind(entries[j].expr) == "paren"
+			return pa && !pb
+		})
+		for _, en := range entries {
+			doc.Exprs = append(doc.Exprs, ExprType{
+				Range: e.rangeOf(en.loc),
+				Kind:  ExprKind(en.expr),
+				Type:  info.Exprs[en.expr].String(),
+			})
+		}
+
+		for ref, t := range info.SymbolTypes {
+			id, ok := e.ref(ref).(int)
+			if !ok || t == nil {
+				continue
+			}
+			s := module.Symbols[id]
+			doc.Symbols = append(doc.Symbols, SymbolType{
+				Symbol: id,
+				Name:   s.OriginalName,
+				Kind:   symbolKinds[s.Kind],
+				Range:  e.symbolRange(s),
+				Type:   t.String(),
+			})
+		}
+		sort.Slice(doc.Symbols, func(i, j int) bool { return doc.Symbols[i].Symbol < doc.Symbols[j].Symbol })
+	}
+
+	if result.Diagnostics != nil {
+		for _, d := range result.Diagnostics.Diagnostics() {
+			doc.Diagnostics = append(doc.Diagnostics, Diagnostic{
+				Severity: d.Severity.String(),
+				Code:     d.Code,
+				Message:  d.Message,
+				Range:    e.rangeOf(ast.Loc{Start: int32(d.Range.Start.Offset), End: int32(d.Range.End.Offset)}),
+			})
+		}
+	}
+	return doc
+}
+
+func exprLoc(expr ast.Expr) (ast.Loc, bool) {
+	switch x := expr.(type) {
+	case *ast.IdentExpr:
+		return x.Loc, true
+	case *ast.LiteralExpr:
+		return x.Loc, true
+	case *ast.BinaryExpr:
+		return x.Loc, true
+	case *ast.UnaryExpr:
+		return x.Loc, true
+	case *ast.CallExpr:
+		return x.Loc, true
+	case *ast.IndexExpr:
+		return x.Loc, true
+	case *ast.MemberExpr:
+		return x.Loc, true
+	case *ast.ParenExpr:
+		return x.Loc, true
+	case *ast.ErrorExpr:
+		return x.Loc, true
+	}
+	return ast.Loc{}, false
+}
@@ -0,0 +1,478 @@
+// Package gogen generates Go source describing minified WGSL shaders: the
+// minified code as string constants, host structs matching the memory
+// layout of uniform and storage buffers, binding indices and entry point
+// names. It backs the "miniray gogen" subcommand, meant to be run from
+// go:generate so that host code and shaders stay in sync.
+package gogen
+
+import (
+	"fmt"
+	"go/format"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/minifier"
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/preprocess"
+	"github.com/HugoDaniel/miniray/internal/reflect"
+)
+
+// Shader is one input of Generate.
+type Shader struct {
+	// Name prefixes every identifier generated for the shader, e.g. "Blur"
+	// gives BlurWGSL, BlurParams and BlurParamsBinding. See Identifier.
+	Name string
+
+	// File is the path of the shader, used in comments.
+	File string
+
+	// Source is the WGSL source.
+	Source string
+}
+
+// Options configures Generate.
+type Options struct {
+	// Package is the name of the generated Go package.
+	Package string
+
+	// Minify configures the minification of every shader.
+	Minify minifier.Options
+}
+
+// Error reports a shader that could not be generated.
+type Error struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Message)
+}
+
+// Generate returns the gofmt-ed Go source for shaders. It reports every
+// shader that fails to minify or whose buffer layouts have no Go
+// equivalent.
+func Generate(opts Options, shaders []Shader) ([]byte, []error) {
+	if !isIdentifier(opts.Package) {
+		return nil, []error{fmt.Errorf("invalid package name %q", opts.Package)}
+	}
+
+	g := &generator{names: make(map[string]string)}
+	g.printf("// Code generated by miniray gogen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n", opts.Package)
+
+	var errs []error
+	for _, shader := range shaders {
+		if err := g.shader(opts.Minify, shader); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	code, err := format.Source([]byte(g.buf.String()))
+	if err != nil {
+		return nil, []error{fmt.Errorf("formatting generated code: %w", err)}
+	}
+	return code, nil
+}
+
+// Identifier converts a file or WGSL name into an exported Go identifier:
+// "blur_h.wgsl" and "blurH" both become "BlurH". Names that do not start
+// with a letter are prefixed with "Shader".
+func Identifier(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	id := b.String()
+	if id == "" || !unicode.IsLetter(rune(id[0])) {
+		id = "Shader" + id
+	}
+	return id
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+type generator struct {
+	buf strings.Builder
+
+	// names maps each generated identifier to the file it was made for
+	names map[string]string
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// declare reserves a package-level identifier for file.
+func (g *generator) declare(name, file string) error {
+	if other, ok := g.names[name]; ok {
+		return &Error{File: file, Message: fmt.Sprintf("generated name %s is also used for %s", name, other)}
+	}
+	g.names[name] = file
+	return nil
+}
+
+// shader generates the declarations of one shader.
+func (g *generator) shader(opts minifier.Options, shader Shader) error {
+	result := minifier.New(opts).MinifyAndReflect(shader.Source)
+	if len(result.Errors) > 0 {
+		e := result.Errors[0]
+		return &Error{File: shader.File, Line: e.Line, Column: e.Column, Message: e.Message}
+	}
+
+	// Layouts come from the original module, where structs keep their names
+	pre := preprocess.Process(shader.Source, opts.Defines)
+	module, parseErrs := parser.New(pre.Code).Parse()
+	if len(parseErrs) > 0 {
+		return &Error{File: shader.File, Message: parseErrs[0].Message}
+	}
+
+	bindings := liveBindings(result.Reflect.Bindings, result.Code)
+	structs, err := g.structs(module, shader, bindings)
+	if err != nil {
+		return err
+	}
+
+	codeName := shader.Name + "WGSL"
+	if err := g.declare(codeName, shader.File); err != nil {
+		return err
+	}
+	g.printf("\n// %s is the minified code of %s.\n", codeName, shader.File)
+	g.printf("const %s = %s\n", codeName, strconv.Quote(result.Code))
+
+	if len(result.Reflect.EntryPoints) > 0 {
+		g.printf("\n// Entry points of %s.\nconst (\n", shader.File)
+		for _, ep := range result.Reflect.EntryPoints {
+			name := shader.Name + "Entry" + Identifier(ep.Name)
+			if err := g.declare(name, shader.File); err != nil {
+				return err
+			}
+			g.printf("\t%s = %q // @%s\n", name, ep.Name, ep.Stage)
+		}
+		g.printf(")\n")
+	}
+
+	if len(bindings) > 0 {
+		g.printf("\n// Bindings of %s.\nconst (\n", shader.File)
+		for _, b := range bindings {
+			name := shader.Name + Identifier(b.Name)
+			if err := g.declare(name+"Group", shader.File); err != nil {
+				return err
+			}
+			if err := g.declare(name+"Binding", shader.File); err != nil {
+				return err
+			}
+			g.printf("\t%sGroup = %d // var%s %s: %s\n", name, b.Group, bindingSpace(b), b.Name, b.Type)
+			g.printf("\t%sBinding = %d\n", name, b.Binding)
+		}
+		g.printf(")\n")
+	}
+
+	for _, s := range structs {
+		g.buf.WriteString(s)
+	}
+	return nil
+}
+
+// liveBindings returns the bindings that are still declared in the
+// minified code; tree shaking removes the unused ones.
+func liveBindings(bindings []reflect.BindingInfo, code string) []reflect.BindingInfo {
+	type slot struct{ group, binding int }
+	live := make(map[slot]bool)
+	for _, b := range reflect.Reflect(code).Bindings {
+		live[slot{b.Group, b.Binding}] = true
+	}
+	var out []reflect.BindingInfo
+	for _, b := range bindings {
+		if live[slot{b.Group, b.Binding}] {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// bindingSpace returns the template list of a binding's var, if any.
+func bindingSpace(b reflect.BindingInfo) string {
+	switch {
+	case b.AddressSpace == "" || b.AddressSpace == "handle":
+		return ""
+	case b.AccessMode != "" && b.AddressSpace == "storage":
+		return "<" + b.AddressSpace + ", " + b.AccessMode + ">"
+	}
+	return "<" + b.AddressSpace + ">"
+}
+
+// structs generates a Go struct for every WGSL struct used, directly or
+// nested, by a uniform or storage binding, in declaration order.
+func (g *generator) structs(module *ast.Module, shader Shader, bindings []reflect.BindingInfo) ([]string, error) {
+	decls := make(map[ast.Ref]*ast.StructDecl)
+	for _, decl := range module.Declarations {
+		if s, ok := decl.(*ast.StructDecl); ok {
+			decls[s.Name] = s
+		}
+	}
+
+	buffers := make(map[string]bool)
+	for _, b := range bindings {
+		if b.AddressSpace == "uniform" || b.AddressSpace == "storage" {
+			buffers[b.Name] = true
+		}
+	}
+
+	used := make(map[ast.Ref]bool)
+	var use func(t ast.Type)
+	use = func(t ast.Type) {
+		switch t := t.(type) {
+		case *ast.IdentType:
+			if s, ok := decls[t.Ref]; ok && !used[t.Ref] {
+				used[t.Ref] = true
+				for _, m := range s.Members {
+					use(m.Type)
+				}
+			}
+		case *ast.ArrayType:
+			use(t.ElemType)
+		case *ast.AtomicType:
+			use(t.ElemType)
+		}
+	}
+	for _, decl := range module.Declarations {
+		if v, ok := decl.(*ast.VarDecl); ok && buffers[symbolName(module, v.Name)] {
+			use(v.Type)
+		}
+	}
+
+	sg := &structGenerator{
+		module: module,
+		layout: reflect.NewLayoutComputer(module),
+		shader: shader,
+		decls:  decls,
+	}
+	var out []string
+	for _, decl := range module.Declarations {
+		s, ok := decl.(*ast.StructDecl)
+		if !ok || !used[s.Name] {
+			continue
+		}
+		name := sg.goName(s.Name)
+		if err := g.declare(name, shader.File); err != nil {
+			return nil, err
+		}
+		code, err := sg.generate(s, name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, code)
+	}
+	return out, nil
+}
+
+func symbolName(module *ast.Module, ref ast.Ref) string {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(module.Symbols) {
+		return ""
+	}
+	return module.Symbols[ref.InnerIndex].OriginalName
+}
+
+type structGenerator struct {
+	module *ast.Module
+	layout *reflect.LayoutComputer
+	shader Shader
+	decls  map[ast.Ref]*ast.StructDecl
+}
+
+func (sg *structGenerator) goName(ref ast.Ref) string {
+	return sg.shader.Name + Identifier(symbolName(sg.module, ref))
+}
+
+// generate returns the Go declaration of s. Go lays fields out at their
+// natural alignment, which never exceeds the WGSL one, so explicit blank
+// fields are enough to place every field at its WGSL offset.
+func (sg *structGenerator) generate(s *ast.StructDecl, name string) (string, error) {
+	layout := sg.layout.GetStructLayout(s.Name)
+	if layout == nil || len(layout.Fields) != len(s.Members) {
+		return "", sg.errorf("cannot compute the layout of struct %s", symbolName(sg.module, s.Name))
+	}
+
+	var b strings.Builder
+	wgslName := symbolName(sg.module, s.Name)
+	fmt.Fprintf(&b, "\n// %s mirrors struct %s of %s (size %d, alignment %d).\n", name, wgslName, sg.shader.File, layout.Size, layout.Alignment)
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+
+	fields := make(map[string]bool)
+	offset := 0
+	for i, m := range s.Members {
+		field := layout.Fields[i]
+		for _, attr := range m.Attributes {
+			if attr.Name != "size" && attr.Name != "align" {
+				continue
+			}
+			if _, ok := sg.layout.AttributeInt(attr); !ok {
+				return "", sg.errorf("struct %s, member %s: @%s must be a positive integer constant", wgslName, field.Name, attr.Name)
+			}
+		}
+		if field.Offset > offset {
+			fmt.Fprintf(&b, "\t_ [%d]byte\n", field.Offset-offset)
+			offset = field.Offset
+		}
+
+		if arr, ok := m.Type.(*ast.ArrayType); ok && arr.Size == nil {
+			// Go has no runtime-sized arrays: the host appends the elements
+			elemType, err := sg.goType(arr.ElemType, true)
+			if err != nil {
+				return "", sg.errorf("struct %s, member %s: %v", wgslName, field.Name, err)
+			}
+			stride := sg.layout.ComputeTypeLayout(arr).Stride
+			fmt.Fprintf(&b, "\t// %s: %s follows at offset %d, as %s elements with stride %d\n", field.Name, field.Type, field.Offset, elemType, stride)
+			continue
+		}
+
+		goType, err := sg.goType(m.Type, false)
+		if err != nil {
+			return "", sg.errorf("struct %s, member %s: %v", wgslName, field.Name, err)
+		}
+		goField := Identifier(field.Name)
+		if fields[goField] {
+			return "", sg.errorf("struct %s: more than one member becomes field %s", wgslName, goField)
+		}
+		fields[goField] = true
+		fmt.Fprintf(&b, "\t%s %s // %s, offset %d\n", goField, goType, field.Type, field.Offset)
+		// A larger @size is padding before the next member
+		offset += sg.layout.ComputeTypeLayout(m.Type).Size
+	}
+	if layout.Size > offset {
+		fmt.Fprintf(&b, "\t_ [%d]byte\n", layout.Size-offset)
+	}
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+func (sg *structGenerator) errorf(format string, args ...interface{}) error {
+	return &Error{File: sg.shader.File, Message: fmt.Sprintf(format, args...)}
+}
+
+// Go types of the host-shareable scalars. f16 has no Go type and is
+// exposed as its bits.
+var scalarTypes = map[string]string{
+	"f32": "float32",
+	"i32": "int32",
+	"u32": "uint32",
+	"f16": "uint16",
+}
+
+// Vector and matrix shorthands such as vec3f and mat4x4h.
+var shorthandType = regexp.MustCompile(`^(?:vec([234])|mat([234])x([234]))([fiuh])$`)
+
+// Element types of the shorthands, by suffix.
+var shorthandScalars = map[string]string{
+	"f": "float32",
+	"i": "int32",
+	"u": "uint32",
+	"h": "uint16",
+}
+
+// goType returns the Go type with the size of t. A vec3 is as large as a
+// vec4 when it is an array element, since its stride is rounded up to its
+// alignment; padded is true in that case.
+func (sg *structGenerator) goType(t ast.Type, padded bool) (string, error) {
+	switch t := t.(type) {
+	case *ast.IdentType:
+		if scalar, ok := scalarTypes[t.Name]; ok {
+			return scalar, nil
+		}
+		if m := shorthandType.FindStringSubmatch(t.Name); m != nil {
+			elem := shorthandScalars[m[4]]
+			if m[1] != "" {
+				return vectorType(int(m[1][0]-'0'), elem, padded), nil
+			}
+			return matrixType(int(m[2][0]-'0'), int(m[3][0]-'0'), elem), nil
+		}
+		if _, ok := sg.decls[t.Ref]; ok {
+			return sg.goName(t.Ref), nil
+		}
+		return "", fmt.Errorf("type %s has no host layout", t.Name)
+
+	case *ast.AtomicType:
+		return sg.goType(t.ElemType, padded)
+
+	case *ast.VecType:
+		elem, err := sg.elemType(t.ElemType)
+		if err != nil {
+			return "", err
+		}
+		return vectorType(int(t.Size), elem, padded), nil
+
+	case *ast.MatType:
+		elem, err := sg.elemType(t.ElemType)
+		if err != nil {
+			return "", err
+		}
+		return matrixType(int(t.Cols), int(t.Rows), elem), nil
+
+	case *ast.ArrayType:
+		layout := sg.layout.ComputeTypeLayout(t)
+		if t.Size == nil || layout.Size == 0 || layout.Stride == 0 {
+			return "", fmt.Errorf("array size must be a literal")
+		}
+		elem, err := sg.goType(t.ElemType, true)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[%d]%s", layout.Size/layout.Stride, elem), nil
+	}
+	return "", fmt.Errorf("type has no host layout")
+}
+
+// elemType returns the Go element type of a vector or matrix template.
+func (sg *structGenerator) elemType(elem ast.Type) (string, error) {
+	if id, ok := elem.(*ast.IdentType); ok {
+		if scalar, ok := scalarTypes[id.Name]; ok {
+			return scalar, nil
+		}
+		return "", fmt.Errorf("element type %s has no host layout", id.Name)
+	}
+	return "", fmt.Errorf("unsupported element type")
+}
+
+func vectorType(n int, elem string, padded bool) string {
+	if n == 3 && padded {
+		n = 4
+	}
+	return fmt.Sprintf("[%d]%s", n, elem)
+}
+
+// matrixType returns a matCxR as an array of column vectors; a column of
+// three rows takes the room of four.
+func matrixType(cols, rows int, elem string) string {
+	return fmt.Sprintf("[%d]%s", cols, vectorType(rows, elem, true))
+}
@@ -0,0 +1,294 @@
+package gogen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+	"github.com/HugoDaniel/miniray/internal/reflect"
+)
+
+const simShader = `
+struct Light { pos: vec3f, intensity: f32, color: vec3<f32> }
+struct Params {
+    view: mat4x4f,
+    normal: mat3x3f,
+    lights: array<Light, 4>,
+    offsets: array<vec3f, 2>,
+    count: u32,
+    h: vec2h,
+}
+struct Particle { p: vec3f, v: vec2f }
+struct Particles { n: atomic<u32>, items: array<Particle> }
+struct Unused { x: f32 }
+@group(0) @binding(0) var<uniform> params: Params;
+@group(0) @binding(1) var<storage, read_write> particles: Particles;
+@group(1) @binding(0) var tex: texture_2d<f32>;
+@group(1) @binding(1) var samp: sampler;
+@group(1) @binding(2) var<storage> unused: Unused;
+
+@compute @workgroup_size(64)
+fn cs_main(@builtin(global_invocation_id) id: vec3u) {
+    let x = textureSampleLevel(tex, samp, vec2f(0.0), 0.0);
+    particles.items[id.x].p += params.lights[0].pos * x.x + params.offsets[1] + params.normal[0];
+    atomicAdd(&particles.n, params.count);
+}
+`
+
+func defaultOptions() Options {
+	return Options{
+		Package: "shaders",
+		Minify:  minifier.DefaultOptions(),
+	}
+}
+
+func generate(t *testing.T, shaders ...Shader) string {
+	t.Helper()
+	code, errs := Generate(defaultOptions(), shaders)
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	return string(code)
+}
+
+// typeCheck type-checks generated code as it would be compiled for amd64.
+func typeCheck(t *testing.T, code string) (*types.Package, types.Sizes) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "shaders_gen.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, code)
+	}
+	sizes := types.SizesFor("gc", "amd64")
+	conf := types.Config{Importer: importer.Default(), Sizes: sizes}
+	pkg, err := conf.Check("shaders", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("generated code does not type-check: %v\n%s", err, code)
+	}
+	return pkg, sizes
+}
+
+func TestGenerateDeclarations(t *testing.T) {
+	code := generate(t, Shader{Name: "Sim", File: "sim.wgsl", Source: simShader})
+
+	if !strings.HasPrefix(code, "// Code generated by miniray gogen. DO NOT EDIT.\n\npackage shaders\n") {
+		t.Errorf("missing generated code header:\n%s", code)
+	}
+
+	pkg, _ := typeCheck(t, code)
+	consts := map[string]string{
+		"SimEntryCsMain":      `"cs_main"`,
+		"SimParamsGroup":      "0",
+		"SimParamsBinding":    "0",
+		"SimParticlesGroup":   "0",
+		"SimParticlesBinding": "1",
+		"SimTexGroup":         "1",
+		"SimTexBinding":       "0",
+		"SimSampGroup":        "1",
+		"SimSampBinding":      "1",
+	}
+	for name, want := range consts {
+		obj, ok := pkg.Scope().Lookup(name).(*types.Const)
+		if !ok {
+			t.Errorf("missing constant %s", name)
+			continue
+		}
+		if got := obj.Val().ExactString(); got != want {
+			t.Errorf("%s = %s, want %s", name, got, want)
+		}
+	}
+
+	// The code constant is the minified shader
+	wgsl, ok := pkg.Scope().Lookup("SimWGSL").(*types.Const)
+	if !ok {
+		t.Fatal("missing constant SimWGSL")
+	}
+	minified := minifier.New(defaultOptions().Minify).Minify(simShader).Code
+	if got := wgsl.Val().ExactString(); got != `"`+minified+`"` {
+		t.Errorf("SimWGSL = %s, want the minified shader %q", got, minified)
+	}
+
+	// Tree shaking removed the unused binding and its struct
+	for _, name := range []string{"SimUnusedGroup", "SimUnusedBinding", "SimUnused"} {
+		if pkg.Scope().Lookup(name) != nil {
+			t.Errorf("unexpected declaration %s for a removed binding", name)
+		}
+	}
+}
+
+func TestGenerateStructLayouts(t *testing.T) {
+	code := generate(t, Shader{Name: "Sim", File: "sim.wgsl", Source: simShader})
+	pkg, sizes := typeCheck(t, code)
+	wgsl := reflect.Reflect(simShader).Structs
+
+	for _, name := range []string{"Light", "Params", "Particle", "Particles"} {
+		obj := pkg.Scope().Lookup("Sim" + name)
+		if obj == nil {
+			t.Errorf("missing struct Sim%s", name)
+			continue
+		}
+		st := obj.Type().Underlying().(*types.Struct)
+		layout := wgsl[name]
+
+		if got := sizes.Sizeof(st); got != int64(layout.Size) {
+			t.Errorf("Sim%s: size %d, want %d", name, got, layout.Size)
+		}
+
+		var fields []*types.Var
+		for i := 0; i < st.NumFields(); i++ {
+			fields = append(fields, st.Field(i))
+		}
+		offsets := sizes.Offsetsof(fields)
+		goOffsets := make(map[string]int64)
+		for i, f := range fields {
+			goOffsets[f.Name()] = offsets[i]
+		}
+		for _, field := range layout.Fields {
+			goField := Identifier(field.Name)
+			offset, ok := goOffsets[goField]
+			if !ok {
+				if strings.HasPrefix(field.Type, "array<") && !strings.Contains(field.Type, ",") {
+					continue // runtime-sized arrays have no field
+				}
+				t.Errorf("Sim%s: missing field %s", name, goField)
+				continue
+			}
+			if offset != int64(field.Offset) {
+				t.Errorf("Sim%s.%s: offset %d, want %d", name, goField, offset, field.Offset)
+			}
+		}
+	}
+
+	if !strings.Contains(code, "// items: array<Particle> follows at offset 16, as SimParticle elements with stride 32") {
+		t.Errorf("missing runtime-sized array note:\n%s", code)
+	}
+}
+
+func TestGenerateSizeAndAlign(t *testing.T) {
+	source := `const WIDE = 64;
+struct P { @size(32) a: f32, b: vec3f, @align(WIDE) c: u32 }
+@group(0) @binding(0) var<uniform> p: P;
+@fragment fn main() -> @location(0) vec4f { return vec4f(p.a, p.b.x, f32(p.c), 1.0); }`
+	code := generate(t, Shader{Name: "Pad", File: "pad.wgsl", Source: source})
+	pkg, sizes := typeCheck(t, code)
+
+	obj := pkg.Scope().Lookup("PadP")
+	if obj == nil {
+		t.Fatalf("missing struct PadP:\n%s", code)
+	}
+	st := obj.Type().Underlying().(*types.Struct)
+	if got := sizes.Sizeof(st); got != 128 {
+		t.Errorf("PadP: size %d, want 128", got)
+	}
+	var fields []*types.Var
+	for i := 0; i < st.NumFields(); i++ {
+		fields = append(fields, st.Field(i))
+	}
+	offsets := sizes.Offsetsof(fields)
+	want := map[string]int64{"A": 0, "B": 32, "C": 64}
+	for i, f := range fields {
+		if offset, ok := want[f.Name()]; ok && offsets[i] != offset {
+			t.Errorf("PadP.%s: offset %d, want %d", f.Name(), offsets[i], offset)
+		}
+	}
+}
+
+func TestGenerateMultipleShaders(t *testing.T) {
+	a := `@group(0) @binding(0) var<uniform> u: vec4f;
+@fragment fn main() -> @location(0) vec4f { return u; }`
+	b := `@vertex fn main() -> @builtin(position) vec4f { return vec4f(0.0); }`
+
+	code := generate(t,
+		Shader{Name: "Tint", File: "tint.wgsl", Source: a},
+		Shader{Name: "Quad", File: "quad.wgsl", Source: b},
+	)
+	pkg, _ := typeCheck(t, code)
+	for _, name := range []string{"TintWGSL", "TintEntryMain", "TintUGroup", "TintUBinding", "QuadWGSL", "QuadEntryMain"} {
+		if pkg.Scope().Lookup(name) == nil {
+			t.Errorf("missing declaration %s", name)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    func(*Options)
+		shaders []Shader
+		want    string
+	}{
+		{
+			name:    "invalid package",
+			opts:    func(o *Options) { o.Package = "my-shaders" },
+			shaders: []Shader{{Name: "A", File: "a.wgsl", Source: "fn f() {}"}},
+			want:    `invalid package name "my-shaders"`,
+		},
+		{
+			name:    "parse error",
+			shaders: []Shader{{Name: "A", File: "a.wgsl", Source: "fn f( {}"}},
+			want:    "a.wgsl:1:",
+		},
+		{
+			name: "duplicate names",
+			shaders: []Shader{
+				{Name: "A", File: "a.wgsl", Source: "fn f() {}"},
+				{Name: "A", File: "a/a.wgsl", Source: "fn f() {}"},
+			},
+			want: "a/a.wgsl: generated name AWGSL is also used for a.wgsl",
+		},
+		{
+			name: "unsized array",
+			shaders: []Shader{{Name: "A", File: "a.wgsl", Source: `const N = 4;
+struct S { v: array<f32, N> }
+@group(0) @binding(0) var<uniform> s: S;
+@fragment fn main() -> @location(0) vec4f { return vec4f(s.v[0]); }`}},
+			want: "a.wgsl: struct S, member v: array size must be a literal",
+		},
+		{
+			name: "non-constant size",
+			shaders: []Shader{{Name: "A", File: "a.wgsl", Source: `override N: u32 = 16u;
+struct S { @size(N) v: f32 }
+@group(0) @binding(0) var<uniform> s: S;
+@fragment fn main() -> @location(0) vec4f { return vec4f(s.v); }`}},
+			want: "a.wgsl: struct S, member v: @size must be a positive integer constant",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions()
+			if tt.opts != nil {
+				tt.opts(&opts)
+			}
+			_, errs := Generate(opts, tt.shaders)
+			if len(errs) == 0 {
+				t.Fatalf("expected an error containing %q", tt.want)
+			}
+			if !strings.Contains(errs[0].Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", errs[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	tests := map[string]string{
+		"blur":      "Blur",
+		"blur_h":    "BlurH",
+		"blurH":     "BlurH",
+		"blur-pass": "BlurPass",
+		"vs_main":   "VsMain",
+		"2d":        "Shader2d",
+		"":          "Shader",
+	}
+	for in, want := range tests {
+		if got := Identifier(in); got != want {
+			t.Errorf("Identifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
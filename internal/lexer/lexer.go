@@ -220,6 +220,10 @@ type Token struct {
 	Start int    // Byte offset in source
 	End   int    // Byte offset of end (exclusive)
 	Value string // For identifiers and literals
+
+	// Trivia is only collected by a lexer created with NewLossless.
+	Leading  []Trivia // Trivia from the previous line break up to the token
+	Trailing []Trivia // Trivia after the token up to the next line break
 }
 
 // Text returns the source text of the token.
@@ -230,6 +234,35 @@ func (t Token) Text(source string) string {
 	return ""
 }
 
+// ----------------------------------------------------------------------------
+// Trivia
+// ----------------------------------------------------------------------------
+
+// TriviaKind identifies a piece of trivia.
+type TriviaKind uint8
+
+const (
+	TriviaWhitespace   TriviaKind = iota // Spaces, tabs and carriage returns
+	TriviaNewline                        // A single '\n'
+	TriviaLineComment                    // "// ..." up to the line break
+	TriviaBlockComment                   // "/* ... */", possibly nested
+)
+
+// Trivia is source text between tokens that has no effect on parsing.
+type Trivia struct {
+	Kind  TriviaKind
+	Start int // Byte offset in source
+	End   int // Byte offset of end (exclusive)
+}
+
+// Text returns the source text of the trivia.
+func (t Trivia) Text(source string) string {
+	if t.Start >= 0 && t.End <= len(source) {
+		return source[t.Start:t.End]
+	}
+	return ""
+}
+
 // ----------------------------------------------------------------------------
 // Keywords
 // ----------------------------------------------------------------------------
@@ -319,6 +352,10 @@ type Lexer struct {
 
 	// Template list tracking
 	templateDepth int
+
+	// Lossless mode: trivia seen since the last token
+	keepTrivia bool
+	trivia     []Trivia
 }
 
 // New creates a new lexer for the given source.
@@ -329,6 +366,14 @@ func New(source string) *Lexer {
 	}
 }
 
+// NewLossless creates a lexer that attaches whitespace and comments to the
+// tokens as trivia, so the source can be rebuilt byte for byte.
+func NewLossless(source string) *Lexer {
+	l := New(source)
+	l.keepTrivia = true
+	return l
+}
+
 // Tokenize returns all tokens in the source.
 func (l *Lexer) Tokenize() []Token {
 	for {
@@ -338,11 +383,43 @@ func (l *Lexer) Tokenize() []Token {
 			break
 		}
 	}
+	if l.keepTrivia {
+		splitTrailingTrivia(l.tokens)
+	}
 	return l.tokens
 }
 
+// splitTrailingTrivia moves the trivia that follows a token on the same
+// line from the next token's leading trivia to its own trailing trivia.
+func splitTrailingTrivia(tokens []Token) {
+	for i := 1; i < len(tokens); i++ {
+		leading := tokens[i].Leading
+		n := 0
+		for n < len(leading) && leading[n].Kind != TriviaNewline {
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		tokens[i-1].Trailing = leading[:n:n]
+		tokens[i].Leading = leading[n:]
+		if len(tokens[i].Leading) == 0 {
+			tokens[i].Leading = nil
+		}
+	}
+}
+
 // Next returns the next token.
 func (l *Lexer) Next() Token {
+	tok := l.next()
+	if l.keepTrivia {
+		tok.Leading = l.trivia
+		l.trivia = nil
+	}
+	return tok
+}
+
+func (l *Lexer) next() Token {
 	l.skipWhitespaceAndComments()
 
 	if l.pos >= len(l.source) {
@@ -374,16 +451,28 @@ func (l *Lexer) skipWhitespaceAndComments() {
 	for l.pos < len(l.source) {
 		ch := l.source[l.pos]
 
+		start := l.pos
+
 		// Fast path: check for common ASCII whitespace first
 		// Space and newline are most common, check them first
 		if ch == ' ' || ch == '\n' {
 			l.pos++
+			if l.keepTrivia {
+				if ch == '\n' {
+					l.addTrivia(TriviaNewline, start)
+				} else {
+					l.addTrivia(TriviaWhitespace, start)
+				}
+			}
 			continue
 		}
 
 		// Other whitespace (less common)
 		if ch == '\t' || ch == '\r' {
 			l.pos++
+			if l.keepTrivia {
+				l.addTrivia(TriviaWhitespace, start)
+			}
 			continue
 		}
 
@@ -394,6 +483,9 @@ func (l *Lexer) skipWhitespaceAndComments() {
 			for l.pos < len(l.source) && l.source[l.pos] != '\n' {
 				l.pos++
 			}
+			if l.keepTrivia {
+				l.addTrivia(TriviaLineComment, start)
+			}
 			continue
 		}
 
@@ -413,6 +505,9 @@ func (l *Lexer) skipWhitespaceAndComments() {
 					l.pos++
 				}
 			}
+			if l.keepTrivia {
+				l.addTrivia(TriviaBlockComment, start)
+			}
 			continue
 		}
 
@@ -420,6 +515,16 @@ func (l *Lexer) skipWhitespaceAndComments() {
 	}
 }
 
+// addTrivia records the trivia from start to the current position, merging
+// adjacent whitespace into a single piece.
+func (l *Lexer) addTrivia(kind TriviaKind, start int) {
+	if n := len(l.trivia); n > 0 && kind == TriviaWhitespace && l.trivia[n-1].Kind == TriviaWhitespace {
+		l.trivia[n-1].End = l.pos
+		return
+	}
+	l.trivia = append(l.trivia, Trivia{Kind: kind, Start: start, End: l.pos})
+}
+
 func (l *Lexer) scanIdentOrKeyword() Token {
 	start := l.pos
 
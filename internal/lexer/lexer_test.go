@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -897,6 +898,61 @@ func TestTokenizeWithError(t *testing.T) {
 	}
 }
 
+func TestTokenizeTrivia(t *testing.T) {
+	source := "// doc\nlet x = 1; // note\n  /* a */ x\n"
+
+	// Trivia is only collected in lossless mode
+	for _, tok := range New(source).Tokenize() {
+		if tok.Leading != nil || tok.Trailing != nil {
+			t.Fatalf("unexpected trivia on %v without lossless mode", tok.Kind)
+		}
+	}
+
+	tokens := NewLossless(source).Tokenize()
+	text := func(trivia []Trivia) []string {
+		var parts []string
+		for _, tr := range trivia {
+			parts = append(parts, tr.Text(source))
+		}
+		return parts
+	}
+
+	tests := []struct {
+		index    int
+		kind     TokenKind
+		leading  []string
+		trailing []string
+	}{
+		{0, TokLet, []string{"// doc", "\n"}, []string{" "}},
+		{4, TokSemicolon, nil, []string{" ", "// note"}},
+		{5, TokIdent, []string{"\n", "  ", "/* a */", " "}, nil},
+		{6, TokEOF, []string{"\n"}, nil},
+	}
+	for _, tt := range tests {
+		tok := tokens[tt.index]
+		if tok.Kind != tt.kind {
+			t.Fatalf("tokens[%d].Kind = %v, want %v", tt.index, tok.Kind, tt.kind)
+		}
+		if got := text(tok.Leading); strings.Join(got, "|") != strings.Join(tt.leading, "|") {
+			t.Errorf("tokens[%d] leading = %q, want %q", tt.index, got, tt.leading)
+		}
+		if got := text(tok.Trailing); strings.Join(got, "|") != strings.Join(tt.trailing, "|") {
+			t.Errorf("tokens[%d] trailing = %q, want %q", tt.index, got, tt.trailing)
+		}
+	}
+
+	// Tokens and trivia cover the source exactly
+	var b strings.Builder
+	for _, tok := range tokens {
+		b.WriteString(strings.Join(text(tok.Leading), ""))
+		b.WriteString(tok.Text(source))
+		b.WriteString(strings.Join(text(tok.Trailing), ""))
+	}
+	if b.String() != source {
+		t.Errorf("rebuilt source = %q, want %q", b.String(), source)
+	}
+}
+
 // ----------------------------------------------------------------------------
 // Edge Cases for Operators
 // ----------------------------------------------------------------------------
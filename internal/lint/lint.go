@@ -0,0 +1,396 @@
+// Package lint implements style and correctness checks for WGSL that go
+// beyond what the specification requires.
+//
+// The linter runs over a parsed module together with the type information
+// produced by the validator. Every rule has a name that can be used to
+// change its severity or turn it off through a diagnostic filter, and all
+// findings are reported into a diagnostic.DiagnosticList so they flow
+// through the same text, JSON and SARIF output as validation errors.
+package lint
+
+import (
+	"fmt"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/diagnostic"
+	"github.com/HugoDaniel/miniray/internal/types"
+	"github.com/HugoDaniel/miniray/internal/validator"
+)
+
+// Rule names.
+const (
+	RuleShadowing           = "shadowing"
+	RuleFloatEquality       = "float_equality"
+	RuleDivisionByZero      = "division_by_zero"
+	RuleTextureSampleInLoop = "texture_sample_in_loop"
+	RulePreferLet           = "prefer_let"
+	RuleRedundantConversion = "redundant_conversion"
+	RuleBindingGaps         = "binding_gaps"
+)
+
+// Rule describes a lint rule.
+type Rule struct {
+	Name        string
+	Description string
+}
+
+// Rules lists every rule reported when linting is enabled. The unused-code
+// rules are checked by the validator, which already tracks liveness.
+var Rules = []Rule{
+	{diagnostic.RuleUnusedFunction, "function unreachable from any entry point"},
+	{diagnostic.RuleUnusedStruct, "struct that is never used"},
+	{diagnostic.RuleUnusedBinding, "resource binding that is never used"},
+	{diagnostic.RuleUnusedConstant, "constant that is never used"},
+	{diagnostic.RuleUnusedParameter, "function parameter that is never used"},
+	{diagnostic.RuleUnusedVariable, "variable or local that is never used"},
+	{diagnostic.RuleDiscardedResult, "call without side effects whose result is discarded"},
+	{RuleShadowing, "declaration shadows a builtin, a module-scope declaration or an outer local"},
+	{RuleFloatEquality, "floating-point values compared with == or !="},
+	{RuleDivisionByZero, "integer division or remainder by a value that may be zero"},
+	{RuleTextureSampleInLoop, "implicit-derivative texture sampling inside a loop"},
+	{RulePreferLet, "var that is never modified and could be a let"},
+	{RuleRedundantConversion, "conversion to the type the value already has"},
+	{RuleBindingGaps, "bind group with unused binding indices between used ones"},
+}
+
+// Options controls which rules run and how they are reported.
+type Options struct {
+	// Filter overrides rule severities or disables rules. Rules default
+	// to warnings.
+	Filter *diagnostic.DiagnosticFilter
+
+	// StrictMode reports warnings as errors.
+	StrictMode bool
+}
+
+// Run lints the module and appends its findings to diags.
+func Run(module *ast.Module, info *validator.TypeInfo, diags *diagnostic.DiagnosticList, opts Options) {
+	if module == nil {
+		return
+	}
+	if opts.Filter == nil {
+		opts.Filter = diagnostic.NewDiagnosticFilter()
+	}
+	if info == nil {
+		info = &validator.TypeInfo{}
+	}
+
+	l := &linter{
+		module:      module,
+		info:        info,
+		diags:       diags,
+		opts:        opts,
+		moduleNames: make(map[string]bool),
+		constInits:  make(map[ast.Ref]ast.Expr),
+	}
+	l.run()
+}
+
+// linter holds the state of a single lint pass.
+type linter struct {
+	module *ast.Module
+	info   *validator.TypeInfo
+	diags  *diagnostic.DiagnosticList
+	opts   Options
+
+	// Names declared at module scope
+	moduleNames map[string]bool
+
+	// Initializers of const declarations, for divisor checks
+	constInits map[ast.Ref]ast.Expr
+
+	// Local scopes of the current function, innermost last
+	scopes []map[string]bool
+
+	// Number of loops enclosing the current statement
+	loopDepth int
+
+	// Function-scope vars of the current function and whether they are
+	// ever modified
+	localVars []*ast.VarDecl
+	mutated   map[ast.Ref]bool
+}
+
+func (l *linter) run() {
+	for _, decl := range l.module.Declarations {
+		switch d := decl.(type) {
+		case *ast.ConstDecl:
+			l.constInits[d.Name] = d.Initializer
+		}
+		if ref := declName(decl); ref.IsValid() {
+			l.moduleNames[l.name(ref)] = true
+		}
+	}
+
+	for _, decl := range l.module.Declarations {
+		if ref := declName(decl); ref.IsValid() {
+			l.checkBuiltinShadowing(ref)
+		}
+		switch d := decl.(type) {
+		case *ast.ConstDecl:
+			l.visitExpr(d.Initializer)
+		case *ast.OverrideDecl:
+			l.visitExpr(d.Initializer)
+		case *ast.VarDecl:
+			l.visitExpr(d.Initializer)
+		case *ast.FunctionDecl:
+			l.visitFunction(d)
+		case *ast.ConstAssertDecl:
+			l.visitExpr(d.Expr)
+		}
+	}
+
+	l.checkBindingGaps()
+}
+
+func (l *linter) visitFunction(fn *ast.FunctionDecl) {
+	l.scopes = []map[string]bool{{}}
+	l.localVars = nil
+	l.mutated = make(map[ast.Ref]bool)
+	l.loopDepth = 0
+
+	for _, param := range fn.Parameters {
+		l.declareLocal(param.Name)
+	}
+	// The body's top level shares the scope of the parameters
+	if fn.Body != nil {
+		for _, stmt := range fn.Body.Stmts {
+			l.visitStmt(stmt)
+		}
+	}
+
+	l.checkPreferLet()
+	l.scopes = nil
+}
+
+func (l *linter) visitBlock(block *ast.CompoundStmt) {
+	if block == nil {
+		return
+	}
+	l.scopes = append(l.scopes, map[string]bool{})
+	for _, stmt := range block.Stmts {
+		l.visitStmt(stmt)
+	}
+	l.scopes = l.scopes[:len(l.scopes)-1]
+}
+
+func (l *linter) visitStmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.CompoundStmt:
+		l.visitBlock(s)
+	case *ast.ReturnStmt:
+		l.visitExpr(s.Value)
+	case *ast.IfStmt:
+		l.visitExpr(s.Condition)
+		l.visitBlock(s.Body)
+		if s.Else != nil {
+			l.visitStmt(s.Else)
+		}
+	case *ast.SwitchStmt:
+		l.visitExpr(s.Expr)
+		for _, c := range s.Cases {
+			for _, sel := range c.Selectors {
+				l.visitExpr(sel)
+			}
+			l.visitBlock(c.Body)
+		}
+	case *ast.ForStmt:
+		l.scopes = append(l.scopes, map[string]bool{})
+		if s.Init != nil {
+			l.visitStmt(s.Init)
+		}
+		l.loopDepth++
+		l.visitExpr(s.Condition)
+		if s.Update != nil {
+			l.visitStmt(s.Update)
+		}
+		l.visitBlock(s.Body)
+		l.loopDepth--
+		l.scopes = l.scopes[:len(l.scopes)-1]
+	case *ast.WhileStmt:
+		l.loopDepth++
+		l.visitExpr(s.Condition)
+		l.visitBlock(s.Body)
+		l.loopDepth--
+	case *ast.LoopStmt:
+		l.loopDepth++
+		l.visitBlock(s.Body)
+		l.visitBlock(s.Continuing)
+		l.loopDepth--
+	case *ast.BreakIfStmt:
+		l.visitExpr(s.Condition)
+	case *ast.AssignStmt:
+		l.markMutated(s.Left)
+		l.visitExpr(s.Left)
+		l.visitExpr(s.Right)
+	case *ast.IncrDecrStmt:
+		l.markMutated(s.Expr)
+		l.visitExpr(s.Expr)
+	case *ast.CallStmt:
+		l.visitExpr(s.Call)
+	case *ast.DeclStmt:
+		switch d := s.Decl.(type) {
+		case *ast.VarDecl:
+			l.visitExpr(d.Initializer)
+			l.declareLocal(d.Name)
+			if d.AddressSpace == ast.AddressSpaceNone || d.AddressSpace == ast.AddressSpaceFunction {
+				l.localVars = append(l.localVars, d)
+			}
+		case *ast.LetDecl:
+			l.visitExpr(d.Initializer)
+			l.declareLocal(d.Name)
+		case *ast.ConstDecl:
+			l.visitExpr(d.Initializer)
+			l.declareLocal(d.Name)
+			l.constInits[d.Name] = d.Initializer
+		}
+	}
+}
+
+func (l *linter) visitExpr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		l.checkFloatEquality(e)
+		l.checkDivision(e)
+		l.visitExpr(e.Left)
+		l.visitExpr(e.Right)
+	case *ast.UnaryExpr:
+		if e.Op == ast.UnaryOpAddr {
+			// A pointer may be used to write through
+			l.markMutated(e.Operand)
+		}
+		l.visitExpr(e.Operand)
+	case *ast.CallExpr:
+		l.checkTextureSampleInLoop(e)
+		l.checkRedundantConversion(e)
+		for _, arg := range e.Args {
+			l.visitExpr(arg)
+		}
+	case *ast.IndexExpr:
+		l.visitExpr(e.Base)
+		l.visitExpr(e.Index)
+	case *ast.MemberExpr:
+		l.visitExpr(e.Base)
+	case *ast.ParenExpr:
+		l.visitExpr(e.Expr)
+	}
+}
+
+// declareLocal checks a local declaration for shadowing and records it in
+// the innermost scope.
+func (l *linter) declareLocal(ref ast.Ref) {
+	name := l.name(ref)
+	if name == "" || name == "_" {
+		return
+	}
+	shadowsOuter := false
+	for i := len(l.scopes) - 2; i >= 0; i-- {
+		if l.scopes[i][name] {
+			shadowsOuter = true
+			break
+		}
+	}
+	switch {
+	case shadowsOuter:
+		l.reportSymbol(RuleShadowing, ref, "'%s' shadows a declaration in an outer scope", name)
+	case l.moduleNames[name]:
+		l.reportSymbol(RuleShadowing, ref, "'%s' shadows a module-scope declaration", name)
+	default:
+		l.checkBuiltinShadowing(ref)
+	}
+	if len(l.scopes) > 0 {
+		l.scopes[len(l.scopes)-1][name] = true
+	}
+}
+
+// markMutated records that the variable behind an lvalue may be written.
+func (l *linter) markMutated(expr ast.Expr) {
+	for {
+		switch e := expr.(type) {
+		case *ast.IdentExpr:
+			if l.mutated != nil {
+				l.mutated[e.Ref] = true
+			}
+			return
+		case *ast.MemberExpr:
+			expr = e.Base
+		case *ast.IndexExpr:
+			expr = e.Base
+		case *ast.ParenExpr:
+			expr = e.Expr
+		case *ast.UnaryExpr:
+			expr = e.Operand
+		default:
+			return
+		}
+	}
+}
+
+// typeOf returns the resolved type of an expression, if known.
+func (l *linter) typeOf(expr ast.Expr) types.Type {
+	if expr == nil || l.info.Exprs == nil {
+		return nil
+	}
+	return l.info.Exprs[expr]
+}
+
+func (l *linter) name(ref ast.Ref) string {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(l.module.Symbols) {
+		return ""
+	}
+	return l.module.Symbols[ref.InnerIndex].OriginalName
+}
+
+func (l *linter) symbol(ref ast.Ref) *ast.Symbol {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(l.module.Symbols) {
+		return nil
+	}
+	return &l.module.Symbols[ref.InnerIndex]
+}
+
+// reportSymbol reports a finding at a symbol's declaration.
+func (l *linter) reportSymbol(rule string, ref ast.Ref, format string, args ...interface{}) {
+	sym := l.symbol(ref)
+	if sym == nil {
+		return
+	}
+	start := int(sym.Loc.Start)
+	l.report(rule, start, start+len(sym.OriginalName), format, args...)
+}
+
+// report adds a finding for a rule, applying the rule's filter.
+func (l *linter) report(rule string, start, end int, format string, args ...interface{}) {
+	if l.opts.Filter.IsDisabled(rule) {
+		return
+	}
+	severity := l.opts.Filter.GetSeverity(rule, diagnostic.Warning)
+	if severity == diagnostic.Warning && l.opts.StrictMode {
+		severity = diagnostic.Error
+	}
+	l.diags.Add(diagnostic.Diagnostic{
+		Severity: severity,
+		Code:     rule,
+		Message:  fmt.Sprintf(format, args...),
+		Range:    l.diags.MakeRange(start, end),
+	})
+}
+
+func declName(decl ast.Decl) ast.Ref {
+	switch d := decl.(type) {
+	case *ast.ConstDecl:
+		return d.Name
+	case *ast.OverrideDecl:
+		return d.Name
+	case *ast.VarDecl:
+		return d.Name
+	case *ast.LetDecl:
+		return d.Name
+	case *ast.FunctionDecl:
+		return d.Name
+	case *ast.StructDecl:
+		return d.Name
+	case *ast.AliasDecl:
+		return d.Name
+	}
+	return ast.InvalidRef()
+}
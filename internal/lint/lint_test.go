@@ -0,0 +1,177 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/diagnostic"
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/validator"
+)
+
+// lint parses, validates and lints source, returning the lint findings.
+func lint(t *testing.T, source string, opts Options) []diagnostic.Diagnostic {
+	t.Helper()
+	module, errs := parser.New(source).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	result := validator.Validate(module, validator.Options{})
+	diags := diagnostic.NewDiagnosticList(source)
+	Run(module, result.TypeInfo, diags, opts)
+	return diags.Diagnostics()
+}
+
+func findRule(diags []diagnostic.Diagnostic, rule, pattern string) *diagnostic.Diagnostic {
+	for i := range diags {
+		if diags[i].Code == rule && strings.Contains(diags[i].Message, pattern) {
+			return &diags[i]
+		}
+	}
+	return nil
+}
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		rule    string
+		pattern string
+		line    int
+	}{
+		{
+			name:    "shadowing builtin",
+			source:  "fn main() {\n    let sin = 1.0;\n}",
+			rule:    RuleShadowing,
+			pattern: "builtin function 'sin'",
+			line:    2,
+		},
+		{
+			name:    "shadowing predeclared type",
+			source:  "struct vec3f { x: f32 }",
+			rule:    RuleShadowing,
+			pattern: "predeclared type 'vec3f'",
+			line:    1,
+		},
+		{
+			name:    "shadowing module scope",
+			source:  "const scale = 2.0;\nfn main() {\n    let scale = 1.0;\n}",
+			rule:    RuleShadowing,
+			pattern: "module-scope declaration",
+			line:    3,
+		},
+		{
+			name:    "shadowing outer local",
+			source:  "fn main() {\n    let x = 1;\n    if (true) {\n        let x = 2;\n    }\n}",
+			rule:    RuleShadowing,
+			pattern: "outer scope",
+			line:    4,
+		},
+		{
+			name:    "float equality",
+			source:  "fn main(a: f32) -> bool {\n    return a == 0.5;\n}",
+			rule:    RuleFloatEquality,
+			pattern: "'=='",
+			line:    2,
+		},
+		{
+			name:    "division by possibly zero",
+			source:  "fn main(a: i32, b: i32) -> i32 {\n    return a / b;\n}",
+			rule:    RuleDivisionByZero,
+			pattern: "division",
+			line:    2,
+		},
+		{
+			name:    "texture sample in loop",
+			source:  "@group(0) @binding(0) var t: texture_2d<f32>;\n@group(0) @binding(1) var s: sampler;\n@fragment fn main() -> @location(0) vec4f {\n    var c = vec4f(0.0);\n    for (var i = 0; i < 4; i++) {\n        c += textureSample(t, s, vec2f(0.5));\n    }\n    return c;\n}",
+			rule:    RuleTextureSampleInLoop,
+			pattern: "textureSample",
+			line:    6,
+		},
+		{
+			name:    "var could be let",
+			source:  "fn main() -> f32 {\n    var x = 1.0;\n    return x;\n}",
+			rule:    RulePreferLet,
+			pattern: "var 'x'",
+			line:    2,
+		},
+		{
+			name:    "redundant conversion",
+			source:  "fn main(a: f32) -> f32 {\n    return f32(a);\n}",
+			rule:    RuleRedundantConversion,
+			pattern: "'f32'",
+			line:    2,
+		},
+		{
+			name:    "binding gaps",
+			source:  "@group(0) @binding(0) var<uniform> a: vec4f;\n@group(0) @binding(2) var<uniform> b: vec4f;",
+			rule:    RuleBindingGaps,
+			pattern: "no binding 1 before 'b'",
+			line:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := lint(t, tt.source, Options{})
+			d := findRule(diags, tt.rule, tt.pattern)
+			if d == nil {
+				t.Fatalf("expected %s finding matching %q, got %v", tt.rule, tt.pattern, diags)
+			}
+			if d.Severity != diagnostic.Warning {
+				t.Errorf("expected warning severity, got %s", d.Severity)
+			}
+			if d.Range.Start.Line != tt.line {
+				t.Errorf("expected line %d, got %d", tt.line, d.Range.Start.Line)
+			}
+		})
+	}
+}
+
+func TestRulesNoFalsePositives(t *testing.T) {
+	source := `
+const N = 4;
+@group(0) @binding(0) var<uniform> a: vec4f;
+@group(0) @binding(1) var<uniform> b: vec4f;
+fn main(x: i32, y: f32) -> f32 {
+    var total = 0.0;
+    var v = vec2f(1.0);
+    let p = &v;
+    (*p).x = 2.0;
+    for (var i = 0; i < N; i++) {
+        total += y;
+    }
+    let q = x / N + x / 2 + x % i32(3);
+    if (q == 1) {
+        total = 1.0;
+    }
+    return total + f32(q);
+}
+`
+	diags := lint(t, source, Options{})
+	for _, d := range diags {
+		t.Errorf("unexpected finding: %s [%s]", d.Message, d.Code)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	source := "fn main(a: f32) -> bool {\n    return a == 0.5;\n}"
+
+	filter := diagnostic.NewDiagnosticFilter()
+	filter.DisableRule(RuleFloatEquality)
+	if d := findRule(lint(t, source, Options{Filter: filter}), RuleFloatEquality, ""); d != nil {
+		t.Errorf("expected %s to be disabled", RuleFloatEquality)
+	}
+
+	filter = diagnostic.NewDiagnosticFilter()
+	filter.SetRule(RuleFloatEquality, diagnostic.Error)
+	d := findRule(lint(t, source, Options{Filter: filter}), RuleFloatEquality, "")
+	if d == nil || d.Severity != diagnostic.Error {
+		t.Errorf("expected %s to be reported as an error, got %v", RuleFloatEquality, d)
+	}
+
+	d = findRule(lint(t, source, Options{StrictMode: true}), RuleFloatEquality, "")
+	if d == nil || d.Severity != diagnostic.Error {
+		t.Errorf("expected strict mode to report %s as an error, got %v", RuleFloatEquality, d)
+	}
+}
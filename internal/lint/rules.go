@@ -0,0 +1,267 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/builtins"
+	"github.com/HugoDaniel/miniray/internal/types"
+)
+
+// checkBuiltinShadowing reports a declaration named like a builtin
+// function or a predeclared type.
+func (l *linter) checkBuiltinShadowing(ref ast.Ref) {
+	name := l.name(ref)
+	switch {
+	case builtins.IsBuiltin(name):
+		l.reportSymbol(RuleShadowing, ref, "'%s' shadows the builtin function '%s'", name, name)
+	case isPredeclaredType(name):
+		l.reportSymbol(RuleShadowing, ref, "'%s' shadows the predeclared type '%s'", name, name)
+	}
+}
+
+// checkFloatEquality reports == and != between floating-point values.
+func (l *linter) checkFloatEquality(e *ast.BinaryExpr) {
+	if e.Op != ast.BinOpEq && e.Op != ast.BinOpNe {
+		return
+	}
+	if isLiteral(e.Left) && isLiteral(e.Right) {
+		return
+	}
+	if !types.IsFloat(l.typeOf(e.Left)) && !types.IsFloat(l.typeOf(e.Right)) {
+		return
+	}
+	op := "=="
+	if e.Op == ast.BinOpNe {
+		op = "!="
+	}
+	start := int(e.Loc.Start)
+	l.report(RuleFloatEquality, start, start+1,
+		"floating-point values compared with '%s'; compare against a tolerance instead", op)
+}
+
+// checkDivision reports integer division or remainder by a divisor that
+// is not a known non-zero constant.
+func (l *linter) checkDivision(e *ast.BinaryExpr) {
+	if e.Op != ast.BinOpDiv && e.Op != ast.BinOpMod {
+		return
+	}
+	if !types.IsInteger(l.typeOf(e.Left)) {
+		return
+	}
+	if l.isNonZeroConst(e.Right, 0) {
+		return
+	}
+	op := "division"
+	if e.Op == ast.BinOpMod {
+		op = "remainder"
+	}
+	start := int(e.Loc.Start)
+	l.report(RuleDivisionByZero, start, start+1,
+		"integer %s by a value that may be zero", op)
+}
+
+// isNonZeroConst reports whether expr is a constant whose components are
+// all known to be non-zero.
+func (l *linter) isNonZeroConst(expr ast.Expr, depth int) bool {
+	if depth > 8 {
+		return false
+	}
+	switch e := expr.(type) {
+	case *ast.LiteralExpr:
+		v := strings.TrimRight(e.Value, "iu")
+		n, err := strconv.ParseInt(v, 0, 64)
+		return err == nil && n != 0
+	case *ast.ParenExpr:
+		return l.isNonZeroConst(e.Expr, depth+1)
+	case *ast.UnaryExpr:
+		return e.Op == ast.UnaryOpNeg && l.isNonZeroConst(e.Operand, depth+1)
+	case *ast.IdentExpr:
+		if init, ok := l.constInits[e.Ref]; ok {
+			return l.isNonZeroConst(init, depth+1)
+		}
+	case *ast.CallExpr:
+		// Conversions and vector constructors of non-zero constants
+		if len(e.Args) == 0 {
+			return false
+		}
+		if ident, ok := e.Func.(*ast.IdentExpr); ok && !isPredeclaredType(ident.Name) {
+			return false
+		}
+		for _, arg := range e.Args {
+			if !l.isNonZeroConst(arg, depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// checkTextureSampleInLoop reports implicit-derivative sampling in loops,
+// where it is easy to end up in non-uniform control flow.
+func (l *linter) checkTextureSampleInLoop(e *ast.CallExpr) {
+	if l.loopDepth == 0 {
+		return
+	}
+	ident, ok := e.Func.(*ast.IdentExpr)
+	if !ok {
+		return
+	}
+	switch ident.Name {
+	case "textureSample", "textureSampleBias", "textureSampleCompare":
+		start := int(ident.Loc.Start)
+		l.report(RuleTextureSampleInLoop, start, start+len(ident.Name),
+			"'%s' inside a loop relies on implicit derivatives; consider textureSampleLevel or textureSampleGrad", ident.Name)
+	}
+}
+
+// checkRedundantConversion reports a single-argument constructor whose
+// argument already has the constructed type.
+func (l *linter) checkRedundantConversion(e *ast.CallExpr) {
+	if len(e.Args) != 1 {
+		return
+	}
+	if e.TemplateType == nil {
+		ident, ok := e.Func.(*ast.IdentExpr)
+		if !ok || !isPredeclaredType(ident.Name) {
+			return
+		}
+	}
+	target := l.typeOf(e)
+	argType := l.typeOf(e.Args[0])
+	if target == nil || argType == nil || !target.IsConstructible() || !argType.Equals(target) {
+		return
+	}
+	start := int(e.Loc.Start)
+	l.report(RuleRedundantConversion, start, start+1,
+		"redundant conversion: value is already of type '%s'", target.String())
+}
+
+// checkPreferLet reports function-scope vars of the current function that
+// are initialized and never modified.
+func (l *linter) checkPreferLet() {
+	for _, d := range l.localVars {
+		if d.Initializer == nil || l.mutated[d.Name] {
+			continue
+		}
+		name := l.name(d.Name)
+		l.reportSymbol(RulePreferLet, d.Name, "var '%s' is never modified; declare it with let", name)
+	}
+}
+
+// checkBindingGaps reports bind groups whose binding indices skip values.
+func (l *linter) checkBindingGaps() {
+	type binding struct {
+		index int
+		ref   ast.Ref
+	}
+	groups := make(map[int][]binding)
+	for _, decl := range l.module.Declarations {
+		d, ok := decl.(*ast.VarDecl)
+		if !ok {
+			continue
+		}
+		group, hasGroup := intAttribute(d.Attributes, "group")
+		index, hasBinding := intAttribute(d.Attributes, "binding")
+		if hasGroup && hasBinding {
+			groups[group] = append(groups[group], binding{index, d.Name})
+		}
+	}
+
+	groupIDs := make([]int, 0, len(groups))
+	for g := range groups {
+		groupIDs = append(groupIDs, g)
+	}
+	sort.Ints(groupIDs)
+
+	for _, g := range groupIDs {
+		bindings := groups[g]
+		sort.SliceStable(bindings, func(i, j int) bool { return bindings[i].index < bindings[j].index })
+		next := 0
+		for _, b := range bindings {
+			if b.index > next {
+				missing := strconv.Itoa(next)
+				if b.index-1 > next {
+					missing = fmt.Sprintf("%d-%d", next, b.index-1)
+				}
+				l.reportSymbol(RuleBindingGaps, b.ref,
+					"@group(%d) has no binding %s before '%s' at @binding(%d)", g, missing, l.name(b.ref), b.index)
+			}
+			if b.index >= next {
+				next = b.index + 1
+			}
+		}
+	}
+}
+
+func intAttribute(attrs []ast.Attribute, name string) (int, bool) {
+	for _, attr := range attrs {
+		if attr.Name != name || len(attr.Args) == 0 {
+			continue
+		}
+		if lit, ok := attr.Args[0].(*ast.LiteralExpr); ok {
+			n, err := strconv.Atoi(strings.TrimRight(lit.Value, "iu"))
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+func isLiteral(expr ast.Expr) bool {
+	for {
+		switch e := expr.(type) {
+		case *ast.LiteralExpr:
+			return true
+		case *ast.ParenExpr:
+			expr = e.Expr
+		case *ast.UnaryExpr:
+			expr = e.Operand
+		default:
+			return false
+		}
+	}
+}
+
+// predeclaredTypes lists the type names WGSL predeclares.
+var predeclaredTypes = map[string]bool{
+	"bool": true, "i32": true, "u32": true, "f32": true, "f16": true,
+	"vec2": true, "vec3": true, "vec4": true,
+	"mat2x2": true, "mat2x3": true, "mat2x4": true,
+	"mat3x2": true, "mat3x3": true, "mat3x4": true,
+	"mat4x2": true, "mat4x3": true, "mat4x4": true,
+	"array": true, "atomic": true, "ptr": true,
+	"sampler": true, "sampler_comparison": true,
+	"texture_1d": true, "texture_2d": true, "texture_2d_array": true,
+	"texture_3d": true, "texture_cube": true, "texture_cube_array": true,
+	"texture_multisampled_2d": true, "texture_external": true,
+	"texture_depth_2d": true, "texture_depth_2d_array": true,
+	"texture_depth_cube": true, "texture_depth_cube_array": true,
+	"texture_depth_multisampled_2d": true,
+	"texture_storage_1d":            true, "texture_storage_2d": true,
+	"texture_storage_2d_array": true, "texture_storage_3d": true,
+}
+
+// isPredeclaredType reports whether name is a predeclared type, including
+// the vecNx and matCxRx shorthand aliases.
+func isPredeclaredType(name string) bool {
+	if predeclaredTypes[name] {
+		return true
+	}
+	if name == "" {
+		return false
+	}
+	suffix := name[len(name)-1:]
+	if suffix != "f" && suffix != "h" && suffix != "i" && suffix != "u" {
+		return false
+	}
+	base := name[:len(name)-1]
+	switch base {
+	case "vec2", "vec3", "vec4":
+		return true
+	}
+	return strings.HasPrefix(base, "mat") && predeclaredTypes[base] && (suffix == "f" || suffix == "h")
+}
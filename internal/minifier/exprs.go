@@ -0,0 +1,269 @@
+package minifier
+
+import (
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/lexer"
+)
+
+// exprTypes answers type questions about expressions that the syntax
+// alone can settle.
+type exprTypes struct {
+	symbols []ast.Symbol
+	consts  map[ast.Ref]*ast.ConstDecl
+}
+
+func newExprTypes(module *ast.Module) *exprTypes {
+	t := &exprTypes{
+		symbols: module.Symbols,
+		consts:  make(map[ast.Ref]*ast.ConstDecl),
+	}
+	ast.Inspect(module, func(n ast.Node) bool {
+		if d, ok := n.(*ast.ConstDecl); ok && d.Name.IsValid() {
+			t.consts[d.Name] = d
+		}
+		return true
+	})
+	return t
+}
+
+func (t *exprTypes) symbol(ref ast.Ref) *ast.Symbol {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(t.symbols) {
+		return nil
+	}
+	return &t.symbols[ref.InnerIndex]
+}
+
+// concrete reports whether e has a concrete type, rather than an abstract
+// integer or float type. Parameters have concrete types.
+func (t *exprTypes) concrete(e ast.Expr, params map[ast.Ref]int) bool {
+	switch e := e.(type) {
+	case *ast.IdentExpr:
+		if _, ok := params[e.Ref]; ok {
+			return true
+		}
+		sym := t.symbol(e.Ref)
+		if sym == nil {
+			return false
+		}
+		if sym.Kind == ast.SymbolConst {
+			d := t.consts[e.Ref]
+			return d != nil && (d.Type != nil || t.concrete(d.Initializer, nil))
+		}
+		return true
+
+	case *ast.LiteralExpr:
+		switch e.Kind {
+		case lexer.TokTrue, lexer.TokFalse:
+			return true
+		case lexer.TokIntLiteral:
+			return strings.HasSuffix(e.Value, "i") || strings.HasSuffix(e.Value, "u")
+		case lexer.TokFloatLiteral:
+			hex := strings.HasPrefix(e.Value, "0x") || strings.HasPrefix(e.Value, "0X")
+			return !hex && (strings.HasSuffix(e.Value, "f") || strings.HasSuffix(e.Value, "h"))
+		}
+		return false
+
+	case *ast.ParenExpr:
+		return t.concrete(e.Expr, params)
+
+	case *ast.UnaryExpr:
+		switch e.Op {
+		case ast.UnaryOpNot, ast.UnaryOpDeref, ast.UnaryOpAddr:
+			return true
+		}
+		return t.concrete(e.Operand, params)
+
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case ast.BinOpLogicalAnd, ast.BinOpLogicalOr,
+			ast.BinOpEq, ast.BinOpNe, ast.BinOpLt, ast.BinOpLe, ast.BinOpGt, ast.BinOpGe:
+			// bool has no abstract counterpart
+			return true
+		case ast.BinOpShl, ast.BinOpShr:
+			return t.concrete(e.Left, params)
+		}
+		return t.concrete(e.Left, params) || t.concrete(e.Right, params)
+
+	case *ast.CallExpr:
+		if e.TemplateType != nil {
+			return true
+		}
+		id, ok := e.Func.(*ast.IdentExpr)
+		if !ok {
+			return false
+		}
+		if sym := t.symbol(id.Ref); sym != nil {
+			// Functions, structs and aliases have concrete types
+			return true
+		}
+		if concreteConstructor(id.Name) {
+			return true
+		}
+		for _, arg := range e.Args {
+			if t.concrete(arg, params) {
+				return true
+			}
+		}
+		return false
+
+	case *ast.IndexExpr:
+		return t.concrete(e.Base, params)
+
+	case *ast.MemberExpr:
+		return t.concrete(e.Base, params)
+	}
+	return false
+}
+
+// concreteConstructor reports whether name is a constructor of a concrete
+// scalar, vector or matrix type, such as f32, vec3f or mat4x4h.
+func concreteConstructor(name string) bool {
+	switch name {
+	case "bool", "i32", "u32", "f32", "f16":
+		return true
+	}
+	if strings.HasPrefix(name, "vec") || strings.HasPrefix(name, "mat") {
+		return strings.HasSuffix(name, "f") || strings.HasSuffix(name, "h") ||
+			strings.HasSuffix(name, "i") || strings.HasSuffix(name, "u")
+	}
+	return false
+}
+
+// isSimple reports whether e is cheap to evaluate more than once.
+func isSimple(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.IdentExpr, *ast.LiteralExpr:
+		return true
+	case *ast.MemberExpr:
+		return isSimple(e.Base)
+	case *ast.ParenExpr:
+		return isSimple(e.Expr)
+	}
+	return false
+}
+
+// isPrimary reports whether e needs no parentheses as an operand.
+func isPrimary(e ast.Expr) bool {
+	switch e.(type) {
+	case *ast.IdentExpr, *ast.LiteralExpr, *ast.CallExpr, *ast.IndexExpr, *ast.MemberExpr, *ast.ParenExpr:
+		return true
+	}
+	return false
+}
+
+// parenSet records the parentheses added around expressions moved into
+// operand position. The printer doesn't add parentheses for precedence.
+type parenSet map[*ast.ParenExpr]bool
+
+// wrap parenthesizes e unless it is a primary expression.
+func (ps parenSet) wrap(e ast.Expr) ast.Expr {
+	if isPrimary(e) {
+		return e
+	}
+	paren := &ast.ParenExpr{Expr: e, Flags: exprFlags(e)}
+	ps[paren] = true
+	return paren
+}
+
+// unwrap removes the parentheses added by wrap below node where the
+// expression is not an operand: arguments, indices, initializers and
+// returned or assigned values. Operands of binary expressions lose them
+// when the operator precedence makes them redundant.
+func (ps parenSet) unwrap(node ast.Node) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		for _, slot := range bareSlots(n) {
+			if paren, ok := (*slot).(*ast.ParenExpr); ok && ps[paren] {
+				*slot = paren.Expr
+			}
+		}
+		if bin, ok := n.(*ast.BinaryExpr); ok {
+			if paren, ok := bin.Left.(*ast.ParenExpr); ok && ps[paren] && bindsTighter(paren.Expr, bin.Op, true) {
+				bin.Left = paren.Expr
+			}
+			if paren, ok := bin.Right.(*ast.ParenExpr); ok && ps[paren] && bindsTighter(paren.Expr, bin.Op, false) {
+				bin.Right = paren.Expr
+			}
+		}
+		return true
+	})
+}
+
+// bindsTighter reports whether e can be an operand of op without
+// parentheses. WGSL doesn't order every pair of operators: relational
+// operators don't chain, && and || don't mix, and shifts and bitwise
+// operators take unary operands only.
+func bindsTighter(e ast.Expr, op ast.BinaryOp, left bool) bool {
+	inner, ok := e.(*ast.BinaryExpr)
+	if !ok {
+		return false
+	}
+	outerLevel, innerLevel := precedence(op), precedence(inner.Op)
+	switch {
+	case outerLevel == 0 || innerLevel == 0:
+		return false
+	case op == ast.BinOpLogicalAnd || op == ast.BinOpLogicalOr:
+		return innerLevel == precedence(ast.BinOpEq) || left && inner.Op == op
+	case innerLevel > outerLevel:
+		return true
+	}
+	return left && innerLevel == outerLevel && innerLevel > precedence(ast.BinOpEq)
+}
+
+// precedence returns the binding strength of an operator that can mix
+// with others, or 0.
+func precedence(op ast.BinaryOp) int {
+	switch op {
+	case ast.BinOpMul, ast.BinOpDiv, ast.BinOpMod:
+		return 5
+	case ast.BinOpAdd, ast.BinOpSub:
+		return 4
+	case ast.BinOpEq, ast.BinOpNe, ast.BinOpLt, ast.BinOpLe, ast.BinOpGt, ast.BinOpGe:
+		return 3
+	case ast.BinOpLogicalAnd:
+		return 2
+	case ast.BinOpLogicalOr:
+		return 1
+	}
+	return 0
+}
+
+// bareSlots returns the expressions of n that are printed without an
+// operator around them.
+func bareSlots(n ast.Node) []*ast.Expr {
+	switch n := n.(type) {
+	case *ast.CallExpr:
+		slots := make([]*ast.Expr, len(n.Args))
+		for i := range n.Args {
+			slots[i] = &n.Args[i]
+		}
+		return slots
+	case *ast.IndexExpr:
+		return []*ast.Expr{&n.Index}
+	case *ast.ParenExpr:
+		return []*ast.Expr{&n.Expr}
+	case *ast.ReturnStmt:
+		return []*ast.Expr{&n.Value}
+	case *ast.AssignStmt:
+		return []*ast.Expr{&n.Right}
+	case *ast.LetDecl:
+		return []*ast.Expr{&n.Initializer}
+	case *ast.VarDecl:
+		return []*ast.Expr{&n.Initializer}
+	case *ast.ConstDecl:
+		return []*ast.Expr{&n.Initializer}
+	}
+	return nil
+}
+
+// exprFlags returns the purity flags of an expression.
+func exprFlags(e ast.Expr) ast.ExprFlags {
+	switch e := e.(type) {
+	case *ast.BinaryExpr:
+		return e.Flags
+	case *ast.UnaryExpr:
+		return e.Flags
+	}
+	return 0
+}
@@ -0,0 +1,664 @@
+package minifier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/dce"
+	"github.com/HugoDaniel/miniray/internal/printer"
+)
+
+// inlineFunctions replaces calls to small helpers with their bodies. A
+// function is a candidate when it is not an entry point, is not
+// recursive, and its body is a single return of a side-effect free
+// expression. Its calls are inlined when that makes the output smaller:
+// either every call is inlined and the declaration dropped, or each
+// inlined call is shorter than the call itself.
+//
+// Arguments are substituted for parameters, so an argument must be
+// side-effect free unless its parameter is used exactly once, and must
+// be a plain name or literal if it is used more than once. A call with an
+// argument that has side effects is only inlined when the body uses the
+// parameters in argument order, outside the right operand of && and ||,
+// and nothing else in the call reads memory the side effect could write. Arguments and
+// bodies of abstract type are left alone, since inlining would skip the
+// conversion to the parameter or return type. Locals of the caller that
+// would capture a name used by the body are renamed.
+//
+// Returns the number of calls inlined.
+func (m *Minifier) inlineFunctions(module *ast.Module) int {
+	in := &inliner{
+		module:      module,
+		purity:      ast.NewPurityContext(module.Symbols),
+		types:       newExprTypes(module),
+		parens:      make(parenSet),
+		names:       m.reservedNames(),
+		treeShaking: m.options.TreeShaking,
+	}
+	for _, sym := range module.Symbols {
+		in.names[sym.OriginalName] = true
+	}
+	// Measure sizes the way the module will be printed. Renamed
+	// identifiers are counted as one character.
+	var ren printer.Renamer
+	if m.options.MinifyIdentifiers {
+		ren = shortNames{}
+	}
+	in.printer = printer.New(printer.Options{
+		MinifyWhitespace:  m.options.MinifyWhitespace,
+		MinifyIdentifiers: m.options.MinifyIdentifiers,
+		MinifySyntax:      m.options.MinifySyntax,
+		Renamer:           ren,
+	}, module.Symbols)
+
+	in.graph = dce.DependencyGraph(module)
+	in.pointers = pointerNames(module)
+	in.markPureFunctions()
+	for _, fn := range in.calleesFirst() {
+		if in.inline(fn) {
+			in.graph = dce.DependencyGraph(module)
+		}
+	}
+	return in.inlined
+}
+
+// shortNames stands in for the minifying renamer when measuring sizes.
+type shortNames struct{}
+
+func (shortNames) NameForSymbol(ast.Ref) string { return "a" }
+
+type inliner struct {
+	module      *ast.Module
+	graph       map[uint32][]uint32
+	pointers    map[ast.Ref]bool // Parameters and lets of pointer type
+	purity      *ast.PurityContext
+	printer     *printer.Printer
+	types       *exprTypes
+	names       map[string]bool // Names in use, for renaming captured locals
+	parens      parenSet
+	treeShaking bool
+	inlined     int
+}
+
+// callSite is a call to the function being inlined.
+type callSite struct {
+	call        *ast.CallExpr
+	caller      *ast.FunctionDecl
+	replacement ast.Expr
+	bare        bool // The call is not an operand, see parenSet.unwrap
+	before      int  // Printed size of the call
+	after       int  // Printed size of the replacement
+}
+
+// returnExpr returns the expression of a function whose body is a single
+// return statement, or nil.
+func returnExpr(fn *ast.FunctionDecl) ast.Expr {
+	if fn.Body == nil || fn.ReturnType == nil || len(fn.Body.Stmts) != 1 {
+		return nil
+	}
+	if ret, ok := fn.Body.Stmts[0].(*ast.ReturnStmt); ok {
+		return ret.Value
+	}
+	return nil
+}
+
+// markPureFunctions registers single-expression functions with a side-
+// effect free body as pure calls, so that calls to them count as pure in
+// other bodies and arguments.
+func (in *inliner) markPureFunctions() {
+	for changed := true; changed; {
+		changed = false
+		for _, decl := range in.module.Declarations {
+			fn, ok := decl.(*ast.FunctionDecl)
+			if !ok {
+				continue
+			}
+			sym := in.types.symbol(fn.Name)
+			expr := returnExpr(fn)
+			if sym == nil || expr == nil || in.purity.PureCalls[sym.OriginalName] || in.recursive(fn.Name.InnerIndex) {
+				continue
+			}
+			if in.purity.ExprCanBeRemovedIfUnused(expr) {
+				in.purity.PureCalls[sym.OriginalName] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// calleesFirst returns the functions ordered so that a function comes
+// after every function it calls. Bodies are then final when a function is
+// considered for inlining.
+func (in *inliner) calleesFirst() []*ast.FunctionDecl {
+	functions := make(map[uint32]*ast.FunctionDecl)
+	for _, decl := range in.module.Declarations {
+		if fn, ok := decl.(*ast.FunctionDecl); ok && fn.Name.IsValid() {
+			functions[fn.Name.InnerIndex] = fn
+		}
+	}
+
+	var order []*ast.FunctionDecl
+	visited := make(map[uint32]bool)
+	var visit func(idx uint32)
+	visit = func(idx uint32) {
+		if visited[idx] {
+			return
+		}
+		visited[idx] = true
+		for _, dep := range in.graph[idx] {
+			visit(dep)
+		}
+		if fn, ok := functions[idx]; ok {
+			order = append(order, fn)
+		}
+	}
+	for _, decl := range in.module.Declarations {
+		if fn, ok := decl.(*ast.FunctionDecl); ok && fn.Name.IsValid() {
+			visit(fn.Name.InnerIndex)
+		}
+	}
+	return order
+}
+
+// recursive reports whether the function calls itself, directly or not.
+func (in *inliner) recursive(idx uint32) bool {
+	visited := make(map[uint32]bool)
+	var reaches func(from uint32) bool
+	reaches = func(from uint32) bool {
+		for _, dep := range in.graph[from] {
+			if dep == idx {
+				return true
+			}
+			if !visited[dep] {
+				visited[dep] = true
+				if reaches(dep) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return reaches(idx)
+}
+
+// references counts the references to a symbol in the dependency graph.
+func (in *inliner) references(idx uint32) int {
+	count := 0
+	for _, deps := range in.graph {
+		for _, dep := range deps {
+			if dep == idx {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// inline inlines the calls to fn where that pays off and reports whether
+// any call was inlined.
+func (in *inliner) inline(fn *ast.FunctionDecl) bool {
+	sym := in.types.symbol(fn.Name)
+	body := returnExpr(fn)
+	if sym == nil || body == nil || sym.Flags.Has(ast.IsEntryPoint) || sym.Flags.Has(ast.MustNotBeRenamed) {
+		return false
+	}
+	if in.recursive(fn.Name.InnerIndex) || !in.purity.ExprCanBeRemovedIfUnused(body) {
+		return false
+	}
+
+	params := make(map[ast.Ref]int)
+	for i, param := range fn.Parameters {
+		if len(param.Attributes) > 0 {
+			return false
+		}
+		params[param.Name] = i
+	}
+	if !in.types.concrete(body, params) {
+		return false
+	}
+	uses := make([]int, len(fn.Parameters))
+	ast.Inspect(body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.IdentExpr); ok {
+			if i, ok := params[id.Ref]; ok {
+				uses[i]++
+			}
+		}
+		return true
+	})
+
+	var accepted []callSite
+	callSize, inlinedSize := 0, 0
+	for _, site := range in.callSites(fn) {
+		if !in.acceptsArgs(fn, site.call, body, params, uses) {
+			continue
+		}
+		site.replacement = in.substitute(body, params, site.call.Args)
+		site.before = len(in.printer.PrintExpr(site.call))
+		site.after = len(in.printer.PrintExpr(site.replacement))
+		if paren, ok := site.replacement.(*ast.ParenExpr); ok && site.bare && in.parens[paren] {
+			site.after -= len("()")
+		}
+		accepted = append(accepted, site)
+		callSize += site.before
+		inlinedSize += site.after
+	}
+	if len(accepted) == 0 {
+		return false
+	}
+
+	// Dropping the declaration only pays off if every call is inlined.
+	// Otherwise only the calls that get shorter are.
+	removeDecl := in.treeShaking && len(accepted) == in.references(fn.Name.InnerIndex)
+	if removeDecl {
+		if inlinedSize >= callSize+len(in.printer.PrintDecl(fn)) {
+			return false
+		}
+	} else {
+		shorter := accepted[:0]
+		for _, site := range accepted {
+			if site.after < site.before {
+				shorter = append(shorter, site)
+			}
+		}
+		accepted = shorter
+		if len(accepted) == 0 {
+			return false
+		}
+	}
+
+	replacements := make(map[*ast.CallExpr]ast.Expr)
+	for _, site := range accepted {
+		replacements[site.call] = site.replacement
+	}
+	for _, site := range accepted {
+		in.renameCaptured(site.caller, body, params)
+	}
+	for _, decl := range in.module.Declarations {
+		if caller, ok := decl.(*ast.FunctionDecl); ok && caller != fn {
+			ast.RewriteExprs(caller, func(e ast.Expr) ast.Expr {
+				if call, ok := e.(*ast.CallExpr); ok {
+					if r, ok := replacements[call]; ok {
+						return r
+					}
+				}
+				return e
+			})
+			in.parens.unwrap(caller)
+		}
+	}
+	in.inlined += len(accepted)
+
+	if removeDecl {
+		for i, decl := range in.module.Declarations {
+			if decl == ast.Decl(fn) {
+				in.module.Declarations = append(in.module.Declarations[:i], in.module.Declarations[i+1:]...)
+				break
+			}
+		}
+	}
+	return true
+}
+
+// callSites finds the calls to fn in expressions. Calls used as
+// statements can't be replaced by an expression and are not included.
+func (in *inliner) callSites(fn *ast.FunctionDecl) []callSite {
+	var sites []callSite
+	for _, decl := range in.module.Declarations {
+		caller, ok := decl.(*ast.FunctionDecl)
+		if !ok || caller == fn {
+			continue
+		}
+		statements := make(map[*ast.CallExpr]bool)
+		bare := make(map[ast.Expr]bool)
+		ast.Inspect(caller, func(n ast.Node) bool {
+			if n, ok := n.(*ast.CallStmt); ok {
+				statements[n.Call] = true
+			}
+			for _, slot := range bareSlots(n) {
+				bare[*slot] = true
+			}
+			if call, ok := n.(*ast.CallExpr); ok {
+				if id, ok := call.Func.(*ast.IdentExpr); ok && id.Ref == fn.Name && !statements[call] {
+					sites = append(sites, callSite{call: call, caller: caller, bare: bare[call]})
+				}
+			}
+			return true
+		})
+	}
+	return sites
+}
+
+// acceptsArgs reports whether the arguments of call can be substituted
+// for the parameters, which the body uses uses[i] times each.
+func (in *inliner) acceptsArgs(fn *ast.FunctionDecl, call *ast.CallExpr, body ast.Expr, params map[ast.Ref]int, uses []int) bool {
+	if len(call.Args) != len(uses) {
+		return false
+	}
+
+	// An argument of abstract type is converted to the parameter type at
+	// the call. Inlined, it must meet an operand of concrete type at each
+	// use to get the same conversion.
+	concreteParams := make(map[ast.Ref]int)
+	var abstract []ast.Ref
+	for i, arg := range call.Args {
+		if in.types.concrete(arg, nil) {
+			concreteParams[fn.Parameters[i].Name] = i
+		} else {
+			abstract = append(abstract, fn.Parameters[i].Name)
+		}
+	}
+	for _, ref := range abstract {
+		if in.convertedUses(body, ref, concreteParams) != uses[params[ref]] {
+			return false
+		}
+	}
+
+	impure := -1
+	for i, arg := range call.Args {
+		if callsFunction(arg, fn.Name) {
+			return false
+		}
+		pure := in.purity.ExprCanBeRemovedIfUnused(arg)
+		if !pure {
+			if impure >= 0 {
+				// More than one argument with side effects could change
+				// the order they happen in
+				return false
+			}
+			impure = i
+		}
+		switch {
+		case uses[i] == 0 && !pure:
+			// The side effects would be lost
+			return false
+		case uses[i] > 1 && !isSimple(arg):
+			// The argument would be evaluated more than once
+			return false
+		}
+	}
+	if impure < 0 {
+		return true
+	}
+
+	// The side effect happens before the body runs. Inlined, it happens
+	// where the parameter is used, so it must still come before every
+	// other argument and must still happen, and nothing it may write can
+	// be read elsewhere.
+	order := in.paramOrder(body, params, fn.Parameters[impure].Name)
+	if order == nil {
+		return false
+	}
+	for i := 1; i < len(order); i++ {
+		if order[i] < order[i-1] {
+			return false
+		}
+	}
+	for i, arg := range call.Args {
+		if i != impure && in.readsMemory(arg, nil) {
+			return false
+		}
+	}
+	return !in.readsMemory(body, params)
+}
+
+// paramOrder returns the parameters in the order body evaluates their
+// uses, or nil if the parameter conditional is used where it might not be
+// evaluated: in the right operand of && or ||.
+func (in *inliner) paramOrder(body ast.Expr, params map[ast.Ref]int, conditional ast.Ref) []int {
+	var order []int
+	ok := true
+	var visit func(e ast.Expr, skipped bool)
+	visit = func(e ast.Expr, skipped bool) {
+		ast.Inspect(e, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.IdentExpr:
+				if i, found := params[n.Ref]; found {
+					order = append(order, i)
+					if skipped && n.Ref == conditional {
+						ok = false
+					}
+				}
+			case *ast.BinaryExpr:
+				if n.Op == ast.BinOpLogicalAnd || n.Op == ast.BinOpLogicalOr {
+					visit(n.Left, skipped)
+					visit(n.Right, true)
+					return false
+				}
+			}
+			return true
+		})
+	}
+	visit(body, false)
+	if !ok {
+		return nil
+	}
+	return order
+}
+
+// readsMemory reports whether e may read a var, directly, through a
+// pointer or in a function it calls. References to params are ignored.
+func (in *inliner) readsMemory(e ast.Expr, params map[ast.Ref]int) bool {
+	found := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IdentExpr:
+			if _, ok := params[n.Ref]; ok {
+				break
+			}
+			if in.pointers[n.Ref] {
+				found = true
+			} else if sym := in.types.symbol(n.Ref); sym != nil {
+				switch sym.Kind {
+				case ast.SymbolVar:
+					found = true
+				case ast.SymbolFunction:
+					found = in.functionReadsMemory(n.Ref.InnerIndex)
+				}
+			}
+		case *ast.UnaryExpr:
+			if n.Op == ast.UnaryOpDeref || n.Op == ast.UnaryOpAddr {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// functionReadsMemory reports whether a function refers to a module-scope
+// var, directly or in a function it calls, or takes a pointer.
+func (in *inliner) functionReadsMemory(idx uint32) bool {
+	visited := make(map[uint32]bool)
+	var reads func(idx uint32) bool
+	reads = func(idx uint32) bool {
+		if visited[idx] {
+			return false
+		}
+		visited[idx] = true
+		if int(idx) < len(in.module.Symbols) && in.module.Symbols[idx].Kind == ast.SymbolVar {
+			return true
+		}
+		for _, dep := range in.graph[idx] {
+			if reads(dep) {
+				return true
+			}
+		}
+		return false
+	}
+	if reads(idx) {
+		return true
+	}
+	for _, decl := range in.module.Declarations {
+		if fn, ok := decl.(*ast.FunctionDecl); ok && fn.Name.InnerIndex == idx {
+			for _, param := range fn.Parameters {
+				if in.pointers[param.Name] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// pointerNames returns the parameters and lets of pointer type.
+func pointerNames(module *ast.Module) map[ast.Ref]bool {
+	pointers := make(map[ast.Ref]bool)
+	ast.Inspect(module, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionDecl:
+			for _, param := range n.Parameters {
+				if _, ok := param.Type.(*ast.PtrType); ok {
+					pointers[param.Name] = true
+				}
+			}
+		case *ast.LetDecl:
+			if _, ok := n.Type.(*ast.PtrType); ok {
+				pointers[n.Name] = true
+			} else if u, ok := n.Initializer.(*ast.UnaryExpr); ok && u.Op == ast.UnaryOpAddr {
+				pointers[n.Name] = true
+			}
+		}
+		return true
+	})
+	return pointers
+}
+
+// convertedUses counts the uses of a parameter in body that are an
+// operand of a binary operator whose other operand has a concrete type,
+// or the shift amount of a shift.
+func (in *inliner) convertedUses(body ast.Expr, param ast.Ref, concreteParams map[ast.Ref]int) int {
+	isParam := func(e ast.Expr) bool {
+		id, ok := e.(*ast.IdentExpr)
+		return ok && id.Ref == param
+	}
+	count := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		b, ok := n.(*ast.BinaryExpr)
+		if !ok {
+			return true
+		}
+		shift := b.Op == ast.BinOpShl || b.Op == ast.BinOpShr
+		if isParam(b.Left) && !shift && in.types.concrete(b.Right, concreteParams) {
+			count++
+		}
+		if isParam(b.Right) && (shift || in.types.concrete(b.Left, concreteParams)) {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// renameCaptured renames the parameters and locals of caller that have
+// the name of something the inlined body refers to.
+func (in *inliner) renameCaptured(caller *ast.FunctionDecl, body ast.Expr, params map[ast.Ref]int) {
+	free := make(map[string]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IdentExpr:
+			if _, ok := params[n.Ref]; ok {
+				break
+			}
+			if sym := in.types.symbol(n.Ref); sym != nil {
+				free[sym.OriginalName] = true
+			} else {
+				free[n.Name] = true
+			}
+		case *ast.IdentType:
+			free[n.Name] = true
+		}
+		return true
+	})
+
+	rename := func(ref ast.Ref) {
+		sym := in.types.symbol(ref)
+		if sym == nil || !free[sym.OriginalName] || sym.Flags.Has(ast.MustNotBeRenamed) {
+			return
+		}
+		name := sym.OriginalName
+		for i := 1; in.names[name]; i++ {
+			name = fmt.Sprintf("%s_%d", strings.TrimRight(sym.OriginalName, "_"), i)
+		}
+		in.names[name] = true
+		sym.OriginalName = name
+	}
+	for _, param := range caller.Parameters {
+		rename(param.Name)
+	}
+	ast.Inspect(caller.Body, func(n ast.Node) bool {
+		switch d := n.(type) {
+		case *ast.LetDecl:
+			rename(d.Name)
+		case *ast.VarDecl:
+			rename(d.Name)
+		case *ast.ConstDecl:
+			rename(d.Name)
+		}
+		return true
+	})
+}
+
+// callsFunction reports whether e contains a call to fn.
+func callsFunction(e ast.Expr, fn ast.Ref) bool {
+	found := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		if id, ok := n.(*ast.IdentExpr); ok && id.Ref == fn {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// substitute returns a copy of body with the parameters replaced by copies
+// of the arguments. The printer doesn't add parentheses for precedence, so
+// operands that aren't primary expressions are wrapped.
+func (in *inliner) substitute(body ast.Expr, params map[ast.Ref]int, args []ast.Expr) ast.Expr {
+	var copyExpr func(e ast.Expr) ast.Expr
+	copyExpr = func(e ast.Expr) ast.Expr {
+		switch e := e.(type) {
+		case *ast.IdentExpr:
+			if i, ok := params[e.Ref]; ok {
+				return in.parens.wrap(copyExpr(args[i]))
+			}
+			c := *e
+			return &c
+		case *ast.LiteralExpr:
+			c := *e
+			return &c
+		case *ast.BinaryExpr:
+			c := *e
+			c.Left = copyExpr(e.Left)
+			c.Right = copyExpr(e.Right)
+			return &c
+		case *ast.UnaryExpr:
+			c := *e
+			c.Operand = copyExpr(e.Operand)
+			return &c
+		case *ast.CallExpr:
+			c := *e
+			c.Func = copyExpr(e.Func)
+			c.Args = make([]ast.Expr, len(e.Args))
+			for i, arg := range e.Args {
+				c.Args[i] = copyExpr(arg)
+			}
+			return &c
+		case *ast.IndexExpr:
+			c := *e
+			c.Base = copyExpr(e.Base)
+			c.Index = copyExpr(e.Index)
+			return &c
+		case *ast.MemberExpr:
+			c := *e
+			c.Base = copyExpr(e.Base)
+			return &c
+		case *ast.ParenExpr:
+			c := *e
+			c.Expr = copyExpr(e.Expr)
+			return &c
+		}
+		return e
+	}
+	return in.parens.wrap(copyExpr(body))
+}
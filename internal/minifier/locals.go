@@ -0,0 +1,588 @@
+package minifier
+
+import (
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/lexer"
+	"github.com/HugoDaniel/miniray/internal/printer"
+)
+
+// optimizeLocals shortens the code around the local variables of every
+// function:
+//   - a let used once, in the statement right after it, is replaced by
+//     its initializer
+//   - a var declared after the last use of an earlier var of the same
+//     type in the same block reuses that var, so `var b: T = e;` becomes
+//     `a = e;`
+//   - x = x op y becomes x op= y, and x += 1 on an integer becomes x++
+//
+// WGSL declares one name per statement, so coalescing is how consecutive
+// vars are merged. A let is only inlined when its initializer is side-
+// effect free and of concrete type, and when nothing in the next statement
+// can write memory the initializer reads before the let would be used.
+// Returns the number of lets inlined and vars coalesced.
+func (m *Minifier) optimizeLocals(module *ast.Module) (lets, vars int) {
+	l := &localizer{
+		purity:  ast.NewPurityContext(module.Symbols),
+		types:   newExprTypes(module),
+		parens:  make(parenSet),
+		printer: printer.New(printer.Options{MinifyWhitespace: true}, module.Symbols),
+		ints:    make(map[ast.Ref]bool),
+	}
+	ast.Inspect(module, func(n ast.Node) bool {
+		if d, ok := n.(*ast.VarDecl); ok && isIntegerVar(d) {
+			l.ints[d.Name] = true
+		}
+		return true
+	})
+
+	for _, decl := range module.Declarations {
+		if fn, ok := decl.(*ast.FunctionDecl); ok && fn.Body != nil {
+			l.function(fn)
+		}
+	}
+	return l.lets, l.vars
+}
+
+type localizer struct {
+	purity  *ast.PurityContext
+	types   *exprTypes
+	parens  parenSet
+	printer *printer.Printer
+	ints    map[ast.Ref]bool // Vars of type i32 or u32
+
+	// State of the current function
+	fn       *ast.FunctionDecl
+	uses     map[ast.Ref]int
+	pointers map[ast.Ref]bool // Parameters and lets of pointer type
+	names    map[string]int   // Locals and parameters by original name
+
+	lets int
+	vars int
+}
+
+func (l *localizer) function(fn *ast.FunctionDecl) {
+	l.fn = fn
+	l.scan()
+
+	// Blocks whose declarations a continuing block can still see
+	continuing := make(map[*ast.CompoundStmt]*ast.CompoundStmt)
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if loop, ok := n.(*ast.LoopStmt); ok && loop.Continuing != nil {
+			continuing[loop.Body] = loop.Continuing
+		}
+		return true
+	})
+
+	l.blocks(func(b *ast.CompoundStmt) { l.inlineLets(b) })
+	l.blocks(func(b *ast.CompoundStmt) { l.coalesceVars(b, continuing[b]) })
+	l.blocks(func(b *ast.CompoundStmt) {
+		for i, s := range b.Stmts {
+			b.Stmts[i] = l.compound(s)
+		}
+	})
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if s, ok := n.(*ast.ForStmt); ok {
+			s.Update = l.compound(s.Update)
+		}
+		return true
+	})
+	l.parens.unwrap(fn)
+}
+
+// scan counts the references to every symbol of the current function and
+// finds its pointers and local names.
+func (l *localizer) scan() {
+	l.uses = make(map[ast.Ref]int)
+	l.pointers = make(map[ast.Ref]bool)
+	l.names = make(map[string]int)
+
+	declare := func(ref ast.Ref) {
+		if sym := l.types.symbol(ref); sym != nil {
+			l.names[sym.OriginalName]++
+		}
+	}
+	for _, param := range l.fn.Parameters {
+		declare(param.Name)
+		if _, ok := param.Type.(*ast.PtrType); ok {
+			l.pointers[param.Name] = true
+		}
+	}
+	ast.Inspect(l.fn.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IdentExpr:
+			l.uses[n.Ref]++
+		case *ast.LetDecl:
+			declare(n.Name)
+			if l.isPointer(n.Type, n.Initializer) {
+				l.pointers[n.Name] = true
+			}
+		case *ast.VarDecl:
+			declare(n.Name)
+		case *ast.ConstDecl:
+			declare(n.Name)
+		}
+		return true
+	})
+}
+
+// isPointer reports whether a let of the given type and initializer holds
+// a pointer.
+func (l *localizer) isPointer(t ast.Type, init ast.Expr) bool {
+	if _, ok := t.(*ast.PtrType); ok {
+		return true
+	}
+	switch e := init.(type) {
+	case *ast.UnaryExpr:
+		return e.Op == ast.UnaryOpAddr
+	case *ast.IdentExpr:
+		return l.pointers[e.Ref]
+	case *ast.ParenExpr:
+		return l.isPointer(nil, e.Expr)
+	}
+	return false
+}
+
+// blocks calls f for every block of the current function, outer blocks
+// first. Statements f leaves in a block are visited afterwards.
+func (l *localizer) blocks(f func(b *ast.CompoundStmt)) {
+	ast.Inspect(l.fn.Body, func(n ast.Node) bool {
+		if b, ok := n.(*ast.CompoundStmt); ok {
+			f(b)
+		}
+		return true
+	})
+}
+
+// ----------------------------------------------------------------------------
+// Let Inlining
+// ----------------------------------------------------------------------------
+
+func (l *localizer) inlineLets(b *ast.CompoundStmt) {
+	for i := 0; i+1 < len(b.Stmts); {
+		if !l.inlineLet(b.Stmts[i], b.Stmts[i+1]) {
+			i++
+			continue
+		}
+		b.Stmts = append(b.Stmts[:i], b.Stmts[i+1:]...)
+		l.lets++
+		// The previous let may now be used by the next statement
+		if i > 0 {
+			i--
+		}
+	}
+}
+
+// inlineLet substitutes the initializer of a let declared by decl into
+// next, and reports whether it did.
+func (l *localizer) inlineLet(decl, next ast.Stmt) bool {
+	ds, ok := decl.(*ast.DeclStmt)
+	if !ok {
+		return false
+	}
+	let, ok := ds.Decl.(*ast.LetDecl)
+	if !ok || !let.Name.IsValid() || l.uses[let.Name] != 1 || l.pointers[let.Name] {
+		return false
+	}
+	init := let.Initializer
+	if init == nil || !l.purity.ExprCanBeRemovedIfUnused(init) || !l.types.concrete(init, nil) {
+		return false
+	}
+
+	slots := valueSlots(next)
+	var slot *ast.Expr
+	for _, s := range slots {
+		if references(*s, let.Name) {
+			slot = s
+			break
+		}
+	}
+	if slot == nil {
+		return false
+	}
+	if l.shadows(next, init) {
+		return false
+	}
+	// Calls evaluated before the use could write what init reads
+	if l.readsMemory(init) {
+		for _, s := range slots {
+			if !l.callsOnlyAround(*s, let.Name) {
+				return false
+			}
+		}
+	}
+
+	if id, ok := (*slot).(*ast.IdentExpr); ok && id.Ref == let.Name {
+		*slot = init
+	} else {
+		ast.RewriteExprs(*slot, func(e ast.Expr) ast.Expr {
+			if id, ok := e.(*ast.IdentExpr); ok && id.Ref == let.Name {
+				return l.parens.wrap(init)
+			}
+			return e
+		})
+	}
+	delete(l.uses, let.Name)
+	return true
+}
+
+// valueSlots returns the expressions a statement evaluates once, before
+// anything else it does.
+func valueSlots(s ast.Stmt) []*ast.Expr {
+	switch s := s.(type) {
+	case *ast.DeclStmt:
+		switch d := s.Decl.(type) {
+		case *ast.LetDecl:
+			return []*ast.Expr{&d.Initializer}
+		case *ast.VarDecl:
+			if d.Initializer != nil {
+				return []*ast.Expr{&d.Initializer}
+			}
+		}
+	case *ast.AssignStmt:
+		return []*ast.Expr{&s.Left, &s.Right}
+	case *ast.IncrDecrStmt:
+		return []*ast.Expr{&s.Expr}
+	case *ast.ReturnStmt:
+		if s.Value != nil {
+			return []*ast.Expr{&s.Value}
+		}
+	case *ast.CallStmt:
+		return bareSlots(s.Call)
+	case *ast.IfStmt:
+		return []*ast.Expr{&s.Condition}
+	case *ast.SwitchStmt:
+		return []*ast.Expr{&s.Expr}
+	}
+	return nil
+}
+
+// references reports whether e refers to the symbol.
+func references(e ast.Expr, ref ast.Ref) bool {
+	found := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		if id, ok := n.(*ast.IdentExpr); ok && id.Ref == ref {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// shadows reports whether next declares a name that init refers to.
+func (l *localizer) shadows(next ast.Stmt, init ast.Expr) bool {
+	ds, ok := next.(*ast.DeclStmt)
+	if !ok {
+		return false
+	}
+	var name ast.Ref
+	switch d := ds.Decl.(type) {
+	case *ast.LetDecl:
+		name = d.Name
+	case *ast.VarDecl:
+		name = d.Name
+	default:
+		return false
+	}
+	sym := l.types.symbol(name)
+	if sym == nil {
+		return false
+	}
+	shadowed := false
+	ast.Inspect(init, func(n ast.Node) bool {
+		if id, ok := n.(*ast.IdentExpr); ok && id.Name == sym.OriginalName {
+			shadowed = true
+		}
+		return !shadowed
+	})
+	return shadowed
+}
+
+// readsMemory reports whether e reads a var, directly or through a
+// pointer.
+func (l *localizer) readsMemory(e ast.Expr) bool {
+	reads := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IdentExpr:
+			if sym := l.types.symbol(n.Ref); (sym != nil && sym.Kind == ast.SymbolVar) || l.pointers[n.Ref] {
+				reads = true
+			}
+		case *ast.UnaryExpr:
+			if n.Op == ast.UnaryOpDeref {
+				reads = true
+			}
+		}
+		return !reads
+	})
+	return reads
+}
+
+// callsOnlyAround reports whether every call in e with side effects has
+// the symbol among its arguments, so that the symbol is read before the
+// call runs.
+func (l *localizer) callsOnlyAround(e ast.Expr, ref ast.Ref) bool {
+	ok := true
+	ast.Inspect(e, func(n ast.Node) bool {
+		if call, is := n.(*ast.CallExpr); is && !l.purity.ExprCanBeRemovedIfUnused(call) && !references(call, ref) {
+			ok = false
+		}
+		return ok
+	})
+	return ok
+}
+
+// ----------------------------------------------------------------------------
+// Var Coalescing
+// ----------------------------------------------------------------------------
+
+// coalesceVars replaces a var declaration by an assignment to an earlier
+// var of the same block that is no longer used. Declarations of the block
+// stay visible to the continuing block of its loop, if any.
+func (l *localizer) coalesceVars(b *ast.CompoundStmt, continuing *ast.CompoundStmt) {
+	for j, s := range b.Stmts {
+		later := localVar(s)
+		if later == nil || later.Type == nil || later.Initializer == nil {
+			continue
+		}
+		for i := 0; i < j; i++ {
+			earlier := localVar(b.Stmts[i])
+			if earlier == nil || !l.reusable(earlier, later, b.Stmts[j+1:], continuing) {
+				continue
+			}
+			l.reuse(earlier, later)
+			if isRef(later.Initializer, earlier.Name) {
+				// var y = x; merged into x would assign x to itself
+				b.Stmts[j] = nil
+			} else {
+				b.Stmts[j] = &ast.AssignStmt{
+					Loc:   later.Loc,
+					Op:    ast.AssignOpSimple,
+					Left:  &ast.IdentExpr{Loc: later.Loc, Name: l.types.symbol(earlier.Name).OriginalName, Ref: earlier.Name},
+					Right: later.Initializer,
+				}
+			}
+			l.vars++
+			break
+		}
+	}
+
+	stmts := b.Stmts[:0]
+	for _, s := range b.Stmts {
+		if s != nil {
+			stmts = append(stmts, s)
+		}
+	}
+	b.Stmts = stmts
+}
+
+// isRef reports whether e, without parentheses, is a reference to ref.
+func isRef(e ast.Expr, ref ast.Ref) bool {
+	for {
+		paren, ok := e.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		e = paren.Expr
+	}
+	id, ok := e.(*ast.IdentExpr)
+	return ok && id.Ref == ref
+}
+
+// localVar returns the var declared by a statement, or nil.
+func localVar(s ast.Stmt) *ast.VarDecl {
+	ds, ok := s.(*ast.DeclStmt)
+	if !ok {
+		return nil
+	}
+	d, ok := ds.Decl.(*ast.VarDecl)
+	if !ok || !d.Name.IsValid() || (d.AddressSpace != ast.AddressSpaceNone && d.AddressSpace != ast.AddressSpaceFunction) {
+		return nil
+	}
+	return d
+}
+
+// reusable reports whether later can be replaced by earlier: both have the
+// same type, earlier is not used by the statements that follow later and
+// never has its address taken, and its name refers to it alone.
+func (l *localizer) reusable(earlier, later *ast.VarDecl, rest []ast.Stmt, continuing *ast.CompoundStmt) bool {
+	sym := l.types.symbol(earlier.Name)
+	if sym == nil || earlier.Type == nil || l.names[sym.OriginalName] != 1 {
+		return false
+	}
+	if l.printer.PrintType(earlier.Type) != l.printer.PrintType(later.Type) {
+		return false
+	}
+
+	used := false
+	check := func(n ast.Node) bool {
+		if id, ok := n.(*ast.IdentExpr); ok && id.Ref == earlier.Name {
+			used = true
+		}
+		return !used
+	}
+	for _, s := range rest {
+		ast.Inspect(s, check)
+	}
+	if continuing != nil {
+		ast.Inspect(continuing, check)
+	}
+	if used {
+		return false
+	}
+
+	addressed := false
+	ast.Inspect(l.fn.Body, func(n ast.Node) bool {
+		if u, ok := n.(*ast.UnaryExpr); ok && u.Op == ast.UnaryOpAddr && references(u.Operand, earlier.Name) {
+			addressed = true
+		}
+		return !addressed
+	})
+	return !addressed
+}
+
+// reuse points the references to later at earlier.
+func (l *localizer) reuse(earlier, later *ast.VarDecl) {
+	name := l.types.symbol(earlier.Name).OriginalName
+	ast.Inspect(l.fn.Body, func(n ast.Node) bool {
+		if id, ok := n.(*ast.IdentExpr); ok && id.Ref == later.Name {
+			id.Ref = earlier.Name
+			id.Name = name
+		}
+		return true
+	})
+	l.uses[earlier.Name] += l.uses[later.Name]
+	delete(l.uses, later.Name)
+}
+
+// ----------------------------------------------------------------------------
+// Compound Assignment
+// ----------------------------------------------------------------------------
+
+var compoundOps = map[ast.BinaryOp]ast.AssignOp{
+	ast.BinOpAdd: ast.AssignOpAdd,
+	ast.BinOpSub: ast.AssignOpSub,
+	ast.BinOpMul: ast.AssignOpMul,
+	ast.BinOpDiv: ast.AssignOpDiv,
+	ast.BinOpMod: ast.AssignOpMod,
+	ast.BinOpAnd: ast.AssignOpAnd,
+	ast.BinOpOr:  ast.AssignOpOr,
+	ast.BinOpXor: ast.AssignOpXor,
+	ast.BinOpShl: ast.AssignOpShl,
+	ast.BinOpShr: ast.AssignOpShr,
+}
+
+// compound rewrites x = x op y to x op= y, and integer x += 1 to x++.
+func (l *localizer) compound(s ast.Stmt) ast.Stmt {
+	assign, ok := s.(*ast.AssignStmt)
+	if !ok {
+		return s
+	}
+	if assign.Op == ast.AssignOpSimple {
+		right := assign.Right
+		if paren, ok := right.(*ast.ParenExpr); ok {
+			right = paren.Expr
+		}
+		bin, ok := right.(*ast.BinaryExpr)
+		if !ok || !l.purity.ExprCanBeRemovedIfUnused(assign.Left) {
+			return s
+		}
+		op, ok := compoundOps[bin.Op]
+		if !ok {
+			return s
+		}
+		switch {
+		case sameLocation(assign.Left, bin.Left):
+			assign.Right = bin.Right
+		case commutative(bin.Op) && sameLocation(assign.Left, bin.Right):
+			assign.Right = bin.Left
+		default:
+			return s
+		}
+		assign.Op = op
+		if paren, ok := assign.Right.(*ast.ParenExpr); ok {
+			assign.Right = paren.Expr
+		}
+	}
+
+	if assign.Op != ast.AssignOpAdd && assign.Op != ast.AssignOpSub {
+		return s
+	}
+	id, ok := assign.Left.(*ast.IdentExpr)
+	lit, isLit := assign.Right.(*ast.LiteralExpr)
+	if !ok || !isLit || !l.ints[id.Ref] || lit.Kind != lexer.TokIntLiteral {
+		return s
+	}
+	switch lit.Value {
+	case "1", "1i", "1u":
+		return &ast.IncrDecrStmt{Loc: assign.Loc, Expr: assign.Left, Increment: assign.Op == ast.AssignOpAdd}
+	}
+	return s
+}
+
+// commutative reports whether the operands of op can be swapped for every
+// operand type. Matrix multiplication is not commutative.
+func commutative(op ast.BinaryOp) bool {
+	switch op {
+	case ast.BinOpAdd, ast.BinOpAnd, ast.BinOpOr, ast.BinOpXor:
+		return true
+	}
+	return false
+}
+
+// sameLocation reports whether two references name the same memory
+// location.
+func sameLocation(a, b ast.Expr) bool {
+	switch a := a.(type) {
+	case *ast.IdentExpr:
+		b, ok := b.(*ast.IdentExpr)
+		return ok && a.Ref == b.Ref && a.Name == b.Name
+	case *ast.MemberExpr:
+		b, ok := b.(*ast.MemberExpr)
+		return ok && a.Member == b.Member && sameLocation(a.Base, b.Base)
+	case *ast.IndexExpr:
+		b, ok := b.(*ast.IndexExpr)
+		return ok && sameLocation(a.Base, b.Base) && sameValue(a.Index, b.Index)
+	case *ast.UnaryExpr:
+		b, ok := b.(*ast.UnaryExpr)
+		return ok && a.Op == ast.UnaryOpDeref && b.Op == ast.UnaryOpDeref && sameLocation(a.Operand, b.Operand)
+	case *ast.ParenExpr:
+		return sameLocation(a.Expr, b)
+	}
+	if paren, ok := b.(*ast.ParenExpr); ok {
+		return sameLocation(a, paren.Expr)
+	}
+	return false
+}
+
+// sameValue reports whether two index expressions are the same name or
+// literal.
+func sameValue(a, b ast.Expr) bool {
+	switch a := a.(type) {
+	case *ast.IdentExpr:
+		b, ok := b.(*ast.IdentExpr)
+		return ok && a.Ref == b.Ref && a.Name == b.Name
+	case *ast.LiteralExpr:
+		b, ok := b.(*ast.LiteralExpr)
+		return ok && a.Kind == b.Kind && a.Value == b.Value
+	}
+	return false
+}
+
+// isIntegerVar reports whether a var is known to hold an i32 or u32.
+func isIntegerVar(d *ast.VarDecl) bool {
+	if t, ok := d.Type.(*ast.IdentType); ok {
+		return t.Name == "i32" || t.Name == "u32"
+	}
+	if d.Type != nil {
+		return false
+	}
+	switch init := d.Initializer.(type) {
+	case *ast.LiteralExpr:
+		// An abstract integer initializer makes an i32
+		return init.Kind == lexer.TokIntLiteral
+	case *ast.CallExpr:
+		id, ok := init.Func.(*ast.IdentExpr)
+		return ok && init.TemplateType == nil && (id.Name == "i32" || id.Name == "u32")
+	}
+	return false
+}
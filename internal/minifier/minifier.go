@@ -5,13 +5,18 @@
 package minifier
 
 import (
+	"maps"
+
 	"github.com/HugoDaniel/miniray/internal/ast"
 	"github.com/HugoDaniel/miniray/internal/dce"
 	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/preprocess"
 	"github.com/HugoDaniel/miniray/internal/printer"
 	"github.com/HugoDaniel/miniray/internal/reflect"
 	"github.com/HugoDaniel/miniray/internal/renamer"
 	"github.com/HugoDaniel/miniray/internal/sourcemap"
+	"github.com/HugoDaniel/miniray/internal/types"
+	"github.com/HugoDaniel/miniray/internal/validator"
 )
 
 // Options controls minification behavior.
@@ -51,6 +56,20 @@ type Options struct {
 
 	// SourceMapOptions configures source map output
 	SourceMapOptions SourceMapOptions
+
+	// Defines are the initial preprocessor defines. The preprocessor runs
+	// when they are set or the source contains directives.
+	Defines map[string]string
+
+	// Overrides specializes override declarations, matched by name or by
+	// @id, into constants with the given values so that tree shaking and
+	// folding can use them. Booleans are given as 0 and 1, as in WebGPU.
+	Overrides map[string]float64
+
+	// Plugins are custom passes run at fixed points of the pipeline.
+	// Symbols referenced by code added after dead code elimination must be
+	// marked ast.IsLive to survive printing.
+	Plugins []Plugin
 }
 
 // SourceMapOptions configures source map generation.
@@ -63,6 +82,10 @@ type SourceMapOptions struct {
 
 	// IncludeSource embeds the original source in "sourcesContent"
 	IncludeSource bool
+
+	// Scopes records function and block scopes, with the original and
+	// generated name of every declaration, in "x_miniray_scopes"
+	Scopes bool
 }
 
 // DefaultOptions returns options for maximum minification.
@@ -89,6 +112,10 @@ type Result struct {
 
 	// SourceMap is the generated source map (nil if not requested)
 	SourceMap *sourcemap.SourceMap
+
+	// Overrides describes the module's override declarations when
+	// Options.Overrides is set, including the specialized ones.
+	Overrides []reflect.OverrideInfo
 }
 
 // Error represents a minification error.
@@ -105,16 +132,34 @@ type Stats struct {
 	SymbolsTotal   int
 	SymbolsRenamed int
 	SymbolsDead    int // Number of symbols removed by tree shaking
+	StatementsDead int // Number of unreachable statements removed
+	CallsInlined   int // Number of function calls replaced by the function body
+	LetsInlined    int // Number of let declarations replaced by their initializer
+	VarsCoalesced  int // Number of var declarations merged into an earlier var
 }
 
-// Minifier performs WGSL minification.
+// Minifier performs WGSL minification. A Minifier can be reused, also
+// from several goroutines at once, as long as its Plugins allow that.
 type Minifier struct {
 	options Options
+
+	// reserved holds the names renamed symbols must not use: WGSL
+	// keywords, reserved words, built-ins and KeepNames. Runs copy it.
+	reserved map[string]bool
 }
 
 // New creates a new minifier with the given options.
 func New(options Options) *Minifier {
-	return &Minifier{options: options}
+	reserved := renamer.ComputeReservedNames()
+	for _, name := range options.KeepNames {
+		reserved[name] = true
+	}
+	return &Minifier{options: options, reserved: reserved}
+}
+
+// reservedNames returns a copy of the reserved names for one run.
+func (m *Minifier) reservedNames() map[string]bool {
+	return maps.Clone(m.reserved)
 }
 
 // Minify minifies the given WGSL source code.
@@ -123,19 +168,12 @@ func (m *Minifier) Minify(source string) Result {
 		Stats: Stats{OriginalSize: len(source)},
 	}
 
-	// 1. Parse into AST
-	p := parser.New(source)
-	module, errs := p.Parse()
+	// 1. Preprocess and parse into AST
+	module, pre, errs := m.parse(source)
 
-	// 2. Report parse errors
+	// 2. Report preprocessor and parse errors
 	if len(errs) > 0 {
-		for _, err := range errs {
-			result.Errors = append(result.Errors, Error{
-				Message: err.Message,
-				Line:    err.Line,
-				Column:  err.Column,
-			})
-		}
+		result.Errors = errs
 		// Return original source on parse error
 		result.Code = source
 		result.Stats.MinifiedSize = len(source)
@@ -143,8 +181,9 @@ func (m *Minifier) Minify(source string) Result {
 	}
 
 	// 3. Minify the parsed module
-	moduleResult := m.MinifyModuleWithSource(module, source)
+	moduleResult, _ := m.minifyModuleWithRenamer(module, pre)
 	result.Code = moduleResult.Code
+	result.Errors = moduleResult.Errors
 	result.Stats = moduleResult.Stats
 	result.Stats.OriginalSize = len(source)
 	result.SourceMap = moduleResult.SourceMap
@@ -152,6 +191,30 @@ func (m *Minifier) Minify(source string) Result {
 	return result
 }
 
+// parse runs the preprocessor and the parser. Error positions refer to the
+// unpreprocessed source.
+func (m *Minifier) parse(source string) (*ast.Module, *preprocess.Result, []Error) {
+	pre := preprocess.Process(source, m.options.Defines)
+	if len(pre.Errors) > 0 {
+		var errs []Error
+		for _, e := range pre.Errors {
+			errs = append(errs, Error{Message: e.Message, Line: e.Line, Column: e.Column})
+		}
+		return nil, pre, errs
+	}
+
+	module, parseErrs := parser.New(pre.Code).Parse()
+	var errs []Error
+	for _, e := range parseErrs {
+		line, col := e.Line, e.Column
+		if pre.Code != pre.Source {
+			line, col = pre.Position(e.Pos)
+		}
+		errs = append(errs, Error{Message: e.Message, Line: line, Column: col})
+	}
+	return module, pre, errs
+}
+
 // MinifyModule minifies a pre-parsed AST module.
 // Note: Source map generation is not available without the original source.
 // Use MinifyModuleWithSource for source map support.
@@ -161,73 +224,7 @@ func (m *Minifier) MinifyModule(module *ast.Module) Result {
 
 // MinifyModuleWithSource minifies a pre-parsed AST module with source map support.
 func (m *Minifier) MinifyModuleWithSource(module *ast.Module, source string) Result {
-	result := Result{}
-
-	// Build reserved names set
-	reserved := renamer.ComputeReservedNames()
-
-	// Add user-specified keep names
-	for _, name := range m.options.KeepNames {
-		reserved[name] = true
-	}
-
-	// Mark API-facing symbols as non-renameable
-	m.markAPIFacingSymbols(module)
-
-	// Run dead code elimination if enabled
-	if m.options.TreeShaking {
-		result.Stats.SymbolsDead = dce.Mark(module)
-	} else {
-		// Mark all symbols as live if tree shaking is disabled
-		for i := range module.Symbols {
-			module.Symbols[i].Flags |= ast.IsLive
-		}
-	}
-
-	// Compute symbol usage before renaming
-	uses := m.computeSymbolUsage(module)
-
-	// Create renamer
-	var ren printer.Renamer
-	if m.options.MinifyIdentifiers {
-		minRenamer := renamer.NewMinifyRenamer(module.Symbols, reserved)
-		minRenamer.AccumulateSymbolUseCounts(uses)
-		minRenamer.AllocateSlots()
-		minRenamer.ReserveUnrenamedSymbolNames() // Prevent conflicts with unrenamed symbols
-		minRenamer.AssignNames()
-		ren = minRenamer
-	} else {
-		ren = renamer.NewNoOpRenamer(module.Symbols)
-	}
-
-	// Create source map generator if enabled
-	var sourceMapGen *sourcemap.Generator
-	if m.options.GenerateSourceMap {
-		sourceMapGen = sourcemap.NewGenerator(source)
-		sourceMapGen.SetFile(m.options.SourceMapOptions.File)
-		sourceMapGen.SetSourceName(m.options.SourceMapOptions.SourceName)
-		sourceMapGen.IncludeSourceContent(m.options.SourceMapOptions.IncludeSource)
-	}
-
-	// Print
-	p := printer.New(printer.Options{
-		MinifyWhitespace:  m.options.MinifyWhitespace,
-		MinifyIdentifiers: m.options.MinifyIdentifiers,
-		MinifySyntax:      m.options.MinifySyntax,
-		TreeShaking:       m.options.TreeShaking,
-		Renamer:           ren,
-		SourceMapGen:      sourceMapGen,
-	}, module.Symbols)
-
-	result.Code = p.Print(module)
-	result.Stats.MinifiedSize = len(result.Code)
-	result.Stats.SymbolsTotal = len(module.Symbols)
-
-	// Generate source map if enabled
-	if sourceMapGen != nil {
-		result.SourceMap = sourceMapGen.Generate()
-	}
-
+	result, _ := m.minifyModuleWithRenamer(module, preprocess.Identity(source))
 	return result
 }
 
@@ -475,19 +472,12 @@ func (m *Minifier) MinifyAndReflect(source string) MinifyAndReflectResult {
 		},
 	}
 
-	// 1. Parse into AST
-	p := parser.New(source)
-	module, errs := p.Parse()
+	// 1. Preprocess and parse into AST
+	module, pre, errs := m.parse(source)
 
-	// 2. Report parse errors
+	// 2. Report preprocessor and parse errors
 	if len(errs) > 0 {
-		for _, err := range errs {
-			result.Errors = append(result.Errors, Error{
-				Message: err.Message,
-				Line:    err.Line,
-				Column:  err.Column,
-			})
-		}
+		result.Errors = errs
 		// Return original source on parse error
 		result.Code = source
 		result.Stats.MinifiedSize = len(source)
@@ -501,31 +491,74 @@ func (m *Minifier) MinifyAndReflect(source string) MinifyAndReflectResult {
 	}
 
 	// 3. Minify and get renamer
-	minResult, ren := m.minifyModuleWithRenamer(module, source)
+	minResult, ren := m.minifyModuleWithRenamer(module, pre)
 	result.Result = minResult
 	result.Stats.OriginalSize = len(source) // Restore original size after assignment
 
 	// 4. Reflect with the renamer for mapped names
 	result.Reflect = reflect.ReflectModuleWithRenamer(module, ren)
+	if result.Overrides != nil {
+		result.Reflect.Overrides = result.Overrides
+	}
 
 	return result
 }
 
 // minifyModuleWithRenamer is like MinifyModuleWithSource but also returns the renamer.
-func (m *Minifier) minifyModuleWithRenamer(module *ast.Module, source string) (Result, printer.Renamer) {
+// The module was parsed from source.Code.
+func (m *Minifier) minifyModuleWithRenamer(module *ast.Module, source *preprocess.Result) (Result, printer.Renamer) {
 	result := Result{}
 
-	// Build reserved names set
-	reserved := renamer.ComputeReservedNames()
+	// Reserved names, including user-specified keep names
+	reserved := m.reservedNames()
 
-	// Add user-specified keep names
-	for _, name := range m.options.KeepNames {
-		reserved[name] = true
+	// Bake known override values into constants
+	if len(m.options.Overrides) > 0 {
+		var errs []string
+		result.Overrides, errs = specializeOverrides(module, m.options.Overrides)
+		if len(errs) > 0 {
+			for _, msg := range errs {
+				result.Errors = append(result.Errors, Error{Message: msg})
+			}
+			return m.abort(result, module, source)
+		}
+	}
+
+	// Run plugins on the parsed module, then on the validated one
+	if err := m.runPlugins(source, func(p *Plugin) error {
+		if p.PostParse == nil {
+			return nil
+		}
+		return p.PostParse(module)
+	}); err != nil {
+		result.Errors = append(result.Errors, *err)
+		return m.abort(result, module, source)
+	}
+	if m.hasPostValidate() {
+		validation := validator.Validate(module, validator.Options{})
+		if err := m.runPlugins(source, func(p *Plugin) error {
+			if p.PostValidate == nil {
+				return nil
+			}
+			return p.PostValidate(module, validation)
+		}); err != nil {
+			result.Errors = append(result.Errors, *err)
+			return m.abort(result, module, source)
+		}
 	}
 
 	// Mark API-facing symbols as non-renameable
 	m.markAPIFacingSymbols(module)
 
+	// Shorten locals, inline small helpers, then remove constant branches
+	// and unreachable statements, so that declarations only they used are
+	// shaken too
+	if m.options.MinifySyntax {
+		result.Stats.LetsInlined, result.Stats.VarsCoalesced = m.optimizeLocals(module)
+		result.Stats.CallsInlined = m.inlineFunctions(module)
+		result.Stats.StatementsDead = pruneStatements(module)
+	}
+
 	// Run dead code elimination if enabled
 	if m.options.TreeShaking {
 		result.Stats.SymbolsDead = dce.Mark(module)
@@ -536,6 +569,17 @@ func (m *Minifier) minifyModuleWithRenamer(module *ast.Module, source string) (R
 		}
 	}
 
+	// Run plugins on the shaken module
+	if err := m.runPlugins(source, func(p *Plugin) error {
+		if p.PreRename == nil {
+			return nil
+		}
+		return p.PreRename(module)
+	}); err != nil {
+		result.Errors = append(result.Errors, *err)
+		return m.abort(result, module, source)
+	}
+
 	// Compute symbol usage before renaming
 	uses := m.computeSymbolUsage(module)
 
@@ -555,10 +599,18 @@ func (m *Minifier) minifyModuleWithRenamer(module *ast.Module, source string) (R
 	// Create source map generator if enabled
 	var sourceMapGen *sourcemap.Generator
 	if m.options.GenerateSourceMap {
-		sourceMapGen = sourcemap.NewGenerator(source)
+		sourceMapGen = sourcemap.NewGenerator(source.Source)
+		sourceMapGen.SetOffsetMapper(source.OriginalOffset)
 		sourceMapGen.SetFile(m.options.SourceMapOptions.File)
 		sourceMapGen.SetSourceName(m.options.SourceMapOptions.SourceName)
 		sourceMapGen.IncludeSourceContent(m.options.SourceMapOptions.IncludeSource)
+		sourceMapGen.IncludeScopes(m.options.SourceMapOptions.Scopes)
+	}
+
+	// Resolve types so the printer can shorten constructors
+	var exprTypes map[ast.Expr]types.Type
+	if m.options.MinifySyntax {
+		exprTypes = resolveTypes(module)
 	}
 
 	// Print
@@ -569,9 +621,26 @@ func (m *Minifier) minifyModuleWithRenamer(module *ast.Module, source string) (R
 		TreeShaking:       m.options.TreeShaking,
 		Renamer:           ren,
 		SourceMapGen:      sourceMapGen,
+		Types:             exprTypes,
 	}, module.Symbols)
 
 	result.Code = p.Print(module)
+
+	// Run plugins on the printed code
+	if err := m.runPlugins(source, func(p *Plugin) error {
+		if p.PostPrint == nil {
+			return nil
+		}
+		code, err := p.PostPrint(result.Code)
+		if err == nil {
+			result.Code = code
+		}
+		return err
+	}); err != nil {
+		result.Errors = append(result.Errors, *err)
+		return m.abort(result, module, source)
+	}
+
 	result.Stats.MinifiedSize = len(result.Code)
 	result.Stats.SymbolsTotal = len(module.Symbols)
 
@@ -583,6 +652,30 @@ func (m *Minifier) minifyModuleWithRenamer(module *ast.Module, source string) (R
 	return result, ren
 }
 
+// resolveTypes returns the types of the expressions of a module, or nil if
+// the module doesn't validate. Types of invalid code can't be trusted to
+// decide what is semantically equal.
+func resolveTypes(module *ast.Module) (exprTypes map[ast.Expr]types.Type) {
+	defer func() {
+		if recover() != nil {
+			exprTypes = nil
+		}
+	}()
+	result := validator.Validate(module, validator.Options{})
+	if result.Diagnostics.HasErrors() || result.TypeInfo == nil {
+		return nil
+	}
+	return result.TypeInfo.Exprs
+}
+
+// abort ends minification after an error, returning the original source.
+func (m *Minifier) abort(result Result, module *ast.Module, source *preprocess.Result) (Result, printer.Renamer) {
+	result.Code = source.Source
+	result.Stats.MinifiedSize = len(source.Source)
+	result.SourceMap = nil
+	return result, renamer.NewNoOpRenamer(module.Symbols)
+}
+
 // ----------------------------------------------------------------------------
 // Convenience Functions
 // ----------------------------------------------------------------------------
@@ -0,0 +1,124 @@
+package minifier
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/lexer"
+	"github.com/HugoDaniel/miniray/internal/reflect"
+)
+
+// specializeOverrides replaces the override declarations named in values,
+// by name or by @id, with const declarations of the given value. Values
+// follow the WebGPU convention: numbers, with booleans as 0 and 1. It
+// returns the module's overrides as reflected before specialization.
+func specializeOverrides(module *ast.Module, values map[string]float64) ([]reflect.OverrideInfo, []string) {
+	var infos []reflect.OverrideInfo
+	var errs []string
+	used := make(map[string]bool)
+
+	for i, decl := range module.Declarations {
+		d, ok := decl.(*ast.OverrideDecl)
+		if !ok {
+			continue
+		}
+		info := reflect.ExtractOverride(d, module.Symbols)
+
+		key := info.Name
+		value, found := values[key]
+		if !found && info.ID != nil {
+			key = strconv.Itoa(*info.ID)
+			value, found = values[key]
+		}
+		if !found {
+			infos = append(infos, info)
+			continue
+		}
+		used[key] = true
+
+		if info.Type == "" {
+			errs = append(errs, fmt.Sprintf("cannot specialize override '%s': declare its type", info.Name))
+			infos = append(infos, info)
+			continue
+		}
+		init, err := overrideLiteral(info.Type, value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("cannot specialize override '%s': %v", info.Name, err))
+			infos = append(infos, info)
+			continue
+		}
+
+		module.Declarations[i] = &ast.ConstDecl{
+			Loc:         d.Loc,
+			Name:        d.Name,
+			Type:        &ast.IdentType{Loc: d.Loc, Name: info.Type, Ref: ast.InvalidRef()},
+			Initializer: init,
+		}
+		if d.Name.IsValid() && int(d.Name.InnerIndex) < len(module.Symbols) {
+			module.Symbols[d.Name.InnerIndex].Kind = ast.SymbolConst
+		}
+
+		info.Specialized = true
+		info.Value = &value
+		infos = append(infos, info)
+	}
+
+	var unknown []string
+	for key := range values {
+		if !used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Sprintf("unknown override '%s'", key))
+	}
+	return infos, errs
+}
+
+// constantFlags marks the expressions built for specialized values.
+const constantFlags = ast.ExprFlagCanBeRemovedIfUnused | ast.ExprFlagIsConstant
+
+// overrideLiteral builds the initializer for a specialized override of
+// the given scalar type.
+func overrideLiteral(typ string, value float64) (ast.Expr, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("value %v is not finite", value)
+	}
+
+	var text string
+	kind := lexer.TokIntLiteral
+	switch typ {
+	case "bool":
+		if value != 0 {
+			return &ast.LiteralExpr{Kind: lexer.TokTrue, Value: "true", Flags: constantFlags}, nil
+		}
+		return &ast.LiteralExpr{Kind: lexer.TokFalse, Value: "false", Flags: constantFlags}, nil
+	case "i32", "u32":
+		lo, hi := float64(math.MinInt32), float64(math.MaxInt32)
+		if typ == "u32" {
+			lo, hi = 0, float64(math.MaxUint32)
+		}
+		if value != math.Trunc(value) || value < lo || value > hi {
+			return nil, fmt.Errorf("value %v is not a valid %s", value, typ)
+		}
+		text = strconv.FormatInt(int64(math.Abs(value)), 10)
+	case "f32", "f16":
+		kind = lexer.TokFloatLiteral
+		text = strconv.FormatFloat(math.Abs(value), 'g', -1, 64)
+		if _, err := strconv.Atoi(text); err == nil {
+			text += ".0"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+
+	var expr ast.Expr = &ast.LiteralExpr{Kind: kind, Value: text, Flags: constantFlags}
+	if value < 0 {
+		expr = &ast.UnaryExpr{Op: ast.UnaryOpNeg, Operand: expr, Flags: constantFlags}
+	}
+	return expr, nil
+}
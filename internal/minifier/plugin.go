@@ -0,0 +1,86 @@
+package minifier
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/preprocess"
+	"github.com/HugoDaniel/miniray/internal/validator"
+)
+
+// Plugin is a custom pass that hooks into the minification pipeline.
+// Every hook is optional. Plugins run in the order of Options.Plugins at
+// each hook point:
+//
+//   - PostParse: before API-facing symbols are marked and dead code is
+//     eliminated. Rewrites here affect reachability.
+//   - PostValidate: after PostParse, with the validator's result. The
+//     validator only runs when a plugin has this hook; validation errors
+//     do not stop minification.
+//   - PreRename: after dead code elimination, before symbol usage is
+//     counted and identifiers are renamed.
+//   - PostPrint: on the printed code. The returned string replaces it;
+//     the source map is not adjusted, so edits should keep positions.
+//
+// A hook that returns an error stops minification; the error is reported
+// in Result.Errors prefixed with the plugin name, if it has one, and the
+// original source is returned as the code. Use ErrorAt to attach a source
+// location.
+type Plugin struct {
+	Name string
+
+	PostParse    func(module *ast.Module) error
+	PostValidate func(module *ast.Module, result *validator.Result) error
+	PreRename    func(module *ast.Module) error
+	PostPrint    func(code string) (string, error)
+}
+
+// LocatedError is a plugin error at a byte range of the source.
+type LocatedError struct {
+	Loc     ast.Loc
+	Message string
+}
+
+func (e *LocatedError) Error() string {
+	return e.Message
+}
+
+// ErrorAt returns an error located at loc for a plugin hook to return.
+func ErrorAt(loc ast.Loc, format string, args ...interface{}) error {
+	return &LocatedError{Loc: loc, Message: fmt.Sprintf(format, args...)}
+}
+
+// runPlugins calls hook for every plugin and converts the first error into
+// a minification error.
+func (m *Minifier) runPlugins(source *preprocess.Result, hook func(p *Plugin) error) *Error {
+	for i := range m.options.Plugins {
+		p := &m.options.Plugins[i]
+		if err := hook(p); err != nil {
+			return pluginError(p.Name, err, source)
+		}
+	}
+	return nil
+}
+
+func pluginError(name string, err error, source *preprocess.Result) *Error {
+	result := &Error{Message: err.Error()}
+	if name != "" {
+		result.Message = fmt.Sprintf("plugin %q: %s", name, err.Error())
+	}
+	var located *LocatedError
+	if errors.As(err, &located) && source.Source != "" {
+		result.Line, result.Column = source.Position(int(located.Loc.Start))
+	}
+	return result
+}
+
+// hasPostValidate reports whether any plugin needs validation results.
+func (m *Minifier) hasPostValidate() bool {
+	for _, p := range m.options.Plugins {
+		if p.PostValidate != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,259 @@
+package minifier
+
+import (
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/parser"
+)
+
+// pruneStatements removes statements that can never run or have no effect:
+//   - if/else branches whose condition is a known constant
+//   - while and for loops whose condition is constantly false
+//   - switch statements on a constant selector, reduced to the chosen case
+//   - statements after return, break and continue, and after discard in
+//     functions that return nothing
+//   - empty if statements with a side-effect free condition
+//
+// Constants are evaluated with the parser's constant folder, so overrides
+// that were specialized into constants take part. Declaration DCE should
+// run afterwards: declarations only referenced from removed code become
+// dead. Returns the number of statements removed.
+func pruneStatements(module *ast.Module) int {
+	if module == nil {
+		return 0
+	}
+
+	p := &pruner{
+		consts: parser.ConstValues(module),
+		purity: ast.NewPurityContext(module.Symbols),
+	}
+	for _, decl := range module.Declarations {
+		fn, ok := decl.(*ast.FunctionDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		p.returnsValue = fn.ReturnType != nil
+		fn.Body.Stmts = p.block(fn.Body.Stmts, true)
+	}
+	return p.removed
+}
+
+type pruner struct {
+	consts       map[ast.Ref]parser.ConstValue
+	purity       *ast.PurityContext
+	returnsValue bool // The current function has a return type
+	removed      int
+}
+
+// block prunes a statement list. Statements after one that always leaves
+// the block are dropped when truncate is set.
+func (p *pruner) block(stmts []ast.Stmt, truncate bool) []ast.Stmt {
+	out := make([]ast.Stmt, 0, len(stmts))
+	for i, s := range stmts {
+		reduced := p.stmt(s)
+		out = append(out, reduced...)
+		if truncate && len(reduced) > 0 && p.terminates(reduced[len(reduced)-1]) {
+			p.removed += len(stmts) - i - 1
+			break
+		}
+	}
+	return out
+}
+
+// stmt prunes a single statement and returns what replaces it: nothing,
+// the statement itself, or the statements of a branch that always runs.
+func (p *pruner) stmt(s ast.Stmt) []ast.Stmt {
+	switch s := s.(type) {
+	case *ast.CompoundStmt:
+		s.Stmts = p.block(s.Stmts, true)
+
+	case *ast.IfStmt:
+		if cond := p.eval(s.Condition); cond.Kind == parser.ConstBool {
+			p.removed++
+			if cond.Bool {
+				return p.inline(s.Body)
+			}
+			switch e := s.Else.(type) {
+			case *ast.CompoundStmt:
+				return p.inline(e)
+			case *ast.IfStmt:
+				return p.stmt(e)
+			}
+			return nil
+		}
+
+		p.prune(s.Body)
+		if s.Else != nil {
+			s.Else = p.elseStmt(s.Else)
+		}
+		if len(s.Body.Stmts) == 0 && s.Else == nil && p.purity.ExprCanBeRemovedIfUnused(s.Condition) {
+			p.removed++
+			return nil
+		}
+
+	case *ast.WhileStmt:
+		if p.isFalse(s.Condition) {
+			p.removed++
+			return nil
+		}
+		p.prune(s.Body)
+
+	case *ast.ForStmt:
+		if p.isFalse(s.Condition) && p.purity.StmtCanBeRemovedIfUnused(s.Init) {
+			p.removed++
+			return nil
+		}
+		p.prune(s.Body)
+
+	case *ast.LoopStmt:
+		// The continuing block may use declarations from the end of the
+		// body, so the body is never truncated
+		if s.Body != nil {
+			s.Body.Stmts = p.block(s.Body.Stmts, s.Continuing == nil)
+		}
+		p.prune(s.Continuing)
+
+	case *ast.SwitchStmt:
+		if c := p.selectCase(s); c != nil {
+			p.removed++
+			return p.inline(c.Body)
+		}
+		for i := range s.Cases {
+			p.prune(s.Cases[i].Body)
+		}
+	}
+
+	return []ast.Stmt{s}
+}
+
+func (p *pruner) prune(b *ast.CompoundStmt) {
+	if b != nil {
+		b.Stmts = p.block(b.Stmts, true)
+	}
+}
+
+// elseStmt prunes the else branch of an if statement. The result is nil,
+// an *ast.IfStmt or an *ast.CompoundStmt.
+func (p *pruner) elseStmt(s ast.Stmt) ast.Stmt {
+	loc := ast.Loc{}
+	switch e := s.(type) {
+	case *ast.CompoundStmt:
+		loc = e.Loc
+	case *ast.IfStmt:
+		loc = e.Loc
+	}
+
+	reduced := p.stmt(s)
+	switch {
+	case len(reduced) == 0:
+		return nil
+	case len(reduced) == 1:
+		switch r := reduced[0].(type) {
+		case *ast.IfStmt:
+			return r
+		case *ast.CompoundStmt:
+			if len(r.Stmts) == 0 {
+				return nil
+			}
+			return r
+		}
+	}
+	return &ast.CompoundStmt{Loc: loc, Stmts: reduced}
+}
+
+// inline returns the statements of a block that replaces its enclosing
+// statement. A block that declares names stays a block, so that its
+// declarations keep their scope.
+func (p *pruner) inline(b *ast.CompoundStmt) []ast.Stmt {
+	if b == nil {
+		return nil
+	}
+	b.Stmts = p.block(b.Stmts, true)
+	for _, s := range b.Stmts {
+		if _, ok := s.(*ast.DeclStmt); ok {
+			return []ast.Stmt{b}
+		}
+	}
+	return b.Stmts
+}
+
+// selectCase returns the case a switch on a constant selector always
+// runs, or nil if it is not known or the case body breaks out of the
+// switch.
+func (p *pruner) selectCase(s *ast.SwitchStmt) *ast.SwitchCase {
+	value := p.eval(s.Expr)
+	if value.Kind != parser.ConstInt {
+		return nil
+	}
+
+	var chosen, fallback *ast.SwitchCase
+	unknown := false
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		if c.Selectors == nil {
+			fallback = c
+			continue
+		}
+		for _, sel := range c.Selectors {
+			v := p.eval(sel)
+			if v.Kind != parser.ConstInt {
+				unknown = true
+			} else if v.Int == value.Int {
+				chosen = c
+			}
+		}
+	}
+	if chosen == nil {
+		// Any selector that can't be evaluated might match
+		if unknown {
+			return nil
+		}
+		chosen = fallback
+	}
+	if chosen == nil || breaksOut(chosen.Body) {
+		return nil
+	}
+	return chosen
+}
+
+// terminates reports whether control never continues past s.
+func (p *pruner) terminates(s ast.Stmt) bool {
+	switch s := s.(type) {
+	case *ast.ReturnStmt, *ast.BreakStmt, *ast.ContinueStmt:
+		return true
+	case *ast.DiscardStmt:
+		// discard demotes the invocation to a helper, which continues
+		// with no observable effects. A function that returns a value
+		// still needs its return statement afterwards.
+		return !p.returnsValue
+	case *ast.CompoundStmt:
+		return len(s.Stmts) > 0 && p.terminates(s.Stmts[len(s.Stmts)-1])
+	case *ast.IfStmt:
+		return s.Else != nil && p.terminates(s.Body) && p.terminates(s.Else)
+	}
+	return false
+}
+
+func (p *pruner) eval(e ast.Expr) parser.ConstValue {
+	return parser.EvaluateConstExpr(e, p.consts)
+}
+
+func (p *pruner) isFalse(e ast.Expr) bool {
+	v := p.eval(e)
+	return v.Kind == parser.ConstBool && !v.Bool
+}
+
+// breaksOut reports whether b contains a break that leaves the enclosing
+// switch, as opposed to one inside a nested loop or switch.
+func breaksOut(b *ast.CompoundStmt) bool {
+	found := false
+	ast.Inspect(b, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.BreakStmt:
+			found = true
+		case *ast.ForStmt, *ast.WhileStmt, *ast.LoopStmt, *ast.SwitchStmt:
+			return false
+		}
+		return !found
+	})
+	return found
+}
@@ -125,7 +125,13 @@ fn unused() -> f32 { return 0.0; }
     return vec4f(c());
 }
 `
-	result := minifyWithDCE(source)
+	// Without MinifySyntax the helpers are not inlined, so tree shaking
+	// sees the whole chain
+	opts := minifier.DefaultOptions()
+	opts.MinifyIdentifiers = false
+	opts.MinifySyntax = false
+	opts.TreeShaking = true
+	result := minifier.New(opts).Minify(source).Code
 
 	// a, b, c, main should be kept, unused should be removed
 	fnCount := strings.Count(result, "fn ")
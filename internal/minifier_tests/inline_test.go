@@ -0,0 +1,161 @@
+package minifier_tests
+
+import (
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+)
+
+func TestInlineFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "single use helper",
+			input: `fn scale(x: f32) -> f32 { return x * 2.0; }
+@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    return vec4f(scale(v));
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{return vec4(v*2.0);}",
+		},
+		{
+			name: "operands are parenthesized",
+			input: `fn sq(x: f32) -> f32 { return x * x; }
+fn add(a: f32, b: f32) -> f32 { return a + b; }
+@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    return vec4f(sq(v) * add(v, 1.0), sq(v), 0.0, 1.0);
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{return vec4(v*v*(v+1.0),v*v,0,1);}",
+		},
+		{
+			name: "nested helpers",
+			input: `fn sq(x: f32) -> f32 { return x * x; }
+fn len2(p: vec2f) -> f32 { return sq(p.x) + sq(p.y); }
+@fragment fn main(@location(0) p: vec2f) -> @location(0) vec4f {
+    return vec4f(len2(p));
+}`,
+			expected: "@fragment fn main(@location(0) p:vec2f)->@location(0) vec4f{return vec4(p.x*p.x+p.y*p.y);}",
+		},
+		{
+			name: "captured local is renamed",
+			input: `const k: f32 = 2.0;
+fn scaled(x: f32) -> f32 { return x * k; }
+@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    let k = v + 1.0;
+    return vec4f(scaled(k), k, 0.0, 1.0);
+}`,
+			expected: "const k:f32=2.0;@fragment fn main(@location(0) v:f32)->@location(0) vec4f{let k_1=v+1.0;return vec4(k_1*k,k_1,0,1);}",
+		},
+		{
+			name: "argument used twice must be simple",
+			input: `fn sq(x: f32) -> f32 { return x * x; }
+@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    return vec4f(sq(v + 1.0), sq(v - 1.0), sq(v * 2.0), 1.0);
+}`,
+			expected: "fn sq(x:f32)->f32{return x*x;}@fragment fn main(@location(0) v:f32)->@location(0) vec4f{return vec4f(sq(v+1.0),sq(v-1.0),sq(v*2.0),1);}",
+		},
+		{
+			name: "abstract argument that isn't converted keeps the call",
+			input: `fn half(x: f32) -> f32 { return x / 2.0; }
+@fragment fn main() -> @location(0) vec4f {
+    let h = half(3);
+    return vec4f(h);
+}`,
+			expected: "fn half(x:f32)->f32{return x/2.0;}@fragment fn main()->@location(0) vec4f{let h=half(3);return vec4f(h);}",
+		},
+		{
+			name: "abstract argument next to a concrete operand",
+			input: `fn offset(x: f32, d: f32) -> f32 { return x + d; }
+@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    return vec4f(offset(v, 1));
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{return vec4(v+1);}",
+		},
+		{
+			name: "functions with side effects are kept",
+			input: `var<private> counter: f32;
+fn bump() -> f32 { counter += 1.0; return counter; }
+fn next() -> f32 { return bump(); }
+@fragment fn main() -> @location(0) vec4f {
+    return vec4f(next());
+}`,
+			expected: "var<private> counter:f32;fn bump()->f32{counter+=1.0;return counter;}fn next()->f32{return bump();}@fragment fn main()->@location(0) vec4f{return vec4f(next());}",
+		},
+		{
+			name: "argument with side effects is not moved past a read",
+			input: `var<private> counter: i32;
+fn bump() -> i32 { counter += 1; return counter; }
+fn add2(a: i32, b: i32) -> i32 { return b + a; }
+@compute @workgroup_size(1) fn main() {
+    let r = add2(bump(), counter);
+    counter = r;
+}`,
+			expected: "var<private> counter:i32;fn bump()->i32{counter++;return counter;}fn add2(a:i32,b:i32)->i32{return b+a;}@compute @workgroup_size(1) fn main(){let r=add2(bump(),counter);counter=r;}",
+		},
+		{
+			name: "argument with side effects used first is inlined",
+			input: `var<private> counter: i32;
+fn bump() -> i32 { counter += 1; return counter; }
+fn add2(a: i32, b: i32) -> i32 { return a + b; }
+@compute @workgroup_size(1) fn main() {
+    counter = add2(bump(), 2i);
+}`,
+			expected: "var<private> counter:i32;fn bump()->i32{counter++;return counter;}@compute @workgroup_size(1) fn main(){counter=bump()+2i;}",
+		},
+		{
+			name: "argument with side effects is not made conditional",
+			input: `var<private> counter: i32;
+fn bump() -> i32 { counter += 1; return counter; }
+fn both(c: bool, a: i32) -> bool { return c && a > 0; }
+@compute @workgroup_size(1) fn main() {
+    if (both(false, bump())) { counter = 0; }
+}`,
+			expected: "var<private> counter:i32;fn bump()->i32{counter++;return counter;}fn both(c:bool,a:i32)->bool{return c&&a>0;}@compute @workgroup_size(1) fn main(){if (both(false,bump())){counter=0;}}",
+		},
+		{
+			name: "large helper used many times is kept",
+			input: `fn mixColor(a: vec3f, b: vec3f) -> vec3f { return mix(a, b, vec3f(0.25, 0.5, 0.75)) * dot(a, b); }
+@fragment fn main(@location(0) a: vec3f, @location(1) b: vec3f) -> @location(0) vec4f {
+    return vec4f(mixColor(a, b) + mixColor(b, a) + mixColor(a, a) + mixColor(b, b), 1.0);
+}`,
+			expected: "fn mixColor(a:vec3f,b:vec3f)->vec3f{return mix(a,b,vec3f(0.25,0.5,0.75))*dot(a,b);}@fragment fn main(@location(0) a:vec3f,@location(1) b:vec3f)->@location(0) vec4f{return vec4f(mixColor(a,b)+mixColor(b,a)+mixColor(a,a)+mixColor(b,b),1);}",
+		},
+	}
+
+	opts := minifier.Options{
+		MinifyWhitespace: true,
+		MinifySyntax:     true,
+		TreeShaking:      true,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := minifier.New(opts).Minify(tt.input)
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if result.Code != tt.expected {
+				t.Errorf("\nexpected: %s\nactual:   %s", tt.expected, result.Code)
+			}
+		})
+	}
+}
+
+func TestInlineFunctionsWithRenaming(t *testing.T) {
+	input := `fn sq(x: f32) -> f32 { return x * x; }
+@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    return vec4f(sq(v));
+}`
+	result := minifier.New(minifier.DefaultOptions()).Minify(input)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	expected := "@fragment fn main(@location(0) a:f32)->@location(0) vec4f{return vec4(a*a);}"
+	if result.Code != expected {
+		t.Errorf("\nexpected: %s\nactual:   %s", expected, result.Code)
+	}
+	if result.Stats.CallsInlined != 1 {
+		t.Errorf("expected 1 inlined call, got %d", result.Stats.CallsInlined)
+	}
+}
@@ -0,0 +1,207 @@
+package minifier_tests
+
+import (
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+)
+
+func TestOptimizeLocals(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "let used once by the next statement",
+			input: `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    let s = v * 2.0;
+    return vec4f(s);
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{return vec4(v*2.0);}",
+		},
+		{
+			name: "chained lets",
+			input: `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    let a = v + 1.0;
+    let b = v - 1.0;
+    let c = a * b;
+    return vec4f(c);
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{return vec4((v+1.0)*(v-1.0));}",
+		},
+		{
+			name: "let used twice is kept",
+			input: `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    let s = v * 2.0;
+    return vec4f(s, s, 0.0, 1.0);
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{let s=v*2.0;return vec4(s,s,0,1);}",
+		},
+		{
+			name: "let used later is kept",
+			input: `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    var x = v;
+    let s = x * 2.0;
+    x = 1.0;
+    return vec4f(s, x, 0.0, 1.0);
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{var x=v;let s=x*2.0;x=1.0;return vec4(s,x,0,1);}",
+		},
+		{
+			name: "abstract let is kept",
+			input: `@fragment fn main() -> @location(0) vec4f {
+    let s = 2.0;
+    return vec4f(s);
+}`,
+			expected: "@fragment fn main()->@location(0) vec4f{let s=2.0;return vec4(s);}",
+		},
+		{
+			name: "impure call before the use",
+			input: `var<private> n: f32;
+fn bump() -> f32 { n = n + 1.0; return n; }
+@fragment fn main() -> @location(0) vec4f {
+    let s = n * 2.0;
+    return vec4f(bump(), s, 0.0, 1.0);
+}`,
+			expected: "var<private> n:f32;fn bump()->f32{n+=1.0;return n;}@fragment fn main()->@location(0) vec4f{let s=n*2.0;return vec4f(bump(),s,0,1);}",
+		},
+		{
+			name: "impure initializer is kept",
+			input: `var<private> n: f32;
+fn bump() -> f32 { n = n + 1.0; return n; }
+@fragment fn main() -> @location(0) vec4f {
+    let s = bump();
+    return vec4f(n, s, 0.0, 1.0);
+}`,
+			expected: "var<private> n:f32;fn bump()->f32{n+=1.0;return n;}@fragment fn main()->@location(0) vec4f{let s=bump();return vec4f(n,s,0,1);}",
+		},
+		{
+			name: "compound assignment",
+			input: `@fragment fn main(@location(0) v: vec4f) -> @location(0) vec4f {
+    var c = v;
+    c = c * 0.5;
+    c.x = c.x - 1.0;
+    c = v + c;
+    return c;
+}`,
+			expected: "@fragment fn main(@location(0) v:vec4f)->@location(0) vec4f{var c=v;c*=0.5;c.x-=1.0;c+=v;return c;}",
+		},
+		{
+			name: "non-commutative operand order is kept",
+			input: `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    var c = v;
+    c = 1.0 - c;
+    return vec4f(c);
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{var c=v;c=1.0-c;return vec4(c);}",
+		},
+		{
+			name: "integer increment",
+			input: `@compute @workgroup_size(1) fn main() {
+    var n: u32 = 0u;
+    for (var i = 0; i < 4; i = i + 1) {
+        n += 1u;
+    }
+    var f = 0.0;
+    f += 1.0;
+}`,
+			expected: "@compute @workgroup_size(1) fn main(){var n:u32=0u;for(var i=0;i<4;i++){n++;}var f=0.0;f+=1.0;}",
+		},
+		{
+			name: "dead var is reused",
+			input: `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    var a: f32 = v;
+    a = a * a;
+    var b: f32 = a + 1.0;
+    b = b * 2.0;
+    return vec4f(b);
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{var a:f32=v;a*=a;a+=1.0;a*=2.0;return vec4(a);}",
+		},
+		{
+			name: "var initialized from the reused var is dropped",
+			input: `@group(0) @binding(0) var<storage, read_write> buf: array<u32, 4>;
+@compute @workgroup_size(1) fn main() {
+    var x: u32 = 1u;
+    x = x + buf[2];
+    var y: u32 = x;
+    buf[3] = y;
+}`,
+			expected: "@group(0) @binding(0) var<storage,read_write> buf:array<u32,4>;@compute @workgroup_size(1) fn main(){var x:u32=1u;x+=buf[2];buf[3]=x;}",
+		},
+		{
+			name: "var of another type is not reused",
+			input: `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    var a: f32 = v;
+    a = a * a;
+    var b: i32 = i32(a);
+    b = b * 2;
+    return vec4f(f32(b));
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{var a:f32=v;a*=a;var b:i32=i32(a);b*=2;return vec4f(f32(b));}",
+		},
+		{
+			name: "var with its address taken is not reused",
+			input: `fn store(p: ptr<function, f32>) { *p = 1.0; }
+@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    var a: f32 = v;
+    store(&a);
+    var b: f32 = a;
+    b = b * 2.0;
+    return vec4f(b);
+}`,
+			expected: "fn store(p:ptr<function,f32>){*p=1.0;}@fragment fn main(@location(0) v:f32)->@location(0) vec4f{var a:f32=v;store(&a);var b:f32=a;b*=2.0;return vec4f(b);}",
+		},
+	}
+
+	opts := minifier.Options{
+		MinifyWhitespace: true,
+		MinifySyntax:     true,
+		TreeShaking:      true,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := minifier.New(opts).Minify(tt.input)
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if result.Code != tt.expected {
+				t.Errorf("\nexpected: %s\nactual:   %s", tt.expected, result.Code)
+			}
+		})
+	}
+}
+
+func TestOptimizeLocalsStats(t *testing.T) {
+	input := `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    var a: f32 = v;
+    let s = a * 2.0;
+    var b: f32 = s;
+    return vec4f(b);
+}`
+	result := minifier.New(minifier.DefaultOptions()).Minify(input)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	expected := "@fragment fn main(@location(0) b:f32)->@location(0) vec4f{var a:f32=b;a*=2.0;return vec4(a);}"
+	if result.Code != expected {
+		t.Errorf("\nexpected: %s\nactual:   %s", expected, result.Code)
+	}
+	if result.Stats.LetsInlined != 1 || result.Stats.VarsCoalesced != 1 {
+		t.Errorf("expected 1 let inlined and 1 var coalesced, got %d and %d",
+			result.Stats.LetsInlined, result.Stats.VarsCoalesced)
+	}
+}
+
+func TestOptimizeLocalsNeedsMinifySyntax(t *testing.T) {
+	input := `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    let s = v * 2.0;
+    return vec4f(s);
+}`
+	result := minifier.New(minifier.Options{MinifyWhitespace: true}).Minify(input)
+	expected := "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{let s=v*2.0;return vec4f(s);}"
+	if result.Code != expected {
+		t.Errorf("\nexpected: %s\nactual:   %s", expected, result.Code)
+	}
+}
@@ -0,0 +1,130 @@
+package minifier_tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+)
+
+const overrideShader = `@id(0) override useFog: bool = false;
+override blockSize: u32 = 64u;
+override scale = 1.5;
+@group(0) @binding(0) var<storage, read_write> data: array<f32>;
+
+@compute @workgroup_size(blockSize)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    if (useFog) {
+        data[id.x] = data[id.x] * scale;
+    }
+}
+`
+
+func TestOverrideSpecialization(t *testing.T) {
+	opts := minifier.DefaultOptions()
+	opts.Overrides = map[string]float64{"0": 1, "blockSize": 128}
+	result := minifier.New(opts).MinifyAndReflect(overrideShader)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	// The branch on the specialized useFog always runs
+	for _, s := range []string{"u32=128", "override", "*scale"} {
+		if !strings.Contains(result.Code, s) {
+			t.Errorf("expected %q in output:\n%s", s, result.Code)
+		}
+	}
+	for _, s := range []string{"useFog", "blockSize", "if"} {
+		if strings.Contains(result.Code, s) {
+			t.Errorf("unexpected %q in output:\n%s", s, result.Code)
+		}
+	}
+
+	overrides := result.Reflect.Overrides
+	if len(overrides) != 3 {
+		t.Fatalf("expected 3 overrides, got %d", len(overrides))
+	}
+	fog := overrides[0]
+	if fog.Name != "useFog" || fog.ID == nil || *fog.ID != 0 || fog.Type != "bool" {
+		t.Errorf("unexpected useFog info: %+v", fog)
+	}
+	if !fog.Specialized || fog.Value == nil || *fog.Value != 1 {
+		t.Errorf("useFog should be specialized to 1: %+v", fog)
+	}
+	if overrides[2].Name != "scale" || overrides[2].Type != "f32" || overrides[2].Specialized {
+		t.Errorf("unexpected scale info: %+v", overrides[2])
+	}
+
+	ep := result.Reflect.EntryPoints[0]
+	if ep.WorkgroupSize[0] != 128 {
+		t.Errorf("workgroup size should resolve the constant, got %v", ep.WorkgroupSize)
+	}
+}
+
+func TestOverrideSpecializationValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]float64
+		want      string
+		absent    []string
+		dead      bool
+	}{
+		{"negative float", map[string]float64{"scale": -2}, "-2.0", nil, false},
+		{"fractional float", map[string]float64{"scale": 0.25}, "0.25", nil, false},
+		// The branch, and the binding and override only it used, are removed
+		{"false bool", map[string]float64{"useFog": 0}, "vec3u){}",
+			[]string{"var<storage", "data", "useFog", "scale"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := minifier.DefaultOptions()
+			opts.Overrides = tt.overrides
+			result := minifier.New(opts).Minify(overrideShader)
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if !strings.Contains(result.Code, tt.want) {
+				t.Errorf("expected %q in output:\n%s", tt.want, result.Code)
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(result.Code, s) {
+					t.Errorf("unexpected %q in output:\n%s", s, result.Code)
+				}
+			}
+			if tt.dead && result.Stats.StatementsDead == 0 {
+				t.Errorf("expected dead statements to be counted, got %+v", result.Stats)
+			}
+		})
+	}
+}
+
+func TestOverrideSpecializationErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string]float64
+		want      string
+	}{
+		{"unknown name", map[string]float64{"missing": 1}, "unknown override 'missing'"},
+		{"unknown id", map[string]float64{"7": 1}, "unknown override '7'"},
+		{"fractional integer", map[string]float64{"blockSize": 1.5}, "not a valid u32"},
+		{"negative unsigned", map[string]float64{"blockSize": -1}, "not a valid u32"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := minifier.DefaultOptions()
+			opts.Overrides = tt.overrides
+			result := minifier.New(opts).Minify(overrideShader)
+			if len(result.Errors) != 1 {
+				t.Fatalf("expected one error, got %v", result.Errors)
+			}
+			if !strings.Contains(result.Errors[0].Message, tt.want) {
+				t.Errorf("expected error containing %q, got %q", tt.want, result.Errors[0].Message)
+			}
+			if result.Code != overrideShader {
+				t.Errorf("failed specialization should return the source unchanged")
+			}
+		})
+	}
+}
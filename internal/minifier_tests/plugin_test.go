@@ -0,0 +1,179 @@
+package minifier_tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/minifier"
+	"github.com/HugoDaniel/miniray/internal/validator"
+)
+
+const pluginShader = `fn debugOnly() -> f32 {
+    return 1.0;
+}
+
+@fragment
+fn main() -> @location(0) vec4f {
+    let v = 0.5;
+    return vec4f(v);
+}
+`
+
+func TestPluginHookOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) minifier.Plugin {
+		return minifier.Plugin{
+			Name: name,
+			PostParse: func(*ast.Module) error {
+				calls = append(calls, name+":post-parse")
+				return nil
+			},
+			PostValidate: func(_ *ast.Module, result *validator.Result) error {
+				if result == nil || result.TypeInfo == nil {
+					t.Error("expected validation result")
+				}
+				calls = append(calls, name+":post-validate")
+				return nil
+			},
+			PreRename: func(*ast.Module) error {
+				calls = append(calls, name+":pre-rename")
+				return nil
+			},
+			PostPrint: func(code string) (string, error) {
+				calls = append(calls, name+":post-print")
+				return code, nil
+			},
+		}
+	}
+
+	opts := minifier.DefaultOptions()
+	opts.Plugins = []minifier.Plugin{record("a"), record("b")}
+	result := minifier.New(opts).Minify(pluginShader)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	expected := "a:post-parse b:post-parse a:post-validate b:post-validate " +
+		"a:pre-rename b:pre-rename a:post-print b:post-print"
+	if got := strings.Join(calls, " "); got != expected {
+		t.Errorf("hook order:\nexpected %s\ngot      %s", expected, got)
+	}
+}
+
+func TestPluginStripsFunctionBeforeDCE(t *testing.T) {
+	// Removing the only call keeps a debug helper from being reachable
+	stripDebug := minifier.Plugin{
+		Name: "strip-debug",
+		PostParse: func(module *ast.Module) error {
+			var decls []ast.Decl
+			for _, decl := range module.Declarations {
+				if fn, ok := decl.(*ast.FunctionDecl); ok && module.Symbols[fn.Name.InnerIndex].OriginalName == "debugOnly" {
+					continue
+				}
+				decls = append(decls, decl)
+			}
+			module.Declarations = decls
+			return nil
+		},
+	}
+
+	opts := minifier.DefaultOptions()
+	opts.TreeShaking = false
+	opts.Plugins = []minifier.Plugin{stripDebug}
+	result := minifier.New(opts).Minify(pluginShader)
+	if strings.Contains(result.Code, "1.0") || strings.Contains(result.Code, "fn a()") {
+		t.Errorf("expected debug function to be stripped, got %s", result.Code)
+	}
+}
+
+func TestPluginPostPrint(t *testing.T) {
+	banner := minifier.Plugin{
+		Name: "banner",
+		PostPrint: func(code string) (string, error) {
+			return "// generated\n" + code, nil
+		},
+	}
+
+	opts := minifier.DefaultOptions()
+	opts.Plugins = []minifier.Plugin{banner}
+	result := minifier.New(opts).Minify(pluginShader)
+	if !strings.HasPrefix(result.Code, "// generated\n") {
+		t.Errorf("expected banner, got %s", result.Code)
+	}
+	if result.Stats.MinifiedSize != len(result.Code) {
+		t.Errorf("expected size of the final code, got %d", result.Stats.MinifiedSize)
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugin  minifier.Plugin
+		message string
+		line    int
+		column  int
+	}{
+		{
+			name: "plain error",
+			plugin: minifier.Plugin{
+				Name:      "fail",
+				PreRename: func(*ast.Module) error { return errors.New("boom") },
+			},
+			message: `plugin "fail": boom`,
+		},
+		{
+			name: "located error",
+			plugin: minifier.Plugin{
+				Name: "no-debug",
+				PostParse: func(module *ast.Module) error {
+					fn := module.Declarations[0].(*ast.FunctionDecl)
+					return minifier.ErrorAt(fn.Loc, "debug function %s not allowed", module.Symbols[fn.Name.InnerIndex].OriginalName)
+				},
+			},
+			message: `plugin "no-debug": debug function debugOnly not allowed`,
+			line:    1,
+			column:  1,
+		},
+		{
+			name: "located error from validation",
+			plugin: minifier.Plugin{
+				Name: "check",
+				PostValidate: func(module *ast.Module, _ *validator.Result) error {
+					fn := module.Declarations[1].(*ast.FunctionDecl)
+					return minifier.ErrorAt(fn.Body.Stmts[0].(*ast.DeclStmt).Loc, "bad let")
+				},
+			},
+			message: `plugin "check": bad let`,
+			line:    7,
+			column:  5,
+		},
+		{
+			name: "post-print error",
+			plugin: minifier.Plugin{
+				Name:      "size",
+				PostPrint: func(string) (string, error) { return "", errors.New("too large") },
+			},
+			message: `plugin "size": too large`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := minifier.DefaultOptions()
+			opts.Plugins = []minifier.Plugin{tt.plugin}
+			result := minifier.New(opts).Minify(pluginShader)
+			if len(result.Errors) != 1 {
+				t.Fatalf("expected one error, got %v", result.Errors)
+			}
+			e := result.Errors[0]
+			if e.Message != tt.message || e.Line != tt.line || e.Column != tt.column {
+				t.Errorf("expected %q at %d:%d, got %q at %d:%d", tt.message, tt.line, tt.column, e.Message, e.Line, e.Column)
+			}
+			if result.Code != pluginShader {
+				t.Error("expected the original source on plugin error")
+			}
+		})
+	}
+}
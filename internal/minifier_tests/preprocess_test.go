@@ -0,0 +1,96 @@
+package minifier_tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+)
+
+const variantShader = `#define SCALE 2.0
+@group(0) @binding(0) var<uniform> tint: vec4f;
+#ifdef SHADOWS
+@group(0) @binding(1) var shadowMap: texture_depth_2d;
+#endif
+
+@fragment
+fn main() -> @location(0) vec4f {
+    var c = tint * SCALE;
+#if SHADOWS && MSAA >= 4
+    c.x += f32(textureDimensions(shadowMap).x);
+#endif
+    return c;
+}
+`
+
+func TestPreprocessorVariants(t *testing.T) {
+	tests := []struct {
+		name           string
+		defines        map[string]string
+		mustContain    []string
+		mustNotContain []string
+	}{
+		{
+			name:           "base",
+			mustContain:    []string{"tint*2.0"},
+			mustNotContain: []string{"shadowMap", "texture_depth_2d", "#"},
+		},
+		{
+			name:           "shadows",
+			defines:        map[string]string{"SHADOWS": ""},
+			mustContain:    []string{"tint*2.0"},
+			mustNotContain: []string{"textureDimensions"},
+		},
+		{
+			name:        "shadows with msaa",
+			defines:     map[string]string{"SHADOWS": "", "MSAA": "4"},
+			mustContain: []string{"texture_depth_2d", "textureDimensions"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := minifier.DefaultOptions()
+			opts.Defines = tt.defines
+			result := minifier.New(opts).MinifyAndReflect(variantShader)
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			for _, s := range tt.mustContain {
+				if !strings.Contains(result.Code, s) {
+					t.Errorf("expected %q in %s", s, result.Code)
+				}
+			}
+			for _, s := range tt.mustNotContain {
+				if strings.Contains(result.Code, s) {
+					t.Errorf("unexpected %q in %s", s, result.Code)
+				}
+			}
+			wantBindings := 1
+			if _, ok := tt.defines["SHADOWS"]; ok {
+				wantBindings = 2
+			}
+			if len(result.Reflect.Bindings) != wantBindings {
+				t.Errorf("expected %d bindings, got %d", wantBindings, len(result.Reflect.Bindings))
+			}
+		})
+	}
+}
+
+func TestPreprocessorErrorPositions(t *testing.T) {
+	result := minifier.Minify("#ifdef A\nconst a = 1;\n")
+	if len(result.Errors) != 1 || result.Errors[0].Line != 1 || !strings.Contains(result.Errors[0].Message, "unterminated") {
+		t.Errorf("expected unterminated conditional on line 1, got %v", result.Errors)
+	}
+
+	// The parse error follows an expansion that shifts columns
+	opts := minifier.DefaultOptions()
+	opts.Defines = map[string]string{"LONG_NAME": "1"}
+	result = minifier.New(opts).Minify("const a = LONG_NAME + ;\n")
+	if len(result.Errors) != 1 {
+		t.Fatalf("expected one error, got %v", result.Errors)
+	}
+	if e := result.Errors[0]; e.Line != 1 || e.Column != 23 {
+		t.Errorf("expected error at 1:23, got %d:%d", e.Line, e.Column)
+	}
+}
@@ -0,0 +1,145 @@
+package minifier_tests
+
+import (
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+)
+
+func TestPruneStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "constant if",
+			input: `const DEBUG = false;
+fn debugColor() -> f32 { return 1.0; }
+@fragment fn main() -> @location(0) vec4f {
+    var c = vec4f(0.0);
+    if (DEBUG) { c.x = debugColor(); }
+    return c;
+}`,
+			expected: "@fragment fn main()->@location(0) vec4f{var c=vec4f(0);return c;}",
+		},
+		{
+			name: "else chain",
+			input: `const MODE = 2;
+@fragment fn main() -> @location(0) vec4f {
+    if (MODE == 1) { return vec4f(1.0); } else if (MODE == 2) { return vec4f(2.0); } else { return vec4f(3.0); }
+}`,
+			expected: "@fragment fn main()->@location(0) vec4f{return vec4f(2);}",
+		},
+		{
+			name: "scoped declarations stay in a block",
+			input: `@fragment fn main() -> @location(0) vec4f {
+    let x = 1.0;
+    if (true) { let x = 2.0; return vec4f(x); }
+}`,
+			expected: "@fragment fn main()->@location(0) vec4f{let x=1.0;{let x=2.0;return vec4(x);}}",
+		},
+		{
+			name: "after return",
+			input: `fn helper() -> f32 { return 2.0; }
+@fragment fn main() -> @location(0) vec4f {
+    return vec4f(1.0);
+    let y = helper();
+}`,
+			expected: "@fragment fn main()->@location(0) vec4f{return vec4f(1);}",
+		},
+		{
+			name: "after both branches return",
+			input: `@fragment fn main(@location(0) v: f32) -> @location(0) vec4f {
+    if (v > 0.0) { return vec4f(1.0); } else { return vec4f(0.0); }
+    return vec4f(2.0);
+}`,
+			expected: "@fragment fn main(@location(0) v:f32)->@location(0) vec4f{if (v>0.0){return vec4f(1);} else{return vec4f(0);}}",
+		},
+		{
+			name: "after break",
+			input: `@compute @workgroup_size(1) fn main() {
+    var i = 0;
+    loop { i++; break; i = 5; }
+}`,
+			expected: "@compute @workgroup_size(1) fn main(){var i=0;loop{i++;break;}}",
+		},
+		{
+			name: "false loops",
+			input: `@compute @workgroup_size(1) fn main() {
+    var i = 0;
+    while (false) { i++; }
+    for (var j = 0; false; j++) { i++; }
+}`,
+			expected: "@compute @workgroup_size(1) fn main(){var i=0;}",
+		},
+		{
+			name: "constant switch",
+			input: `const QUALITY = 2u;
+@compute @workgroup_size(1) fn main() {
+    var n = 0u;
+    switch (QUALITY) {
+        case 1u: { n = 4u; }
+        case 2u, 3u: { n = 8u; }
+        default: { n = 1u; }
+    }
+}`,
+			expected: "@compute @workgroup_size(1) fn main(){var n=0u;n=8u;}",
+		},
+		{
+			name: "switch case that breaks is kept",
+			input: `@compute @workgroup_size(1) fn main() {
+    var n = 0u;
+    switch (1u) {
+        case 1u: { if (n > 0u) { break; } n = 4u; }
+        default: {}
+    }
+}`,
+			expected: "@compute @workgroup_size(1) fn main(){var n=0u;switch (1u){case 1u:{if (n>0u){break;}n=4u;}default:{}}}",
+		},
+		{
+			name: "empty if",
+			input: `@compute @workgroup_size(1) fn main() {
+    var n = 0u;
+    if (n > 1u) {} else {}
+}`,
+			expected: "@compute @workgroup_size(1) fn main(){var n=0u;}",
+		},
+		{
+			name: "discard in a function returning a value",
+			input: `@fragment fn main() -> @location(0) vec4f {
+    discard;
+    return vec4f(1.0);
+}`,
+			expected: "@fragment fn main()->@location(0) vec4f{discard;return vec4f(1);}",
+		},
+	}
+
+	opts := minifier.Options{
+		MinifyWhitespace: true,
+		MinifySyntax:     true,
+		TreeShaking:      true,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := minifier.New(opts).Minify(tt.input)
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if result.Code != tt.expected {
+				t.Errorf("\nexpected: %s\nactual:   %s", tt.expected, result.Code)
+			}
+		})
+	}
+}
+
+func TestPruneStatementsNeedsMinifySyntax(t *testing.T) {
+	input := `@compute @workgroup_size(1) fn main() { if (false) { return; } }`
+	result := minifier.New(minifier.Options{MinifyWhitespace: true, TreeShaking: true}).Minify(input)
+	if result.Code != "@compute @workgroup_size(1) fn main(){if (false){return;}}" {
+		t.Errorf("unexpected output: %s", result.Code)
+	}
+	if result.Stats.StatementsDead != 0 {
+		t.Errorf("expected no pruned statements, got %d", result.Stats.StatementsDead)
+	}
+}
@@ -239,3 +239,50 @@ func countBindings(m *ast.Module) int {
 	}
 	return count
 }
+
+// TestTintSemanticPreservationLocals runs shaders that exercise let
+// inlining, var coalescing and compound assignments through the same
+// pipeline as the Tint test files.
+func TestTintSemanticPreservationLocals(t *testing.T) {
+	shaders := map[string]string{
+		"let_inlining.wgsl": `@group(0) @binding(0) var<uniform> scale: f32;
+@fragment fn main(@location(0) uv: vec2f) -> @location(0) vec4f {
+    let d = length(uv - vec2f(0.5));
+    let s = d * scale;
+    let c = vec3f(s, 1.0 - s, 0.5);
+    return vec4f(c, 1.0);
+}`,
+		"var_coalescing.wgsl": `@group(0) @binding(0) var<storage, read_write> out: array<f32>;
+@compute @workgroup_size(64) fn main(@builtin(global_invocation_id) id: vec3u) {
+    var a: f32 = out[id.x];
+    a = a * a;
+    var b: f32 = a + 1.0;
+    b = b * 0.5;
+    out[id.x] = b;
+}`,
+		"compound_assignment.wgsl": `@group(0) @binding(0) var<storage, read_write> out: array<u32>;
+@compute @workgroup_size(1) fn main() {
+    var sum: u32 = 0u;
+    for (var i: u32 = 0u; i < 8u; i = i + 1u) {
+        sum = sum + i;
+        sum = sum ^ (i << 1u);
+    }
+    var n = 0;
+    n = n + 1;
+    out[0] = sum + u32(n);
+}`,
+	}
+
+	dir := t.TempDir()
+	for name, source := range shaders {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		t.Run(name, func(t *testing.T) {
+			if result := testSemanticPreservation(t, path); result != tintTestPassed {
+				t.Errorf("expected %s to pass, got result %d", name, result)
+			}
+		})
+	}
+}
@@ -9,10 +9,16 @@
 // - Better constant propagation (all declarations known before binding)
 // - More accurate symbol use counts (for frequency-based minification)
 // - Cleaner separation of concerns
+//
+// Every node records its full byte range. In lossless mode the module also
+// keeps the token stream with whitespace and comments attached as trivia,
+// which is enough to rebuild the source byte for byte (see package cst).
 package parser
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/HugoDaniel/miniray/internal/ast"
 	"github.com/HugoDaniel/miniray/internal/lexer"
@@ -25,6 +31,7 @@ type Parser struct {
 	tokens    []lexer.Token
 	pos       int
 	lineIndex *sourcemap.LineIndex // For converting byte offsets to line/column
+	lossless  bool                 // Keep the token stream in the module
 
 	// Symbol table
 	symbols []ast.Symbol
@@ -43,6 +50,10 @@ type Parser struct {
 
 	// Errors
 	errors []ParseError
+
+	// Token index just after a ';' that was missing at the end of a line.
+	// Parsing continues as if it were there.
+	insertedSemicolon int
 }
 
 // ConstValue represents a compile-time constant value.
@@ -63,30 +74,52 @@ const (
 	ConstBool
 )
 
-// ParseError represents a parsing error.
+// ParseError represents a parsing error. The range covers the offending
+// token; End equals Pos at end of input.
 type ParseError struct {
-	Message string
-	Pos     int
-	Line    int
-	Column  int
+	Message   string
+	Pos       int
+	End       int
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
 }
 
 func (e ParseError) Error() string {
 	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
 }
 
+// Options configures the parser.
+type Options struct {
+	// Lossless keeps whitespace and comments as token trivia and stores
+	// the token stream in Module.Tokens.
+	Lossless bool
+}
+
 // New creates a new parser for the given source.
 func New(source string) *Parser {
+	return NewWithOptions(source, Options{})
+}
+
+// NewWithOptions creates a new parser with the given options.
+func NewWithOptions(source string, opts Options) *Parser {
 	lex := lexer.New(source)
+	if opts.Lossless {
+		lex = lexer.NewLossless(source)
+	}
 	tokens := lex.Tokenize()
 
 	return &Parser{
 		source:      source,
 		tokens:      tokens,
+		lossless:    opts.Lossless,
 		lineIndex:   sourcemap.NewLineIndex(source),
 		symbols:     make([]ast.Symbol, 0),
 		scope:       ast.NewScope(nil),
 		constValues: make(map[ast.Ref]ConstValue),
+
+		insertedSemicolon: -1,
 	}
 }
 
@@ -105,6 +138,9 @@ func (p *Parser) Parse() (*ast.Module, []ParseError) {
 	p.visitModule(module)
 
 	module.Symbols = p.symbols
+	if p.lossless {
+		module.Tokens = p.tokens
+	}
 
 	return module, p.errors
 }
@@ -139,6 +175,20 @@ func (p *Parser) advance() lexer.Token {
 func (p *Parser) expect(kind lexer.TokenKind) (lexer.Token, bool) {
 	tok := p.current()
 	if tok.Kind != kind {
+		// A ';' missing at the end of a line is reported after the previous
+		// token, and the statement is treated as complete
+		if kind == lexer.TokSemicolon && p.pos > 0 && p.pos <= len(p.tokens) {
+			prev := p.tokens[p.pos-1]
+			next := tok.Start
+			if tok.Kind == lexer.TokEOF {
+				next = len(p.source)
+			}
+			if prev.End <= next && strings.Contains(p.source[prev.End:next], "\n") {
+				p.errorAt(prev.End, prev.End, fmt.Sprintf("expected %s at end of line", kind))
+				p.insertedSemicolon = p.pos
+				return tok, false
+			}
+		}
 		p.error(fmt.Sprintf("expected %s, got %s", kind, tok.Kind))
 		// Don't advance here - let caller decide how to recover
 		// This prevents consuming tokens that might be needed for error recovery
@@ -158,15 +208,163 @@ func (p *Parser) match(kind lexer.TokenKind) bool {
 
 func (p *Parser) error(msg string) {
 	tok := p.current()
-	line, col := p.lineIndex.ByteOffsetToLineColumn(tok.Start)
+	if tok.Kind == lexer.TokEOF {
+		tok.Start, tok.End = len(p.source), len(p.source)
+	}
+	p.errorAt(tok.Start, tok.End, msg)
+}
+
+// errorAt reports an error covering the byte range [start, end).
+func (p *Parser) errorAt(start, end int, msg string) {
+	// Only the first error at a position is reported; the rest are
+	// cascades from the same mistake.
+	if n := len(p.errors); n > 0 && p.errors[n-1].Pos == start {
+		return
+	}
+
+	line, col := p.lineIndex.ByteOffsetToLineColumn(start)
+	endLine, endCol := p.lineIndex.ByteOffsetToLineColumn(end)
 	p.errors = append(p.errors, ParseError{
-		Message: msg,
-		Pos:     tok.Start,
-		Line:    line + 1, // Convert to 1-based
-		Column:  col + 1,  // Convert to 1-based
+		Message:   msg,
+		Pos:       start,
+		End:       end,
+		Line:      line + 1, // Convert to 1-based
+		Column:    col + 1,  // Convert to 1-based
+		EndLine:   endLine + 1,
+		EndColumn: endCol + 1,
 	})
 }
 
+// locFrom returns the range from start to the end of the last consumed
+// token. A node that consumed nothing gets an empty range at start.
+func (p *Parser) locFrom(start int) ast.Loc {
+	end := start
+	if p.pos > 0 && p.pos <= len(p.tokens) && p.tokens[p.pos-1].End > start {
+		end = p.tokens[p.pos-1].End
+	}
+	return ast.Loc{Start: int32(start), End: int32(end)}
+}
+
+// ----------------------------------------------------------------------------
+// Error Recovery
+// ----------------------------------------------------------------------------
+
+// isStatementKeyword reports whether kind can only start a statement or a
+// declaration, making it a safe place to resume after a syntax error.
+func isStatementKeyword(kind lexer.TokenKind) bool {
+	switch kind {
+	case lexer.TokLet, lexer.TokVar, lexer.TokConst, lexer.TokConstAssert,
+		lexer.TokReturn, lexer.TokIf, lexer.TokSwitch, lexer.TokFor,
+		lexer.TokWhile, lexer.TokLoop, lexer.TokBreak, lexer.TokContinue,
+		lexer.TokDiscard:
+		return true
+	}
+	return isDeclarationKeyword(kind)
+}
+
+// isDeclarationKeyword reports whether kind starts a module-scope-only
+// declaration. Seeing one inside a function body means a '}' is missing.
+func isDeclarationKeyword(kind lexer.TokenKind) bool {
+	switch kind {
+	case lexer.TokFn, lexer.TokStruct, lexer.TokAlias, lexer.TokOverride,
+		lexer.TokEnable, lexer.TokRequires, lexer.TokDiagnostic:
+		return true
+	}
+	return false
+}
+
+// atRecoveryPoint reports whether the current token ends or delimits the
+// construct being parsed. Expression and type errors leave such tokens in
+// place so the enclosing rule can still match them.
+func (p *Parser) atRecoveryPoint() bool {
+	kind := p.current().Kind
+	switch kind {
+	case lexer.TokEOF, lexer.TokSemicolon, lexer.TokComma, lexer.TokColon,
+		lexer.TokEq, lexer.TokRParen, lexer.TokRBracket,
+		lexer.TokLBrace, lexer.TokRBrace:
+		return true
+	}
+	return isStatementKeyword(kind)
+}
+
+// atStatementBoundary reports whether the previous statement ended with a
+// ';' or '}', real or inserted, so the next token starts a new statement.
+func (p *Parser) atStatementBoundary() bool {
+	if p.insertedSemicolon == p.pos {
+		return true
+	}
+	if p.pos == 0 || p.pos > len(p.tokens) {
+		return false
+	}
+	prev := p.tokens[p.pos-1].Kind
+	return prev == lexer.TokSemicolon || prev == lexer.TokRBrace
+}
+
+// synchronizeStmt skips tokens after a statement-level error until the
+// parser reaches a statement boundary: just past a ';', or at a '}' or a
+// keyword that starts a new statement. Nested braces are skipped whole.
+func (p *Parser) synchronizeStmt() {
+	depth := 0
+	for {
+		kind := p.current().Kind
+		switch {
+		case kind == lexer.TokEOF:
+			return
+		case kind == lexer.TokLBrace:
+			depth++
+		case kind == lexer.TokRBrace:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case kind == lexer.TokSemicolon && depth == 0:
+			p.advance()
+			return
+		case isDeclarationKeyword(kind):
+			return
+		case isStatementKeyword(kind) && depth == 0:
+			return
+		}
+		p.advance()
+	}
+}
+
+// synchronizeDecl skips tokens after a module-scope error until the next
+// token that can start a declaration outside of any braces.
+func (p *Parser) synchronizeDecl() {
+	depth := 0
+	for {
+		kind := p.current().Kind
+		switch {
+		case kind == lexer.TokEOF:
+			return
+		case kind == lexer.TokLBrace:
+			depth++
+		case kind == lexer.TokRBrace:
+			if depth > 0 {
+				depth--
+			}
+			p.advance()
+			if depth == 0 {
+				return
+			}
+			continue
+		case kind == lexer.TokSemicolon && depth == 0:
+			p.advance()
+			return
+		case kind == lexer.TokFn || kind == lexer.TokStruct:
+			// These always start a new declaration, even inside an
+			// unterminated body
+			return
+		case depth == 0 && (isDeclarationKeyword(kind) || kind == lexer.TokAt ||
+			kind == lexer.TokConst || kind == lexer.TokConstAssert ||
+			kind == lexer.TokVar || kind == lexer.TokLet):
+			return
+		}
+		p.advance()
+	}
+}
+
 // ----------------------------------------------------------------------------
 // Symbol Table (Pass 1)
 // ----------------------------------------------------------------------------
@@ -176,6 +374,7 @@ func (p *Parser) declareSymbolAt(name string, kind ast.SymbolKind, flags ast.Sym
 
 	p.symbols = append(p.symbols, ast.Symbol{
 		OriginalName: name,
+		Loc:          ast.Loc{Start: int32(loc)},
 		Kind:         kind,
 		Flags:        flags,
 		UseCount:     0, // Will be counted in visit pass
@@ -244,12 +443,14 @@ func (p *Parser) visitDecl(d ast.Decl) {
 		}
 
 	case *ast.OverrideDecl:
+		p.visitAttributes(decl.Attributes)
 		p.visitType(decl.Type)
 		if decl.Initializer != nil {
 			decl.Initializer = p.visitExpr(decl.Initializer)
 		}
 
 	case *ast.VarDecl:
+		p.visitAttributes(decl.Attributes)
 		p.visitType(decl.Type)
 		if decl.Initializer != nil {
 			decl.Initializer = p.visitExpr(decl.Initializer)
@@ -264,7 +465,9 @@ func (p *Parser) visitDecl(d ast.Decl) {
 
 	case *ast.StructDecl:
 		// Visit struct member types (they may reference other structs/aliases)
+		// and attributes such as @location(N)
 		for i := range decl.Members {
+			p.visitAttributes(decl.Members[i].Attributes)
 			p.visitType(decl.Members[i].Type)
 		}
 
@@ -278,12 +481,19 @@ func (p *Parser) visitDecl(d ast.Decl) {
 }
 
 func (p *Parser) visitFunctionDecl(decl *ast.FunctionDecl) {
-	// Visit parameter types (in module scope, before entering function scope)
+	// Attribute arguments such as @workgroup_size(N) may name module-scope
+	// constants and overrides
+	p.visitAttributes(decl.Attributes)
+
+	// Visit parameter attributes and types (in module scope, before
+	// entering function scope)
 	for i := range decl.Parameters {
+		p.visitAttributes(decl.Parameters[i].Attributes)
 		p.visitType(decl.Parameters[i].Type)
 	}
 
 	// Visit return type
+	p.visitAttributes(decl.ReturnAttr)
 	p.visitType(decl.ReturnType)
 
 	// Enter function scope (recorded during parse)
@@ -296,6 +506,20 @@ func (p *Parser) visitFunctionDecl(decl *ast.FunctionDecl) {
 	p.exitScope()
 }
 
+func (p *Parser) visitAttributes(attrs []ast.Attribute) {
+	for i := range attrs {
+		switch attrs[i].Name {
+		case "builtin", "interpolate", "diagnostic":
+			// Arguments are enumerants, not references, even when a
+			// declaration has the same name
+			continue
+		}
+		for j := range attrs[i].Args {
+			attrs[i].Args[j] = p.visitExpr(attrs[i].Args[j])
+		}
+	}
+}
+
 func (p *Parser) visitStmt(s ast.Stmt) {
 	switch stmt := s.(type) {
 	case *ast.CompoundStmt:
@@ -545,6 +769,49 @@ func (p *Parser) exitScope() {
 // evaluateConstExpr attempts to evaluate a constant expression.
 // This enables constant propagation during the visit pass.
 func (p *Parser) evaluateConstExpr(e ast.Expr) ConstValue {
+	return EvaluateConstExpr(e, p.constValues)
+}
+
+// ConstValues evaluates every const declaration of a bound module, at
+// module scope and inside function bodies, and returns the values that
+// are known at compile time. It is the same table the parser builds
+// during its visit pass, recomputed so that it reflects later changes to
+// the tree such as specialized overrides.
+func ConstValues(module *ast.Module) map[ast.Ref]ConstValue {
+	values := make(map[ast.Ref]ConstValue)
+	if module == nil {
+		return values
+	}
+
+	var decls []*ast.ConstDecl
+	ast.Inspect(module, func(n ast.Node) bool {
+		if d, ok := n.(*ast.ConstDecl); ok && d.Name.IsValid() {
+			decls = append(decls, d)
+		}
+		return true
+	})
+
+	// Module-scope constants may be used before their declaration, so
+	// repeat until no more values can be resolved
+	for changed := true; changed; {
+		changed = false
+		for _, d := range decls {
+			if _, ok := values[d.Name]; ok {
+				continue
+			}
+			if val := EvaluateConstExpr(d.Initializer, values); val.Kind != ConstNone {
+				values[d.Name] = val
+				changed = true
+			}
+		}
+	}
+	return values
+}
+
+// EvaluateConstExpr attempts to evaluate e using the known constant
+// values. It returns a ConstValue of kind ConstNone if e is not a
+// constant it can evaluate.
+func EvaluateConstExpr(e ast.Expr, consts map[ast.Ref]ConstValue) ConstValue {
 	if e == nil {
 		return ConstValue{}
 	}
@@ -553,13 +820,21 @@ func (p *Parser) evaluateConstExpr(e ast.Expr) ConstValue {
 	case *ast.LiteralExpr:
 		switch expr.Kind {
 		case lexer.TokIntLiteral:
-			var val int64
-			fmt.Sscanf(expr.Value, "%d", &val)
-			return ConstValue{Kind: ConstInt, Int: val}
+			text := strings.TrimRight(expr.Value, "iu")
+			if val, err := strconv.ParseInt(text, 0, 64); err == nil {
+				return ConstValue{Kind: ConstInt, Int: val}
+			}
 		case lexer.TokFloatLiteral:
-			var val float64
-			fmt.Sscanf(expr.Value, "%f", &val)
-			return ConstValue{Kind: ConstFloat, Float: val}
+			text := expr.Value
+			if !strings.HasPrefix(text, "0x") && !strings.HasPrefix(text, "0X") {
+				text = strings.TrimRight(text, "fh")
+			} else if !strings.ContainsAny(text, "pP") {
+				// Hex floats without an exponent are not accepted by Go
+				return ConstValue{}
+			}
+			if val, err := strconv.ParseFloat(text, 64); err == nil {
+				return ConstValue{Kind: ConstFloat, Float: val}
+			}
 		case lexer.TokTrue:
 			return ConstValue{Kind: ConstBool, Bool: true}
 		case lexer.TokFalse:
@@ -568,12 +843,12 @@ func (p *Parser) evaluateConstExpr(e ast.Expr) ConstValue {
 
 	case *ast.IdentExpr:
 		// Look up constant value
-		if val, ok := p.constValues[expr.Ref]; ok {
+		if val, ok := consts[expr.Ref]; ok {
 			return val
 		}
 
 	case *ast.UnaryExpr:
-		operand := p.evaluateConstExpr(expr.Operand)
+		operand := EvaluateConstExpr(expr.Operand, consts)
 		if operand.Kind == ConstNone {
 			return ConstValue{}
 		}
@@ -592,8 +867,8 @@ func (p *Parser) evaluateConstExpr(e ast.Expr) ConstValue {
 		}
 
 	case *ast.BinaryExpr:
-		left := p.evaluateConstExpr(expr.Left)
-		right := p.evaluateConstExpr(expr.Right)
+		left := EvaluateConstExpr(expr.Left, consts)
+		right := EvaluateConstExpr(expr.Right, consts)
 		if left.Kind == ConstNone || right.Kind == ConstNone {
 			return ConstValue{}
 		}
@@ -681,7 +956,7 @@ func (p *Parser) evaluateConstExpr(e ast.Expr) ConstValue {
 		}
 
 	case *ast.ParenExpr:
-		return p.evaluateConstExpr(expr.Expr)
+		return EvaluateConstExpr(expr.Expr, consts)
 	}
 
 	return ConstValue{}
@@ -715,17 +990,35 @@ func (p *Parser) parseTranslationUnit(module *ast.Module) {
 parseDecls:
 	// Parse declarations
 	for p.current().Kind != lexer.TokEOF {
+		// Empty global declaration
+		if p.match(lexer.TokSemicolon) {
+			continue
+		}
+
+		start, errCount := p.pos, len(p.errors)
 		decl := p.parseDeclaration()
 		if decl != nil {
 			module.Declarations = append(module.Declarations, decl)
-		} else {
-			// Error recovery: skip to next likely declaration start
-			p.advance()
+		} else if len(p.errors) == errCount {
+			p.error(fmt.Sprintf("expected declaration, got %s", p.current().Kind))
+		}
+
+		// Error recovery: skip to the next likely declaration start. A
+		// repeated error at the same position is dropped, so check for
+		// progress too, or a token no rule consumes would stop the loop
+		if len(p.errors) > errCount || p.pos == start {
+			if p.pos == start {
+				p.advance()
+			}
+			if !p.atStatementBoundary() {
+				p.synchronizeDecl()
+			}
 		}
 	}
 }
 
 func (p *Parser) parseEnableDirective() *ast.EnableDirective {
+	start := p.current().Start
 	p.expect(lexer.TokEnable)
 	dir := &ast.EnableDirective{}
 
@@ -746,10 +1039,12 @@ func (p *Parser) parseEnableDirective() *ast.EnableDirective {
 	}
 
 	p.expect(lexer.TokSemicolon)
+	dir.Loc = p.locFrom(start)
 	return dir
 }
 
 func (p *Parser) parseRequiresDirective() *ast.RequiresDirective {
+	start := p.current().Start
 	p.expect(lexer.TokRequires)
 	dir := &ast.RequiresDirective{}
 
@@ -763,10 +1058,12 @@ func (p *Parser) parseRequiresDirective() *ast.RequiresDirective {
 	}
 
 	p.expect(lexer.TokSemicolon)
+	dir.Loc = p.locFrom(start)
 	return dir
 }
 
 func (p *Parser) parseDiagnosticDirective() *ast.DiagnosticDirective {
+	start := p.current().Start
 	p.expect(lexer.TokDiagnostic)
 	p.expect(lexer.TokLParen)
 
@@ -782,41 +1079,63 @@ func (p *Parser) parseDiagnosticDirective() *ast.DiagnosticDirective {
 
 	p.expect(lexer.TokRParen)
 	p.expect(lexer.TokSemicolon)
+	dir.Loc = p.locFrom(start)
 	return dir
 }
 
 func (p *Parser) parseDeclaration() ast.Decl {
+	// The declaration's range includes its attributes
+	start := p.current().Start
+
 	// Parse attributes
 	attrs := p.parseAttributes()
 
 	switch p.current().Kind {
 	case lexer.TokConst:
 		if p.peek(1).Kind == lexer.TokIdent {
-			return p.parseConstDecl()
+			decl := p.parseConstDecl()
+			decl.Loc = p.locFrom(start)
+			return decl
 		}
 		// const_assert
-		return p.parseConstAssert()
+		decl := p.parseConstAssert()
+		decl.Loc = p.locFrom(start)
+		return decl
 
 	case lexer.TokConstAssert:
-		return p.parseConstAssert()
+		decl := p.parseConstAssert()
+		decl.Loc = p.locFrom(start)
+		return decl
 
 	case lexer.TokOverride:
-		return p.parseOverrideDecl(attrs)
+		decl := p.parseOverrideDecl(attrs)
+		decl.Loc = p.locFrom(start)
+		return decl
 
 	case lexer.TokVar:
-		return p.parseVarDecl(attrs)
+		decl := p.parseVarDecl(attrs)
+		decl.Loc = p.locFrom(start)
+		return decl
 
 	case lexer.TokLet:
-		return p.parseLetDecl()
+		decl := p.parseLetDecl()
+		decl.Loc = p.locFrom(start)
+		return decl
 
 	case lexer.TokFn:
-		return p.parseFunctionDecl(attrs)
+		decl := p.parseFunctionDecl(attrs)
+		decl.Loc = p.locFrom(start)
+		return decl
 
 	case lexer.TokStruct:
-		return p.parseStructDecl()
+		decl := p.parseStructDecl()
+		decl.Loc = p.locFrom(start)
+		return decl
 
 	case lexer.TokAlias:
-		return p.parseAliasDecl()
+		decl := p.parseAliasDecl()
+		decl.Loc = p.locFrom(start)
+		return decl
 
 	default:
 		if len(attrs) > 0 {
@@ -830,10 +1149,14 @@ func (p *Parser) parseAttributes() []ast.Attribute {
 	var attrs []ast.Attribute
 
 	for p.current().Kind == lexer.TokAt {
+		start := p.current().Start
 		p.advance() // @
 
 		attr := ast.Attribute{}
-		if tok, ok := p.expect(lexer.TokIdent); ok {
+		if p.current().Kind == lexer.TokDiagnostic {
+			// @diagnostic is the one attribute named by a keyword
+			attr.Name = p.advance().Value
+		} else if tok, ok := p.expect(lexer.TokIdent); ok {
 			attr.Name = tok.Value
 		}
 
@@ -842,6 +1165,7 @@ func (p *Parser) parseAttributes() []ast.Attribute {
 			p.expect(lexer.TokRParen)
 		}
 
+		attr.Loc = p.locFrom(start)
 		attrs = append(attrs, attr)
 	}
 
@@ -989,6 +1313,7 @@ func (p *Parser) parseParameters() []ast.Parameter {
 	var params []ast.Parameter
 
 	for {
+		start := p.current().Start
 		param := ast.Parameter{}
 		param.Attributes = p.parseAttributes()
 
@@ -998,6 +1323,7 @@ func (p *Parser) parseParameters() []ast.Parameter {
 
 		p.expect(lexer.TokColon)
 		param.Type = p.parseType()
+		param.Loc = p.locFrom(start)
 
 		params = append(params, param)
 
@@ -1024,6 +1350,7 @@ func (p *Parser) parseStructDecl() *ast.StructDecl {
 	p.expect(lexer.TokLBrace)
 
 	for p.current().Kind != lexer.TokRBrace && p.current().Kind != lexer.TokEOF {
+		start := p.pos
 		member := ast.StructMember{}
 		member.Attributes = p.parseAttributes()
 
@@ -1033,11 +1360,17 @@ func (p *Parser) parseStructDecl() *ast.StructDecl {
 
 		p.expect(lexer.TokColon)
 		member.Type = p.parseType()
+		member.Loc = p.locFrom(p.tokens[start].Start)
 
 		decl.Members = append(decl.Members, member)
 
 		// Optional trailing comma
 		p.match(lexer.TokComma)
+
+		// Give up on the struct body if nothing could be consumed
+		if p.pos == start {
+			break
+		}
 	}
 
 	p.expect(lexer.TokRBrace)
@@ -1084,15 +1417,21 @@ func (p *Parser) parseType() ast.Type {
 
 		// Check for template arguments
 		if p.current().Kind == lexer.TokLt {
-			return p.parseTemplatedType(name)
+			t := p.parseTemplatedType(name)
+			setTypeLoc(t, p.locFrom(tok.Start))
+			return t
 		}
 
-		return &ast.IdentType{Name: name, Ref: ast.InvalidRef()}
+		return &ast.IdentType{Loc: p.locFrom(tok.Start), Name: name, Ref: ast.InvalidRef()}
 
 	default:
-		p.error("expected type, got " + tok.Value)
-		p.advance() // Skip the unexpected token to avoid infinite loop
-		return &ast.IdentType{Name: "error", Ref: ast.InvalidRef()}
+		p.error("expected type, got " + tok.Kind.String())
+		// Skip the unexpected token to avoid infinite loop, unless the
+		// enclosing rule can resume at it
+		if !p.atRecoveryPoint() {
+			p.advance()
+		}
+		return &ast.IdentType{Loc: p.locFrom(tok.Start), Name: "error", Ref: ast.InvalidRef()}
 	}
 }
 
@@ -1155,6 +1494,28 @@ func (p *Parser) parseTemplatedType(name string) ast.Type {
 	}
 }
 
+// setTypeLoc sets the range of a type node.
+func setTypeLoc(t ast.Type, loc ast.Loc) {
+	switch t := t.(type) {
+	case *ast.IdentType:
+		t.Loc = loc
+	case *ast.VecType:
+		t.Loc = loc
+	case *ast.MatType:
+		t.Loc = loc
+	case *ast.ArrayType:
+		t.Loc = loc
+	case *ast.PtrType:
+		t.Loc = loc
+	case *ast.AtomicType:
+		t.Loc = loc
+	case *ast.SamplerType:
+		t.Loc = loc
+	case *ast.TextureType:
+		t.Loc = loc
+	}
+}
+
 // parseTextureType parses a texture type if the name matches a known texture type.
 // Returns nil if not a texture type.
 func (p *Parser) parseTextureType(name string) ast.Type {
@@ -1240,6 +1601,7 @@ func (p *Parser) parseTemplateArgExpr() ast.Expr {
 }
 
 func (p *Parser) parseTemplateAdditiveExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseTemplateMultiplicativeExpr()
 
 	for {
@@ -1254,11 +1616,12 @@ func (p *Parser) parseTemplateAdditiveExpr() ast.Expr {
 		}
 		p.advance()
 		right := p.parseTemplateMultiplicativeExpr()
-		left = &ast.BinaryExpr{Op: op, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: op, Left: left, Right: right}
 	}
 }
 
 func (p *Parser) parseTemplateMultiplicativeExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseTemplateUnaryExpr()
 
 	for {
@@ -1275,11 +1638,12 @@ func (p *Parser) parseTemplateMultiplicativeExpr() ast.Expr {
 		}
 		p.advance()
 		right := p.parseTemplateUnaryExpr()
-		left = &ast.BinaryExpr{Op: op, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: op, Left: left, Right: right}
 	}
 }
 
 func (p *Parser) parseTemplateUnaryExpr() ast.Expr {
+	start := p.current().Start
 	var op ast.UnaryOp
 	hasOp := true
 
@@ -1297,7 +1661,7 @@ func (p *Parser) parseTemplateUnaryExpr() ast.Expr {
 	if hasOp {
 		p.advance()
 		operand := p.parseTemplateUnaryExpr()
-		return &ast.UnaryExpr{Op: op, Operand: operand}
+		return &ast.UnaryExpr{Loc: p.locFrom(start), Op: op, Operand: operand}
 	}
 
 	return p.parseTemplatePrimaryExpr()
@@ -1309,26 +1673,29 @@ func (p *Parser) parseTemplatePrimaryExpr() ast.Expr {
 	switch tok.Kind {
 	case lexer.TokIntLiteral, lexer.TokFloatLiteral:
 		p.advance()
-		return &ast.LiteralExpr{Kind: tok.Kind, Value: tok.Value}
+		return &ast.LiteralExpr{Loc: p.locFrom(tok.Start), Kind: tok.Kind, Value: tok.Value}
 
 	case lexer.TokTrue, lexer.TokFalse:
 		p.advance()
-		return &ast.LiteralExpr{Kind: tok.Kind, Value: tok.Value}
+		return &ast.LiteralExpr{Loc: p.locFrom(tok.Start), Kind: tok.Kind, Value: tok.Value}
 
 	case lexer.TokIdent:
 		p.advance()
-		return &ast.IdentExpr{Loc: ast.Loc{Start: int32(tok.Start)}, Name: tok.Value, Ref: ast.InvalidRef()}
+		return &ast.IdentExpr{Loc: p.locFrom(tok.Start), Name: tok.Value, Ref: ast.InvalidRef()}
 
 	case lexer.TokLParen:
 		p.advance()
 		expr := p.parseTemplateArgExpr()
 		p.expect(lexer.TokRParen)
-		return &ast.ParenExpr{Expr: expr}
+		return &ast.ParenExpr{Loc: p.locFrom(tok.Start), Expr: expr}
 
 	default:
 		p.error("expected expression")
-		p.advance()
-		return nil
+		// Leave delimiters in place for the enclosing rule to match
+		if !p.atRecoveryPoint() {
+			p.advance()
+		}
+		return &ast.ErrorExpr{Loc: p.locFrom(tok.Start)}
 	}
 }
 
@@ -1377,66 +1744,72 @@ func (p *Parser) parseExpression() ast.Expr {
 }
 
 func (p *Parser) parseLogicalOrExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseLogicalAndExpr()
 
 	for p.current().Kind == lexer.TokPipePipe {
 		p.advance()
 		right := p.parseLogicalAndExpr()
-		left = &ast.BinaryExpr{Op: ast.BinOpLogicalOr, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: ast.BinOpLogicalOr, Left: left, Right: right}
 	}
 
 	return left
 }
 
 func (p *Parser) parseLogicalAndExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseBitwiseOrExpr()
 
 	for p.current().Kind == lexer.TokAmpAmp {
 		p.advance()
 		right := p.parseBitwiseOrExpr()
-		left = &ast.BinaryExpr{Op: ast.BinOpLogicalAnd, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: ast.BinOpLogicalAnd, Left: left, Right: right}
 	}
 
 	return left
 }
 
 func (p *Parser) parseBitwiseOrExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseBitwiseXorExpr()
 
 	for p.current().Kind == lexer.TokPipe {
 		p.advance()
 		right := p.parseBitwiseXorExpr()
-		left = &ast.BinaryExpr{Op: ast.BinOpOr, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: ast.BinOpOr, Left: left, Right: right}
 	}
 
 	return left
 }
 
 func (p *Parser) parseBitwiseXorExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseBitwiseAndExpr()
 
 	for p.current().Kind == lexer.TokCaret {
 		p.advance()
 		right := p.parseBitwiseAndExpr()
-		left = &ast.BinaryExpr{Op: ast.BinOpXor, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: ast.BinOpXor, Left: left, Right: right}
 	}
 
 	return left
 }
 
 func (p *Parser) parseBitwiseAndExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseEqualityExpr()
 
 	for p.current().Kind == lexer.TokAmp {
 		p.advance()
 		right := p.parseEqualityExpr()
-		left = &ast.BinaryExpr{Op: ast.BinOpAnd, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: ast.BinOpAnd, Left: left, Right: right}
 	}
 
 	return left
 }
 
 func (p *Parser) parseEqualityExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseRelationalExpr()
 
 	for {
@@ -1451,11 +1824,12 @@ func (p *Parser) parseEqualityExpr() ast.Expr {
 		}
 		p.advance()
 		right := p.parseRelationalExpr()
-		left = &ast.BinaryExpr{Op: op, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: op, Left: left, Right: right}
 	}
 }
 
 func (p *Parser) parseRelationalExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseShiftExpr()
 
 	for {
@@ -1474,11 +1848,12 @@ func (p *Parser) parseRelationalExpr() ast.Expr {
 		}
 		p.advance()
 		right := p.parseShiftExpr()
-		left = &ast.BinaryExpr{Op: op, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: op, Left: left, Right: right}
 	}
 }
 
 func (p *Parser) parseShiftExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseAdditiveExpr()
 
 	for {
@@ -1493,11 +1868,12 @@ func (p *Parser) parseShiftExpr() ast.Expr {
 		}
 		p.advance()
 		right := p.parseAdditiveExpr()
-		left = &ast.BinaryExpr{Op: op, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: op, Left: left, Right: right}
 	}
 }
 
 func (p *Parser) parseAdditiveExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseMultiplicativeExpr()
 
 	for {
@@ -1512,11 +1888,12 @@ func (p *Parser) parseAdditiveExpr() ast.Expr {
 		}
 		p.advance()
 		right := p.parseMultiplicativeExpr()
-		left = &ast.BinaryExpr{Op: op, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: op, Left: left, Right: right}
 	}
 }
 
 func (p *Parser) parseMultiplicativeExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parseUnaryExpr()
 
 	for {
@@ -1533,11 +1910,12 @@ func (p *Parser) parseMultiplicativeExpr() ast.Expr {
 		}
 		p.advance()
 		right := p.parseUnaryExpr()
-		left = &ast.BinaryExpr{Op: op, Left: left, Right: right}
+		left = &ast.BinaryExpr{Loc: p.locFrom(start), Op: op, Left: left, Right: right}
 	}
 }
 
 func (p *Parser) parseUnaryExpr() ast.Expr {
+	start := p.current().Start
 	var op ast.UnaryOp
 	hasOp := true
 
@@ -1559,13 +1937,14 @@ func (p *Parser) parseUnaryExpr() ast.Expr {
 	if hasOp {
 		p.advance()
 		operand := p.parseUnaryExpr()
-		return &ast.UnaryExpr{Op: op, Operand: operand}
+		return &ast.UnaryExpr{Loc: p.locFrom(start), Op: op, Operand: operand}
 	}
 
 	return p.parsePostfixExpr()
 }
 
 func (p *Parser) parsePostfixExpr() ast.Expr {
+	start := p.current().Start
 	left := p.parsePrimaryExpr()
 
 	for {
@@ -1573,20 +1952,20 @@ func (p *Parser) parsePostfixExpr() ast.Expr {
 		case lexer.TokDot:
 			p.advance()
 			if tok, ok := p.expect(lexer.TokIdent); ok {
-				left = &ast.MemberExpr{Base: left, Member: tok.Value}
+				left = &ast.MemberExpr{Loc: p.locFrom(start), Base: left, Member: tok.Value}
 			}
 
 		case lexer.TokLBracket:
 			p.advance()
 			index := p.parseExpression()
 			p.expect(lexer.TokRBracket)
-			left = &ast.IndexExpr{Base: left, Index: index}
+			left = &ast.IndexExpr{Loc: p.locFrom(start), Base: left, Index: index}
 
 		case lexer.TokLParen:
 			p.advance()
 			args := p.parseExpressionList()
 			p.expect(lexer.TokRParen)
-			left = &ast.CallExpr{Func: left, Args: args}
+			left = &ast.CallExpr{Loc: p.locFrom(start), Func: left, Args: args}
 
 		default:
 			return left
@@ -1600,22 +1979,22 @@ func (p *Parser) parsePrimaryExpr() ast.Expr {
 	switch tok.Kind {
 	case lexer.TokIntLiteral, lexer.TokFloatLiteral:
 		p.advance()
-		return &ast.LiteralExpr{Kind: tok.Kind, Value: tok.Value}
+		return &ast.LiteralExpr{Loc: p.locFrom(tok.Start), Kind: tok.Kind, Value: tok.Value}
 
 	case lexer.TokTrue, lexer.TokFalse:
 		p.advance()
-		return &ast.LiteralExpr{Kind: tok.Kind, Value: tok.Value}
+		return &ast.LiteralExpr{Loc: p.locFrom(tok.Start), Kind: tok.Kind, Value: tok.Value}
 
 	case lexer.TokIdent:
 		p.advance()
 		name := tok.Value
-		loc := ast.Loc{Start: int32(tok.Start)}
+		loc := p.locFrom(tok.Start)
 
 		// Check for templated type constructor: array<T, N>(...) or vec2<f32>(...)
 		// Only consider this if the identifier looks like a type constructor
 		// (array, vec2, vec3, vec4, mat*, etc.)
 		if p.current().Kind == lexer.TokLt && isTemplatedTypeName(name) {
-			return p.parseTemplatedConstructor(name)
+			return p.parseTemplatedConstructor(name, loc)
 		}
 
 		// Note: ref binding happens in visit pass
@@ -1625,12 +2004,15 @@ func (p *Parser) parsePrimaryExpr() ast.Expr {
 		p.advance()
 		expr := p.parseExpression()
 		p.expect(lexer.TokRParen)
-		return &ast.ParenExpr{Expr: expr}
+		return &ast.ParenExpr{Loc: p.locFrom(tok.Start), Expr: expr}
 
 	default:
 		p.error("expected expression")
-		p.advance()
-		return nil
+		// Leave delimiters in place for the enclosing rule to match
+		if !p.atRecoveryPoint() {
+			p.advance()
+		}
+		return &ast.ErrorExpr{Loc: p.locFrom(tok.Start)}
 	}
 }
 
@@ -1653,16 +2035,17 @@ func isTemplatedTypeName(name string) bool {
 }
 
 // parseTemplatedConstructor parses a templated type constructor like array<T, N>(...) or vec2<f32>(...)
-func (p *Parser) parseTemplatedConstructor(name string) ast.Expr {
+func (p *Parser) parseTemplatedConstructor(name string, loc ast.Loc) ast.Expr {
 	// Parse the templated type using existing infrastructure
 	// parseTemplatedType expects '<' to not be consumed yet
 	templatedType := p.parseTemplatedType(name)
+	setTypeLoc(templatedType, p.locFrom(int(loc.Start)))
 
 	// Now expect the constructor call
 	if p.current().Kind != lexer.TokLParen {
 		// Not a constructor, just return as identifier
 		// (This handles things like array<f32, N> as a type, not a call)
-		return &ast.IdentExpr{Name: name, Ref: ast.InvalidRef()}
+		return &ast.IdentExpr{Loc: loc, Name: name, Ref: ast.InvalidRef()}
 	}
 
 	p.advance() // consume (
@@ -1671,6 +2054,7 @@ func (p *Parser) parseTemplatedConstructor(name string) ast.Expr {
 
 	// Create a call expression with the parsed template type
 	return &ast.CallExpr{
+		Loc:          p.locFrom(int(loc.Start)),
 		TemplateType: templatedType,
 		Args:         args,
 	}
@@ -1723,28 +2107,29 @@ func (p *Parser) parseStatement() ast.Stmt {
 		return p.parseLoopStmt()
 
 	case lexer.TokBreak:
-		p.advance()
+		start := p.advance().Start
 		if p.match(lexer.TokIf) {
 			cond := p.parseExpression()
 			p.expect(lexer.TokSemicolon)
-			return &ast.BreakIfStmt{Condition: cond}
+			return &ast.BreakIfStmt{Loc: p.locFrom(start), Condition: cond}
 		}
 		p.expect(lexer.TokSemicolon)
-		return &ast.BreakStmt{}
+		return &ast.BreakStmt{Loc: p.locFrom(start)}
 
 	case lexer.TokContinue:
-		p.advance()
+		start := p.advance().Start
 		p.expect(lexer.TokSemicolon)
-		return &ast.ContinueStmt{}
+		return &ast.ContinueStmt{Loc: p.locFrom(start)}
 
 	case lexer.TokDiscard:
-		p.advance()
+		start := p.advance().Start
 		p.expect(lexer.TokSemicolon)
-		return &ast.DiscardStmt{}
+		return &ast.DiscardStmt{Loc: p.locFrom(start)}
 
 	case lexer.TokConst, lexer.TokLet, lexer.TokVar:
+		start := p.current().Start
 		decl := p.parseDeclaration()
-		return &ast.DeclStmt{Decl: decl}
+		return &ast.DeclStmt{Loc: p.locFrom(start), Decl: decl}
 
 	default:
 		// Expression statement or assignment
@@ -1753,23 +2138,47 @@ func (p *Parser) parseStatement() ast.Stmt {
 }
 
 func (p *Parser) parseCompoundStmt() *ast.CompoundStmt {
-	p.expect(lexer.TokLBrace)
+	stmt := &ast.CompoundStmt{}
+	start := p.current().Start
+	_, hasBrace := p.expect(lexer.TokLBrace)
 	p.pushScope()
 
-	stmt := &ast.CompoundStmt{}
-	for p.current().Kind != lexer.TokRBrace && p.current().Kind != lexer.TokEOF {
+	// Without the opening brace, leave the following statements to the
+	// enclosing block rather than swallowing them up to its '}'
+	for hasBrace && p.current().Kind != lexer.TokRBrace && p.current().Kind != lexer.TokEOF {
+		// A module-scope declaration means this block is missing its '}'
+		if isDeclarationKeyword(p.current().Kind) {
+			break
+		}
+
+		startPos, errCount := p.pos, len(p.errors)
 		s := p.parseStatement()
+		if len(p.errors) > errCount || p.pos == startPos {
+			if p.pos == startPos {
+				p.advance()
+			}
+			if !p.atStatementBoundary() {
+				p.synchronizeStmt()
+			}
+			if s == nil {
+				s = &ast.ErrorStmt{Loc: p.locFrom(p.tokens[startPos].Start)}
+			}
+		}
 		if s != nil {
 			stmt.Stmts = append(stmt.Stmts, s)
 		}
 	}
 
 	p.popScope()
-	p.expect(lexer.TokRBrace)
+	if hasBrace {
+		p.expect(lexer.TokRBrace)
+	}
+	stmt.Loc = p.locFrom(start)
 	return stmt
 }
 
 func (p *Parser) parseReturnStmt() *ast.ReturnStmt {
+	start := p.current().Start
 	p.expect(lexer.TokReturn)
 	stmt := &ast.ReturnStmt{}
 
@@ -1778,10 +2187,12 @@ func (p *Parser) parseReturnStmt() *ast.ReturnStmt {
 	}
 
 	p.expect(lexer.TokSemicolon)
+	stmt.Loc = p.locFrom(start)
 	return stmt
 }
 
 func (p *Parser) parseIfStmt() *ast.IfStmt {
+	start := p.current().Start
 	p.expect(lexer.TokIf)
 	stmt := &ast.IfStmt{}
 
@@ -1796,10 +2207,12 @@ func (p *Parser) parseIfStmt() *ast.IfStmt {
 		}
 	}
 
+	stmt.Loc = p.locFrom(start)
 	return stmt
 }
 
 func (p *Parser) parseSwitchStmt() *ast.SwitchStmt {
+	start := p.current().Start
 	p.expect(lexer.TokSwitch)
 	stmt := &ast.SwitchStmt{}
 
@@ -1807,12 +2220,20 @@ func (p *Parser) parseSwitchStmt() *ast.SwitchStmt {
 	p.expect(lexer.TokLBrace)
 
 	for p.current().Kind != lexer.TokRBrace && p.current().Kind != lexer.TokEOF {
+		caseStart := p.current().Start
 		c := ast.SwitchCase{}
 
 		if p.match(lexer.TokDefault) {
 			// default case
+		} else if !p.match(lexer.TokCase) {
+			// Skip to the next clause
+			p.error(fmt.Sprintf("expected case or default, got %s", p.current().Kind))
+			for k := p.current().Kind; k != lexer.TokCase && k != lexer.TokDefault &&
+				k != lexer.TokRBrace && k != lexer.TokEOF; k = p.current().Kind {
+				p.advance()
+			}
+			continue
 		} else {
-			p.expect(lexer.TokCase)
 			// Parse selectors
 			c.Selectors = append(c.Selectors, p.parseExpression())
 			for p.match(lexer.TokComma) {
@@ -1822,14 +2243,17 @@ func (p *Parser) parseSwitchStmt() *ast.SwitchStmt {
 
 		p.expect(lexer.TokColon)
 		c.Body = p.parseCompoundStmt()
+		c.Loc = p.locFrom(caseStart)
 		stmt.Cases = append(stmt.Cases, c)
 	}
 
 	p.expect(lexer.TokRBrace)
+	stmt.Loc = p.locFrom(start)
 	return stmt
 }
 
 func (p *Parser) parseForStmt() *ast.ForStmt {
+	start := p.current().Start
 	p.expect(lexer.TokFor)
 	p.expect(lexer.TokLParen)
 	p.pushScope()
@@ -1840,7 +2264,9 @@ func (p *Parser) parseForStmt() *ast.ForStmt {
 	if p.current().Kind != lexer.TokSemicolon {
 		switch p.current().Kind {
 		case lexer.TokVar, lexer.TokLet:
-			stmt.Init = &ast.DeclStmt{Decl: p.parseDeclaration()}
+			initStart := p.current().Start
+			decl := p.parseDeclaration()
+			stmt.Init = &ast.DeclStmt{Loc: p.locFrom(initStart), Decl: decl}
 		default:
 			stmt.Init = p.parseExpressionOrAssignment()
 		}
@@ -1863,12 +2289,14 @@ func (p *Parser) parseForStmt() *ast.ForStmt {
 	stmt.Body = p.parseCompoundStmt()
 
 	p.popScope()
+	stmt.Loc = p.locFrom(start)
 	return stmt
 }
 
 // parseForUpdateStmt parses the update statement in a for loop.
 // Unlike regular statements, for loop updates don't end with a semicolon.
 func (p *Parser) parseForUpdateStmt() ast.Stmt {
+	start := p.current().Start
 	left := p.parseExpression()
 
 	// Check for assignment
@@ -1900,10 +2328,10 @@ func (p *Parser) parseForUpdateStmt() ast.Stmt {
 		op = ast.AssignOpShr
 	case lexer.TokPlusPlus:
 		p.advance()
-		return &ast.IncrDecrStmt{Expr: left, Increment: true}
+		return &ast.IncrDecrStmt{Loc: p.locFrom(start), Expr: left, Increment: true}
 	case lexer.TokMinusMinus:
 		p.advance()
-		return &ast.IncrDecrStmt{Expr: left, Increment: false}
+		return &ast.IncrDecrStmt{Loc: p.locFrom(start), Expr: left, Increment: false}
 	default:
 		hasAssign = false
 	}
@@ -1911,12 +2339,12 @@ func (p *Parser) parseForUpdateStmt() ast.Stmt {
 	if hasAssign {
 		p.advance()
 		right := p.parseExpression()
-		return &ast.AssignStmt{Op: op, Left: left, Right: right}
+		return &ast.AssignStmt{Loc: p.locFrom(start), Op: op, Left: left, Right: right}
 	}
 
 	// Call expression without semicolon
 	if call, ok := left.(*ast.CallExpr); ok {
-		return &ast.CallStmt{Call: call}
+		return &ast.CallStmt{Loc: p.locFrom(start), Call: call}
 	}
 
 	p.error("expected for loop update statement")
@@ -1924,14 +2352,17 @@ func (p *Parser) parseForUpdateStmt() ast.Stmt {
 }
 
 func (p *Parser) parseWhileStmt() *ast.WhileStmt {
+	start := p.current().Start
 	p.expect(lexer.TokWhile)
 	stmt := &ast.WhileStmt{}
 	stmt.Condition = p.parseExpression()
 	stmt.Body = p.parseCompoundStmt()
+	stmt.Loc = p.locFrom(start)
 	return stmt
 }
 
 func (p *Parser) parseLoopStmt() *ast.LoopStmt {
+	start := p.current().Start
 	p.expect(lexer.TokLoop)
 	stmt := &ast.LoopStmt{}
 	stmt.Body = p.parseCompoundStmt()
@@ -1940,10 +2371,12 @@ func (p *Parser) parseLoopStmt() *ast.LoopStmt {
 		stmt.Continuing = p.parseCompoundStmt()
 	}
 
+	stmt.Loc = p.locFrom(start)
 	return stmt
 }
 
 func (p *Parser) parseExpressionOrAssignment() ast.Stmt {
+	start, errCount := p.current().Start, len(p.errors)
 	left := p.parseExpression()
 
 	// Check for assignment
@@ -1976,11 +2409,11 @@ func (p *Parser) parseExpressionOrAssignment() ast.Stmt {
 	case lexer.TokPlusPlus:
 		p.advance()
 		p.expect(lexer.TokSemicolon)
-		return &ast.IncrDecrStmt{Expr: left, Increment: true}
+		return &ast.IncrDecrStmt{Loc: p.locFrom(start), Expr: left, Increment: true}
 	case lexer.TokMinusMinus:
 		p.advance()
 		p.expect(lexer.TokSemicolon)
-		return &ast.IncrDecrStmt{Expr: left, Increment: false}
+		return &ast.IncrDecrStmt{Loc: p.locFrom(start), Expr: left, Increment: false}
 	default:
 		hasAssign = false
 	}
@@ -1989,15 +2422,17 @@ func (p *Parser) parseExpressionOrAssignment() ast.Stmt {
 		p.advance()
 		right := p.parseExpression()
 		p.expect(lexer.TokSemicolon)
-		return &ast.AssignStmt{Op: op, Left: left, Right: right}
+		return &ast.AssignStmt{Loc: p.locFrom(start), Op: op, Left: left, Right: right}
 	}
 
 	// Call statement
+	call, isCall := left.(*ast.CallExpr)
+	if !isCall && len(p.errors) == errCount && p.current().Kind == lexer.TokSemicolon {
+		p.errorAt(start, p.tokens[p.pos-1].End, "expected statement, got expression")
+	}
 	p.expect(lexer.TokSemicolon)
-	if call, ok := left.(*ast.CallExpr); ok {
-		return &ast.CallStmt{Call: call}
+	if isCall {
+		return &ast.CallStmt{Loc: p.locFrom(start), Call: call}
 	}
-
-	p.error("expected statement")
 	return nil
 }
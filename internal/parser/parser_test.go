@@ -3,6 +3,7 @@ package parser
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/HugoDaniel/miniray/internal/ast"
 	"github.com/HugoDaniel/miniray/internal/lexer"
@@ -563,6 +564,197 @@ func TestInvalidDirective(t *testing.T) {
 	expectNoParse(t, "enable f16, ;")
 }
 
+// ----------------------------------------------------------------------------
+// Error Recovery Tests
+// ----------------------------------------------------------------------------
+
+// expectParseErrors verifies that parsing reports exactly the given errors,
+// each as "line:column message-substring".
+func expectParseErrors(t *testing.T, input string, expected ...string) {
+	t.Helper()
+	t.Run(input+"_errors", func(t *testing.T) {
+		t.Helper()
+		_, errs := New(input).Parse()
+		if len(errs) != len(expected) {
+			t.Fatalf("expected %d errors, got %d: %v", len(expected), len(errs), errs)
+		}
+		for i, want := range expected {
+			loc, msg, _ := strings.Cut(want, " ")
+			got := errs[i].Error()
+			if !strings.HasPrefix(got, loc+": ") || !strings.Contains(got, msg) {
+				t.Errorf("error %d: expected %q, got %q", i, want, got)
+			}
+		}
+	})
+}
+
+func TestStatementRecovery(t *testing.T) {
+	// Each broken statement is reported once and the rest of the body is
+	// still checked
+	expectParseErrors(t, "fn f() {\n  let a = 1 +;\n  let b: = 2;\n  x = );\n  return;\n}",
+		"2:14 expected expression",
+		"3:10 expected type",
+		"4:7 expected expression")
+
+	// Junk is skipped up to the next ';' or statement keyword
+	expectParseErrors(t, "fn f() {\n  foo bar baz;\n  let a = 1 +;\n}",
+		"2:7 expected ;",
+		"3:14 expected expression")
+
+	// Non-call expressions are not statements
+	expectParseErrors(t, "fn f() {\n  a + b;\n  1 +;\n}",
+		"2:3 expected statement",
+		"3:6 expected expression")
+
+	// A statement that ended with '}' is not resynchronized
+	expectParseErrors(t, "fn f() {\n  if (a {\n  }\n  b c;\n}",
+		"2:9 expected )",
+		"4:5 expected ;")
+}
+
+func TestMissingSemicolonRecovery(t *testing.T) {
+	// A ';' missing at the end of a line is reported after the last token and
+	// the next line is parsed normally
+	expectParseErrors(t, "fn f() {\n  var a = 1\n  a = ;\n}",
+		"2:12 expected ; at end of line",
+		"3:7 expected expression")
+	expectParseErrors(t, "const a = 1\nconst b = 2\n",
+		"1:12 expected ; at end of line",
+		"2:12 expected ; at end of line")
+}
+
+func TestDeclarationRecovery(t *testing.T) {
+	// Garbage between declarations is reported
+	expectParseErrors(t, "fn a() {}\ngarbage here;\nfn b() { 1 +; }",
+		"2:1 expected declaration",
+		"3:13 expected expression")
+
+	// Empty global declarations are allowed
+	expectNoError(t, "const a = 1;;\n;fn f() {}")
+
+	// A missing '}' ends the body at the next function
+	expectParseErrors(t, "fn a() {\n  let x = 1;\nfn b() {\n  let y = ;\n}",
+		"3:1 expected }",
+		"4:11 expected expression")
+
+	// A bad switch clause skips to the next clause
+	expectParseErrors(t, "fn f() {\n  switch (x) {\n    oops\n    default: {}\n  }\n  1 +;\n}",
+		"3:5 expected case or default",
+		"6:6 expected expression")
+}
+
+func TestErrorRanges(t *testing.T) {
+	_, errs := New("fn f() {\n  let a = 1 + foo bar;\n}").Parse()
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	e := errs[0]
+	if e.Line != 2 || e.Column != 19 || e.EndLine != 2 || e.EndColumn != 22 {
+		t.Errorf("expected range 2:19-2:22, got %d:%d-%d:%d", e.Line, e.Column, e.EndLine, e.EndColumn)
+	}
+	if e.End-e.Pos != len("bar") {
+		t.Errorf("expected error to cover 'bar', got [%d, %d)", e.Pos, e.End)
+	}
+}
+
+func TestErrorNodes(t *testing.T) {
+	module, errs := New("fn f() {\n  let a = ;\n  foo bar;\n  return;\n}").Parse()
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	fn := module.Declarations[0].(*ast.FunctionDecl)
+	if len(fn.Body.Stmts) != 3 {
+		t.Fatalf("expected 3 statements, got %d", len(fn.Body.Stmts))
+	}
+	let := fn.Body.Stmts[0].(*ast.DeclStmt).Decl.(*ast.LetDecl)
+	if _, ok := let.Initializer.(*ast.ErrorExpr); !ok {
+		t.Errorf("expected ErrorExpr initializer, got %T", let.Initializer)
+	}
+	if _, ok := fn.Body.Stmts[1].(*ast.ErrorStmt); !ok {
+		t.Errorf("expected ErrorStmt, got %T", fn.Body.Stmts[1])
+	}
+	if _, ok := fn.Body.Stmts[2].(*ast.ReturnStmt); !ok {
+		t.Errorf("expected ReturnStmt after recovery, got %T", fn.Body.Stmts[2])
+	}
+}
+
+func TestRecoveryTerminates(t *testing.T) {
+	// Every prefix of a valid shader must parse without hanging or panicking
+	source := `struct V { @location(0) uv: vec2f, @builtin(position) pos: vec4f, }
+@group(0) @binding(0) var<uniform> u: array<vec4f, 4>;
+@vertex fn main(@builtin(vertex_index) i: u32) -> V {
+  var out: V;
+  for (var j = 0u; j < i; j++) { if (j == 2u) { break; } else { continue; } }
+  switch (i) { case 0u, 1u: { out.uv = vec2f(0.0); } default: {} }
+  loop { continuing { break if i > 3u; } }
+  out.pos = u[i % 4u] * vec4<f32>(1.0);
+  return out;
+}`
+	for i := range source {
+		New(source[:i]).Parse()
+		New(source[i:]).Parse()
+	}
+}
+
+func TestRecoveryMakesProgress(t *testing.T) {
+	// A token no rule consumes, reported at the position of the previous
+	// error, must still be skipped
+	for _, tt := range []struct {
+		input   string
+		wantErr bool
+	}{
+		{"@diagnostic(off, derivative_uniformity) @fragment fn main() {}", false},
+		{"@enable fn f(){}", true},
+		{"@requires fn f(){}", true},
+		{"fn f() { enable x; }", true},
+		{"fn f() { @diagnostic(off,x) { } }", true},
+	} {
+		done := make(chan []ParseError, 1)
+		go func() {
+			_, errs := New(tt.input).Parse()
+			done <- errs
+		}()
+		select {
+		case errs := <-done:
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("%q: wantErr %v, got %v", tt.input, tt.wantErr, errs)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q: parser did not terminate", tt.input)
+		}
+	}
+}
+
+func TestDiagnosticAttribute(t *testing.T) {
+	expectPrinted(t, "@diagnostic(off, derivative_uniformity) @fragment fn main() {}",
+		"@diagnostic(off, derivative_uniformity) @fragment fn main() {\n}\n")
+}
+
+func TestLosslessMode(t *testing.T) {
+	source := "// doc\nconst a = 1; // one\n"
+
+	module, _ := New(source).Parse()
+	if module.Tokens != nil {
+		t.Error("expected no token stream without lossless mode")
+	}
+
+	module, errs := NewWithOptions(source, Options{Lossless: true}).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	if len(module.Tokens) != 6 {
+		t.Fatalf("expected 6 tokens, got %d", len(module.Tokens))
+	}
+	if first := module.Tokens[0]; len(first.Leading) != 2 || first.Leading[0].Text(source) != "// doc" {
+		t.Errorf("expected doc comment in leading trivia, got %v", first.Leading)
+	}
+
+	decl := module.Declarations[0].(*ast.ConstDecl)
+	if got := source[decl.Loc.Start:decl.Loc.End]; got != "const a = 1;" {
+		t.Errorf("declaration range: got %q", got)
+	}
+}
+
 // ----------------------------------------------------------------------------
 // ParseError Tests
 // ----------------------------------------------------------------------------
@@ -1317,6 +1509,38 @@ func TestSwitchOnlyDefault(t *testing.T) {
 		"fn foo() {\n    switch x {\n        default: {\n        }\n    }\n}\n")
 }
 
+func TestConstValues(t *testing.T) {
+	// Module-scope constants may be used before they are declared
+	source := `const total = base + 0x10;
+const base = 4u;
+const scale = 1.5f;
+const on = total > 16 && scale < 2.0;
+fn f() { const local = total * 2; }`
+	p := New(source)
+	module, errs := p.Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	values := ConstValues(module)
+	want := map[string]ConstValue{
+		"total": {Kind: ConstInt, Int: 20},
+		"base":  {Kind: ConstInt, Int: 4},
+		"scale": {Kind: ConstFloat, Float: 1.5},
+		"on":    {Kind: ConstBool, Bool: true},
+		"local": {Kind: ConstInt, Int: 40},
+	}
+	for i, sym := range module.Symbols {
+		expected, ok := want[sym.OriginalName]
+		if !ok {
+			continue
+		}
+		if got := values[ast.Ref{InnerIndex: uint32(i)}]; got != expected {
+			t.Errorf("%s: expected %+v, got %+v", sym.OriginalName, expected, got)
+		}
+	}
+}
+
 // ----------------------------------------------------------------------------
 // Templated Constructor Edge Cases
 // ----------------------------------------------------------------------------
@@ -1544,6 +1768,38 @@ func TestConstExprWithNonConstOperand(t *testing.T) {
 	expectNoError(t, "var x: i32; const a = -x;")
 }
 
+func TestConstValuesOfModule(t *testing.T) {
+	// Module-scope constants may be used before they are declared
+	source := `const total = base + 0x10;
+const base = 4u;
+const scale = 1.5f;
+const on = total > 16 && scale < 2.0;
+fn f() { const local = total * 2; }`
+	p := New(source)
+	module, errs := p.Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+
+	values := ConstValues(module)
+	want := map[string]ConstValue{
+		"total": {Kind: ConstInt, Int: 20},
+		"base":  {Kind: ConstInt, Int: 4},
+		"scale": {Kind: ConstFloat, Float: 1.5},
+		"on":    {Kind: ConstBool, Bool: true},
+		"local": {Kind: ConstInt, Int: 40},
+	}
+	for i, sym := range module.Symbols {
+		expected, ok := want[sym.OriginalName]
+		if !ok {
+			continue
+		}
+		if got := values[ast.Ref{InnerIndex: uint32(i)}]; got != expected {
+			t.Errorf("%s: expected %+v, got %+v", sym.OriginalName, expected, got)
+		}
+	}
+}
+
 // ----------------------------------------------------------------------------
 // Templated Constructor Edge Case
 // ----------------------------------------------------------------------------
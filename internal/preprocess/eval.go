@@ -0,0 +1,277 @@
+package preprocess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxExpansionDepth bounds the expansion of names inside #if expressions.
+const maxExpansionDepth = 32
+
+// evaluate computes the value of an #if expression.
+func evaluate(expr string, defines map[string]string) (int64, error) {
+	e := &evaluator{defines: defines}
+	if err := e.tokenize(expr, 0); err != nil {
+		return 0, err
+	}
+	if len(e.tokens) == 0 {
+		return 0, fmt.Errorf("empty expression")
+	}
+	value, err := e.parseBinary(0)
+	if err != nil {
+		return 0, err
+	}
+	if e.pos < len(e.tokens) {
+		return 0, fmt.Errorf("unexpected %q", e.tokens[e.pos])
+	}
+	return value, nil
+}
+
+type evaluator struct {
+	defines map[string]string
+	tokens  []string
+	pos     int
+}
+
+// tokenize splits expr into tokens, replacing defined names by the tokens
+// of their values. defined(NAME) is resolved here, before expansion.
+func (e *evaluator) tokenize(expr string, depth int) error {
+	if depth > maxExpansionDepth {
+		return fmt.Errorf("macro expansion too deep")
+	}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case isDigit(c):
+			j := skipNumber(expr, i)
+			e.tokens = append(e.tokens, expr[i:j])
+			i = j
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(expr) && isIdentPart(expr[j]) {
+				j++
+			}
+			name := expr[i:j]
+			i = j
+			if name == "defined" {
+				end, err := e.defined(expr, i)
+				if err != nil {
+					return err
+				}
+				i = end
+				continue
+			}
+			if value, ok := e.defines[name]; ok {
+				if strings.TrimSpace(value) == "" {
+					value = "1"
+				}
+				if err := e.tokenize(value, depth+1); err != nil {
+					return err
+				}
+				continue
+			}
+			e.tokens = append(e.tokens, name)
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<<", ">>"} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("()!~+-*/%<>&|^", rune(c)) {
+					return fmt.Errorf("unexpected character %q", c)
+				}
+				op = string(c)
+			}
+			e.tokens = append(e.tokens, op)
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+// defined parses the operand of defined, with or without parentheses,
+// starting at i. It appends "1" or "0" and returns the end offset.
+func (e *evaluator) defined(expr string, i int) (int, error) {
+	rest := strings.TrimLeft(expr[i:], " \t")
+	i = len(expr) - len(rest)
+	paren := strings.HasPrefix(rest, "(")
+	if paren {
+		rest = strings.TrimLeft(rest[1:], " \t")
+		i = len(expr) - len(rest)
+	}
+	j := 0
+	for j < len(rest) && isIdentPart(rest[j]) {
+		j++
+	}
+	if !isIdentifier(rest[:j]) {
+		return 0, fmt.Errorf("defined expects a name")
+	}
+	_, ok := e.defines[rest[:j]]
+	i += j
+	if paren {
+		rest = strings.TrimLeft(expr[i:], " \t")
+		if !strings.HasPrefix(rest, ")") {
+			return 0, fmt.Errorf("missing ) after defined")
+		}
+		i = len(expr) - len(rest) + 1
+	}
+	if ok {
+		e.tokens = append(e.tokens, "1")
+	} else {
+		e.tokens = append(e.tokens, "0")
+	}
+	return i, nil
+}
+
+// binaryPrecedence follows C; higher binds tighter.
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, ">": 7, "<=": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+}
+
+func (e *evaluator) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos]
+	}
+	return ""
+}
+
+// parseBinary parses operators binding tighter than minPrec by precedence
+// climbing.
+func (e *evaluator) parseBinary(minPrec int) (int64, error) {
+	left, err := e.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := e.peek()
+		prec, ok := binaryPrecedence[op]
+		if !ok || prec <= minPrec {
+			return left, nil
+		}
+		e.pos++
+		right, err := e.parseBinary(prec)
+		if err != nil {
+			return 0, err
+		}
+		if left, err = apply(op, left, right); err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (e *evaluator) parseUnary() (int64, error) {
+	tok := e.peek()
+	if tok == "" {
+		return 0, fmt.Errorf("unexpected end of expression")
+	}
+	e.pos++
+	switch tok {
+	case "!", "-", "+", "~":
+		v, err := e.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch tok {
+		case "!":
+			return boolValue(v == 0), nil
+		case "-":
+			return -v, nil
+		case "~":
+			return ^v, nil
+		}
+		return v, nil
+	case "(":
+		v, err := e.parseBinary(0)
+		if err != nil {
+			return 0, err
+		}
+		if e.peek() != ")" {
+			return 0, fmt.Errorf("missing )")
+		}
+		e.pos++
+		return v, nil
+	case "true":
+		return 1, nil
+	case "false":
+		return 0, nil
+	}
+	if isDigit(tok[0]) {
+		v, err := strconv.ParseInt(strings.TrimRight(tok, "iu"), 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid integer %q", tok)
+		}
+		return v, nil
+	}
+	if isIdentifier(tok) {
+		return 0, nil // undefined names are 0
+	}
+	return 0, fmt.Errorf("unexpected %q", tok)
+}
+
+func apply(op string, a, b int64) (int64, error) {
+	switch op {
+	case "||":
+		return boolValue(a != 0 || b != 0), nil
+	case "&&":
+		return boolValue(a != 0 && b != 0), nil
+	case "|":
+		return a | b, nil
+	case "^":
+		return a ^ b, nil
+	case "&":
+		return a & b, nil
+	case "==":
+		return boolValue(a == b), nil
+	case "!=":
+		return boolValue(a != b), nil
+	case "<":
+		return boolValue(a < b), nil
+	case ">":
+		return boolValue(a > b), nil
+	case "<=":
+		return boolValue(a <= b), nil
+	case ">=":
+		return boolValue(a >= b), nil
+	case "<<":
+		return a << uint64(b&63), nil
+	case ">>":
+		return a >> uint64(b&63), nil
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/", "%":
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		if op == "/" {
+			return a / b, nil
+		}
+		return a % b, nil
+	}
+	return 0, fmt.Errorf("unknown operator %q", op)
+}
+
+func boolValue(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
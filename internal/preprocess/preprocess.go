@@ -0,0 +1,490 @@
+// Package preprocess implements a C-like preprocessor for WGSL feature
+// variants.
+//
+// Supported directives, each on its own line:
+//
+//	#define NAME [value]
+//	#undef NAME
+//	#ifdef NAME / #ifndef NAME
+//	#if expr / #elif expr
+//	#else
+//	#endif
+//
+// Defined names are replaced in active code by their value. Replacement is
+// WGSL-aware: comments, member names after '.' and attribute names after
+// '@' are left alone. #if expressions support integer literals, true and
+// false, defined(NAME), parentheses and the C arithmetic, comparison and
+// logical operators. Undefined names evaluate to 0 and names defined
+// without a value to 1.
+//
+// The output keeps the line structure of the input: directive lines and
+// lines in inactive branches become empty. Result.OriginalOffset maps
+// positions in the output back to the input, which lets source maps and
+// diagnostics point at the unpreprocessed file.
+package preprocess
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/sourcemap"
+)
+
+// Error is a preprocessing error at a 1-based line and column of the input.
+type Error struct {
+	Message string
+	Line    int
+	Column  int
+}
+
+// Result is the output of Process.
+type Result struct {
+	// Code is the preprocessed source.
+	Code string
+
+	// Source is the input.
+	Source string
+
+	// Errors lists malformed directives and unbalanced conditionals.
+	Errors []Error
+
+	// segments map ranges of Code to Source, sorted by output offset. Each
+	// segment runs until the next one.
+	segments []segment
+
+	lineIndex *sourcemap.LineIndex
+}
+
+type segment struct {
+	out, orig int
+	// replaced marks macro expansions, which all map to the start of the
+	// replaced name
+	replaced bool
+}
+
+// HasDirectives reports whether source contains a preprocessor directive.
+func HasDirectives(source string) bool {
+	for _, line := range strings.Split(source, "\n") {
+		if strings.HasPrefix(strings.TrimLeft(line, " \t"), "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// Identity returns the result for source that needs no preprocessing, such
+// as a module that was parsed directly.
+func Identity(source string) *Result {
+	return &Result{Code: source, Source: source}
+}
+
+// Process preprocesses source with the given initial defines.
+func Process(source string, defines map[string]string) *Result {
+	r := Identity(source)
+	if len(defines) == 0 && !HasDirectives(source) {
+		return r
+	}
+
+	p := &preprocessor{
+		result:  r,
+		defines: make(map[string]string, len(defines)),
+	}
+	for name, value := range defines {
+		p.defines[name] = value
+	}
+	p.run()
+	return r
+}
+
+// OriginalOffset converts a byte offset in Code to the corresponding
+// offset in Source.
+func (r *Result) OriginalOffset(offset int) int {
+	if len(r.segments) == 0 {
+		return offset
+	}
+	i := sort.Search(len(r.segments), func(i int) bool {
+		return r.segments[i].out > offset
+	}) - 1
+	if i < 0 {
+		return offset
+	}
+	s := r.segments[i]
+	if s.replaced {
+		return s.orig
+	}
+	return s.orig + offset - s.out
+}
+
+// Position converts a byte offset in Code to a 1-based line and column in
+// Source.
+func (r *Result) Position(offset int) (line, col int) {
+	if r.lineIndex == nil {
+		r.lineIndex = sourcemap.NewLineIndex(r.Source)
+	}
+	line, col = r.lineIndex.ByteOffsetToLineColumn(r.OriginalOffset(offset))
+	return line + 1, col + 1
+}
+
+// conditional is an open #if/#ifdef group.
+type conditional struct {
+	line int
+	// parentActive is whether the enclosing code is active
+	parentActive bool
+	// active is whether the current branch is being emitted
+	active bool
+	// taken is whether any branch so far was taken
+	taken   bool
+	sawElse bool
+}
+
+type preprocessor struct {
+	result  *Result
+	defines map[string]string
+	stack   []conditional
+	out     strings.Builder
+
+	// inComment tracks block comment nesting across lines
+	inComment int
+}
+
+func (p *preprocessor) active() bool {
+	return len(p.stack) == 0 || p.stack[len(p.stack)-1].active
+}
+
+func (p *preprocessor) errorf(line, col int, format string, args ...interface{}) {
+	p.result.Errors = append(p.result.Errors, Error{
+		Message: fmt.Sprintf(format, args...),
+		Line:    line,
+		Column:  col,
+	})
+}
+
+func (p *preprocessor) run() {
+	source := p.result.Source
+	lineNum := 0
+	for start := 0; start < len(source); {
+		lineNum++
+		end := strings.IndexByte(source[start:], '\n')
+		if end < 0 {
+			end = len(source)
+		} else {
+			end += start + 1
+		}
+		line := source[start:end]
+		content := strings.TrimRight(line, "\r\n")
+		terminator := line[len(content):]
+
+		trimmed := strings.TrimLeft(content, " \t")
+		if p.inComment == 0 && strings.HasPrefix(trimmed, "#") {
+			col := len(content) - len(trimmed) + 1
+			p.directive(trimmed[1:], lineNum, col)
+			p.emitRaw(terminator, end-len(terminator))
+		} else if p.active() {
+			p.emitLine(content, start)
+			p.emitRaw(terminator, end-len(terminator))
+		} else {
+			p.skipLine(content)
+			p.emitRaw(terminator, end-len(terminator))
+		}
+		start = end
+	}
+
+	for _, c := range p.stack {
+		p.errorf(c.line, 1, "unterminated conditional directive")
+	}
+	p.result.Code = p.out.String()
+}
+
+// emitRaw copies text that starts at orig in the source.
+func (p *preprocessor) emitRaw(text string, orig int) {
+	if text == "" {
+		return
+	}
+	p.mark(orig, false)
+	p.out.WriteString(text)
+}
+
+// mark starts a new segment at the current output offset.
+func (p *preprocessor) mark(orig int, replaced bool) {
+	seg := segment{out: p.out.Len(), orig: orig, replaced: replaced}
+	segs := p.result.segments
+	if n := len(segs); n > 0 {
+		last := segs[n-1]
+		if last.out == seg.out {
+			segs[n-1] = seg
+			return
+		}
+		if !last.replaced && !replaced && last.orig+seg.out-last.out == seg.orig {
+			return // continues the previous segment
+		}
+	}
+	p.result.segments = append(segs, seg)
+}
+
+// emitLine copies an active line starting at offset orig, expanding
+// defined names outside comments.
+func (p *preprocessor) emitLine(line string, orig int) {
+	copied := 0
+	i := 0
+	for i < len(line) {
+		if p.inComment > 0 {
+			i = p.scanComment(line, i)
+			continue
+		}
+		c := line[i]
+		switch {
+		case c == '/' && i+1 < len(line) && line[i+1] == '/':
+			i = len(line)
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			p.inComment++
+			i += 2
+		case isDigit(c):
+			i = skipNumber(line, i)
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(line) && isIdentPart(line[j]) {
+				j++
+			}
+			name := line[i:j]
+			value, ok := p.defines[name]
+			if ok && !isMemberOrAttribute(line, i) {
+				p.emitRaw(line[copied:i], orig+copied)
+				p.mark(orig+i, true)
+				p.out.WriteString(p.expand(value, map[string]bool{name: true}))
+				copied = j
+			}
+			i = j
+		default:
+			i++
+		}
+	}
+	p.emitRaw(line[copied:], orig+copied)
+}
+
+// skipLine tracks comments on a line in an inactive branch.
+func (p *preprocessor) skipLine(line string) {
+	for i := 0; i < len(line); {
+		if p.inComment > 0 {
+			i = p.scanComment(line, i)
+			continue
+		}
+		if strings.HasPrefix(line[i:], "//") {
+			return
+		}
+		if strings.HasPrefix(line[i:], "/*") {
+			p.inComment++
+			i += 2
+			continue
+		}
+		i++
+	}
+}
+
+// scanComment advances through a block comment from i, handling nesting.
+func (p *preprocessor) scanComment(line string, i int) int {
+	switch {
+	case strings.HasPrefix(line[i:], "*/"):
+		p.inComment--
+		return i + 2
+	case strings.HasPrefix(line[i:], "/*"):
+		p.inComment++
+		return i + 2
+	}
+	return i + 1
+}
+
+// expand replaces defined names in a macro value. seen holds the names
+// being expanded, which are not replaced again.
+func (p *preprocessor) expand(value string, seen map[string]bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		if isDigit(value[i]) {
+			j := skipNumber(value, i)
+			b.WriteString(value[i:j])
+			i = j
+			continue
+		}
+		if !isIdentStart(value[i]) {
+			b.WriteByte(value[i])
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(value) && isIdentPart(value[j]) {
+			j++
+		}
+		name := value[i:j]
+		if inner, ok := p.defines[name]; ok && !seen[name] && !isMemberOrAttribute(value, i) {
+			seen[name] = true
+			b.WriteString(p.expand(inner, seen))
+			delete(seen, name)
+		} else {
+			b.WriteString(name)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+func (p *preprocessor) directive(text string, line, col int) {
+	name, rest := splitWord(text)
+	rest = stripComment(rest)
+
+	switch name {
+	case "if", "ifdef", "ifndef":
+		c := conditional{line: line, parentActive: p.active()}
+		if c.parentActive {
+			switch name {
+			case "if":
+				c.active = p.evaluate(rest, line, col)
+			case "ifdef", "ifndef":
+				ident := p.directiveName(name, rest, line, col)
+				_, defined := p.defines[ident]
+				c.active = defined == (name == "ifdef")
+			}
+			c.taken = c.active
+		}
+		p.stack = append(p.stack, c)
+
+	case "elif", "else":
+		if len(p.stack) == 0 {
+			p.errorf(line, col, "#%s without #if", name)
+			return
+		}
+		c := &p.stack[len(p.stack)-1]
+		if c.sawElse {
+			p.errorf(line, col, "#%s after #else", name)
+			return
+		}
+		if name == "else" {
+			c.sawElse = true
+			c.active = c.parentActive && !c.taken
+		} else {
+			c.active = c.parentActive && !c.taken && p.evaluate(rest, line, col)
+		}
+		c.taken = c.taken || c.active
+
+	case "endif":
+		if len(p.stack) == 0 {
+			p.errorf(line, col, "#endif without #if")
+			return
+		}
+		p.stack = p.stack[:len(p.stack)-1]
+
+	case "define":
+		if !p.active() {
+			return
+		}
+		ident, value := splitWord(rest)
+		if !isIdentifier(ident) {
+			p.errorf(line, col, "#define expects a name")
+			return
+		}
+		p.defines[ident] = strings.TrimSpace(value)
+
+	case "undef":
+		if !p.active() {
+			return
+		}
+		delete(p.defines, p.directiveName(name, rest, line, col))
+
+	default:
+		// Unknown directives in skipped branches are ignored, as in C
+		if p.active() {
+			p.errorf(line, col, "unknown preprocessor directive #%s", name)
+		}
+	}
+}
+
+// directiveName returns the single name argument of a directive.
+func (p *preprocessor) directiveName(directive, rest string, line, col int) string {
+	ident, extra := splitWord(rest)
+	if !isIdentifier(ident) || strings.TrimSpace(extra) != "" {
+		p.errorf(line, col, "#%s expects a single name", directive)
+	}
+	return ident
+}
+
+func (p *preprocessor) evaluate(expr string, line, col int) bool {
+	value, err := evaluate(expr, p.defines)
+	if err != nil {
+		p.errorf(line, col, "invalid #if expression: %s", err)
+		return false
+	}
+	return value != 0
+}
+
+// ----------------------------------------------------------------------------
+// Helpers
+// ----------------------------------------------------------------------------
+
+func splitWord(s string) (word, rest string) {
+	s = strings.TrimLeft(s, " \t")
+	i := 0
+	for i < len(s) && s[i] != ' ' && s[i] != '\t' && s[i] != '(' {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// stripComment removes a trailing line or block comment from a directive.
+func stripComment(s string) string {
+	if i := strings.Index(s, "//"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.Index(s, "/*"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// isMemberOrAttribute reports whether the identifier at i follows '.' or
+// '@', ignoring whitespace.
+func isMemberOrAttribute(s string, i int) bool {
+	for i--; i >= 0; i-- {
+		switch s[i] {
+		case ' ', '\t':
+			continue
+		case '.', '@':
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+func isIdentifier(s string) bool {
+	if s == "" || !isIdentStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentPart(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentStart accepts any non-ASCII byte so that Unicode identifiers are
+// kept whole.
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// skipNumber returns the end of the numeric literal starting at i, so that
+// suffixes and hex digits are not mistaken for names.
+func skipNumber(s string, i int) int {
+	for i < len(s) && (isIdentPart(s[i]) || s[i] == '.') {
+		i++
+	}
+	return i
+}
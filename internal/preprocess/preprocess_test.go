@@ -0,0 +1,171 @@
+package preprocess
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConditionals(t *testing.T) {
+	source := `#ifdef SHADOWS
+shadow
+#else
+noshadow
+#endif
+#ifndef SKINNING
+static
+#endif
+#if MSAA >= 4 && !defined(LOW)
+msaa
+#elif MSAA
+some_msaa
+#else
+no_msaa
+#endif
+`
+	tests := []struct {
+		defines  map[string]string
+		expected []string
+	}{
+		{nil, []string{"noshadow", "static", "no_msaa"}},
+		{map[string]string{"SHADOWS": ""}, []string{"shadow", "static", "no_msaa"}},
+		{map[string]string{"SKINNING": "1", "MSAA": "4"}, []string{"noshadow", "msaa"}},
+		{map[string]string{"MSAA": "4", "LOW": ""}, []string{"noshadow", "static", "some_msaa"}},
+		{map[string]string{"MSAA": "2"}, []string{"noshadow", "static", "some_msaa"}},
+	}
+
+	for _, tt := range tests {
+		r := Process(source, tt.defines)
+		if len(r.Errors) > 0 {
+			t.Fatalf("unexpected errors: %v", r.Errors)
+		}
+		got := strings.Fields(r.Code)
+		if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+			t.Errorf("defines %v: expected %v, got %v", tt.defines, tt.expected, got)
+		}
+		if strings.Count(r.Code, "\n") != strings.Count(source, "\n") {
+			t.Errorf("defines %v: line count changed", tt.defines)
+		}
+	}
+}
+
+func TestNestedConditionals(t *testing.T) {
+	source := "#if A\n#if B\nab\n#else\na\n#endif\n#else\n#if B\nb\n#endif\nnone\n#endif\n"
+	tests := map[string]map[string]string{
+		"ab":     {"A": "1", "B": "1"},
+		"a":      {"A": "1"},
+		"b none": {"B": "1"},
+		"none":   {},
+	}
+	for expected, defines := range tests {
+		r := Process(source, defines)
+		if got := strings.Join(strings.Fields(r.Code), " "); got != expected {
+			t.Errorf("defines %v: expected %q, got %q", defines, expected, got)
+		}
+	}
+}
+
+func TestSubstitution(t *testing.T) {
+	source := `#define COUNT 4u
+#define SCALE (COUNT * 2u)
+@group(0) @binding(0) var<uniform> u: array<vec4f, COUNT>; // COUNT
+fn f(v: vec4f) -> f32 {
+    /* SCALE */ let s = SCALE + 0x1COUNT;
+    return v.COUNT + f32(s);
+}
+`
+	r := Process(source, map[string]string{"group": "nope"})
+	if len(r.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	for _, want := range []string{
+		"array<vec4f, 4u>; // COUNT",
+		"/* SCALE */ let s = (4u * 2u) + 0x1COUNT;",
+		"v.COUNT",
+		"@group(0)",
+	} {
+		if !strings.Contains(r.Code, want) {
+			t.Errorf("expected %q in output:\n%s", want, r.Code)
+		}
+	}
+}
+
+func TestRecursiveDefine(t *testing.T) {
+	r := Process("#define A B\n#define B A\nlet x = A;\n", nil)
+	if !strings.Contains(r.Code, "let x = A;") {
+		t.Errorf("expected self-reference to stop expansion, got %q", r.Code)
+	}
+}
+
+func TestDefineAndUndef(t *testing.T) {
+	source := "#define X 1\nlet a = X;\n#undef X\nlet b = X;\n#if 0\n#define Y 2\n#endif\nlet c = Y;\n"
+	r := Process(source, nil)
+	got := strings.Join(strings.Fields(r.Code), " ")
+	if got != "let a = 1; let b = X; let c = Y;" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestDirectivesInComments(t *testing.T) {
+	source := "/*\n#if 0\n*/\nlet a = 1;\n"
+	r := Process(source, map[string]string{"DUMMY": "1"})
+	if len(r.Errors) > 0 || r.Code != source {
+		t.Errorf("expected directives in comments to be ignored, got %q %v", r.Code, r.Errors)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		message string
+		line    int
+	}{
+		{"#if 1\nlet a = 1;\n", "unterminated conditional directive", 1},
+		{"let a = 1;\n#endif\n", "#endif without #if", 2},
+		{"#if 1\n#else\n#else\n#endif\n", "#else after #else", 3},
+		{"#if (1\n#endif\n", "invalid #if expression: missing )", 1},
+		{"#if 1 / 0\n#endif\n", "invalid #if expression: division by zero", 1},
+		{"  #include \"x.wgsl\"\n", "unknown preprocessor directive #include", 1},
+		{"#define\n", "#define expects a name", 1},
+		{"#ifdef A B\n#endif\n", "#ifdef expects a single name", 1},
+	}
+	for _, tt := range tests {
+		r := Process(tt.source, nil)
+		if len(r.Errors) != 1 {
+			t.Errorf("%q: expected one error, got %v", tt.source, r.Errors)
+			continue
+		}
+		if r.Errors[0].Message != tt.message || r.Errors[0].Line != tt.line {
+			t.Errorf("%q: expected %q on line %d, got %q on line %d", tt.source, tt.message, tt.line, r.Errors[0].Message, r.Errors[0].Line)
+		}
+	}
+}
+
+func TestOriginalOffset(t *testing.T) {
+	source := "#define N 16\nlet a = N + b;\n"
+	r := Process(source, nil)
+	if r.Code != "\nlet a = 16 + b;\n" {
+		t.Fatalf("unexpected output %q", r.Code)
+	}
+
+	orig := func(s string) int { return strings.Index(source, s) }
+	out := func(s string) int { return strings.Index(r.Code, s) }
+	checks := []struct {
+		out, orig int
+	}{
+		{out("let"), orig("let")},
+		{out("16"), orig("N +")},
+		{out("16") + 1, orig("N +")},
+		{out("+ b"), orig("+ b")},
+		{out("b;"), orig("b;")},
+	}
+	for _, c := range checks {
+		if got := r.OriginalOffset(c.out); got != c.orig {
+			t.Errorf("OriginalOffset(%d) = %d, want %d", c.out, got, c.orig)
+		}
+	}
+
+	plain := Process("let a = 1;", nil)
+	if plain.Code != "let a = 1;" || plain.OriginalOffset(4) != 4 {
+		t.Error("expected identity without directives")
+	}
+}
@@ -9,11 +9,15 @@
 package printer
 
 import (
+	"math"
+	"strconv"
 	"strings"
 
 	"github.com/HugoDaniel/miniray/internal/ast"
 	"github.com/HugoDaniel/miniray/internal/dce"
+	"github.com/HugoDaniel/miniray/internal/lexer"
 	"github.com/HugoDaniel/miniray/internal/sourcemap"
+	"github.com/HugoDaniel/miniray/internal/types"
 )
 
 // Options controls printer output.
@@ -35,6 +39,11 @@ type Options struct {
 
 	// SourceMapGen is the source map generator (nil to disable)
 	SourceMapGen *sourcemap.Generator
+
+	// Types holds the resolved type of each expression, as computed by the
+	// validator. MinifySyntax only drops a constructor template when these
+	// show that the inferred type stays the same (nil to keep templates).
+	Types map[ast.Expr]types.Type
 }
 
 // Renamer provides minified names for symbols.
@@ -73,6 +82,27 @@ func (p *Printer) Print(module *ast.Module) string {
 	return p.buf.String()
 }
 
+// PrintExpr outputs a single expression as a string.
+func (p *Printer) PrintExpr(e ast.Expr) string {
+	p.buf.Reset()
+	p.printExpr(e)
+	return p.buf.String()
+}
+
+// PrintType outputs a single type as a string.
+func (p *Printer) PrintType(t ast.Type) string {
+	p.buf.Reset()
+	p.printType(t)
+	return p.buf.String()
+}
+
+// PrintDecl outputs a single declaration as a string.
+func (p *Printer) PrintDecl(d ast.Decl) string {
+	p.buf.Reset()
+	p.printDecl(d)
+	return p.buf.String()
+}
+
 // ----------------------------------------------------------------------------
 // Output Helpers
 // ----------------------------------------------------------------------------
@@ -125,30 +155,82 @@ func (p *Printer) printSemicolon() {
 }
 
 func (p *Printer) printName(ref ast.Ref) {
+	p.printNameAt(ref, ast.Loc{})
+}
+
+// printNameAt prints the name of ref for an occurrence at loc in the source.
+// Source maps point the occurrence at loc, or at the symbol's declaration
+// when loc is empty (as for declarations and synthesized nodes), and record
+// the original name of every renamed occurrence.
+func (p *Printer) printNameAt(ref ast.Ref, loc ast.Loc) {
 	if !ref.IsValid() || int(ref.InnerIndex) >= len(p.symbols) {
 		return
 	}
 
 	sym := &p.symbols[ref.InnerIndex]
+	name := p.nameFor(ref)
 
 	// Record source map mapping before printing
 	if p.options.SourceMapGen != nil {
-		// Determine if we need to record the original name
 		originalName := ""
-		if p.options.MinifyIdentifiers && p.options.Renamer != nil {
-			renamedName := p.options.Renamer.NameForSymbol(ref)
-			if renamedName != sym.OriginalName {
-				originalName = sym.OriginalName
-			}
+		if name != sym.OriginalName {
+			originalName = sym.OriginalName
 		}
-		p.options.SourceMapGen.AddMapping(p.outputLine, p.outputCol, int(sym.Loc.Start), originalName)
+		srcOffset := int(sym.Loc.Start)
+		if loc.End > loc.Start {
+			srcOffset = int(loc.Start)
+		}
+		p.options.SourceMapGen.AddMapping(p.outputLine, p.outputCol, srcOffset, originalName)
+	}
+
+	p.print(name)
+}
+
+// printDeclName prints the name a declaration introduces, recording it as a
+// binding of the current source map scope.
+func (p *Printer) printDeclName(ref ast.Ref) {
+	p.addBinding(ref)
+	p.printName(ref)
+}
+
+func (p *Printer) addBinding(ref ast.Ref) {
+	if p.scopesEnabled() && ref.IsValid() && int(ref.InnerIndex) < len(p.symbols) {
+		p.options.SourceMapGen.AddBinding(p.symbols[ref.InnerIndex].OriginalName, p.nameFor(ref))
 	}
+}
 
-	// Print the name
+// nameFor returns the name ref is printed with.
+func (p *Printer) nameFor(ref ast.Ref) string {
 	if p.options.MinifyIdentifiers && p.options.Renamer != nil {
-		p.print(p.options.Renamer.NameForSymbol(ref))
-	} else {
-		p.print(sym.OriginalName)
+		return p.options.Renamer.NameForSymbol(ref)
+	}
+	return p.symbols[ref.InnerIndex].OriginalName
+}
+
+// symbolName returns the original name of ref.
+func (p *Printer) symbolName(ref ast.Ref) string {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(p.symbols) {
+		return ""
+	}
+	return p.symbols[ref.InnerIndex].OriginalName
+}
+
+func (p *Printer) scopesEnabled() bool {
+	return p.options.SourceMapGen != nil && p.options.SourceMapGen.ScopesEnabled()
+}
+
+// enterScope opens a source map scope for the source range loc, starting
+// at the current output position.
+func (p *Printer) enterScope(kind, name string, loc ast.Loc) {
+	if p.scopesEnabled() {
+		p.options.SourceMapGen.EnterScope(kind, name, int(loc.Start), int(loc.End), p.outputLine, p.outputCol)
+	}
+}
+
+// exitScope closes the current source map scope at the output position.
+func (p *Printer) exitScope() {
+	if p.scopesEnabled() {
+		p.options.SourceMapGen.ExitScope(p.outputLine, p.outputCol)
 	}
 }
 
@@ -157,6 +239,9 @@ func (p *Printer) printName(ref ast.Ref) {
 // ----------------------------------------------------------------------------
 
 func (p *Printer) printModule(m *ast.Module) {
+	p.enterScope(sourcemap.ScopeModule, "", ast.Loc{Start: 0, End: -1})
+	defer p.exitScope()
+
 	// Directives
 	for _, dir := range m.Directives {
 		p.printDirective(dir)
@@ -231,7 +316,7 @@ func (p *Printer) printDecl(d ast.Decl) {
 	switch decl := d.(type) {
 	case *ast.ConstDecl:
 		p.print("const ")
-		p.printName(decl.Name)
+		p.printDeclName(decl.Name)
 		if decl.Type != nil {
 			p.print(":")
 			p.printSpace()
@@ -246,7 +331,7 @@ func (p *Printer) printDecl(d ast.Decl) {
 	case *ast.OverrideDecl:
 		p.printAttributes(decl.Attributes)
 		p.print("override ")
-		p.printName(decl.Name)
+		p.printDeclName(decl.Name)
 		if decl.Type != nil {
 			p.print(":")
 			p.printSpace()
@@ -274,7 +359,7 @@ func (p *Printer) printDecl(d ast.Decl) {
 			p.print(">")
 		}
 		p.print(" ")
-		p.printName(decl.Name)
+		p.printDeclName(decl.Name)
 		if decl.Type != nil {
 			p.print(":")
 			p.printSpace()
@@ -290,7 +375,7 @@ func (p *Printer) printDecl(d ast.Decl) {
 
 	case *ast.LetDecl:
 		p.print("let ")
-		p.printName(decl.Name)
+		p.printDeclName(decl.Name)
 		if decl.Type != nil {
 			p.print(":")
 			p.printSpace()
@@ -303,6 +388,9 @@ func (p *Printer) printDecl(d ast.Decl) {
 		p.printSemicolon()
 
 	case *ast.FunctionDecl:
+		// The function's name belongs to the enclosing scope
+		p.addBinding(decl.Name)
+		p.enterScope(sourcemap.ScopeFunction, p.symbolName(decl.Name), decl.Loc)
 		p.printAttributes(decl.Attributes)
 		p.print("fn ")
 		p.printName(decl.Name)
@@ -313,7 +401,7 @@ func (p *Printer) printDecl(d ast.Decl) {
 				p.printSpace()
 			}
 			p.printAttributes(param.Attributes)
-			p.printName(param.Name)
+			p.printDeclName(param.Name)
 			p.print(":")
 			p.printSpace()
 			p.printType(param.Type)
@@ -327,12 +415,13 @@ func (p *Printer) printDecl(d ast.Decl) {
 			p.printType(decl.ReturnType)
 		}
 		p.printSpace()
-		p.printCompoundStmt(decl.Body)
+		p.printBlock(decl.Body)
+		p.exitScope()
 		p.printNewline()
 
 	case *ast.StructDecl:
 		p.print("struct ")
-		p.printName(decl.Name)
+		p.printDeclName(decl.Name)
 		p.printSpace()
 		p.print("{")
 		p.indent++
@@ -354,7 +443,7 @@ func (p *Printer) printDecl(d ast.Decl) {
 
 	case *ast.AliasDecl:
 		p.print("alias ")
-		p.printName(decl.Name)
+		p.printDeclName(decl.Name)
 		p.printSpace()
 		p.print("=")
 		p.printSpace()
@@ -403,7 +492,7 @@ func (p *Printer) printType(t ast.Type) {
 	case *ast.IdentType:
 		// Use renamed name if the type reference was bound to a symbol
 		if typ.Ref.IsValid() {
-			p.printName(typ.Ref)
+			p.printNameAt(typ.Ref, typ.Loc)
 		} else {
 			p.print(typ.Name)
 		}
@@ -411,6 +500,8 @@ func (p *Printer) printType(t ast.Type) {
 	case *ast.VecType:
 		if typ.Shorthand != "" {
 			p.print(typ.Shorthand)
+		} else if name := p.shorthand("vec", typ.Size, 0, typ.ElemType); name != "" {
+			p.print(name)
 		} else {
 			p.print("vec")
 			p.print(string('0' + typ.Size))
@@ -423,6 +514,8 @@ func (p *Printer) printType(t ast.Type) {
 	case *ast.MatType:
 		if typ.Shorthand != "" {
 			p.print(typ.Shorthand)
+		} else if name := p.shorthand("mat", typ.Cols, typ.Rows, typ.ElemType); name != "" {
+			p.print(name)
 		} else {
 			p.print("mat")
 			p.print(string('0' + typ.Cols))
@@ -534,7 +627,7 @@ func (p *Printer) printExpr(e ast.Expr) {
 	switch expr := e.(type) {
 	case *ast.IdentExpr:
 		if expr.Ref.IsValid() {
-			p.printName(expr.Ref)
+			p.printNameAt(expr.Ref, expr.Loc)
 		} else {
 			p.print(expr.Name)
 		}
@@ -549,6 +642,9 @@ func (p *Printer) printExpr(e ast.Expr) {
 		p.printUnaryExpr(expr)
 
 	case *ast.CallExpr:
+		if p.options.MinifySyntax && p.printConstructor(expr) {
+			return
+		}
 		// Check for templated type constructor first
 		if expr.TemplateType != nil {
 			p.printType(expr.TemplateType)
@@ -592,6 +688,368 @@ func (p *Printer) printLiteral(lit *ast.LiteralExpr) {
 	}
 }
 
+// ----------------------------------------------------------------------------
+// Type and Constructor Shortening (MinifySyntax)
+// ----------------------------------------------------------------------------
+
+// shorthand returns the predeclared alias of a vector or matrix type, such
+// as vec3f for vec3<f32>, or "" when there is none or MinifySyntax is off.
+// Matrices have aliases for f32 and f16 elements only.
+func (p *Printer) shorthand(prefix string, cols, rows uint8, elem ast.Type) string {
+	if !p.options.MinifySyntax {
+		return ""
+	}
+	kind, ok := p.scalarType(elem)
+	if !ok {
+		return ""
+	}
+	suffix := scalarSuffix(kind)
+	if suffix == "" || prefix == "mat" && kind != types.ScalarF32 && kind != types.ScalarF16 {
+		return ""
+	}
+	name := prefix + string('0'+cols)
+	if prefix == "mat" {
+		name += "x" + string('0'+rows)
+	}
+	return name + suffix
+}
+
+// scalarType returns the scalar kind a type names, unless a declaration
+// shadows the predeclared type.
+func (p *Printer) scalarType(t ast.Type) (types.ScalarKind, bool) {
+	id, ok := t.(*ast.IdentType)
+	if !ok || p.userDefined(id.Ref) {
+		return 0, false
+	}
+	switch id.Name {
+	case "bool":
+		return types.ScalarBool, true
+	case "i32":
+		return types.ScalarI32, true
+	case "u32":
+		return types.ScalarU32, true
+	case "f32":
+		return types.ScalarF32, true
+	case "f16":
+		return types.ScalarF16, true
+	}
+	return 0, false
+}
+
+// userDefined reports whether ref names a declaration of the module.
+func (p *Printer) userDefined(ref ast.Ref) bool {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(p.symbols) {
+		return false
+	}
+	kind := p.symbols[ref.InnerIndex].Kind
+	return kind != ast.SymbolBuiltin && kind != ast.SymbolUnbound
+}
+
+// scalarSuffix returns the suffix of the predeclared aliases for vectors
+// and matrices of a scalar kind, and of its literals.
+func scalarSuffix(kind types.ScalarKind) string {
+	switch kind {
+	case types.ScalarF32:
+		return "f"
+	case types.ScalarF16:
+		return "h"
+	case types.ScalarI32:
+		return "i"
+	case types.ScalarU32:
+		return "u"
+	}
+	return ""
+}
+
+// constructor describes a vector, matrix or array constructor call.
+type constructor struct {
+	width int        // Number of components of a vector, 0 otherwise
+	elem  types.Type // Scalar of a vector or matrix, element of an array; nil if inferred
+	bare  string     // Spelling that leaves the element type to inference
+}
+
+// constructorOf returns the constructor a call invokes, or nil if it is
+// not a vector, matrix or array constructor.
+func (p *Printer) constructorOf(call *ast.CallExpr) *constructor {
+	switch t := call.TemplateType.(type) {
+	case *ast.VecType:
+		c := &constructor{width: int(t.Size), bare: "vec" + string('0'+t.Size)}
+		if kind, ok := p.scalarType(t.ElemType); ok {
+			c.elem = &types.Scalar{Kind: kind}
+		}
+		return c
+	case *ast.MatType:
+		c := &constructor{bare: "mat" + string('0'+t.Cols) + "x" + string('0'+t.Rows)}
+		if kind, ok := p.scalarType(t.ElemType); ok {
+			c.elem = &types.Scalar{Kind: kind}
+		}
+		return c
+	case *ast.ArrayType:
+		c := &constructor{bare: "array"}
+		if kind, ok := p.scalarType(t.ElemType); ok {
+			c.elem = &types.Scalar{Kind: kind}
+		} else if arr, ok := valueType(p.options.Types[call]).(*types.Array); ok {
+			c.elem = arr.Element
+		}
+		return c
+	case nil:
+	default:
+		return nil
+	}
+
+	id, ok := call.Func.(*ast.IdentExpr)
+	if !ok || p.userDefined(id.Ref) {
+		return nil
+	}
+	name := id.Name
+	c := &constructor{}
+	switch {
+	case len(name) >= 4 && strings.HasPrefix(name, "vec") && name[3] >= '2' && name[3] <= '4':
+		c.width = int(name[3] - '0')
+		c.bare = name[:4]
+	case len(name) >= 6 && strings.HasPrefix(name, "mat") && name[4] == 'x' &&
+		name[3] >= '2' && name[3] <= '4' && name[5] >= '2' && name[5] <= '4':
+		c.bare = name[:6]
+	default:
+		return nil
+	}
+	switch name[len(c.bare):] {
+	case "":
+	case "f":
+		c.elem = &types.Scalar{Kind: types.ScalarF32}
+	case "h":
+		c.elem = &types.Scalar{Kind: types.ScalarF16}
+	case "i":
+		c.elem = &types.Scalar{Kind: types.ScalarI32}
+	case "u":
+		c.elem = &types.Scalar{Kind: types.ScalarU32}
+	default:
+		return nil
+	}
+	return c
+}
+
+// printConstructor prints a vector, matrix or array constructor in its
+// shortest form and reports whether the call was one:
+//   - a splat repeats one simple argument for every component, so
+//     vec3f(x, x, x) becomes vec3f(x)
+//   - literal arguments drop their suffix and fraction where conversion
+//     to the element type gives the same value, so vec3<f32>(1.0f)
+//     becomes vec3f(1)
+//   - the element type is left to inference when an argument that is not
+//     a literal already has it, and every other argument converts to it,
+//     so vec3f(x, 1.0, y) becomes vec3(x,1,y) for an f32 x
+func (p *Printer) printConstructor(call *ast.CallExpr) bool {
+	c := p.constructorOf(call)
+	if c == nil {
+		return false
+	}
+
+	args := call.Args
+	if c.width > 1 && len(args) == c.width && splat(args) {
+		args = args[:1]
+	}
+	literals := make([]string, len(args))
+	if scalar, ok := c.elem.(*types.Scalar); ok {
+		for i, arg := range args {
+			literals[i] = shortLiteral(arg, scalar.Kind)
+		}
+	}
+
+	if c.elem != nil && p.inferable(c, args, literals) {
+		p.print(c.bare)
+	} else if call.TemplateType != nil {
+		p.printType(call.TemplateType)
+	} else {
+		p.printExpr(call.Func)
+	}
+	p.print("(")
+	for i, arg := range args {
+		if i > 0 {
+			p.print(",")
+			p.printSpace()
+		}
+		if literals[i] != "" {
+			p.print(literals[i])
+		} else {
+			p.printExpr(arg)
+		}
+	}
+	p.print(")")
+	return true
+}
+
+// inferable reports whether the constructor infers its element type from
+// the arguments. One argument other than a literal must have the element
+// type, and the others must have it or be abstract and convert to it.
+func (p *Printer) inferable(c *constructor, args []ast.Expr, literals []string) bool {
+	if p.options.Types == nil || len(args) == 0 {
+		return false
+	}
+	scalar, _ := c.elem.(*types.Scalar)
+	anchored := false
+	for i, arg := range args {
+		t := valueType(p.options.Types[arg])
+		if t == nil {
+			return false
+		}
+		elem := t
+		if c.bare != "array" {
+			elem = scalarOf(t)
+		} else if _, ok := t.(*types.Scalar); !ok {
+			// Arrays don't convert their elements
+			scalar = nil
+		}
+		switch {
+		case elem == nil:
+			return false
+		case elem.Equals(c.elem):
+			// Literals are abstract once shortened
+			anchored = anchored || literals[i] == "" && !isLiteral(arg)
+		case scalar == nil:
+			return false
+		default:
+			abstract, ok := elem.(*types.Scalar)
+			if !ok || !converts(abstract.Kind, scalar.Kind) {
+				return false
+			}
+		}
+	}
+	return anchored
+}
+
+// converts reports whether a value of an abstract kind converts to a
+// concrete scalar kind.
+func converts(from, to types.ScalarKind) bool {
+	switch from {
+	case types.ScalarAbstractInt:
+		return to != types.ScalarBool
+	case types.ScalarAbstractFloat:
+		return to == types.ScalarF32 || to == types.ScalarF16
+	}
+	return false
+}
+
+// valueType strips the reference from the type of a memory view.
+func valueType(t types.Type) types.Type {
+	if ref, ok := t.(*types.Reference); ok {
+		return ref.Element
+	}
+	return t
+}
+
+// scalarOf returns the scalar type of a scalar, vector or matrix.
+func scalarOf(t types.Type) types.Type {
+	switch t := t.(type) {
+	case *types.Scalar:
+		return t
+	case *types.Vector:
+		return t.Element
+	case *types.Matrix:
+		return t.Element
+	}
+	return nil
+}
+
+// splat reports whether all arguments are the same name, member access or
+// literal, so that evaluating it once gives the same components.
+func splat(args []ast.Expr) bool {
+	for _, arg := range args[1:] {
+		if !sameSimpleExpr(args[0], arg) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameSimpleExpr(a, b ast.Expr) bool {
+	switch a := a.(type) {
+	case *ast.IdentExpr:
+		b, ok := b.(*ast.IdentExpr)
+		return ok && a.Name == b.Name && a.Ref == b.Ref
+	case *ast.LiteralExpr:
+		b, ok := b.(*ast.LiteralExpr)
+		return ok && a.Kind == b.Kind && a.Value == b.Value
+	case *ast.MemberExpr:
+		b, ok := b.(*ast.MemberExpr)
+		return ok && a.Member == b.Member && sameSimpleExpr(a.Base, b.Base)
+	case *ast.UnaryExpr:
+		b, ok := b.(*ast.UnaryExpr)
+		return ok && a.Op == ast.UnaryOpNeg && b.Op == ast.UnaryOpNeg && sameSimpleExpr(a.Operand, b.Operand)
+	}
+	return false
+}
+
+// isLiteral reports whether e is a literal, possibly negated.
+func isLiteral(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.LiteralExpr:
+		return true
+	case *ast.UnaryExpr:
+		return e.Op == ast.UnaryOpNeg && isLiteral(e.Operand)
+	}
+	return false
+}
+
+// shortLiteral returns the shortest spelling of a numeric literal argument
+// that converts to the same value of the given scalar kind, or "" if the
+// literal is not an argument that can be shortened.
+func shortLiteral(e ast.Expr, kind types.ScalarKind) string {
+	if u, ok := e.(*ast.UnaryExpr); ok && u.Op == ast.UnaryOpNeg {
+		if kind == types.ScalarU32 {
+			return ""
+		}
+		if s := shortLiteral(u.Operand, kind); s != "" && s[0] != '-' {
+			return "-" + s
+		}
+		return ""
+	}
+	lit, ok := e.(*ast.LiteralExpr)
+	if !ok {
+		return ""
+	}
+	value := lit.Value
+	hex := strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "0X")
+
+	switch lit.Kind {
+	case lexer.TokIntLiteral:
+		suffix := scalarSuffix(kind)
+		if (kind == types.ScalarI32 || kind == types.ScalarU32) && strings.HasSuffix(value, suffix) {
+			return strings.TrimSuffix(value, suffix)
+		}
+		return ""
+
+	case lexer.TokFloatLiteral:
+		if hex || kind != types.ScalarF32 && kind != types.ScalarF16 {
+			return ""
+		}
+		text := strings.TrimRight(value, "fh")
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return ""
+		}
+		// Integers below this bound are exact in the element type
+		limit := float64(1 << 24)
+		if kind == types.ScalarF16 {
+			limit = 1 << 11
+		}
+		short := ""
+		if f == math.Trunc(f) && f <= limit {
+			short = strconv.FormatInt(int64(f), 10)
+		}
+		// An abstract float is rounded twice on its way to f32
+		if kind == types.ScalarF32 && text != value {
+			if f32, err := strconv.ParseFloat(text, 32); err == nil && float64(float32(f)) == f32 && (short == "" || len(text) < len(short)) {
+				short = text
+			}
+		}
+		if short != "" && len(short) < len(value) {
+			return short
+		}
+	}
+	return ""
+}
+
 func (p *Printer) printBinaryExpr(expr *ast.BinaryExpr) {
 	// TODO: Parenthesization based on precedence
 	p.printExpr(expr.Left)
@@ -632,6 +1090,14 @@ func unaryOpString(op ast.UnaryOp) string {
 // ----------------------------------------------------------------------------
 
 func (p *Printer) printCompoundStmt(stmt *ast.CompoundStmt) {
+	p.enterScope(sourcemap.ScopeBlock, "", stmt.Loc)
+	p.printBlock(stmt)
+	p.exitScope()
+}
+
+// printBlock prints the braces and statements of stmt without opening a
+// source map scope, for function bodies which share the function's scope.
+func (p *Printer) printBlock(stmt *ast.CompoundStmt) {
 	p.print("{")
 	p.indent++
 	for _, s := range stmt.Stmts {
@@ -648,15 +1114,7 @@ func (p *Printer) printCompoundStmt(stmt *ast.CompoundStmt) {
 func (p *Printer) printStmtNoTrailingNewline(s ast.Stmt) {
 	switch stmt := s.(type) {
 	case *ast.CompoundStmt:
-		p.print("{")
-		p.indent++
-		for _, sub := range stmt.Stmts {
-			p.printNewline()
-			p.printStmtNoTrailingNewline(sub)
-		}
-		p.indent--
-		p.printNewline()
-		p.print("}")
+		p.printCompoundStmt(stmt)
 
 	case *ast.ReturnStmt:
 		p.print("return")
@@ -670,15 +1128,7 @@ func (p *Printer) printStmtNoTrailingNewline(s ast.Stmt) {
 		p.print("if ")
 		p.printExpr(stmt.Condition)
 		p.printSpace()
-		p.print("{")
-		p.indent++
-		for _, sub := range stmt.Body.Stmts {
-			p.printNewline()
-			p.printStmtNoTrailingNewline(sub)
-		}
-		p.indent--
-		p.printNewline()
-		p.print("}")
+		p.printCompoundStmt(stmt.Body)
 		if stmt.Else != nil {
 			// Check if else branch is another if statement (else if)
 			if _, isElseIf := stmt.Else.(*ast.IfStmt); isElseIf {
@@ -686,15 +1136,7 @@ func (p *Printer) printStmtNoTrailingNewline(s ast.Stmt) {
 				elseIf := stmt.Else.(*ast.IfStmt)
 				p.printExpr(elseIf.Condition)
 				p.printSpace()
-				p.print("{")
-				p.indent++
-				for _, sub := range elseIf.Body.Stmts {
-					p.printNewline()
-					p.printStmtNoTrailingNewline(sub)
-				}
-				p.indent--
-				p.printNewline()
-				p.print("}")
+				p.printCompoundStmt(elseIf.Body)
 				if elseIf.Else != nil {
 					p.printElseChainNoTrailing(elseIf.Else)
 				}
@@ -727,15 +1169,7 @@ func (p *Printer) printStmtNoTrailingNewline(s ast.Stmt) {
 			}
 			p.print(":")
 			p.printSpace()
-			p.print("{")
-			p.indent++
-			for _, sub := range c.Body.Stmts {
-				p.printNewline()
-				p.printStmtNoTrailingNewline(sub)
-			}
-			p.indent--
-			p.printNewline()
-			p.print("}")
+			p.printCompoundStmt(c.Body)
 		}
 		p.indent--
 		p.printNewline()
@@ -748,40 +1182,16 @@ func (p *Printer) printStmtNoTrailingNewline(s ast.Stmt) {
 		p.print("while ")
 		p.printExpr(stmt.Condition)
 		p.printSpace()
-		p.print("{")
-		p.indent++
-		for _, sub := range stmt.Body.Stmts {
-			p.printNewline()
-			p.printStmtNoTrailingNewline(sub)
-		}
-		p.indent--
-		p.printNewline()
-		p.print("}")
+		p.printCompoundStmt(stmt.Body)
 
 	case *ast.LoopStmt:
 		p.print("loop")
 		p.printSpace()
-		p.print("{")
-		p.indent++
-		for _, sub := range stmt.Body.Stmts {
-			p.printNewline()
-			p.printStmtNoTrailingNewline(sub)
-		}
-		p.indent--
-		p.printNewline()
-		p.print("}")
+		p.printCompoundStmt(stmt.Body)
 		if stmt.Continuing != nil {
 			p.print(" continuing")
 			p.printSpace()
-			p.print("{")
-			p.indent++
-			for _, sub := range stmt.Continuing.Stmts {
-				p.printNewline()
-				p.printStmtNoTrailingNewline(sub)
-			}
-			p.indent--
-			p.printNewline()
-			p.print("}")
+			p.printCompoundStmt(stmt.Continuing)
 		}
 
 	case *ast.BreakStmt:
@@ -829,7 +1239,7 @@ func (p *Printer) printDeclNoTrailingNewline(d ast.Decl) {
 	switch decl := d.(type) {
 	case *ast.ConstDecl:
 		p.print("const ")
-		p.printName(decl.Name)
+		p.printDeclName(decl.Name)
 		if decl.Type != nil {
 			p.print(":")
 			p.printSpace()
@@ -843,7 +1253,7 @@ func (p *Printer) printDeclNoTrailingNewline(d ast.Decl) {
 
 	case *ast.LetDecl:
 		p.print("let ")
-		p.printName(decl.Name)
+		p.printDeclName(decl.Name)
 		if decl.Type != nil {
 			p.print(":")
 			p.printSpace()
@@ -869,7 +1279,7 @@ func (p *Printer) printDeclNoTrailingNewline(d ast.Decl) {
 			p.print(">")
 		}
 		p.print(" ")
-		p.printName(decl.Name)
+		p.printDeclName(decl.Name)
 		if decl.Type != nil {
 			p.print(":")
 			p.printSpace()
@@ -894,15 +1304,7 @@ func (p *Printer) printElseChainNoTrailing(stmt ast.Stmt) {
 		p.print(" else if ")
 		p.printExpr(ifStmt.Condition)
 		p.printSpace()
-		p.print("{")
-		p.indent++
-		for _, sub := range ifStmt.Body.Stmts {
-			p.printNewline()
-			p.printStmtNoTrailingNewline(sub)
-		}
-		p.indent--
-		p.printNewline()
-		p.print("}")
+		p.printCompoundStmt(ifStmt.Body)
 		if ifStmt.Else != nil {
 			p.printElseChainNoTrailing(ifStmt.Else)
 		}
@@ -914,6 +1316,10 @@ func (p *Printer) printElseChainNoTrailing(stmt ast.Stmt) {
 }
 
 func (p *Printer) printForStmt(stmt *ast.ForStmt) {
+	// The init declaration is scoped to the loop
+	p.enterScope(sourcemap.ScopeBlock, "", stmt.Loc)
+	defer p.exitScope()
+
 	p.print("for")
 	p.printSpace()
 	p.print("(")
@@ -944,7 +1350,7 @@ func (p *Printer) printForInit(s ast.Stmt) {
 		switch decl := stmt.Decl.(type) {
 		case *ast.VarDecl:
 			p.print("var ")
-			p.printName(decl.Name)
+			p.printDeclName(decl.Name)
 			if decl.Type != nil {
 				p.print(":")
 				p.printSpace()
@@ -958,7 +1364,7 @@ func (p *Printer) printForInit(s ast.Stmt) {
 			}
 		case *ast.LetDecl:
 			p.print("let ")
-			p.printName(decl.Name)
+			p.printDeclName(decl.Name)
 			if decl.Type != nil {
 				p.print(":")
 				p.printSpace()
@@ -7,6 +7,7 @@ import (
 	"github.com/HugoDaniel/miniray/internal/dce"
 	"github.com/HugoDaniel/miniray/internal/parser"
 	"github.com/HugoDaniel/miniray/internal/sourcemap"
+	"github.com/HugoDaniel/miniray/internal/validator"
 )
 
 // ----------------------------------------------------------------------------
@@ -949,6 +950,82 @@ func TestArrayConstructor(t *testing.T) {
 	expectPrinted(t, "const x = array<i32, 3>(1, 2, 3);", "const x = array<i32, 3>(1, 2, 3);\n")
 }
 
+// ----------------------------------------------------------------------------
+// Type Shorthand and Constructor Shortening (MinifySyntax)
+// ----------------------------------------------------------------------------
+
+// expectPrintedTyped verifies fully minified output with the validator's
+// types available to the printer.
+func expectPrintedTyped(t *testing.T, input string, expected string) {
+	t.Helper()
+	t.Run(input+"_typed", func(t *testing.T) {
+		t.Helper()
+		p := parser.New(input)
+		module, errs := p.Parse()
+		if len(errs) > 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		result := validator.Validate(module, validator.Options{})
+		if result.Diagnostics.HasErrors() {
+			t.Fatalf("validation errors: %v", result.Diagnostics.Diagnostics())
+		}
+		pr := New(Options{
+			MinifyWhitespace: true,
+			MinifySyntax:     true,
+			Types:            result.TypeInfo.Exprs,
+		}, module.Symbols)
+		actual := pr.Print(module)
+		if actual != expected {
+			t.Errorf("\ninput:\n%s\nexpected:\n%s\nactual:\n%s", input, expected, actual)
+		}
+	})
+}
+
+func TestTypeShorthand(t *testing.T) {
+	expectPrintedMangleMinify(t, "var<private> v: vec3<f32>;", "var<private> v:vec3f;")
+	expectPrintedMangleMinify(t, "var<private> v: vec2<i32>;", "var<private> v:vec2i;")
+	expectPrintedMangleMinify(t, "var<private> v: vec4<u32>;", "var<private> v:vec4u;")
+	expectPrintedMangleMinify(t, "var<private> m: mat4x4<f32>;", "var<private> m:mat4x4f;")
+	expectPrintedMangleMinify(t, "var<private> a: array<vec2<f32>, 4>;", "var<private> a:array<vec2f,4>;")
+
+	// No alias for bool vectors or integer matrices
+	expectPrintedMangleMinify(t, "var<private> v: vec3<bool>;", "var<private> v:vec3<bool>;")
+
+	// Only under MinifySyntax
+	expectPrintedMinify(t, "var<private> v: vec3<f32>;", "var<private> v:vec3<f32>;")
+}
+
+func TestConstructorShortening(t *testing.T) {
+	// Literals convert to the element type of a typed constructor
+	expectPrintedMangleMinify(t, "const v = vec3<f32>(1.0);", "const v=vec3f(1);")
+	expectPrintedMangleMinify(t, "const v = vec3f(1.0f, 0.5f, 2.50);", "const v=vec3f(1,0.5,2.50);")
+	expectPrintedMangleMinify(t, "const v = vec2i(1i, -2i);", "const v=vec2i(1,-2);")
+	expectPrintedMangleMinify(t, "const v = vec2u(0x10u, 3u);", "const v=vec2u(0x10,3);")
+	expectPrintedMangleMinify(t, "const v = mat2x2f(1.0, 0.0, 0.0, 1.0);", "const v=mat2x2f(1,0,0,1);")
+	expectPrintedMangleMinify(t, "const v = array<f32, 2>(1.0, 2.0);", "const v=array<f32,2>(1,2);")
+
+	// Hex digits are not suffixes, and rounding twice may change a value
+	expectPrintedMangleMinify(t, "const v = vec2f(0xff, 1e3);", "const v=vec2f(0xff,1e3);")
+	expectPrintedMangleMinify(t, "const v = vec2f(16777217.0, 0.1f);", "const v=vec2f(16777217.0,0.1);")
+
+	// Splats
+	expectPrintedMangleMinify(t, "const v = vec3f(1.0, 1.0, 1.0);", "const v=vec3f(1);")
+	expectPrintedMangleMinify(t, "fn f(x: f32) -> vec3f { return vec3f(x, x, x); }", "fn f(x:f32)->vec3f{return vec3f(x);}")
+	expectPrintedMangleMinify(t, "fn f(x: f32) -> vec3f { return vec3f(x, x, -x); }", "fn f(x:f32)->vec3f{return vec3f(x,x,-x);}")
+
+	// Templates are only dropped with types to check inference against
+	expectPrintedMangleMinify(t, "fn f(x: f32) -> vec3f { return vec3f(x, 1.0, 2.0); }", "fn f(x:f32)->vec3f{return vec3f(x,1,2);}")
+	expectPrintedTyped(t, "fn f(x: f32) -> vec3f { return vec3f(x, 1.0, 2.0); }", "fn f(x:f32)->vec3f{return vec3(x,1,2);}")
+	expectPrintedTyped(t, "fn f(x: f32) -> vec4f { return vec4<f32>(vec2f(x), x, 1.0f); }", "fn f(x:f32)->vec4f{return vec4(vec2(x),x,1);}")
+	expectPrintedTyped(t, "fn f(c: vec2f) -> mat2x2f { return mat2x2<f32>(c, c); }", "fn f(c:vec2f)->mat2x2f{return mat2x2(c,c);}")
+	expectPrintedTyped(t, "fn f(x: i32) -> i32 { let a = array<i32, 3>(x, x, x); return a[0]; }", "fn f(x:i32)->i32{let a=array(x,x,x);return a[0];}")
+
+	// Conversions and abstract arguments keep the element type
+	expectPrintedTyped(t, "fn f(v: vec3i) -> vec3f { return vec3f(v); }", "fn f(v:vec3i)->vec3f{return vec3f(v);}")
+	expectPrintedTyped(t, "fn f() -> vec3f { return vec3f(1.0, 2.0, 3.0); }", "fn f()->vec3f{return vec3f(1,2,3);}")
+	expectPrintedTyped(t, "fn f(x: f32) -> vec2f { return vec2f(x, 1.0f); }", "fn f(x:f32)->vec2f{return vec2(x,1);}")
+}
+
 // ----------------------------------------------------------------------------
 // Struct with trailing comma (covers member printing)
 // ----------------------------------------------------------------------------
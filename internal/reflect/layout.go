@@ -5,6 +5,7 @@ import (
 
 	"github.com/HugoDaniel/miniray/internal/ast"
 	"github.com/HugoDaniel/miniray/internal/lexer"
+	"github.com/HugoDaniel/miniray/internal/parser"
 )
 
 // Renamer provides minified names for symbols.
@@ -18,6 +19,7 @@ type LayoutComputer struct {
 	module      *ast.Module
 	structCache map[string]*StructLayout
 	renamer     Renamer // optional renamer for mapped names
+	consts      map[ast.Ref]parser.ConstValue
 }
 
 // NewLayoutComputer creates a layout computer for a module.
@@ -194,6 +196,23 @@ func (lc *LayoutComputer) evaluateConstExpr(expr ast.Expr) int {
 	}
 }
 
+// AttributeInt evaluates the argument of an attribute such as @size(16) or
+// @align(N). It reports false if the argument is not a positive integer
+// const-expression.
+func (lc *LayoutComputer) AttributeInt(attr ast.Attribute) (int, bool) {
+	if len(attr.Args) != 1 {
+		return 0, false
+	}
+	if lc.consts == nil {
+		lc.consts = parser.ConstValues(lc.module)
+	}
+	val := parser.EvaluateConstExpr(attr.Args[0], lc.consts)
+	if val.Kind != parser.ConstInt || val.Int <= 0 {
+		return 0, false
+	}
+	return int(val.Int), true
+}
+
 // GetStructLayout returns the layout for a struct by reference.
 // Returns nil if the reference is not a struct.
 func (lc *LayoutComputer) GetStructLayout(ref ast.Ref) *StructLayout {
@@ -250,6 +269,19 @@ func (lc *LayoutComputer) computeStructLayout(decl *ast.StructDecl) *StructLayou
 		if memberLayout.Alignment == 0 {
 			memberLayout.Alignment = 1
 		}
+		// @align and @size override the alignment and size of the type
+		for _, attr := range member.Attributes {
+			if attr.Name != "align" && attr.Name != "size" {
+				continue
+			}
+			if n, ok := lc.AttributeInt(attr); ok {
+				if attr.Name == "align" {
+					memberLayout.Alignment = n
+				} else {
+					memberLayout.Size = n
+				}
+			}
+		}
 
 		// Align offset to member alignment
 		offset = roundUp(offset, memberLayout.Alignment)
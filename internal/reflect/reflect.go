@@ -5,6 +5,7 @@ package reflect
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/HugoDaniel/miniray/internal/ast"
 	"github.com/HugoDaniel/miniray/internal/lexer"
@@ -16,6 +17,7 @@ type ReflectResult struct {
 	Bindings    []BindingInfo           `json:"bindings"`
 	Structs     map[string]StructLayout `json:"structs"`
 	EntryPoints []EntryPointInfo        `json:"entryPoints"`
+	Overrides   []OverrideInfo          `json:"overrides,omitempty"`
 	Errors      []string                `json:"errors,omitempty"`
 }
 
@@ -72,6 +74,15 @@ type EntryPointInfo struct {
 	WorkgroupSize []int  `json:"workgroupSize"` // null for vertex/fragment
 }
 
+// OverrideInfo describes a pipeline-overridable constant.
+type OverrideInfo struct {
+	Name        string   `json:"name"`
+	ID          *int     `json:"id,omitempty"`    // from @id(n)
+	Type        string   `json:"type"`            // empty if inferred from a non-literal initializer
+	Specialized bool     `json:"specialized"`     // replaced by a constant during minification
+	Value       *float64 `json:"value,omitempty"` // the specialized value
+}
+
 // Reflect extracts binding and struct information from WGSL source.
 func Reflect(source string) ReflectResult {
 	// Parse the source
@@ -125,7 +136,15 @@ func ReflectModuleWithRenamer(module *ast.Module, renamer Renamer) ReflectResult
 		}
 	}
 
-	// Second pass: collect bindings and entry points
+	// Constants can size workgroups, e.g. after override specialization
+	consts := make(map[ast.Ref]ast.Expr)
+	for _, decl := range module.Declarations {
+		if c, ok := decl.(*ast.ConstDecl); ok {
+			consts[c.Name] = c.Initializer
+		}
+	}
+
+	// Second pass: collect bindings, entry points and overrides
 	for _, decl := range module.Declarations {
 		switch d := decl.(type) {
 		case *ast.VarDecl:
@@ -135,10 +154,13 @@ func ReflectModuleWithRenamer(module *ast.Module, renamer Renamer) ReflectResult
 			}
 
 		case *ast.FunctionDecl:
-			entryPoint := extractEntryPoint(d, module.Symbols)
+			entryPoint := extractEntryPoint(d, module.Symbols, consts)
 			if entryPoint != nil {
 				result.EntryPoints = append(result.EntryPoints, *entryPoint)
 			}
+
+		case *ast.OverrideDecl:
+			result.Overrides = append(result.Overrides, ExtractOverride(d, module.Symbols))
 		}
 	}
 
@@ -211,7 +233,7 @@ func extractBinding(varDecl *ast.VarDecl, symbols []ast.Symbol, lc *LayoutComput
 }
 
 // extractEntryPoint extracts entry point info from a FunctionDecl if it's an entry point.
-func extractEntryPoint(fn *ast.FunctionDecl, symbols []ast.Symbol) *EntryPointInfo {
+func extractEntryPoint(fn *ast.FunctionDecl, symbols []ast.Symbol, consts map[ast.Ref]ast.Expr) *EntryPointInfo {
 	var stage string
 	var workgroupSize []int
 
@@ -224,7 +246,11 @@ func extractEntryPoint(fn *ast.FunctionDecl, symbols []ast.Symbol) *EntryPointIn
 		case "compute":
 			stage = "compute"
 		case "workgroup_size":
-			workgroupSize = parseWorkgroupSize(attr.Args)
+			args := make([]ast.Expr, len(attr.Args))
+			for i, arg := range attr.Args {
+				args[i] = resolveConst(arg, consts)
+			}
+			workgroupSize = parseWorkgroupSize(args)
 		}
 	}
 
@@ -239,6 +265,74 @@ func extractEntryPoint(fn *ast.FunctionDecl, symbols []ast.Symbol) *EntryPointIn
 	}
 }
 
+// resolveConst replaces a reference to a constant by its initializer.
+func resolveConst(expr ast.Expr, consts map[ast.Ref]ast.Expr) ast.Expr {
+	for depth := 0; depth < 8; depth++ {
+		ident, ok := expr.(*ast.IdentExpr)
+		if !ok {
+			break
+		}
+		init, ok := consts[ident.Ref]
+		if !ok {
+			break
+		}
+		expr = init
+	}
+	return expr
+}
+
+// ExtractOverride describes an override declaration. The type is the
+// declared one, or inferred from a literal initializer.
+func ExtractOverride(d *ast.OverrideDecl, symbols []ast.Symbol) OverrideInfo {
+	info := OverrideInfo{
+		Name: getSymbolName(d.Name, symbols),
+		Type: OverrideType(d),
+	}
+	for _, attr := range d.Attributes {
+		if attr.Name == "id" && len(attr.Args) > 0 {
+			if id := parseIntAttr(attr.Args[0]); id >= 0 {
+				info.ID = &id
+			}
+		}
+	}
+	return info
+}
+
+// OverrideType returns the scalar type of an override: the declared type
+// name, or the concrete type of a literal initializer. It returns "" if
+// the type cannot be determined syntactically.
+func OverrideType(d *ast.OverrideDecl) string {
+	if d.Type != nil {
+		if ident, ok := d.Type.(*ast.IdentType); ok {
+			return ident.Name
+		}
+		return ""
+	}
+	init := d.Initializer
+	if unary, ok := init.(*ast.UnaryExpr); ok && unary.Op == ast.UnaryOpNeg {
+		init = unary.Operand
+	}
+	lit, ok := init.(*ast.LiteralExpr)
+	if !ok {
+		return ""
+	}
+	switch lit.Kind {
+	case lexer.TokTrue, lexer.TokFalse:
+		return "bool"
+	case lexer.TokIntLiteral:
+		if strings.HasSuffix(lit.Value, "u") {
+			return "u32"
+		}
+		return "i32"
+	case lexer.TokFloatLiteral:
+		if strings.HasSuffix(lit.Value, "h") {
+			return "f16"
+		}
+		return "f32"
+	}
+	return ""
+}
+
 // parseIntAttr parses an integer attribute argument.
 func parseIntAttr(expr ast.Expr) int {
 	if expr == nil {
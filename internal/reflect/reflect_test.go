@@ -157,6 +157,48 @@ struct WithVec3 {
 	}
 }
 
+func TestSizeAndAlignAttributes(t *testing.T) {
+	source := `
+const WIDE = 64;
+struct P {
+    @size(32) a: f32,
+    b: vec3f,
+    @align(WIDE) c: u32,
+}
+@group(0) @binding(0) var<uniform> p: P;
+`
+	result := Reflect(source)
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	layout := result.Structs["P"]
+	if layout.Alignment != 64 {
+		t.Errorf("expected alignment 64, got %d", layout.Alignment)
+	}
+	if layout.Size != 128 {
+		t.Errorf("expected size 128, got %d", layout.Size)
+	}
+
+	expectedFields := []struct {
+		name   string
+		offset int
+		size   int
+		align  int
+	}{
+		{"a", 0, 32, 4},
+		{"b", 32, 12, 16},
+		{"c", 64, 4, 64},
+	}
+	for i, expected := range expectedFields {
+		field := layout.Fields[i]
+		if field.Offset != expected.offset || field.Size != expected.size || field.Alignment != expected.align {
+			t.Errorf("field %s: expected offset %d, size %d, alignment %d, got %d, %d, %d",
+				expected.name, expected.offset, expected.size, expected.align, field.Offset, field.Size, field.Alignment)
+		}
+	}
+}
+
 func TestEntryPoints(t *testing.T) {
 	source := `
 @compute @workgroup_size(8, 8, 1)
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/HugoDaniel/miniray/internal/compiler"
+	"github.com/HugoDaniel/miniray/internal/minifier"
+	"github.com/HugoDaniel/miniray/internal/reflect"
+	"github.com/HugoDaniel/miniray/pkg/api"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// MaxRequestSize is the largest request body a Server reads.
+const MaxRequestSize = 64 << 20
+
+// maxContexts is the number of minification option sets a Server keeps
+// warm contexts for.
+const maxContexts = 16
+
+// Request is a JSON-RPC 2.0 request. A request without an ID is a
+// notification: it runs, but gets no response.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Params are the parameters of every method. Options is the options JSON
+// of the method's C API function: MinifyOptions for "minify" and
+// ValidateOptions for "validate"; "reflect" and "format" take none.
+//
+// Document, which only "validate" takes, names the document the source
+// is a version of, such as its path. Validations of a document are
+// incremental; without one, every validation starts from scratch.
+type Params struct {
+	Source   *string         `json:"source"`
+	Options  json.RawMessage `json:"options,omitempty"`
+	Document string          `json:"document,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response; it has either a result or an error.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC 2.0 error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// methods are the JSON-RPC methods of a Server. Their errors are invalid
+// options.
+var methods = map[string]func(s *Server, params Params) (interface{}, error){
+	"minify":   (*Server).minify,
+	"validate": (*Server).validate,
+	"reflect":  (*Server).reflect,
+	"format":   (*Server).format,
+}
+
+// Server answers JSON-RPC 2.0 requests for minify, validate, reflect and
+// format, posted over HTTP singly or in batches. It keeps a warm context
+// for each of the recently used sets of minification options, and one
+// compiler that caches parsed modules and validates the versions of each
+// named document incrementally. It is safe for concurrent use; the calls
+// of a batch run concurrently.
+type Server struct {
+	validation *compiler.Compiler
+
+	mu       sync.Mutex
+	contexts map[string]*serverContext
+	clock    uint64
+}
+
+type serverContext struct {
+	context *Context
+	used    uint64
+}
+
+// NewServer creates a server with empty caches.
+func NewServer() *Server {
+	return &Server{
+		validation: compiler.New(minifier.DefaultOptions(), 0),
+		contexts:   make(map[string]*serverContext),
+	}
+}
+
+// ServeHTTP answers a request or batch posted as the request body. A body
+// of notifications only gets 204 No Content.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "JSON-RPC requests must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	response := s.Handle(body)
+	if response == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(response)
+}
+
+// Handle answers the JSON of a request or batch. It returns nil when there
+// is nothing to answer because all requests were notifications.
+func (s *Server) Handle(data []byte) []byte {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '[' {
+		return marshalResponse(s.call(data))
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return marshalResponse(errorResponse(nil, CodeParseError, err.Error()))
+	}
+	if len(batch) == 0 {
+		return marshalResponse(errorResponse(nil, CodeInvalidRequest, "empty batch"))
+	}
+
+	responses := make([]*Response, len(batch))
+	var wg sync.WaitGroup
+	for i, data := range batch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = s.call(data)
+		}()
+	}
+	wg.Wait()
+
+	// Notifications have no place in the response array
+	answered := responses[:0]
+	for _, r := range responses {
+		if r != nil {
+			answered = append(answered, r)
+		}
+	}
+	if len(answered) == 0 {
+		return nil
+	}
+	return marshalResponse(answered)
+}
+
+// call runs one request, returning nil for a notification.
+func (s *Server) call(data []byte) *Response {
+	if !json.Valid(data) {
+		return errorResponse(nil, CodeParseError, "invalid JSON")
+	}
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil || req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(nil, CodeInvalidRequest, "not a JSON-RPC 2.0 request")
+	}
+
+	result, rpcErr := s.dispatch(req)
+	if req.ID == nil {
+		return nil
+	}
+	if rpcErr != nil {
+		return &Response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &Response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// dispatch runs the method of req. A panic becomes an internal error, so
+// one bad shader does not take the server down.
+func (s *Server) dispatch(req Request) (result interface{}, rpcErr *Error) {
+	method, ok := methods[req.Method]
+	if !ok {
+		return nil, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+	var params Params
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.Source == nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: `params must be an object with a "source" string`}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, rpcErr = nil, &Error{Code: CodeInternalError, Message: fmt.Sprint(r)}
+		}
+	}()
+	result, err := method(s, params)
+	if err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: "invalid options: " + err.Error()}
+	}
+	return result, nil
+}
+
+func (s *Server) minify(params Params) (interface{}, error) {
+	opts, err := ParseMinifyOptions(string(params.Options))
+	if err != nil {
+		return nil, err
+	}
+	return s.context(opts).Minify(*params.Source), nil
+}
+
+func (s *Server) validate(params Params) (interface{}, error) {
+	opts, err := ParseValidateOptions(string(params.Options))
+	if err != nil {
+		return nil, err
+	}
+	check := s.validation.Validate
+	if params.Document != "" {
+		check = func(code string, opts api.ValidateOptions) api.ValidateResult {
+			return s.validation.ValidateDocument(params.Document, code, opts)
+		}
+	}
+	return ValidateWith(check, *params.Source, opts), nil
+}
+
+func (s *Server) reflect(params Params) (interface{}, error) {
+	return reflect.Reflect(*params.Source), nil
+}
+
+func (s *Server) format(params Params) (interface{}, error) {
+	return Format(*params.Source), nil
+}
+
+// context returns the warm context for opts, replacing the least recently
+// used one when there are too many.
+func (s *Server) context(opts MinifyOptions) *Context {
+	// Maps encode sorted by key, so equal options encode the same
+	data, _ := json.Marshal(opts)
+	key := string(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock++
+	if c, ok := s.contexts[key]; ok {
+		c.used = s.clock
+		return c.context
+	}
+	if len(s.contexts) >= maxContexts {
+		var oldest string
+		for k, c := range s.contexts {
+			if oldest == "" || c.used < s.contexts[oldest].used {
+				oldest = k
+			}
+		}
+		delete(s.contexts, oldest)
+	}
+	c := &serverContext{context: newContext(opts), used: s.clock}
+	s.contexts[key] = c
+	return c.context
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *Response {
+	return &Response{JSONRPC: "2.0", ID: id, Error: &Error{Code: code, Message: message}}
+}
+
+// marshalResponse encodes a response or batch, or returns nil for a nil
+// response.
+func marshalResponse(v interface{}) []byte {
+	if r, ok := v.(*Response); ok && r == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		// Results are plain data, so this only happens for a broken method
+		data, _ = json.Marshal(errorResponse(nil, CodeInternalError, err.Error()))
+	}
+	return data
+}
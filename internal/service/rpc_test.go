@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newUnixServer serves a Server on a Unix socket and returns a client that
+// talks to it, as a build tool would.
+func newUnixServer(t *testing.T) (*Server, *http.Client) {
+	t.Helper()
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "miniray.sock"))
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	server := NewServer()
+	ts := httptest.NewUnstartedServer(server)
+	ts.Listener.Close()
+	ts.Listener = listener
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", listener.Addr().String())
+		},
+	}}
+	return server, client
+}
+
+func post(t *testing.T, client *http.Client, body string) (int, string) {
+	t.Helper()
+	resp, err := client.Post("http://miniray/", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func rpc(t *testing.T, client *http.Client, method string, params interface{}, result interface{}) *Error {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	status, data := post(t, client, string(body))
+	if status != http.StatusOK {
+		t.Fatalf("%s: status %d: %s", method, status, data)
+	}
+	var resp struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		t.Fatalf("%s: %v: %s", method, err, data)
+	}
+	if resp.ID != 1 {
+		t.Errorf("%s: id = %d, want 1", method, resp.ID)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		t.Fatalf("%s: %v: %s", method, err, resp.Result)
+	}
+	return nil
+}
+
+func TestServerMethods(t *testing.T) {
+	_, client := newUnixServer(t)
+
+	var minified MinifyResult
+	if err := rpc(t, client, "minify", map[string]interface{}{
+		"source":  testShader,
+		"options": map[string]interface{}{"keepNames": []string{"tint"}},
+	}, &minified); err != nil {
+		t.Fatalf("minify: %+v", err)
+	}
+	if want := Minify(testShader, mustParseMinifyOptions(t, `{"keepNames": ["tint"]}`)); minified.Code != want.Code {
+		t.Errorf("minify = %q, want %q", minified.Code, want.Code)
+	}
+
+	var validated struct {
+		Valid      bool `json:"valid"`
+		ErrorCount int  `json:"errorCount"`
+	}
+	if err := rpc(t, client, "validate", map[string]interface{}{
+		"source":  "fn f() -> i32 { return 1.5; }",
+		"options": map[string]interface{}{"strictMode": true},
+	}, &validated); err != nil {
+		t.Fatalf("validate: %+v", err)
+	}
+	if validated.Valid || validated.ErrorCount != 1 {
+		t.Errorf("validate = %+v, want one error", validated)
+	}
+
+	var reflected struct {
+		Bindings []struct {
+			Name string `json:"name"`
+		} `json:"bindings"`
+	}
+	if err := rpc(t, client, "reflect", map[string]interface{}{"source": testShader}, &reflected); err != nil {
+		t.Fatalf("reflect: %+v", err)
+	}
+	if len(reflected.Bindings) != 1 || reflected.Bindings[0].Name != "uniforms" {
+		t.Errorf("reflect bindings = %+v", reflected.Bindings)
+	}
+
+	var formatted FormatResult
+	if err := rpc(t, client, "format", map[string]interface{}{"source": "const a=1;fn f(){let b=a;}"}, &formatted); err != nil {
+		t.Fatalf("format: %+v", err)
+	}
+	if !strings.Contains(formatted.Code, "const a = 1;\n") || len(formatted.Diagnostics) != 0 {
+		t.Errorf("format = %+v", formatted)
+	}
+	if err := rpc(t, client, "format", map[string]interface{}{"source": "// keep me\nfn f() -> f32 { /* why */ return 1.0; }"}, &formatted); err != nil {
+		t.Fatalf("format: %+v", err)
+	}
+	if !strings.Contains(formatted.Code, "// keep me\n") || !strings.Contains(formatted.Code, "/* why */") {
+		t.Errorf("format dropped comments: %q", formatted.Code)
+	}
+}
+
+func TestServerValidateDocuments(t *testing.T) {
+	_, client := newUnixServer(t)
+
+	source := "fn f() -> f32 { return 1.0; }\nfn g() -> f32 { return 2.0; }\n"
+	edited := strings.Replace(source, "2.0", "3.0", 1)
+	rechecked := func(params map[string]interface{}) int {
+		t.Helper()
+		params["options"] = map[string]interface{}{"stats": true}
+		var validated struct {
+			Stats struct {
+				Rechecked int `json:"rechecked"`
+			} `json:"stats"`
+		}
+		if err := rpc(t, client, "validate", params, &validated); err != nil {
+			t.Fatalf("validate: %+v", err)
+		}
+		return validated.Stats.Rechecked
+	}
+
+	// Without a document, no validation depends on another
+	rechecked(map[string]interface{}{"source": source})
+	if n := rechecked(map[string]interface{}{"source": edited}); n != 2 {
+		t.Errorf("stateless validation rechecked %d declarations, want 2", n)
+	}
+
+	// Versions of a document only check what changed, whatever other
+	// documents were validated in between
+	rechecked(map[string]interface{}{"source": source, "document": "a.wgsl"})
+	rechecked(map[string]interface{}{"source": "const c = 1;", "document": "b.wgsl"})
+	if n := rechecked(map[string]interface{}{"source": edited, "document": "a.wgsl"}); n != 1 {
+		t.Errorf("document validation rechecked %d declarations, want 1", n)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	_, client := newUnixServer(t)
+
+	var result json.RawMessage
+	tests := []struct {
+		method string
+		params interface{}
+		code   int
+	}{
+		{"compile", map[string]interface{}{"source": ""}, CodeMethodNotFound},
+		{"minify", map[string]interface{}{}, CodeInvalidParams},
+		{"minify", []string{"const a = 1;"}, CodeInvalidParams},
+		{"minify", map[string]interface{}{"source": "", "options": map[string]interface{}{"keepNames": "tint"}}, CodeInvalidParams},
+	}
+	for _, tt := range tests {
+		err := rpc(t, client, tt.method, tt.params, &result)
+		if err == nil || err.Code != tt.code {
+			t.Errorf("%s %v: error = %+v, want code %d", tt.method, tt.params, err, tt.code)
+		}
+	}
+
+	for body, code := range map[string]int{
+		`{"jsonrpc": "2.0", "id": 1, "method": `: CodeParseError,
+		`{"id": 1, "method": "minify"}`:          CodeInvalidRequest,
+		`[]`:                                     CodeInvalidRequest,
+	} {
+		_, data := post(t, client, body)
+		var resp Response
+		if err := json.Unmarshal([]byte(data), &resp); err != nil || resp.Error == nil || resp.Error.Code != code {
+			t.Errorf("%s: response %s, want code %d", body, data, code)
+		}
+	}
+
+	resp, err := client.Get("http://miniray/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d", resp.StatusCode)
+	}
+}
+
+func TestServerBatch(t *testing.T) {
+	_, client := newUnixServer(t)
+
+	status, data := post(t, client, `[
+		{"jsonrpc": "2.0", "id": "a", "method": "minify", "params": {"source": "const a = 1;"}},
+		{"jsonrpc": "2.0", "method": "format", "params": {"source": "const a = 1;"}},
+		{"jsonrpc": "2.0", "id": "b", "method": "nope", "params": {"source": ""}}
+	]`)
+	if status != http.StatusOK {
+		t.Fatalf("status %d: %s", status, data)
+	}
+	var responses []Response
+	if err := json.Unmarshal([]byte(data), &responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 2 || string(responses[0].ID) != `"a"` || responses[0].Error != nil ||
+		string(responses[1].ID) != `"b"` || responses[1].Error == nil {
+		t.Errorf("batch responses = %s", data)
+	}
+
+	// Notifications only are not answered
+	if status, data := post(t, client, `{"jsonrpc": "2.0", "method": "format", "params": {"source": ""}}`); status != http.StatusNoContent || data != "" {
+		t.Errorf("notification: status %d, body %q", status, data)
+	}
+}
+
+func TestServerConcurrentUse(t *testing.T) {
+	server, client := newUnixServer(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result MinifyResult
+			options := map[string]interface{}{"keepNames": []string{fmt.Sprintf("n%d", i%4)}}
+			if err := rpc(t, client, "minify", map[string]interface{}{"source": testShader, "options": options}, &result); err != nil || result.Code == "" {
+				t.Errorf("minify: %+v, %+v", err, result)
+			}
+		}()
+	}
+	wg.Wait()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if n := len(server.contexts); n == 0 || n > maxContexts {
+		t.Errorf("%d warm contexts", n)
+	}
+}
+
+func TestServerEvictsContexts(t *testing.T) {
+	server := NewServer()
+	for i := 0; i <= maxContexts; i++ {
+		server.context(MinifyOptions{Defines: map[string]string{"N": fmt.Sprint(i)}})
+	}
+	if len(server.contexts) != maxContexts {
+		t.Fatalf("%d warm contexts, want %d", len(server.contexts), maxContexts)
+	}
+
+	// The same options reuse their context
+	opts := MinifyOptions{Defines: map[string]string{"N": fmt.Sprint(maxContexts)}}
+	if server.context(opts) != server.context(opts) {
+		t.Error("equal options got different contexts")
+	}
+}
@@ -0,0 +1,319 @@
+// Package service implements the JSON interface shared by the C library
+// (cmd/miniray-lib) and the miniray server: the option and result shapes,
+// the operations on them, and contexts that keep warm caches.
+//
+// Options follow the config file schema (miniray.json), so omitted options
+// take the same defaults everywhere.
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/HugoDaniel/miniray/internal/compiler"
+	"github.com/HugoDaniel/miniray/internal/config"
+	"github.com/HugoDaniel/miniray/internal/cst"
+	"github.com/HugoDaniel/miniray/internal/minifier"
+	"github.com/HugoDaniel/miniray/internal/preprocess"
+	"github.com/HugoDaniel/miniray/internal/reflect"
+	"github.com/HugoDaniel/miniray/internal/sourcemap"
+	"github.com/HugoDaniel/miniray/pkg/api"
+)
+
+// MinifyOptions is the options JSON of minification (miniray_minify,
+// miniray_minify_and_reflect and miniray_minify_and_validate). It is the
+// config file schema (miniray.json), so omitted options take the same
+// defaults, plus the options that only make sense per call.
+type MinifyOptions struct {
+	config.Config
+
+	// Defines sets preprocessor names for #ifdef/#if
+	Defines map[string]string `json:"defines,omitempty"`
+
+	// Overrides bakes override values, keyed by name or @id, into constants
+	Overrides map[string]float64 `json:"overrides,omitempty"`
+
+	// Source map generation
+	SourceMap        bool   `json:"sourceMap,omitempty"`
+	SourceMapSources bool   `json:"sourceMapSources,omitempty"`
+	SourceMapScopes  bool   `json:"sourceMapScopes,omitempty"`
+	SourceName       string `json:"sourceName,omitempty"`
+	File             string `json:"file,omitempty"`
+
+	// Validate configures the validation of MinifyAndValidate
+	Validate ValidateOptions `json:"validate"`
+}
+
+// ValidateOptions is the options JSON of validation (miniray_validate).
+type ValidateOptions struct {
+	StrictMode        bool              `json:"strictMode"`
+	DiagnosticFilters map[string]string `json:"diagnosticFilters"`
+	Lint              bool              `json:"lint"`
+	Stats             bool              `json:"stats"`
+	Defines           map[string]string `json:"defines,omitempty"`
+}
+
+// Stats mirrors minifier.Stats.
+type Stats struct {
+	OriginalSize   int `json:"originalSize"`
+	MinifiedSize   int `json:"minifiedSize"`
+	SymbolsTotal   int `json:"symbolsTotal"`
+	SymbolsRenamed int `json:"symbolsRenamed"`
+	SymbolsDead    int `json:"symbolsDead"`
+	StatementsDead int `json:"statementsDead"`
+	CallsInlined   int `json:"callsInlined"`
+	LetsInlined    int `json:"letsInlined"`
+	VarsCoalesced  int `json:"varsCoalesced"`
+}
+
+// MinifyResult is the JSON result structure for minification.
+// Errors repeats the messages of the error diagnostics.
+type MinifyResult struct {
+	Code         string               `json:"code"`
+	Errors       []string             `json:"errors,omitempty"`
+	Diagnostics  []api.DiagnosticInfo `json:"diagnostics"`
+	OriginalSize int                  `json:"originalSize"`
+	MinifiedSize int                  `json:"minifiedSize"`
+	Stats        Stats                `json:"stats"`
+	Hash         string               `json:"hash,omitempty"`
+	SourceMap    string               `json:"sourceMap,omitempty"`
+}
+
+// MinifyAndReflectResult combines minification and reflection results
+type MinifyAndReflectResult struct {
+	MinifyResult
+	Reflect reflect.ReflectResult `json:"reflect"`
+}
+
+// MinifyAndValidateResult combines minification and validation results
+type MinifyAndValidateResult struct {
+	MinifyResult
+	Validation api.ValidateResult `json:"validation"`
+}
+
+// ParseMinifyOptions decodes options JSON; empty JSON gives the defaults.
+func ParseMinifyOptions(data string) (MinifyOptions, error) {
+	var opts MinifyOptions
+	if data == "" {
+		return opts, nil
+	}
+	err := json.Unmarshal([]byte(data), &opts)
+	return opts, err
+}
+
+// ParseValidateOptions decodes options JSON; empty JSON gives the defaults.
+func ParseValidateOptions(data string) (ValidateOptions, error) {
+	var opts ValidateOptions
+	if data == "" {
+		return opts, nil
+	}
+	err := json.Unmarshal([]byte(data), &opts)
+	return opts, err
+}
+
+// MinifierOptions converts the options for the minifier.
+func (o MinifyOptions) MinifierOptions() minifier.Options {
+	opts := o.Config.ToOptions()
+	opts.Defines = o.Defines
+	opts.Overrides = o.Overrides
+	opts.GenerateSourceMap = o.SourceMap
+	opts.SourceMapOptions = minifier.SourceMapOptions{
+		File:          o.File,
+		SourceName:    o.SourceName,
+		IncludeSource: o.SourceMapSources,
+		Scopes:        o.SourceMapScopes,
+	}
+	return opts
+}
+
+// ValidateOptions returns the options of the validation that follows
+// minification. Lint severities of the config schema apply unless the
+// diagnostic filters set the same rule, and defines default to the ones
+// used for minification.
+func (o MinifyOptions) ValidateOptions() ValidateOptions {
+	opts := o.Validate
+	if len(o.Lint) > 0 {
+		filters := make(map[string]string, len(o.Lint)+len(opts.DiagnosticFilters))
+		for rule, severity := range o.Lint {
+			filters[rule] = severity
+		}
+		for rule, severity := range opts.DiagnosticFilters {
+			filters[rule] = severity
+		}
+		opts.DiagnosticFilters = filters
+	}
+	if opts.Defines == nil {
+		opts.Defines = o.Defines
+	}
+	return opts
+}
+
+func newMinifyResult(result minifier.Result) MinifyResult {
+	r := MinifyResult{
+		Code:         result.Code,
+		Diagnostics:  make([]api.DiagnosticInfo, 0, len(result.Errors)),
+		OriginalSize: result.Stats.OriginalSize,
+		MinifiedSize: result.Stats.MinifiedSize,
+		Stats:        Stats(result.Stats),
+	}
+	for _, e := range result.Errors {
+		r.Errors = append(r.Errors, e.Message)
+		r.Diagnostics = append(r.Diagnostics, api.DiagnosticInfo{
+			Severity: "error",
+			Message:  e.Message,
+			Line:     e.Line,
+			Column:   e.Column,
+		})
+	}
+	if len(result.Errors) == 0 {
+		r.Hash = api.ContentHash(result.Code)
+	}
+	if result.SourceMap != nil {
+		r.SourceMap = result.SourceMap.ToJSON()
+	}
+	return r
+}
+
+// Minify minifies source.
+func Minify(source string, opts MinifyOptions) MinifyResult {
+	return newMinifyResult(minifier.New(opts.MinifierOptions()).Minify(source))
+}
+
+// MinifyAndReflect minifies source and reflects it with the minified names.
+func MinifyAndReflect(source string, opts MinifyOptions) MinifyAndReflectResult {
+	result := minifier.New(opts.MinifierOptions()).MinifyAndReflect(source)
+	return MinifyAndReflectResult{
+		MinifyResult: newMinifyResult(result.Result),
+		Reflect:      result.Reflect,
+	}
+}
+
+// MinifyAndValidate validates the source, as selected by the defines, and
+// minifies it. Both always run, so the diagnostics are complete even when
+// minification fails.
+func MinifyAndValidate(source string, opts MinifyOptions) MinifyAndValidateResult {
+	return MinifyAndValidateResult{
+		MinifyResult: Minify(source, opts),
+		Validation:   Validate(source, opts.ValidateOptions()),
+	}
+}
+
+// Validate preprocesses and validates source. Diagnostics are reported at
+// their position in source.
+func Validate(source string, opts ValidateOptions) api.ValidateResult {
+	return ValidateWith(api.ValidateWithOptions, source, opts)
+}
+
+// ValidateWith is Validate with the preprocessed code validated by check,
+// such as the Validate method of a compiler.Compiler.
+func ValidateWith(check func(string, api.ValidateOptions) api.ValidateResult, source string, opts ValidateOptions) api.ValidateResult {
+	pre := preprocess.Process(source, opts.Defines)
+	if len(pre.Errors) > 0 {
+		result := api.ValidateResult{Diagnostics: make([]api.DiagnosticInfo, 0, len(pre.Errors))}
+		for _, e := range pre.Errors {
+			result.Diagnostics = append(result.Diagnostics, api.DiagnosticInfo{
+				Severity: "error",
+				Message:  e.Message,
+				Line:     e.Line,
+				Column:   e.Column,
+			})
+			result.ErrorCount++
+		}
+		return result
+	}
+
+	result := check(pre.Code, api.ValidateOptions{
+		StrictMode:        opts.StrictMode,
+		DiagnosticFilters: opts.DiagnosticFilters,
+		Lint:              opts.Lint,
+		Stats:             opts.Stats,
+	})
+	if pre.Code == source {
+		return result
+	}
+
+	// Map positions in the preprocessed code back to the source
+	index := sourcemap.NewLineIndex(pre.Code)
+	position := func(line, col int) (int, int) {
+		if line <= 0 {
+			return line, col
+		}
+		return pre.Position(index.LineColumnToByteOffset(line-1, col-1))
+	}
+	for i := range result.Diagnostics {
+		d := &result.Diagnostics[i]
+		d.Line, d.Column = position(d.Line, d.Column)
+		d.EndLine, d.EndColumn = position(d.EndLine, d.EndColumn)
+	}
+	return result
+}
+
+// Context minifies and validates with fixed options, which are parsed
+// once. It is the state behind a miniray_context handle: a compiler that
+// caches reserved names, minified results and parsed modules, and
+// validates incrementally. It is safe for concurrent use.
+type Context struct {
+	validate ValidateOptions
+	compiler *compiler.Compiler
+}
+
+// NewContext creates a context for the options JSON of minification.
+func NewContext(optionsJSON string) (*Context, error) {
+	opts, err := ParseMinifyOptions(optionsJSON)
+	if err != nil {
+		return nil, err
+	}
+	return newContext(opts), nil
+}
+
+func newContext(opts MinifyOptions) *Context {
+	return &Context{
+		validate: opts.ValidateOptions(),
+		compiler: compiler.New(opts.MinifierOptions(), 0),
+	}
+}
+
+// Minify minifies source with the options of the context.
+func (c *Context) Minify(source string) MinifyResult {
+	return newMinifyResult(c.compiler.Minify(source))
+}
+
+// Validate validates source with the validation options of the context,
+// incrementally from the previous source validated with the context.
+func (c *Context) Validate(source string) api.ValidateResult {
+	return ValidateWith(func(code string, opts api.ValidateOptions) api.ValidateResult {
+		return c.compiler.ValidateDocument("", code, opts)
+	}, source, c.validate)
+}
+
+// MinifyAndValidate is MinifyAndValidate with the options of the context.
+func (c *Context) MinifyAndValidate(source string) MinifyAndValidateResult {
+	return MinifyAndValidateResult{
+		MinifyResult: c.Minify(source),
+		Validation:   c.Validate(source),
+	}
+}
+
+// FormatResult is the JSON result of formatting.
+type FormatResult struct {
+	Code        string               `json:"code"`
+	Diagnostics []api.DiagnosticInfo `json:"diagnostics"`
+}
+
+// Format prints source with the indentation and spacing of the printer's
+// readable mode, keeping its comments. Source with syntax errors is not
+// formatted; its errors are reported instead.
+func Format(source string) FormatResult {
+	code, errs := cst.Format(source)
+	result := FormatResult{Code: code, Diagnostics: make([]api.DiagnosticInfo, 0, len(errs))}
+	for _, e := range errs {
+		result.Diagnostics = append(result.Diagnostics, api.DiagnosticInfo{
+			Severity:  "error",
+			Message:   e.Message,
+			Line:      e.Line,
+			Column:    e.Column,
+			EndLine:   e.EndLine,
+			EndColumn: e.EndColumn,
+		})
+	}
+	return result
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/minifier"
+)
+
+const testShader = `struct Uniforms { color: vec4f }
+@group(0) @binding(0) var<uniform> uniforms: Uniforms;
+
+fn unused() -> f32 { return 1.0; }
+
+@fragment fn main() -> @location(0) vec4f {
+    let tint = uniforms.color;
+    return tint;
+}
+`
+
+func mustParseMinifyOptions(t *testing.T, data string) MinifyOptions {
+	t.Helper()
+	opts, err := ParseMinifyOptions(data)
+	if err != nil {
+		t.Fatalf("ParseMinifyOptions(%s): %v", data, err)
+	}
+	return opts
+}
+
+func TestOptionsUseConfigSchema(t *testing.T) {
+	// Omitted options take the defaults, as in miniray.json
+	opts := mustParseMinifyOptions(t, `{"minifyIdentifiers": false}`).MinifierOptions()
+	want := minifier.DefaultOptions()
+	want.MinifyIdentifiers = false
+	if opts.MinifyWhitespace != want.MinifyWhitespace || opts.MinifyIdentifiers != want.MinifyIdentifiers ||
+		opts.MinifySyntax != want.MinifySyntax || opts.TreeShaking != want.TreeShaking {
+		t.Errorf("options = %+v, want defaults without identifier renaming", opts)
+	}
+
+	opts = mustParseMinifyOptions(t, `{
+		"mangleProps": true,
+		"preserveUniformStructTypes": true,
+		"keepNames": ["tint"],
+		"defines": {"SHADOWS": "1"},
+		"overrides": {"scale": 2},
+		"sourceMap": true,
+		"sourceMapSources": true,
+		"sourceMapScopes": true,
+		"sourceName": "shader.wgsl",
+		"file": "shader.min.wgsl"
+	}`).MinifierOptions()
+	if !opts.MangleProps || !opts.PreserveUniformStructTypes {
+		t.Errorf("mangleProps and preserveUniformStructTypes were ignored: %+v", opts)
+	}
+	if len(opts.KeepNames) != 1 || opts.Defines["SHADOWS"] != "1" || opts.Overrides["scale"] != 2 {
+		t.Errorf("keepNames, defines or overrides were ignored: %+v", opts)
+	}
+	sm := opts.SourceMapOptions
+	if !opts.GenerateSourceMap || !sm.IncludeSource || !sm.Scopes || sm.SourceName != "shader.wgsl" || sm.File != "shader.min.wgsl" {
+		t.Errorf("source map options were ignored: %+v", opts)
+	}
+
+	if _, err := ParseMinifyOptions(`{"minifyWhitespace": "yes"}`); err == nil {
+		t.Error("expected an error for a mistyped option")
+	}
+}
+
+func TestMinifyResult(t *testing.T) {
+	result := Minify(testShader, mustParseMinifyOptions(t, `{"sourceMap": true, "sourceName": "shader.wgsl"}`))
+
+	if len(result.Diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %+v", result.Diagnostics)
+	}
+	if result.Stats.SymbolsTotal == 0 || result.Stats.SymbolsDead == 0 || result.Stats.LetsInlined != 1 {
+		t.Errorf("symbol counts missing from stats: %+v", result.Stats)
+	}
+	if result.Stats.OriginalSize != len(testShader) || result.OriginalSize != len(testShader) {
+		t.Errorf("original size = %d/%d, want %d", result.Stats.OriginalSize, result.OriginalSize, len(testShader))
+	}
+	if len(result.Hash) != 8 {
+		t.Errorf("hash = %q", result.Hash)
+	}
+	if !strings.Contains(result.SourceMap, `"sources":["shader.wgsl"]`) {
+		t.Errorf("source map = %s", result.SourceMap)
+	}
+
+	// The JSON keeps the fields of earlier versions
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{`"code":`, `"originalSize":`, `"minifiedSize":`, `"diagnostics":[]`, `"stats":{`, `"hash":`, `"sourceMap":`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("JSON result has no %s: %s", key, data)
+		}
+	}
+}
+
+func TestMinifyErrorPositions(t *testing.T) {
+	result := Minify("fn f() {\n  let x = ;\n}", MinifyOptions{})
+	if len(result.Errors) == 0 || len(result.Diagnostics) != len(result.Errors) {
+		t.Fatalf("errors = %v, diagnostics = %+v", result.Errors, result.Diagnostics)
+	}
+	d := result.Diagnostics[0]
+	if d.Severity != "error" || d.Line != 2 || d.Column != 11 {
+		t.Errorf("diagnostic = %+v, want an error at 2:11", d)
+	}
+	if result.Hash != "" {
+		t.Errorf("hash = %q for a failed minification", result.Hash)
+	}
+}
+
+func TestMinifyAndValidate(t *testing.T) {
+	source := `#ifdef BROKEN
+fn f() -> i32 { return 1.5; }
+#endif
+@fragment fn main() -> @location(0) vec4f { return vec4f(1.0); }
+`
+	result := MinifyAndValidate(source, MinifyOptions{})
+	if !result.Validation.Valid || result.Code == "" {
+		t.Errorf("expected a valid default variant: %+v", result)
+	}
+
+	result = MinifyAndValidate(source, mustParseMinifyOptions(t, `{"defines": {"BROKEN": ""}}`))
+	if result.Validation.Valid || result.Validation.ErrorCount != 1 {
+		t.Fatalf("expected one error with BROKEN defined: %+v", result.Validation)
+	}
+	d := result.Validation.Diagnostics[0]
+	if d.Line != 2 || d.Column != 17 {
+		t.Errorf("diagnostic at %d:%d, want 2:17 in the source", d.Line, d.Column)
+	}
+	if !strings.Contains(result.Code, "fn main") {
+		t.Errorf("minification did not run: %q", result.Code)
+	}
+}
+
+func TestMinifyAndValidateLintSeverities(t *testing.T) {
+	source := `fn unused() {}
+@fragment fn main() -> @location(0) vec4f { return vec4f(1.0); }
+`
+	count := func(options string) int {
+		result := MinifyAndValidate(source, mustParseMinifyOptions(t, options))
+		return result.Validation.WarningCount + result.Validation.ErrorCount
+	}
+
+	if n := count(`{"validate": {"lint": true}}`); n == 0 {
+		t.Fatal("expected a lint warning for the unused function")
+	}
+	if n := count(`{"lint": {"unused_function": "off"}, "validate": {"lint": true}}`); n != 0 {
+		t.Errorf("config lint severities were ignored: %d diagnostics", n)
+	}
+	if n := count(`{"lint": {"unused_function": "off"}, "validate": {"lint": true, "diagnosticFilters": {"unused_function": "warning"}}}`); n == 0 {
+		t.Error("diagnosticFilters should take precedence over lint severities")
+	}
+}
+
+func TestValidatePreprocessorErrors(t *testing.T) {
+	result := Validate("#ifdef A\nfn f() {}\n", ValidateOptions{})
+	if result.Valid || result.ErrorCount != 1 || result.Diagnostics[0].Line == 0 {
+		t.Errorf("expected a positioned error for the unterminated #ifdef: %+v", result)
+	}
+}
+
+func TestContext(t *testing.T) {
+	options := `{"keepNames": ["tint"], "defines": {"BROKEN": ""}, "validate": {"lint": true}}`
+	ctx, err := NewContext(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := mustParseMinifyOptions(t, options)
+
+	if got, want := ctx.Minify(testShader), Minify(testShader, opts); got.Code != want.Code || got.Hash != want.Hash {
+		t.Errorf("context minify = %+v, want %+v", got, want)
+	}
+
+	source := `#ifdef BROKEN
+fn f() -> i32 { return 1.5; }
+#endif
+@fragment fn main() -> @location(0) vec4f { return vec4f(1.0); }
+`
+	for i := 0; i < 2; i++ {
+		got, want := ctx.MinifyAndValidate(source), MinifyAndValidate(source, opts)
+		if got.Validation.ErrorCount != 1 || got.Validation.WarningCount != want.Validation.WarningCount {
+			t.Fatalf("run %d: validation = %+v, want %+v", i, got.Validation, want.Validation)
+		}
+		if d := got.Validation.Diagnostics[0]; d.Line != 2 || d.Column != 17 {
+			t.Errorf("run %d: diagnostic at %d:%d, want 2:17 in the source", i, d.Line, d.Column)
+		}
+	}
+	if minifyStats, validateStats := ctx.compiler.Stats(); minifyStats.Hits != 1 || validateStats.Hits != 1 {
+		t.Errorf("unchanged source was not reused: minify %+v, validate %+v", minifyStats, validateStats)
+	}
+
+	if _, err := NewContext(`{"keepNames": "tint"}`); err == nil {
+		t.Error("expected an error for mistyped options")
+	}
+}
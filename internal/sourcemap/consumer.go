@@ -0,0 +1,197 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+)
+
+// Consumer answers position queries against a decoded source map.
+// Lines and columns are 0-indexed, as in Mapping.
+type Consumer struct {
+	Map *SourceMap
+
+	// mappings sorted by generated position
+	generated []Mapping
+
+	// mappings with a source, sorted by original position
+	original []Mapping
+}
+
+// OriginalPosition is a position in an original source file.
+type OriginalPosition struct {
+	Source string // Source file name (empty if the map has none)
+	Line   int    // Source line (0-indexed)
+	Column int    // Source column (0-indexed)
+	Name   string // Original identifier name (empty if none)
+}
+
+// GeneratedPosition is a position in the generated file.
+type GeneratedPosition struct {
+	Line   int // Generated line (0-indexed)
+	Column int // Generated column (0-indexed)
+}
+
+// Parse decodes a source map from its JSON representation.
+func Parse(data []byte) (*SourceMap, error) {
+	var sm SourceMap
+	if err := json.Unmarshal(data, &sm); err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+	if sm.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", sm.Version)
+	}
+	return &sm, nil
+}
+
+// NewConsumer creates a consumer for the source map in data.
+func NewConsumer(data []byte) (*Consumer, error) {
+	sm, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewConsumerForMap(sm)
+}
+
+// NewConsumerForMap creates a consumer for an already decoded source map.
+func NewConsumerForMap(sm *SourceMap) (*Consumer, error) {
+	mappings, err := DecodeMappings(sm.Mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Consumer{Map: sm, generated: mappings}
+	sort.SliceStable(c.generated, func(i, j int) bool {
+		return lessGenerated(c.generated[i], c.generated[j])
+	})
+
+	for _, m := range mappings {
+		if m.SrcIndex >= 0 && m.SrcIndex < len(sm.Sources) {
+			c.original = append(c.original, m)
+		} else if len(sm.Sources) == 0 && m.SrcIndex == 0 {
+			// Maps of unnamed sources still carry positions
+			c.original = append(c.original, m)
+		}
+	}
+	sort.SliceStable(c.original, func(i, j int) bool {
+		a, b := c.original[i], c.original[j]
+		if a.SrcIndex != b.SrcIndex {
+			return a.SrcIndex < b.SrcIndex
+		}
+		if a.SrcLine != b.SrcLine {
+			return a.SrcLine < b.SrcLine
+		}
+		if a.SrcCol != b.SrcCol {
+			return a.SrcCol < b.SrcCol
+		}
+		return lessGenerated(a, b)
+	})
+
+	return c, nil
+}
+
+func lessGenerated(a, b Mapping) bool {
+	if a.GenLine != b.GenLine {
+		return a.GenLine < b.GenLine
+	}
+	return a.GenCol < b.GenCol
+}
+
+// Mappings returns all mappings sorted by generated position.
+func (c *Consumer) Mappings() []Mapping {
+	return c.generated
+}
+
+// OriginalPositionFor returns the original position of the generated
+// position line:col. It uses the closest mapping at or before col on the
+// same line, and reports false if the line has none.
+func (c *Consumer) OriginalPositionFor(line, col int) (OriginalPosition, bool) {
+	m, ok := c.mappingFor(line, col)
+	if !ok {
+		return OriginalPosition{}, false
+	}
+	return c.originalOf(m), true
+}
+
+// mappingFor returns the closest mapping at or before line:col on that line.
+func (c *Consumer) mappingFor(line, col int) (Mapping, bool) {
+	i := sort.Search(len(c.generated), func(i int) bool {
+		m := c.generated[i]
+		return m.GenLine > line || (m.GenLine == line && m.GenCol > col)
+	})
+	if i == 0 || c.generated[i-1].GenLine != line {
+		return Mapping{}, false
+	}
+	return c.generated[i-1], true
+}
+
+func (c *Consumer) originalOf(m Mapping) OriginalPosition {
+	pos := OriginalPosition{Line: m.SrcLine, Column: m.SrcCol}
+	if m.SrcIndex >= 0 && m.SrcIndex < len(c.Map.Sources) {
+		pos.Source = c.sourceName(m.SrcIndex)
+	}
+	if m.HasName && m.NameIndex >= 0 && m.NameIndex < len(c.Map.Names) {
+		pos.Name = c.Map.Names[m.NameIndex]
+	}
+	return pos
+}
+
+func (c *Consumer) sourceName(index int) string {
+	source := c.Map.Sources[index]
+	if c.Map.SourceRoot != "" {
+		source = path.Join(c.Map.SourceRoot, source)
+	}
+	return source
+}
+
+// GeneratedPositionFor returns the first generated position of the
+// original position line:col in source. It uses the closest mapping at or
+// before col on the same line, or else the first one after it. An empty
+// source selects the first source of the map.
+func (c *Consumer) GeneratedPositionFor(source string, line, col int) (GeneratedPosition, bool) {
+	index, ok := c.sourceIndex(source)
+	if !ok {
+		return GeneratedPosition{}, false
+	}
+
+	var best *Mapping
+	for i := range c.original {
+		m := &c.original[i]
+		if m.SrcIndex != index || m.SrcLine != line {
+			continue
+		}
+		if m.SrcCol > col {
+			if best == nil {
+				best = m
+			}
+			break
+		}
+		// Prefer the first generated position of the closest column
+		if best == nil || m.SrcCol != best.SrcCol {
+			best = m
+		}
+	}
+	if best == nil {
+		return GeneratedPosition{}, false
+	}
+	return GeneratedPosition{Line: best.GenLine, Column: best.GenCol}, true
+}
+
+func (c *Consumer) sourceIndex(source string) (int, bool) {
+	if source == "" {
+		return 0, true
+	}
+	for i, s := range c.Map.Sources {
+		if s == source || c.sourceName(i) == source {
+			return i, true
+		}
+	}
+	// Fall back to matching the file name alone
+	for i, s := range c.Map.Sources {
+		if path.Base(s) == path.Base(source) {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,99 @@
+package sourcemap
+
+import (
+	"testing"
+)
+
+// newTestConsumer maps "const x = 1;\nconst y = x;" to "const a=1;const b=a;".
+func newTestConsumer(t *testing.T) *Consumer {
+	t.Helper()
+	g := NewGenerator("const x = 1;\nconst y = x;")
+	g.SetFile("out.wgsl")
+	g.SetSourceName("in.wgsl")
+	g.AddMapping(0, 0, 0, "")
+	g.AddMapping(0, 6, 6, "x")
+	g.AddMapping(0, 10, 13, "")
+	g.AddMapping(0, 16, 19, "y")
+	g.AddMapping(0, 18, 23, "x")
+
+	c, err := NewConsumer([]byte(g.Generate().ToJSON()))
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	return c
+}
+
+func TestConsumerOriginalPositionFor(t *testing.T) {
+	c := newTestConsumer(t)
+
+	tests := []struct {
+		line, col int
+		want      OriginalPosition
+	}{
+		{0, 0, OriginalPosition{Source: "in.wgsl", Line: 0, Column: 0}},
+		{0, 6, OriginalPosition{Source: "in.wgsl", Line: 0, Column: 6, Name: "x"}},
+		{0, 8, OriginalPosition{Source: "in.wgsl", Line: 0, Column: 6, Name: "x"}},
+		{0, 16, OriginalPosition{Source: "in.wgsl", Line: 1, Column: 6, Name: "y"}},
+		{0, 18, OriginalPosition{Source: "in.wgsl", Line: 1, Column: 10, Name: "x"}},
+	}
+	for _, tt := range tests {
+		got, ok := c.OriginalPositionFor(tt.line, tt.col)
+		if !ok || got != tt.want {
+			t.Errorf("OriginalPositionFor(%d, %d) = %+v, %v; want %+v", tt.line, tt.col, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := c.OriginalPositionFor(1, 0); ok {
+		t.Error("OriginalPositionFor on a line without mappings should fail")
+	}
+}
+
+func TestConsumerGeneratedPositionFor(t *testing.T) {
+	c := newTestConsumer(t)
+
+	tests := []struct {
+		source    string
+		line, col int
+		want      GeneratedPosition
+	}{
+		{"in.wgsl", 0, 6, GeneratedPosition{Line: 0, Column: 6}},
+		{"in.wgsl", 0, 8, GeneratedPosition{Line: 0, Column: 6}},
+		{"in.wgsl", 1, 0, GeneratedPosition{Line: 0, Column: 10}},
+		{"in.wgsl", 1, 11, GeneratedPosition{Line: 0, Column: 18}},
+		{"", 1, 6, GeneratedPosition{Line: 0, Column: 16}},
+		{"src/in.wgsl", 1, 6, GeneratedPosition{Line: 0, Column: 16}},
+	}
+	for _, tt := range tests {
+		got, ok := c.GeneratedPositionFor(tt.source, tt.line, tt.col)
+		if !ok || got != tt.want {
+			t.Errorf("GeneratedPositionFor(%q, %d, %d) = %+v, %v; want %+v", tt.source, tt.line, tt.col, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := c.GeneratedPositionFor("other.wgsl", 0, 0); ok {
+		t.Error("GeneratedPositionFor on an unknown source should fail")
+	}
+	if _, ok := c.GeneratedPositionFor("in.wgsl", 5, 0); ok {
+		t.Error("GeneratedPositionFor on a line without mappings should fail")
+	}
+}
+
+func TestConsumerSourceRoot(t *testing.T) {
+	c, err := NewConsumer([]byte(`{"version":3,"sourceRoot":"shaders","sources":["a.wgsl"],"names":[],"mappings":"AAAA"}`))
+	if err != nil {
+		t.Fatalf("NewConsumer: %v", err)
+	}
+	pos, ok := c.OriginalPositionFor(0, 3)
+	if !ok || pos.Source != "shaders/a.wgsl" {
+		t.Errorf("OriginalPositionFor = %+v, %v; want source shaders/a.wgsl", pos, ok)
+	}
+}
+
+func TestConsumerInvalidMap(t *testing.T) {
+	if _, err := NewConsumer([]byte(`{`)); err == nil {
+		t.Error("expected an error for malformed JSON")
+	}
+	if _, err := NewConsumer([]byte(`{"version":2,"sources":[],"names":[],"mappings":""}`)); err == nil {
+		t.Error("expected an error for a version 2 map")
+	}
+}
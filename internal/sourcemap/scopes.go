@@ -0,0 +1,100 @@
+package sourcemap
+
+// Scope kinds used in the x_miniray_scopes extension.
+const (
+	ScopeModule   = "module"
+	ScopeFunction = "function"
+	ScopeBlock    = "block"
+)
+
+// Scope describes a lexical scope of the original source and the range of
+// generated code it became. Positions are [line, column] pairs, 0-indexed,
+// with the same column units as the mappings. Scopes are emitted in the
+// "x_miniray_scopes" field of the source map when enabled with
+// Generator.IncludeScopes.
+type Scope struct {
+	Kind           string    `json:"kind"`
+	Name           string    `json:"name,omitempty"`
+	Start          [2]int    `json:"start"`
+	End            [2]int    `json:"end"`
+	GeneratedStart [2]int    `json:"generatedStart"`
+	GeneratedEnd   [2]int    `json:"generatedEnd"`
+	Bindings       []Binding `json:"bindings,omitempty"`
+	Children       []*Scope  `json:"children,omitempty"`
+}
+
+// Binding pairs a name declared in a scope with its generated name.
+type Binding struct {
+	Name      string `json:"name"`
+	Generated string `json:"generated"`
+}
+
+// IncludeScopes sets whether to record scopes in "x_miniray_scopes".
+func (g *Generator) IncludeScopes(include bool) {
+	g.includeScopes = include
+}
+
+// ScopesEnabled reports whether scopes are being recorded.
+func (g *Generator) ScopesEnabled() bool {
+	return g.includeScopes
+}
+
+// EnterScope opens a scope nested in the current one. srcStart and srcEnd
+// are byte offsets in the source, like AddMapping's srcOffset; a negative
+// srcEnd stands for the end of the source. genLine and genCol give where
+// the scope starts in the generated output.
+func (g *Generator) EnterScope(kind, name string, srcStart, srcEnd, genLine, genCol int) {
+	if !g.includeScopes {
+		return
+	}
+
+	scope := &Scope{
+		Kind:           kind,
+		Name:           name,
+		Start:          g.sourcePosition(srcStart),
+		GeneratedStart: [2]int{genLine, genCol},
+	}
+	if srcEnd < 0 {
+		line, col := g.lineIndex.ByteOffsetToLineColumnUTF16(len(g.source))
+		scope.End = [2]int{line, col}
+	} else {
+		scope.End = g.sourcePosition(srcEnd)
+	}
+
+	if n := len(g.scopeStack); n > 0 {
+		parent := g.scopeStack[n-1]
+		parent.Children = append(parent.Children, scope)
+	} else {
+		g.scopes = append(g.scopes, scope)
+	}
+	g.scopeStack = append(g.scopeStack, scope)
+}
+
+// ExitScope closes the current scope, which ends at genLine:genCol in the
+// generated output.
+func (g *Generator) ExitScope(genLine, genCol int) {
+	if !g.includeScopes || len(g.scopeStack) == 0 {
+		return
+	}
+	n := len(g.scopeStack)
+	g.scopeStack[n-1].GeneratedEnd = [2]int{genLine, genCol}
+	g.scopeStack = g.scopeStack[:n-1]
+}
+
+// AddBinding records that name is declared in the current scope and is
+// called generated in the output.
+func (g *Generator) AddBinding(name, generated string) {
+	if !g.includeScopes || len(g.scopeStack) == 0 {
+		return
+	}
+	scope := g.scopeStack[len(g.scopeStack)-1]
+	scope.Bindings = append(scope.Bindings, Binding{Name: name, Generated: generated})
+}
+
+func (g *Generator) sourcePosition(offset int) [2]int {
+	if g.mapOffset != nil {
+		offset = g.mapOffset(offset)
+	}
+	line, col := g.lineIndex.ByteOffsetToLineColumnUTF16(offset)
+	return [2]int{line, col}
+}
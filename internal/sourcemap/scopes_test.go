@@ -0,0 +1,74 @@
+package sourcemap
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestScopesDisabledByDefault(t *testing.T) {
+	g := NewGenerator("fn f() {}")
+	g.EnterScope(ScopeModule, "", 0, -1, 0, 0)
+	g.AddBinding("f", "a")
+	g.ExitScope(0, 8)
+
+	sm := g.Generate()
+	if sm.Scopes != nil {
+		t.Errorf("Scopes = %v, want nil", sm.Scopes)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(sm.ToJSON()), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["x_miniray_scopes"]; ok {
+		t.Error("x_miniray_scopes should be omitted when scopes are disabled")
+	}
+}
+
+func TestScopesNesting(t *testing.T) {
+	source := "const k = 1;\nfn f(p: i32) {\n    let v = p;\n}"
+	g := NewGenerator(source)
+	g.IncludeScopes(true)
+
+	g.EnterScope(ScopeModule, "", 0, -1, 0, 0)
+	g.AddBinding("k", "b")
+	g.AddBinding("f", "c")
+	g.EnterScope(ScopeFunction, "f", 13, len(source), 0, 10)
+	g.AddBinding("p", "a")
+	g.AddBinding("v", "d")
+	g.ExitScope(0, 31)
+	g.ExitScope(0, 31)
+
+	sm := g.Generate()
+	if len(sm.Scopes) != 1 {
+		t.Fatalf("expected 1 root scope, got %d", len(sm.Scopes))
+	}
+	module := sm.Scopes[0]
+	if module.Kind != ScopeModule || module.Start != [2]int{0, 0} || module.End != [2]int{3, 1} {
+		t.Errorf("module scope = %+v", module)
+	}
+	if len(module.Bindings) != 2 || module.Bindings[1] != (Binding{Name: "f", Generated: "c"}) {
+		t.Errorf("module bindings = %v", module.Bindings)
+	}
+	if len(module.Children) != 1 {
+		t.Fatalf("expected 1 child scope, got %d", len(module.Children))
+	}
+	fn := module.Children[0]
+	if fn.Kind != ScopeFunction || fn.Name != "f" || fn.Start != [2]int{1, 0} || fn.End != [2]int{3, 1} {
+		t.Errorf("function scope = %+v", fn)
+	}
+	if fn.GeneratedStart != [2]int{0, 10} || fn.GeneratedEnd != [2]int{0, 31} {
+		t.Errorf("function generated range = %v-%v", fn.GeneratedStart, fn.GeneratedEnd)
+	}
+	if len(fn.Bindings) != 2 || fn.Bindings[0] != (Binding{Name: "p", Generated: "a"}) {
+		t.Errorf("function bindings = %v", fn.Bindings)
+	}
+
+	// The extension survives a round trip through the consumer
+	c, err := NewConsumer([]byte(sm.ToJSON()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Map.Scopes) != 1 || len(c.Map.Scopes[0].Children) != 1 {
+		t.Errorf("decoded scopes = %+v", c.Map.Scopes)
+	}
+}
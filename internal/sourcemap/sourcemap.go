@@ -16,6 +16,9 @@ type SourceMap struct {
 	SourcesContent []string `json:"sourcesContent,omitempty"`
 	Names          []string `json:"names"`
 	Mappings       string   `json:"mappings"`
+
+	// Scopes is the "x_miniray_scopes" extension (nil unless enabled)
+	Scopes []*Scope `json:"x_miniray_scopes,omitempty"`
 }
 
 // Mapping represents a decoded source map mapping.
@@ -40,6 +43,10 @@ type Generator struct {
 	sourceName    string
 	includeSource bool
 
+	// mapOffset converts offsets in the parsed text to offsets in source,
+	// when the two differ (nil otherwise)
+	mapOffset func(int) int
+
 	// Current generated line for tracking
 	currentGenLine int
 
@@ -48,6 +55,11 @@ type Generator struct {
 	// Mozilla's source-map library that returns null for lines without a
 	// mapping at column 0.
 	coverLinesWithoutMappings bool
+
+	// Scope recording for the x_miniray_scopes extension
+	includeScopes bool
+	scopes        []*Scope
+	scopeStack    []*Scope
 }
 
 // NewGenerator creates a new source map generator for the given original source.
@@ -77,6 +89,13 @@ func (g *Generator) IncludeSourceContent(include bool) {
 	g.includeSource = include
 }
 
+// SetOffsetMapper sets a function that converts offsets passed to
+// AddMapping into offsets in the original source. It is used when the
+// parsed text was derived from the source, e.g. by a preprocessor.
+func (g *Generator) SetOffsetMapper(mapOffset func(int) int) {
+	g.mapOffset = mapOffset
+}
+
 // SetCoverLinesWithoutMappings enables or disables the line coverage workaround.
 // When enabled (default), a mapping at column 0 is added for any line that would
 // otherwise have no mappings. This works around a bug in Mozilla's source-map
@@ -90,6 +109,9 @@ func (g *Generator) SetCoverLinesWithoutMappings(cover bool) {
 // srcOffset is the byte offset in the original source.
 // name is the original name (empty string if no name mapping needed).
 func (g *Generator) AddMapping(genLine, genCol, srcOffset int, name string) {
+	if g.mapOffset != nil {
+		srcOffset = g.mapOffset(srcOffset)
+	}
 	srcLine, srcCol := g.lineIndex.ByteOffsetToLineColumnUTF16(srcOffset)
 
 	m := Mapping{
@@ -134,6 +156,10 @@ func (g *Generator) Generate() *SourceMap {
 		sm.SourcesContent = []string{g.source}
 	}
 
+	if g.includeScopes {
+		sm.Scopes = g.scopes
+	}
+
 	return sm
 }
 
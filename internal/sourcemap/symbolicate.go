@@ -0,0 +1,146 @@
+package sourcemap
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Compiler messages locate errors as "file:line:col" or ":line:col", with
+// 1-indexed lines and columns (Tint, Dawn and browsers all use this form).
+var messagePosition = regexp.MustCompile(`([^\s:()'"]*:)?\b(\d+):(\d+)\b`)
+
+// Compiler messages quote identifiers in single quotes.
+var quotedIdentifier = regexp.MustCompile(`'([A-Za-z_][A-Za-z0-9_]*)'`)
+
+// Symbolicator rewrites compiler messages about generated code so that they
+// refer to original positions and identifier names.
+type Symbolicator struct {
+	consumer *Consumer
+
+	// renamed maps each generated identifier to the mappings naming it
+	renamed map[string][]Mapping
+}
+
+// NewSymbolicator creates a symbolicator for c. generated is the generated
+// code the map describes; it is needed to recognize renamed identifiers and
+// may be empty, in which case only positions are rewritten.
+func NewSymbolicator(c *Consumer, generated string) *Symbolicator {
+	s := &Symbolicator{consumer: c, renamed: make(map[string][]Mapping)}
+	if generated == "" {
+		return s
+	}
+
+	lines := strings.Split(generated, "\n")
+	for _, m := range c.generated {
+		if !m.HasName || m.GenLine >= len(lines) {
+			continue
+		}
+		if ident := identifierAt(lines[m.GenLine], m.GenCol); ident != "" {
+			s.renamed[ident] = append(s.renamed[ident], m)
+		}
+	}
+	return s
+}
+
+// identifierAt returns the identifier starting at byte col of line.
+func identifierAt(line string, col int) string {
+	if col < 0 || col >= len(line) {
+		return ""
+	}
+	end := col
+	for end < len(line) && isIdentByte(line[end], end == col) {
+		end++
+	}
+	return line[col:end]
+}
+
+func isIdentByte(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// Symbolicate rewrites every line of text. Positions are replaced by the
+// original file:line:col they map to, and quoted identifiers that were
+// renamed are replaced by their original names. Lines without a position
+// use the last position seen, so that notes following an error resolve
+// names in the same scope.
+func (s *Symbolicator) Symbolicate(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	line, col, hasPosition := 0, 0, false
+
+	for i, text := range lines {
+		if loc := messagePosition.FindStringSubmatchIndex(text); loc != nil {
+			line, _ = strconv.Atoi(text[loc[4]:loc[5]])
+			col, _ = strconv.Atoi(text[loc[6]:loc[7]])
+			line, col, hasPosition = line-1, col-1, true
+		}
+		text = messagePosition.ReplaceAllStringFunc(text, s.position)
+		if len(s.renamed) > 0 {
+			text = quotedIdentifier.ReplaceAllStringFunc(text, func(quoted string) string {
+				name, ok := s.originalName(quoted[1:len(quoted)-1], line, col, hasPosition)
+				if !ok {
+					return quoted
+				}
+				return "'" + name + "'"
+			})
+		}
+		lines[i] = text
+	}
+	return strings.Join(lines, "")
+}
+
+// position rewrites a single "file:line:col" match.
+func (s *Symbolicator) position(match string) string {
+	parts := messagePosition.FindStringSubmatch(match)
+	line, _ := strconv.Atoi(parts[2])
+	col, _ := strconv.Atoi(parts[3])
+	pos, ok := s.consumer.OriginalPositionFor(line-1, col-1)
+	if !ok {
+		return match
+	}
+	file := parts[1]
+	if pos.Source != "" {
+		file = pos.Source + ":"
+	}
+	return fmt.Sprintf("%s%d:%d", file, pos.Line+1, pos.Column+1)
+}
+
+// originalName returns the original name of the generated identifier
+// ident. Short names are reused across scopes, so when several original
+// names are possible the one whose mapping is at, or closest before, the
+// message position wins. Without a position all candidates must agree.
+func (s *Symbolicator) originalName(ident string, line, col int, hasPosition bool) (string, bool) {
+	candidates := s.renamed[ident]
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	names := s.consumer.Map.Names
+	nameOf := func(m Mapping) string {
+		if m.NameIndex < 0 || m.NameIndex >= len(names) {
+			return ""
+		}
+		return names[m.NameIndex]
+	}
+
+	if hasPosition {
+		best := candidates[0]
+		for _, m := range candidates {
+			if m.GenLine > line || (m.GenLine == line && m.GenCol > col) {
+				break
+			}
+			best = m
+		}
+		name := nameOf(best)
+		return name, name != ""
+	}
+
+	name := nameOf(candidates[0])
+	for _, m := range candidates[1:] {
+		if nameOf(m) != name {
+			return "", false
+		}
+	}
+	return name, name != ""
+}
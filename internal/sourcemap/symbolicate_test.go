@@ -0,0 +1,73 @@
+package sourcemap
+
+import (
+	"testing"
+)
+
+func TestSymbolicate(t *testing.T) {
+	c := newTestConsumer(t)
+	s := NewSymbolicator(c, "const a=1;const b=a;")
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "position with file",
+			input: "out.wgsl:1:17 error: redeclaration of 'b'\n",
+			want:  "in.wgsl:2:7 error: redeclaration of 'y'\n",
+		},
+		{
+			name:  "position without file",
+			input: "Error while parsing WGSL: :1:19 error: unresolved identifier 'a'\n",
+			want:  "Error while parsing WGSL: in.wgsl:2:11 error: unresolved identifier 'x'\n",
+		},
+		{
+			name:  "note uses the previous position",
+			input: ":1:7 error: bad 'a'\nnote: see 'b'",
+			want:  "in.wgsl:1:7 error: bad 'x'\nnote: see 'y'",
+		},
+		{
+			name:  "unmapped position and unknown names are kept",
+			input: ":3:1 error: unknown 'zz'",
+			want:  ":3:1 error: unknown 'zz'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Symbolicate(tt.input); got != tt.want {
+				t.Errorf("\nexpected: %q\nactual:   %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSymbolicateWithoutGeneratedCode(t *testing.T) {
+	s := NewSymbolicator(newTestConsumer(t), "")
+	got := s.Symbolicate(":1:17 error: redeclaration of 'b'")
+	want := "in.wgsl:2:7 error: redeclaration of 'b'"
+	if got != want {
+		t.Errorf("\nexpected: %q\nactual:   %q", want, got)
+	}
+}
+
+func TestSymbolicateReusedNames(t *testing.T) {
+	// "a" is the renamed form of both "first" and "second"
+	g := NewGenerator("fn f(first: i32) {}\nfn g(second: i32) {}")
+	g.SetSourceName("in.wgsl")
+	g.AddMapping(0, 5, 5, "first")
+	g.AddMapping(0, 18, 26, "second")
+	c, err := NewConsumerForMap(g.Generate())
+	if err != nil {
+		t.Fatalf("NewConsumerForMap: %v", err)
+	}
+	s := NewSymbolicator(c, "fn f(a:i32){}fn g(a:i32){}")
+
+	if got, want := s.Symbolicate(":1:19 error: 'a'"), "in.wgsl:2:7 error: 'second'"; got != want {
+		t.Errorf("\nexpected: %q\nactual:   %q", want, got)
+	}
+	if got, want := s.Symbolicate("error: 'a'"), "error: 'a'"; got != want {
+		t.Errorf("ambiguous name without a position should be kept\nexpected: %q\nactual:   %q", want, got)
+	}
+}
@@ -2,6 +2,7 @@ package sourcemap_tests
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -588,3 +589,201 @@ fn main(@builtin(global_invocation_id) id: vec3<u32>) {
 		})
 	}
 }
+
+func TestSourceMapPreprocessed(t *testing.T) {
+	source := `#define SCALE 2.0
+#ifdef DEBUG
+const debugValue = 1.0;
+#endif
+const longName = SCALE; const other = longName;
+fn f() -> f32 {
+    return other;
+}`
+
+	result := minifier.Minify(source, minifier.Options{
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		GenerateSourceMap: true,
+		SourceMapOptions:  minifier.SourceMapOptions{IncludeSource: true},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Minification errors: %v", result.Errors)
+	}
+	if len(result.SourceMap.SourcesContent) != 1 || result.SourceMap.SourcesContent[0] != source {
+		t.Error("Expected sourcesContent to hold the unpreprocessed source")
+	}
+
+	mappings, err := sourcemap.DecodeMappings(result.SourceMap.Mappings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "other" sits after the expanded SCALE on line 5; its mapping must use
+	// the column in the original line
+	wantCol := strings.Index("const longName = SCALE; const other = longName;", "other")
+	found := false
+	for _, m := range mappings {
+		if m.HasName && result.SourceMap.Names[m.NameIndex] == "other" && m.SrcLine == 4 {
+			found = true
+			if m.SrcCol != wantCol {
+				t.Errorf("'other' mapped to column %d, want %d", m.SrcCol, wantCol)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected a mapping for 'other' on line 5")
+	}
+}
+
+func TestSourceMapSymbolicateCompilerError(t *testing.T) {
+	source := `const longVariableName = 42;
+fn helperFunction(someParameter: i32) -> i32 {
+    return someParameter * 2 + longVariableName;
+}`
+
+	result := minifier.Minify(source, minifier.Options{
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		GenerateSourceMap: true,
+		SourceMapOptions:  minifier.SourceMapOptions{SourceName: "shader.wgsl"},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Minification errors: %v", result.Errors)
+	}
+
+	consumer, err := sourcemap.NewConsumer([]byte(result.SourceMap.ToJSON()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Point an error at the parameter of the minified function
+	fnAt := strings.Index(result.Code, "fn ")
+	paramAt := strings.Index(result.Code[fnAt:], "(") + fnAt + 1
+	param := result.Code[paramAt : paramAt+strings.Index(result.Code[paramAt:], ":")]
+	message := fmt.Sprintf(":1:%d error: unused '%s'", paramAt+1, param)
+
+	symbolicator := sourcemap.NewSymbolicator(consumer, result.Code)
+	got := symbolicator.Symbolicate(message)
+	want := "shader.wgsl:2:19 error: unused 'someParameter'"
+	if got != want {
+		t.Errorf("\nexpected: %s\nactual:   %s", want, got)
+	}
+
+	pos, ok := consumer.GeneratedPositionFor("shader.wgsl", 1, 18)
+	if !ok || pos.Line != 0 || pos.Column != paramAt {
+		t.Errorf("GeneratedPositionFor = %+v, %v; want column %d", pos, ok, paramAt)
+	}
+}
+
+func TestSourceMapEveryRenamedOccurrence(t *testing.T) {
+	source := `struct Particle { pos: vec2f }
+fn advance(particle: Particle, speed: f32) -> vec2f {
+    let offset = particle.pos * speed;
+    return particle.pos + offset;
+}
+@compute @workgroup_size(1) fn main() {
+    let moved = advance(Particle(vec2f(0.0)), 2.0);
+}`
+
+	result := minifier.Minify(source, minifier.Options{
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		GenerateSourceMap: true,
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Minification errors: %v", result.Errors)
+	}
+
+	consumer, err := sourcemap.NewConsumerForMap(result.SourceMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(source, "\n")
+
+	// Every named mapping must point at an occurrence of that name in the
+	// source, not only at its declaration
+	occurrences := make(map[string]int)
+	for _, m := range consumer.Mappings() {
+		if !m.HasName {
+			continue
+		}
+		name := result.SourceMap.Names[m.NameIndex]
+		if got := lines[m.SrcLine][m.SrcCol:]; !strings.HasPrefix(got, name) {
+			t.Errorf("mapping for %q points at %q", name, got)
+		}
+		occurrences[name]++
+	}
+
+	want := map[string]int{"Particle": 3, "advance": 2, "particle": 3, "speed": 2, "offset": 2}
+	for name, count := range want {
+		if occurrences[name] != count {
+			t.Errorf("%q has %d named mappings, want %d", name, occurrences[name], count)
+		}
+	}
+}
+
+func TestSourceMapScopes(t *testing.T) {
+	source := `const scale = 2.0;
+fn apply(value: f32) -> f32 {
+    var result = value;
+    for (var i = 0; i < 4; i++) {
+        result = result * scale;
+    }
+    return result;
+}`
+
+	result := minifier.Minify(source, minifier.Options{
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		GenerateSourceMap: true,
+		SourceMapOptions:  minifier.SourceMapOptions{Scopes: true},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("Minification errors: %v", result.Errors)
+	}
+
+	scopes := result.SourceMap.Scopes
+	if len(scopes) != 1 || scopes[0].Kind != sourcemap.ScopeModule {
+		t.Fatalf("expected a single module scope, got %+v", scopes)
+	}
+	module := scopes[0]
+	if len(module.Children) != 1 {
+		t.Fatalf("expected one function scope, got %d", len(module.Children))
+	}
+	fn := module.Children[0]
+	if fn.Kind != sourcemap.ScopeFunction || fn.Name != "apply" || fn.Start[0] != 1 || fn.End[0] != 7 {
+		t.Errorf("function scope = %+v", fn)
+	}
+
+	generated := func(scope *sourcemap.Scope, name string) string {
+		for _, b := range scope.Bindings {
+			if b.Name == name {
+				return b.Generated
+			}
+		}
+		return ""
+	}
+	for _, name := range []string{"value", "result"} {
+		g := generated(fn, name)
+		if g == "" || g == name {
+			t.Errorf("expected %q to be bound and renamed in the function scope, got %q", name, g)
+		}
+	}
+	if g := generated(module, "scale"); g == "" {
+		t.Error("expected 'scale' to be bound in the module scope")
+	}
+
+	// The loop variable lives in the for statement's scope
+	if len(fn.Children) != 1 || fn.Children[0].Kind != sourcemap.ScopeBlock {
+		t.Fatalf("expected a block scope for the loop, got %+v", fn.Children)
+	}
+	loop := fn.Children[0]
+	if g := generated(loop, "i"); g == "" {
+		t.Error("expected 'i' to be bound in the loop scope")
+	}
+	code := result.Code
+	from := loop.GeneratedStart[1]
+	to := loop.GeneratedEnd[1]
+	if !strings.HasPrefix(code[from:to], "for(") || !strings.HasSuffix(code[from:to], "}") {
+		t.Errorf("loop scope covers %q", code[from:to])
+	}
+}
@@ -902,6 +902,30 @@ func IsSampler(t Type) bool {
 	return ok
 }
 
+// IsAtomic returns true if t is an atomic type.
+func IsAtomic(t Type) bool {
+	_, ok := t.(*Atomic)
+	return ok
+}
+
+// ContainsAtomic returns true if t is an atomic type or a struct or array
+// that (transitively) contains one.
+func ContainsAtomic(t Type) bool {
+	switch ty := t.(type) {
+	case *Atomic:
+		return true
+	case *Array:
+		return ContainsAtomic(ty.Element)
+	case *Struct:
+		for _, f := range ty.Fields {
+			if ContainsAtomic(f.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // IsNumeric returns true if t is a numeric type (scalar or vector of numeric).
 func IsNumeric(t Type) bool {
 	if s, ok := t.(*Scalar); ok {
@@ -1117,7 +1141,8 @@ func MultiplyResultType(left, right Type) Type {
 }
 
 // AddSubResultType returns the result type of a +/- b, or nil if invalid.
-// Addition and subtraction require matching types or vector/matrix operations.
+// Addition and subtraction require matching types or vector/matrix
+// operations, and broadcast a scalar to the width of a vector.
 func AddSubResultType(left, right Type) Type {
 	// Common type handles most cases
 	if common := CommonType(left, right); common != nil {
@@ -1140,6 +1165,22 @@ func AddSubResultType(left, right Type) Type {
 		}
 	}
 
+	// Vector +/- Scalar and Scalar +/- Vector
+	if leftVec, ok := leftConc.(*Vector); ok {
+		if rightScalar, ok := rightConc.(*Scalar); ok {
+			if elem := commonScalarType(leftVec.Element, rightScalar); elem != nil {
+				return &Vector{Width: leftVec.Width, Element: elem}
+			}
+		}
+	}
+	if leftScalar, ok := leftConc.(*Scalar); ok {
+		if rightVec, ok := rightConc.(*Vector); ok {
+			if elem := commonScalarType(leftScalar, rightVec.Element); elem != nil {
+				return &Vector{Width: rightVec.Width, Element: elem}
+			}
+		}
+	}
+
 	// Matrix +/- Matrix with compatible element types
 	if leftMat, ok := leftConc.(*Matrix); ok {
 		if rightMat, ok := rightConc.(*Matrix); ok {
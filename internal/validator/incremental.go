@@ -0,0 +1,584 @@
+package validator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/dce"
+	"github.com/HugoDaniel/miniray/internal/diagnostic"
+	"github.com/HugoDaniel/miniray/internal/types"
+)
+
+// Incremental validates successive versions of a module, such as the text
+// of an editor buffer, with fixed options.
+//
+// Each version is compared with the previous one declaration by
+// declaration. A module-scope declaration is checked again when its text
+// changed, when the set of declarations it references changed or one of
+// them moved across it, when one of them is checked again, or when it
+// mentions a name that was declared or removed; the dependencies come
+// from the same graph that drives dead code elimination. The struct layouts, types, entry points and diagnostics of
+// the other declarations are reused, moved to their new offsets. The
+// unused lint looks at the whole module and always runs again.
+//
+// An Incremental is not safe for concurrent use. The modules it validates
+// are only read; the last one is kept until the next call.
+type Incremental struct {
+	options Options
+	prev    *version
+}
+
+// NewIncremental creates an incremental validator with the given options.
+func NewIncremental(options Options) *Incremental {
+	return &Incremental{options: options}
+}
+
+// Validate validates module, reusing the results of the declarations it
+// shares with the previously validated module. The result is the one
+// Validate returns for the module, apart from Timings, Rechecked and
+// Reused.
+func (inc *Incremental) Validate(module *ast.Module) *Result {
+	start := time.Now()
+	v := newValidator(module, inc.options)
+	run := newIncrementalRun(module, inc.prev)
+	if run == nil {
+		// Declarations that cannot be told apart by name are not tracked
+		inc.prev = nil
+		return v.run()
+	}
+	run.planning = time.Since(start)
+
+	if inc.prev != nil {
+		// Most types are carried over; size the maps for them
+		prev := inc.prev.result.TypeInfo
+		v.typeInfo.ExprTypes = make(map[int]types.Type, len(prev.ExprTypes))
+		v.typeInfo.Exprs = make(map[ast.Expr]types.Type, len(prev.Exprs))
+		v.symbolTypes = make(map[ast.Ref]types.Type, len(prev.SymbolTypes))
+	}
+	v.incremental = run
+	result := v.run()
+	inc.prev = run.finish(v, result)
+	return result
+}
+
+// version is what an Incremental remembers of the last validated module.
+type version struct {
+	module *ast.Module
+	result *Result
+	decls  []*declResult
+	byKey  map[string]int
+	names  map[string]bool
+}
+
+// declResult is what a module-scope declaration contributed to a
+// validation. Offsets are those of the module it belongs to.
+type declResult struct {
+	key  string
+	loc  ast.Loc
+	hash [sha256.Size]byte
+	deps []dependency
+
+	diags      [numPhases][]diagnostic.Diagnostic
+	entryPoint *EntryPointIO
+
+	// exprs and symbols hold the types of the expressions in the
+	// declaration and of the parameters and locals it declares, by the
+	// position of their node in ast.Inspect order. Identical text parses to
+	// identical trees, so positions carry over to the next version.
+	exprs   []nodeType
+	symbols []nodeType
+	// exprTypes holds the TypeInfo.ExprTypes entries of the declaration,
+	// relative to its start
+	exprTypes []nodeType
+
+	// typ is what the declaration gives its name: the struct, the aliased
+	// type or the type of a constant, override or variable
+	typ    types.Type
+	hasTyp bool
+}
+
+type nodeType struct {
+	node int // node position, or offset in exprTypes
+	typ  types.Type
+}
+
+// dependency is a module-scope declaration referenced by another one.
+type dependency struct {
+	key    string
+	before bool // declared before the declaration referencing it
+}
+
+// incrementalRun carries one incremental validation.
+type incrementalRun struct {
+	module *ast.Module
+	prev   *version
+	decls  []*declResult
+	byKey  map[string]int
+	names  map[string]bool
+
+	// old is the index of each declaration in prev, or -1
+	old []int
+	// clean marks the declarations whose results are replayed
+	clean []bool
+	// dependents lists the declarations referencing each declaration
+	dependents [][]int
+
+	planning time.Duration
+	reused   int
+}
+
+// newIncrementalRun compares module with the previous version and decides
+// which declarations to check again. It returns nil when module has two
+// declarations with the same name, even of different kinds: structs and
+// aliases are resolved by name alone.
+func newIncrementalRun(module *ast.Module, prev *version) *incrementalRun {
+	decls := module.Declarations
+	r := &incrementalRun{
+		module:     module,
+		prev:       prev,
+		decls:      make([]*declResult, len(decls)),
+		byKey:      make(map[string]int, len(decls)),
+		names:      make(map[string]bool, len(decls)),
+		old:        make([]int, len(decls)),
+		clean:      make([]bool, len(decls)),
+		dependents: make([][]int, len(decls)),
+	}
+
+	declOf := make(map[uint32]int)
+	assertions := make(map[[sha256.Size]byte]int)
+	for i, decl := range decls {
+		loc, name, kind := declInfo(decl)
+		d := &declResult{loc: loc, hash: sha256.Sum256([]byte(module.Source[loc.Start:loc.End]))}
+		switch {
+		case name.IsValid() && int(name.InnerIndex) < len(module.Symbols) && module.Symbols[name.InnerIndex].OriginalName != "":
+			original := module.Symbols[name.InnerIndex].OriginalName
+			if r.names[original] {
+				return nil
+			}
+			r.names[original] = true
+			d.key = kind + " " + original
+			declOf[name.InnerIndex] = i
+		case kind == "const_assert":
+			// Assertions have no name; identical ones are told apart by
+			// their order
+			d.key = kind + " " + hex.EncodeToString(d.hash[:]) + "#" + strconv.Itoa(assertions[d.hash])
+			assertions[d.hash]++
+		default:
+			return nil
+		}
+		if _, ok := r.byKey[d.key]; ok {
+			return nil
+		}
+		r.byKey[d.key] = i
+		r.decls[i] = d
+	}
+
+	graph := dce.DependencyGraph(module)
+	for i, decl := range decls {
+		_, name, _ := declInfo(decl)
+		if !name.IsValid() {
+			continue
+		}
+		for _, index := range graph[name.InnerIndex] {
+			j, ok := declOf[index]
+			if !ok {
+				continue
+			}
+			dep := dependency{key: r.decls[j].key, before: j < i}
+			if !slices.Contains(r.decls[i].deps, dep) {
+				r.decls[i].deps = append(r.decls[i].deps, dep)
+				r.dependents[j] = append(r.dependents[j], i)
+			}
+		}
+		slices.SortFunc(r.decls[i].deps, func(a, b dependency) int {
+			if a.key != b.key {
+				if a.key < b.key {
+					return -1
+				}
+				return 1
+			}
+			if a.before == b.before {
+				return 0
+			}
+			if a.before {
+				return -1
+			}
+			return 1
+		})
+	}
+
+	for i, d := range r.decls {
+		r.old[i] = -1
+		if prev == nil {
+			continue
+		}
+		if j, ok := prev.byKey[d.key]; ok {
+			r.old[i] = j
+			r.clean[i] = prev.decls[j].hash == d.hash && slices.Equal(prev.decls[j].deps, d.deps)
+		}
+	}
+
+	// A name that appeared or went away can change what an identifier
+	// resolves to, even where the dependency graph has no edge for it
+	if prev != nil {
+		changed := make(map[string]bool)
+		for name := range r.names {
+			if !prev.names[name] {
+				changed[name] = true
+			}
+		}
+		for name := range prev.names {
+			if !r.names[name] {
+				changed[name] = true
+			}
+		}
+		if len(changed) > 0 {
+			for i, decl := range decls {
+				if r.clean[i] && mentions(decl, changed) {
+					r.clean[i] = false
+				}
+			}
+		}
+	}
+
+	// Checking a declaration again means checking everything depending on
+	// it, which can in turn make replaying other declarations impossible
+	for changed := true; changed; {
+		r.propagate()
+		changed = false
+		for i := range r.clean {
+			if r.clean[i] && !r.replayable(i) {
+				r.clean[i] = false
+				changed = true
+			}
+		}
+	}
+	return r
+}
+
+// mentions reports whether an identifier in decl is one of names.
+func mentions(decl ast.Decl, names map[string]bool) bool {
+	found := false
+	ast.Inspect(decl, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.IdentExpr:
+			found = found || names[n.Name]
+		case *ast.IdentType:
+			found = found || names[n.Name]
+		}
+		return !found
+	})
+	return found
+}
+
+// propagate marks the dependents of declarations checked again as checked
+// again.
+func (r *incrementalRun) propagate() {
+	var queue []int
+	for i, clean := range r.clean {
+		if !clean {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for _, j := range r.dependents[i] {
+			if r.clean[j] {
+				r.clean[j] = false
+				queue = append(queue, j)
+			}
+		}
+	}
+}
+
+// replayable reports whether the previous results of clean declaration i
+// can be reused.
+func (r *incrementalRun) replayable(i int) bool {
+	switch r.module.Declarations[i].(type) {
+	case *ast.StructDecl, *ast.AliasDecl:
+		// Declarations checked before a type see it unresolved; reusing
+		// the resolved type would change their results
+		for _, j := range r.dependents[i] {
+			if j < i && !r.clean[j] {
+				return false
+			}
+		}
+	}
+
+	// Every offset in the results must land in a replayed declaration
+	old := r.prev.decls[r.old[i]]
+	for _, diags := range old.diags {
+		for _, d := range diags {
+			if _, _, ok := r.moveRange(d.Range); !ok {
+				return false
+			}
+			for _, related := range d.Related {
+				if _, _, ok := r.moveRange(related.Range); !ok {
+					return false
+				}
+			}
+		}
+	}
+	if ep := old.entryPoint; ep != nil {
+		if _, ok := r.move(ep.Loc); !ok {
+			return false
+		}
+		for _, ios := range [][]StageIO{ep.Inputs, ep.Outputs} {
+			for _, io := range ios {
+				if _, ok := r.move(io.Loc); !ok {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// move maps an offset of the previous module to the current one. It fails
+// unless the offset lies in a replayed declaration.
+func (r *incrementalRun) move(offset int) (int, bool) {
+	decls := r.prev.decls
+	j := sort.Search(len(decls), func(j int) bool { return int(decls[j].loc.End) > offset })
+	if j == len(decls) || int(decls[j].loc.Start) > offset {
+		return 0, false
+	}
+	i, ok := r.byKey[decls[j].key]
+	if !ok || !r.clean[i] {
+		return 0, false
+	}
+	return offset - int(decls[j].loc.Start) + int(r.decls[i].loc.Start), true
+}
+
+// moveRange maps a diagnostic range of the previous module to the current
+// one.
+func (r *incrementalRun) moveRange(rng diagnostic.Range) (start, end int, ok bool) {
+	if start, ok = r.move(rng.Start.Offset); !ok {
+		return 0, 0, false
+	}
+	if rng.End.Offset <= rng.Start.Offset {
+		return start, start + rng.End.Offset - rng.Start.Offset, true
+	}
+	// The end is exclusive and may be the end of the declaration
+	if end, ok = r.move(rng.End.Offset - 1); !ok {
+		return 0, 0, false
+	}
+	return start, end + 1, true
+}
+
+// replay applies the previous results of declaration i for phase p, and
+// reports whether it did so; declarations that changed are checked.
+func (r *incrementalRun) replay(v *Validator, i int, p phase) bool {
+	if !r.clean[i] {
+		return false
+	}
+	old, d := r.prev.decls[r.old[i]], r.decls[i]
+
+	switch p {
+	case phaseTypes:
+		r.reused++
+		r.reuseTypeInfo(v, i)
+		d.typ, d.hasTyp = old.typ, old.hasTyp
+		d.exprs, d.symbols, d.exprTypes = old.exprs, old.symbols, old.exprTypes
+		switch decl := v.module.Declarations[i].(type) {
+		case *ast.StructDecl:
+			// The layout is already resolved; no declaration checked
+			// before the struct depends on it, or it would not be replayed
+			v.structTypes[v.symbolName(decl.Name)] = old.typ.(*types.Struct)
+			v.structDecls[v.symbolName(decl.Name)] = decl
+		case *ast.AliasDecl:
+			v.aliasTypes[v.symbolName(decl.Name)] = nil
+		}
+
+	case phaseLayouts:
+		if decl, ok := v.module.Declarations[i].(*ast.AliasDecl); ok {
+			v.aliasTypes[v.symbolName(decl.Name)] = old.typ
+		}
+
+	case phaseDeclarations:
+		switch decl := v.module.Declarations[i].(type) {
+		case *ast.ConstDecl, *ast.OverrideDecl, *ast.LetDecl, *ast.VarDecl:
+			_, name, _ := declInfo(decl)
+			if old.hasTyp {
+				v.symbolTypes[name] = old.typ
+			}
+			if decl, ok := decl.(*ast.VarDecl); ok {
+				v.varDecls[decl.Name] = decl
+			}
+		}
+
+	case phaseFunctions:
+		if old.entryPoint != nil {
+			d.entryPoint = r.moveEntryPoint(old.entryPoint)
+			v.entryPoints = append(v.entryPoints, d.entryPoint)
+		}
+	}
+
+	if len(old.diags[p]) > 0 {
+		d.diags[p] = make([]diagnostic.Diagnostic, 0, len(old.diags[p]))
+	}
+	for _, diag := range old.diags[p] {
+		diag.Range = r.moveDiagnosticRange(v.diags, diag.Range)
+		if len(diag.Related) > 0 {
+			related := make([]diagnostic.RelatedInfo, len(diag.Related))
+			for k, info := range diag.Related {
+				related[k] = diagnostic.RelatedInfo{Range: r.moveDiagnosticRange(v.diags, info.Range), Message: info.Message}
+			}
+			diag.Related = related
+		}
+		v.diags.Add(diag)
+		d.diags[p] = append(d.diags[p], diag)
+	}
+	return true
+}
+
+func (r *incrementalRun) moveDiagnosticRange(diags *diagnostic.DiagnosticList, rng diagnostic.Range) diagnostic.Range {
+	start, end, _ := r.moveRange(rng)
+	return diags.MakeRange(start, end)
+}
+
+func (r *incrementalRun) moveEntryPoint(ep *EntryPointIO) *EntryPointIO {
+	moved := *ep
+	moved.Loc, _ = r.move(ep.Loc)
+	moved.Inputs = r.moveStageIO(ep.Inputs)
+	moved.Outputs = r.moveStageIO(ep.Outputs)
+	return &moved
+}
+
+func (r *incrementalRun) moveStageIO(ios []StageIO) []StageIO {
+	if ios == nil {
+		return nil
+	}
+	moved := slices.Clone(ios)
+	for k := range moved {
+		moved[k].Loc, _ = r.move(moved[k].Loc)
+	}
+	return moved
+}
+
+// reuseTypeInfo copies the expression types of declaration i, and the
+// types of the parameters and locals it declares, from the previous
+// version. The type of the declaration's own name is set in its phase.
+func (r *incrementalRun) reuseTypeInfo(v *Validator, i int) {
+	old := r.prev.decls[r.old[i]]
+	exprs, symbols := old.exprs, old.symbols
+	node := 0
+	ast.Inspect(r.module.Declarations[i], func(n ast.Node) bool {
+		if len(exprs) > 0 && exprs[0].node == node {
+			if expr, ok := n.(ast.Expr); ok {
+				v.typeInfo.Exprs[expr] = exprs[0].typ
+			}
+			exprs = exprs[1:]
+		}
+		if len(symbols) > 0 && symbols[0].node == node {
+			_, ref, _ := declInfo(n)
+			v.symbolTypes[ref] = symbols[0].typ
+			symbols = symbols[1:]
+		}
+		node++
+		return true
+	})
+
+	start := int(r.decls[i].loc.Start)
+	for _, et := range old.exprTypes {
+		v.typeInfo.ExprTypes[start+et.node] = et.typ
+	}
+}
+
+// recordTypeInfo keeps the types that checking declaration i produced.
+func (r *incrementalRun) recordTypeInfo(v *Validator, i int) {
+	d := r.decls[i]
+	start, end := int(d.loc.Start), int(d.loc.End)
+	seen := make(map[int]bool)
+	node := 0
+	ast.Inspect(r.module.Declarations[i], func(n ast.Node) bool {
+		if expr, ok := n.(ast.Expr); ok {
+			if t, ok := v.typeInfo.Exprs[expr]; ok {
+				d.exprs = append(d.exprs, nodeType{node, t})
+
+				// Only checked expressions have an entry at their start
+				if offset := v.exprLoc(expr); offset >= start && offset < end && !seen[offset] {
+					seen[offset] = true
+					if t, ok := v.typeInfo.ExprTypes[offset]; ok {
+						d.exprTypes = append(d.exprTypes, nodeType{offset - start, t})
+					}
+				}
+			}
+		}
+		if _, ref, _ := declInfo(n); node > 0 && ref.IsValid() {
+			if t, ok := v.symbolTypes[ref]; ok {
+				d.symbols = append(d.symbols, nodeType{node, t})
+			}
+		}
+		node++
+		return true
+	})
+}
+
+// record keeps what checking declaration i in phase p added to the
+// validator, given the number of diagnostics and entry points before.
+func (r *incrementalRun) record(v *Validator, i int, p phase, diags, entryPoints int) {
+	d := r.decls[i]
+	d.diags[p] = slices.Clone(v.diags.Diagnostics()[diags:])
+	if len(v.entryPoints) > entryPoints {
+		d.entryPoint = v.entryPoints[entryPoints]
+	}
+}
+
+// finish returns the version to compare the next module with.
+func (r *incrementalRun) finish(v *Validator, result *Result) *version {
+	for i, decl := range r.module.Declarations {
+		if r.clean[i] {
+			continue
+		}
+		r.recordTypeInfo(v, i)
+		d := r.decls[i]
+		_, name, _ := declInfo(decl)
+		switch decl.(type) {
+		case *ast.StructDecl:
+			d.typ, d.hasTyp = v.structTypes[v.symbolName(name)]
+		case *ast.AliasDecl:
+			d.typ, d.hasTyp = v.aliasTypes[v.symbolName(name)]
+		case *ast.ConstDecl, *ast.OverrideDecl, *ast.LetDecl, *ast.VarDecl:
+			d.typ, d.hasTyp = v.symbolTypes[name]
+		}
+	}
+	return &version{
+		module: r.module,
+		result: result,
+		decls:  r.decls,
+		byKey:  r.byKey,
+		names:  r.names,
+	}
+}
+
+// declInfo returns the location, name and kind of a declaration, or of a
+// parameter, which only has a name.
+func declInfo(node ast.Node) (ast.Loc, ast.Ref, string) {
+	switch d := node.(type) {
+	case *ast.ConstDecl:
+		return d.Loc, d.Name, "const"
+	case *ast.OverrideDecl:
+		return d.Loc, d.Name, "override"
+	case *ast.VarDecl:
+		return d.Loc, d.Name, "var"
+	case *ast.LetDecl:
+		return d.Loc, d.Name, "let"
+	case *ast.FunctionDecl:
+		return d.Loc, d.Name, "fn"
+	case *ast.StructDecl:
+		return d.Loc, d.Name, "struct"
+	case *ast.AliasDecl:
+		return d.Loc, d.Name, "alias"
+	case *ast.ConstAssertDecl:
+		return d.Loc, ast.InvalidRef(), "const_assert"
+	case *ast.Parameter:
+		return d.Loc, d.Name, "param"
+	}
+	return ast.Loc{}, ast.InvalidRef(), ""
+}
@@ -0,0 +1,233 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/diagnostic"
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/types"
+)
+
+// StageIO describes one user-defined or builtin value crossing a shader
+// stage boundary.
+type StageIO struct {
+	// Name is the parameter or struct member name.
+	Name string
+	// Location is the @location index, or -1 for builtins.
+	Location int
+	// Builtin is the @builtin name, empty for user-defined IO.
+	Builtin string
+	// Type is the resolved type of the value.
+	Type types.Type
+	// Interpolation is the @interpolate type ("perspective", "linear",
+	// "flat"), empty when not specified.
+	Interpolation string
+	// Sampling is the @interpolate sampling, empty when not specified.
+	Sampling string
+	// Loc is the byte offset of the declaration.
+	Loc int
+}
+
+// EntryPointIO describes the inputs and outputs of an entry point.
+type EntryPointIO struct {
+	Name    string
+	Stage   ShaderStage
+	Loc     int
+	Inputs  []StageIO
+	Outputs []StageIO
+}
+
+// Input returns the user-defined input at the given location.
+func (ep *EntryPointIO) Input(location int) *StageIO {
+	return findLocation(ep.Inputs, location)
+}
+
+// Output returns the user-defined output at the given location.
+func (ep *EntryPointIO) Output(location int) *StageIO {
+	return findLocation(ep.Outputs, location)
+}
+
+func findLocation(ios []StageIO, location int) *StageIO {
+	for i := range ios {
+		if ios[i].Builtin == "" && ios[i].Location == location {
+			return &ios[i]
+		}
+	}
+	return nil
+}
+
+// EffectiveInterpolation returns the interpolation type and sampling
+// after applying the WGSL defaults: integers are always flat, everything
+// else defaults to perspective/center, and flat defaults to first.
+func (io *StageIO) EffectiveInterpolation() (string, string) {
+	interp, sampling := io.Interpolation, io.Sampling
+	if interp == "" {
+		if types.IsInteger(io.Type) {
+			interp = "flat"
+		} else {
+			interp = "perspective"
+		}
+	}
+	if sampling == "" {
+		if interp == "flat" {
+			sampling = "first"
+		} else {
+			sampling = "center"
+		}
+	}
+	return interp, sampling
+}
+
+// collectEntryPointIO gathers the IO of the current entry point and reports
+// locations or builtins used more than once on the same side.
+func (v *Validator) collectEntryPointIO(fn *ast.FunctionDecl) *EntryPointIO {
+	ep := &EntryPointIO{
+		Name:  v.symbolName(fn.Name),
+		Stage: v.currentStage,
+		Loc:   v.symbolLoc(fn.Name),
+	}
+
+	for _, param := range fn.Parameters {
+		ep.Inputs = v.appendStageIO(ep.Inputs, v.symbolName(param.Name), v.symbolLoc(param.Name),
+			param.Attributes, v.symbolTypes[param.Name])
+	}
+	if fn.ReturnType != nil {
+		ep.Outputs = v.appendStageIO(ep.Outputs, "return value", ep.Loc, fn.ReturnAttr, v.returnType)
+	}
+
+	v.checkDuplicateIO(ep.Name, "input", ep.Inputs)
+	v.checkDuplicateIO(ep.Name, "output", ep.Outputs)
+	return ep
+}
+
+// appendStageIO appends a single IO value, expanding struct types into
+// their members.
+func (v *Validator) appendStageIO(ios []StageIO, name string, loc int, attrs []ast.Attribute, t types.Type) []StageIO {
+	if st, ok := t.(*types.Struct); ok && len(attrs) == 0 {
+		decl := v.structDecls[st.Name]
+		if decl == nil {
+			return ios
+		}
+		for i, member := range decl.Members {
+			var memberType types.Type
+			if i < len(st.Fields) {
+				memberType = st.Fields[i].Type
+			}
+			ios = v.appendStageIO(ios, v.symbolName(member.Name), v.symbolLoc(member.Name),
+				member.Attributes, memberType)
+		}
+		return ios
+	}
+
+	io := StageIO{Name: name, Location: -1, Type: t, Loc: loc}
+	found := false
+	for _, attr := range attrs {
+		switch attr.Name {
+		case "location":
+			if len(attr.Args) > 0 {
+				if n, ok := v.attrInt(attr.Args[0]); ok && n >= 0 {
+					io.Location = n
+					found = true
+				} else if !v.invalidLocations[attr.Loc.Start] {
+					// A struct shared by entry points is collected for each
+					v.invalidLocations[attr.Loc.Start] = true
+					v.errorWithCode(int(attr.Loc.Start), string(diagnostic.CodeInvalidLocation),
+						"@location of '%s' must be a non-negative integer constant", name)
+				}
+			}
+		case "builtin":
+			if len(attr.Args) > 0 {
+				if ident, ok := attr.Args[0].(*ast.IdentExpr); ok {
+					io.Builtin = ident.Name
+					found = true
+				}
+			}
+		case "interpolate":
+			if len(attr.Args) > 0 {
+				if ident, ok := attr.Args[0].(*ast.IdentExpr); ok {
+					io.Interpolation = ident.Name
+				}
+			}
+			if len(attr.Args) > 1 {
+				if ident, ok := attr.Args[1].(*ast.IdentExpr); ok {
+					io.Sampling = ident.Name
+				}
+			}
+		}
+	}
+	if !found {
+		return ios
+	}
+	return append(ios, io)
+}
+
+func (v *Validator) checkDuplicateIO(entry, dir string, ios []StageIO) {
+	locations := make(map[int]string)
+	builtins := make(map[string]string)
+	for _, io := range ios {
+		if io.Builtin != "" {
+			if prev, ok := builtins[io.Builtin]; ok {
+				v.errorWithCode(io.Loc, string(diagnostic.CodeDuplicateBuiltin),
+					"@builtin(%s) is used by both '%s' and '%s' in %s of entry point '%s'",
+					io.Builtin, prev, io.Name, dir, entry)
+				continue
+			}
+			builtins[io.Builtin] = io.Name
+			continue
+		}
+		if prev, ok := locations[io.Location]; ok {
+			v.errorWithCode(io.Loc, string(diagnostic.CodeDuplicateLocation),
+				"@location(%d) is used by both '%s' and '%s' in %s of entry point '%s'",
+				io.Location, prev, io.Name, dir, entry)
+			continue
+		}
+		locations[io.Location] = io.Name
+	}
+}
+
+// attrInt evaluates an integer attribute argument, which may be any
+// const-expression such as 0x1 or the name of a const.
+func (v *Validator) attrInt(expr ast.Expr) (int, bool) {
+	if v.constValues == nil {
+		v.constValues = parser.ConstValues(v.module)
+	}
+	val := parser.EvaluateConstExpr(expr, v.constValues)
+	if val.Kind != parser.ConstInt {
+		return 0, false
+	}
+	return int(val.Int), true
+}
+
+// ValidateStageInterface checks that the outputs of a vertex entry point
+// feed the inputs of a fragment entry point. Every user-defined fragment
+// input must be written by the vertex stage with the same type and
+// interpolation. Diagnostics are reported at the fragment inputs.
+func ValidateStageInterface(vertex, fragment *EntryPointIO, diags *diagnostic.DiagnosticList) {
+	for i := range fragment.Inputs {
+		in := &fragment.Inputs[i]
+		if in.Builtin != "" {
+			continue
+		}
+		out := vertex.Output(in.Location)
+		if out == nil {
+			diags.AddErrorWithCode(in.Loc, string(diagnostic.CodeInterfaceMissingLocation),
+				fmt.Sprintf("fragment input '%s' at @location(%d) is not written by vertex entry point '%s'",
+					in.Name, in.Location, vertex.Name))
+			continue
+		}
+		if in.Type != nil && out.Type != nil && !in.Type.Equals(out.Type) {
+			diags.AddErrorWithCode(in.Loc, string(diagnostic.CodeInterfaceTypeMismatch),
+				fmt.Sprintf("fragment input '%s' at @location(%d) has type '%s' but vertex output '%s' has type '%s'",
+					in.Name, in.Location, in.Type.String(), out.Name, out.Type.String()))
+			continue
+		}
+		inInterp, inSampling := in.EffectiveInterpolation()
+		outInterp, outSampling := out.EffectiveInterpolation()
+		if inInterp != outInterp || inSampling != outSampling {
+			diags.AddErrorWithCode(in.Loc, string(diagnostic.CodeInterpolationMismatch),
+				fmt.Sprintf("fragment input '%s' at @location(%d) uses @interpolate(%s, %s) but vertex output '%s' uses @interpolate(%s, %s)",
+					in.Name, in.Location, inInterp, inSampling, out.Name, outInterp, outSampling))
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/dce"
+	"github.com/HugoDaniel/miniray/internal/diagnostic"
+)
+
+// lintUnused reports declarations that are never used and statements
+// whose results are discarded. Module-scope liveness comes from the same
+// reachability analysis that drives tree shaking, so a declaration is
+// reported exactly when the minifier would drop it. Symbols whose name
+// starts with an underscore are never reported.
+func (v *Validator) lintUnused() {
+	v.live = dce.Reachable(v.module)
+	purity := ast.NewPurityContext(v.module.Symbols)
+
+	for _, decl := range v.module.Declarations {
+		switch d := decl.(type) {
+		case *ast.FunctionDecl:
+			if !v.isLive(d.Name) {
+				v.lintWarning(diagnostic.RuleUnusedFunction, d.Name, "function '%s' is never used")
+				continue
+			}
+			v.lintFunction(d, purity)
+
+		case *ast.StructDecl:
+			if !v.isLive(d.Name) {
+				v.lintWarning(diagnostic.RuleUnusedStruct, d.Name, "struct '%s' is never used")
+			}
+
+		case *ast.ConstDecl:
+			if !v.isLive(d.Name) {
+				v.lintWarning(diagnostic.RuleUnusedConstant, d.Name, "constant '%s' is never used")
+			}
+
+		case *ast.VarDecl:
+			if v.isLive(d.Name) {
+				continue
+			}
+			if hasAttribute(d.Attributes, "binding") {
+				v.lintWarning(diagnostic.RuleUnusedBinding, d.Name, "binding '%s' is never used")
+			} else {
+				v.lintWarning(diagnostic.RuleUnusedVariable, d.Name, "variable '%s' is never used")
+			}
+		}
+	}
+}
+
+// lintFunction reports unused parameters and locals, and discarded
+// results, inside a live function.
+func (v *Validator) lintFunction(fn *ast.FunctionDecl, purity *ast.PurityContext) {
+	isEntryPoint := v.symbolFlags(fn.Name).Has(ast.IsEntryPoint)
+	for _, param := range fn.Parameters {
+		// Entry point IO is part of the pipeline interface even when unread
+		if isEntryPoint && len(param.Attributes) > 0 {
+			continue
+		}
+		if v.symbolUseCount(param.Name) == 0 {
+			v.lintWarning(diagnostic.RuleUnusedParameter, param.Name, "parameter '%s' is never used")
+		}
+	}
+	if fn.Body != nil {
+		v.lintStmt(fn.Body, purity)
+	}
+}
+
+func (v *Validator) lintStmt(stmt ast.Stmt, purity *ast.PurityContext) {
+	switch s := stmt.(type) {
+	case *ast.CompoundStmt:
+		for _, child := range s.Stmts {
+			v.lintStmt(child, purity)
+		}
+	case *ast.IfStmt:
+		v.lintStmt(s.Body, purity)
+		if s.Else != nil {
+			v.lintStmt(s.Else, purity)
+		}
+	case *ast.SwitchStmt:
+		for _, c := range s.Cases {
+			v.lintStmt(c.Body, purity)
+		}
+	case *ast.ForStmt:
+		if s.Init != nil {
+			v.lintStmt(s.Init, purity)
+		}
+		v.lintStmt(s.Body, purity)
+	case *ast.WhileStmt:
+		v.lintStmt(s.Body, purity)
+	case *ast.LoopStmt:
+		v.lintStmt(s.Body, purity)
+		if s.Continuing != nil {
+			v.lintStmt(s.Continuing, purity)
+		}
+	case *ast.DeclStmt:
+		var name ast.Ref
+		switch d := s.Decl.(type) {
+		case *ast.VarDecl:
+			name = d.Name
+		case *ast.LetDecl:
+			name = d.Name
+		case *ast.ConstDecl:
+			name = d.Name
+		default:
+			return
+		}
+		if v.symbolUseCount(name) == 0 {
+			v.lintWarning(diagnostic.RuleUnusedVariable, name, "local '%s' is never used")
+		}
+	case *ast.CallStmt:
+		if purity.ExprCanBeRemovedIfUnused(s.Call) {
+			callee, loc, end := "call", int(s.Call.Loc.Start), int(s.Call.Loc.Start)+1
+			if ident, ok := s.Call.Func.(*ast.IdentExpr); ok {
+				callee = "'" + ident.Name + "'"
+				loc, end = int(ident.Loc.Start), int(ident.Loc.Start)+len(ident.Name)
+			}
+			v.addLintDiagnostic(diagnostic.RuleDiscardedResult, loc, end,
+				fmt.Sprintf("result of %s is discarded and the call has no side effects", callee))
+		}
+	}
+}
+
+// lintWarning reports a lint diagnostic at a symbol's declaration. The
+// format receives the symbol name.
+func (v *Validator) lintWarning(rule string, ref ast.Ref, format string) {
+	name := v.symbolName(ref)
+	if name == "" || strings.HasPrefix(name, "_") {
+		return
+	}
+	loc := v.symbolLoc(ref)
+	v.addLintDiagnostic(rule, loc, loc+len(name), fmt.Sprintf(format, name))
+}
+
+// addLintDiagnostic adds a lint diagnostic, applying the rule's filter.
+// Lint rules default to warnings; the rule name doubles as the code.
+func (v *Validator) addLintDiagnostic(rule string, start, end int, message string) {
+	filters := v.options.DiagnosticFilters
+	if filters.IsDisabled(rule) {
+		return
+	}
+	severity := filters.GetSeverity(rule, diagnostic.Warning)
+	if severity == diagnostic.Warning && v.options.StrictMode {
+		severity = diagnostic.Error
+	}
+	v.diags.Add(diagnostic.Diagnostic{
+		Severity: severity,
+		Code:     rule,
+		Message:  message,
+		Range:    v.diags.MakeRange(start, end),
+	})
+}
+
+func (v *Validator) isLive(ref ast.Ref) bool {
+	return !ref.IsValid() || v.live == nil || v.live[ref.InnerIndex]
+}
+
+func (v *Validator) symbolFlags(ref ast.Ref) ast.SymbolFlags {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(v.module.Symbols) {
+		return 0
+	}
+	return v.module.Symbols[ref.InnerIndex].Flags
+}
+
+func (v *Validator) symbolUseCount(ref ast.Ref) uint32 {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(v.module.Symbols) {
+		return 1
+	}
+	return v.module.Symbols[ref.InnerIndex].UseCount
+}
+
+func hasAttribute(attrs []ast.Attribute, name string) bool {
+	for _, attr := range attrs {
+		if attr.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -8,10 +8,12 @@ package validator
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/HugoDaniel/miniray/internal/ast"
 	"github.com/HugoDaniel/miniray/internal/builtins"
 	"github.com/HugoDaniel/miniray/internal/diagnostic"
+	"github.com/HugoDaniel/miniray/internal/parser"
 	"github.com/HugoDaniel/miniray/internal/types"
 )
 
@@ -44,6 +46,8 @@ type Options struct {
 	StrictMode bool
 	// DiagnosticFilters control which diagnostics are reported.
 	DiagnosticFilters *diagnostic.DiagnosticFilter
+	// Lint enables warnings for unused declarations and discarded results.
+	Lint bool
 }
 
 // Result contains validation results.
@@ -54,12 +58,56 @@ type Result struct {
 	Diagnostics *diagnostic.DiagnosticList
 	// TypeInfo contains resolved type information (for tooling).
 	TypeInfo *TypeInfo
+	// EntryPoints describes the inputs and outputs of each entry point.
+	EntryPoints []*EntryPointIO
+	// Timings contains the time spent in each phase.
+	Timings Timings
+	// Rechecked is the number of module-scope declarations that were
+	// checked, and Reused the number whose results incremental validation
+	// replayed from the previous version.
+	Rechecked int
+	Reused    int
 }
 
+// Timings contains the time spent in each validation phase.
+type Timings struct {
+	TypeDeclarations time.Duration
+	StructLayouts    time.Duration
+	Declarations     time.Duration
+	Functions        time.Duration
+	Uniformity       time.Duration
+	Lint             time.Duration
+	// Reuse is the time incremental validation spent comparing the
+	// module with the previous version.
+	Reuse time.Duration
+}
+
+// Total returns the time spent in all phases.
+func (t Timings) Total() time.Duration {
+	return t.TypeDeclarations + t.StructLayouts + t.Declarations + t.Functions +
+		t.Uniformity + t.Lint + t.Reuse
+}
+
+// phase is a validation phase that checks declarations one at a time.
+type phase uint8
+
+const (
+	phaseTypes phase = iota
+	phaseLayouts
+	phaseDeclarations
+	phaseFunctions
+	phaseUniformity
+	numPhases
+)
+
 // TypeInfo stores resolved type information for expressions.
 type TypeInfo struct {
 	// ExprTypes maps expression locations to their resolved types.
 	ExprTypes map[int]types.Type
+	// Exprs maps each checked expression node to its resolved type.
+	// Unlike ExprTypes it distinguishes nested expressions that start
+	// at the same offset.
+	Exprs map[ast.Expr]types.Type
 	// SymbolTypes maps symbol references to their types.
 	SymbolTypes map[ast.Ref]types.Type
 	// Structs maps struct names to their resolved types.
@@ -90,12 +138,46 @@ type Validator struct {
 	// Alias resolution cache
 	aliasTypes map[string]types.Type
 
+	// Struct declarations by name, used to read member IO attributes
+	structDecls map[string]*ast.StructDecl
+
+	// Entry point IO collected while validating functions
+	entryPoints []*EntryPointIO
+
+	// Variable declarations by symbol, used to find the address space and
+	// access mode behind a reference
+	varDecls map[ast.Ref]*ast.VarDecl
+
+	// allowAtomicRef is set while checking an expression whose reference
+	// is used directly (operand of &, left side of an assignment), so an
+	// atomic there is not reported as a load
+	allowAtomicRef bool
+
+	// Values of const declarations, computed when an attribute argument
+	// is first evaluated
+	constValues map[ast.Ref]parser.ConstValue
+
+	// @location attributes already reported as invalid, by position
+	invalidLocations map[int32]bool
+
+	// live holds the symbols reachable from entry points, computed for
+	// the unused lint; nil when every symbol is live
+	live map[uint32]bool
+
+	// incremental replays and records per-declaration results when
+	// validating through an Incremental
+	incremental *incrementalRun
+
 	// Uniformity tracking
 	uniformityAnalyzer *UniformityAnalyzer
 }
 
 // Validate performs semantic validation on the given module.
 func Validate(module *ast.Module, options Options) *Result {
+	return newValidator(module, options).run()
+}
+
+func newValidator(module *ast.Module, options Options) *Validator {
 	v := &Validator{
 		module:      module,
 		diags:       diagnostic.NewDiagnosticList(module.Source),
@@ -103,8 +185,13 @@ func Validate(module *ast.Module, options Options) *Result {
 		symbolTypes: make(map[ast.Ref]types.Type),
 		structTypes: make(map[string]*types.Struct),
 		aliasTypes:  make(map[string]types.Type),
+		structDecls: make(map[string]*ast.StructDecl),
+		varDecls:    make(map[ast.Ref]*ast.VarDecl),
+
+		invalidLocations: make(map[int32]bool),
 		typeInfo: &TypeInfo{
 			ExprTypes:   make(map[int]types.Type),
+			Exprs:       make(map[ast.Expr]types.Type),
 			SymbolTypes: make(map[ast.Ref]types.Type),
 			Structs:     make(map[string]*types.Struct),
 		},
@@ -113,30 +200,75 @@ func Validate(module *ast.Module, options Options) *Result {
 	if options.DiagnosticFilters == nil {
 		v.options.DiagnosticFilters = diagnostic.NewDiagnosticFilter()
 	}
+	return v
+}
+
+// run runs the validation phases.
+func (v *Validator) run() *Result {
+	var timings Timings
 
 	// Phase 1: Collect type declarations (structs, aliases)
-	v.collectTypeDeclarations()
+	timings.TypeDeclarations = timed(v.collectTypeDeclarations)
 
 	// Phase 2: Resolve struct layouts
-	v.resolveStructLayouts()
+	timings.StructLayouts = timed(v.resolveStructLayouts)
 
 	// Phase 3: Validate declarations
-	v.validateDeclarations()
+	timings.Declarations = timed(v.validateDeclarations)
 
 	// Phase 4: Validate functions and statements
-	v.validateFunctions()
+	timings.Functions = timed(v.validateFunctions)
 
 	// Phase 5: Uniformity analysis
-	v.analyzeUniformity()
+	timings.Uniformity = timed(v.analyzeUniformity)
+
+	// Phase 6: Lint for unused code
+	if v.options.Lint {
+		timings.Lint = timed(v.lintUnused)
+	}
 
 	// Copy type info
 	v.typeInfo.SymbolTypes = v.symbolTypes
 	v.typeInfo.Structs = v.structTypes
 
-	return &Result{
+	result := &Result{
 		Valid:       !v.diags.HasErrors(),
 		Diagnostics: v.diags,
 		TypeInfo:    v.typeInfo,
+		EntryPoints: v.entryPoints,
+		Timings:     timings,
+		Rechecked:   len(v.module.Declarations),
+	}
+	if v.incremental != nil {
+		result.Timings.Reuse = v.incremental.planning
+		result.Reused = v.incremental.reused
+		result.Rechecked -= v.incremental.reused
+	}
+	return result
+}
+
+func timed(phase func()) time.Duration {
+	start := time.Now()
+	phase()
+	return time.Since(start)
+}
+
+// eachDecl calls check with every module-scope declaration, in order. In
+// incremental validation the results of unchanged declarations are
+// replayed from the previous version instead, and those of the others are
+// recorded for the next one.
+func (v *Validator) eachDecl(p phase, check func(ast.Decl)) {
+	for i, decl := range v.module.Declarations {
+		if v.incremental == nil {
+			check(decl)
+			continue
+		}
+		if v.incremental.replay(v, i, p) {
+			continue
+		}
+		diags, entryPoints := len(v.diags.Diagnostics()), len(v.entryPoints)
+		check(decl)
+		v.incremental.record(v, i, p, diags, entryPoints)
 	}
 }
 
@@ -145,26 +277,27 @@ func Validate(module *ast.Module, options Options) *Result {
 // ----------------------------------------------------------------------------
 
 func (v *Validator) collectTypeDeclarations() {
-	for _, decl := range v.module.Declarations {
+	v.eachDecl(phaseTypes, func(decl ast.Decl) {
 		switch d := decl.(type) {
 		case *ast.StructDecl:
 			name := v.symbolName(d.Name)
 			if name == "" {
-				continue
+				return
 			}
 			// Create struct type placeholder
 			st := &types.Struct{Name: name}
 			v.structTypes[name] = st
+			v.structDecls[name] = d
 
 		case *ast.AliasDecl:
 			name := v.symbolName(d.Name)
 			if name == "" {
-				continue
+				return
 			}
 			// Resolve alias type later
 			v.aliasTypes[name] = nil // Placeholder
 		}
-	}
+	})
 }
 
 // ----------------------------------------------------------------------------
@@ -172,18 +305,19 @@ func (v *Validator) collectTypeDeclarations() {
 // ----------------------------------------------------------------------------
 
 func (v *Validator) resolveStructLayouts() {
-	for _, decl := range v.module.Declarations {
+	v.eachDecl(phaseLayouts, func(decl ast.Decl) {
 		switch d := decl.(type) {
 		case *ast.StructDecl:
 			name := v.symbolName(d.Name)
 			st := v.structTypes[name]
 			if st == nil {
-				continue
+				return
 			}
 
 			// Resolve member types
 			for _, member := range d.Members {
 				memberName := v.symbolName(member.Name)
+				v.checkAtomicElementTypes(int(member.Loc.Start), member.Type)
 				memberType := v.resolveType(member.Type)
 				if memberType == nil {
 					v.error(int(member.Loc.Start), "cannot resolve type for struct member '%s'", memberName)
@@ -203,11 +337,11 @@ func (v *Validator) resolveStructLayouts() {
 			aliasType := v.resolveType(d.Type)
 			if aliasType == nil {
 				v.error(int(d.Loc.Start), "cannot resolve type alias '%s'", name)
-				continue
+				return
 			}
 			v.aliasTypes[name] = aliasType
 		}
-	}
+	})
 }
 
 // ----------------------------------------------------------------------------
@@ -215,7 +349,7 @@ func (v *Validator) resolveStructLayouts() {
 // ----------------------------------------------------------------------------
 
 func (v *Validator) validateDeclarations() {
-	for _, decl := range v.module.Declarations {
+	v.eachDecl(phaseDeclarations, func(decl ast.Decl) {
 		switch d := decl.(type) {
 		case *ast.ConstDecl:
 			v.validateConstDecl(d)
@@ -226,7 +360,7 @@ func (v *Validator) validateDeclarations() {
 		case *ast.LetDecl:
 			v.validateLetDecl(d)
 		}
-	}
+	})
 }
 
 func (v *Validator) validateConstDecl(d *ast.ConstDecl) {
@@ -308,12 +442,17 @@ func (v *Validator) validateOverrideDecl(d *ast.OverrideDecl) {
 func (v *Validator) validateVarDecl(d *ast.VarDecl) {
 	name := v.symbolName(d.Name)
 
-	// Determine type
-	var declType types.Type
+	v.varDecls[d.Name] = d
+
+	// Determine type. Without a declared type the initializer's is used,
+	// and the initializer is not checked again below
+	var declType, initType types.Type
 	if d.Type != nil {
+		v.checkAtomicElementTypes(int(d.Loc.Start), d.Type)
 		declType = v.resolveType(d.Type)
 	} else if d.Initializer != nil {
-		declType = v.checkExpr(d.Initializer)
+		initType = v.checkExpr(d.Initializer)
+		declType = initType
 	}
 
 	if declType == nil {
@@ -325,14 +464,21 @@ func (v *Validator) validateVarDecl(d *ast.VarDecl) {
 	// Validate address space constraints
 	v.validateAddressSpace(d, declType)
 
+	// Storage textures must use a known texel format
+	if tex, ok := declType.(*types.Texture); ok && tex.Kind == types.TextureStorage &&
+		builtins.TexelFormatChannelType(tex.TexelFormat) == nil {
+		v.errorWithCode(int(d.Loc.Start), string(diagnostic.CodeInvalidTexelFormat),
+			"var '%s' uses unknown texel format '%s'", name, tex.TexelFormat)
+	}
+
 	// Check initializer compatibility
-	if d.Initializer != nil {
-		initType := v.checkExpr(d.Initializer)
-		if initType != nil && !types.CanConvertTo(initType, declType) {
-			v.errorWithCode(int(d.Loc.Start), string(diagnostic.CodeTypeMismatch),
-				"cannot initialize var '%s' of type '%s' with '%s'",
-				name, declType.String(), initType.String())
-		}
+	if d.Type != nil && d.Initializer != nil {
+		initType = v.checkExpr(d.Initializer)
+	}
+	if initType != nil && !types.CanConvertTo(initType, declType) {
+		v.errorWithCode(int(d.Loc.Start), string(diagnostic.CodeTypeMismatch),
+			"cannot initialize var '%s' of type '%s' with '%s'",
+			name, declType.String(), initType.String())
 	}
 
 	// Check for required @group/@binding
@@ -392,6 +538,24 @@ func (v *Validator) validateLetDecl(d *ast.LetDecl) {
 func (v *Validator) validateAddressSpace(d *ast.VarDecl, varType types.Type) {
 	name := v.symbolName(d.Name)
 
+	// Atomics may only live in workgroup or read_write storage memory
+	if types.ContainsAtomic(varType) {
+		space := types.AddressSpace(d.AddressSpace)
+		if d.AddressSpace == ast.AddressSpaceNone {
+			space = types.AddressSpaceFunction
+		}
+		access := effectiveAccessMode(space, types.AccessMode(d.AccessMode))
+		if !atomicAddressSpaceAllowed(space, access) {
+			got := space.String()
+			if space == types.AddressSpaceStorage {
+				got += "' with access mode '" + access.String()
+			}
+			v.errorWithCode(int(d.Loc.Start), string(diagnostic.CodeInvalidAtomicAddressSpace),
+				"var '%s' contains an atomic type and must be in address space 'workgroup' or 'storage' with access mode 'read_write', got '%s'",
+				name, got)
+		}
+	}
+
 	switch d.AddressSpace {
 	case ast.AddressSpaceWorkgroup:
 		// workgroup only allowed at module scope in compute shaders
@@ -434,11 +598,11 @@ func (v *Validator) validateAddressSpace(d *ast.VarDecl, varType types.Type) {
 // ----------------------------------------------------------------------------
 
 func (v *Validator) validateFunctions() {
-	for _, decl := range v.module.Declarations {
+	v.eachDecl(phaseFunctions, func(decl ast.Decl) {
 		if fn, ok := decl.(*ast.FunctionDecl); ok {
 			v.validateFunction(fn)
 		}
-	}
+	})
 }
 
 func (v *Validator) validateFunction(fn *ast.FunctionDecl) {
@@ -469,11 +633,21 @@ func (v *Validator) validateFunction(fn *ast.FunctionDecl) {
 
 	// Validate parameters
 	for _, param := range fn.Parameters {
+		v.checkAtomicElementTypes(int(param.Loc.Start), param.Type)
 		paramType := v.resolveType(param.Type)
 		if paramType != nil {
 			v.symbolTypes[param.Name] = paramType
 		}
 
+		// Pointers to atomics must point into workgroup or storage memory
+		if ptr, ok := paramType.(*types.Pointer); ok && types.ContainsAtomic(ptr.Element) {
+			if ptr.AddressSpace != types.AddressSpaceWorkgroup && ptr.AddressSpace != types.AddressSpaceStorage {
+				v.errorWithCode(int(param.Loc.Start), string(diagnostic.CodeInvalidAtomicAddressSpace),
+					"parameter '%s' points to an atomic type and must use address space 'workgroup' or 'storage', got '%s'",
+					v.symbolName(param.Name), ptr.AddressSpace.String())
+			}
+		}
+
 		// Validate parameter attributes
 		v.validateParameterAttributes(param)
 	}
@@ -522,6 +696,8 @@ func (v *Validator) validateParameterAttributes(param ast.Parameter) {
 func (v *Validator) validateEntryPoint(fn *ast.FunctionDecl) {
 	name := v.symbolName(fn.Name)
 
+	v.entryPoints = append(v.entryPoints, v.collectEntryPointIO(fn))
+
 	switch v.currentStage {
 	case StageVertex:
 		// Must return @builtin(position) vec4<f32>
@@ -802,6 +978,7 @@ func (v *Validator) validateDiscardStmt(s *ast.DiscardStmt) {
 }
 
 func (v *Validator) validateAssignStmt(s *ast.AssignStmt) {
+	v.allowAtomicRef = true
 	lhsType := v.checkExpr(s.Left)
 	rhsType := v.checkExpr(s.Right)
 
@@ -809,6 +986,12 @@ func (v *Validator) validateAssignStmt(s *ast.AssignStmt) {
 		return
 	}
 
+	if types.IsAtomic(lhsType) {
+		v.errorWithCode(int(s.Loc.Start), string(diagnostic.CodeAtomicDirectAccess),
+			"cannot store directly to '%s', use atomicStore", lhsType.String())
+		return
+	}
+
 	// Check LHS is assignable (reference type)
 	// For now, just check type compatibility
 	if !types.CanConvertTo(rhsType, lhsType) {
@@ -818,11 +1001,18 @@ func (v *Validator) validateAssignStmt(s *ast.AssignStmt) {
 }
 
 func (v *Validator) validateIncrDecrStmt(s *ast.IncrDecrStmt) {
+	v.allowAtomicRef = true
 	exprType := v.checkExpr(s.Expr)
 	if exprType == nil {
 		return
 	}
 
+	if types.IsAtomic(exprType) {
+		v.errorWithCode(int(s.Loc.Start), string(diagnostic.CodeAtomicDirectAccess),
+			"cannot increment or decrement '%s' directly, use atomicAdd or atomicSub", exprType.String())
+		return
+	}
+
 	if !types.IsInteger(exprType) {
 		v.errorWithCode(int(s.Loc.Start), string(diagnostic.CodeTypeMismatch),
 			"increment/decrement requires integer type, got '%s'", exprType.String())
@@ -855,6 +1045,10 @@ func (v *Validator) checkExpr(expr ast.Expr) types.Type {
 		return nil
 	}
 
+	// Only the outermost expression may be used as a reference
+	allowAtomicRef := v.allowAtomicRef
+	v.allowAtomicRef = false
+
 	var t types.Type
 
 	switch e := expr.(type) {
@@ -873,11 +1067,21 @@ func (v *Validator) checkExpr(expr ast.Expr) types.Type {
 	case *ast.MemberExpr:
 		t = v.checkMember(e)
 	case *ast.ParenExpr:
+		v.allowAtomicRef = allowAtomicRef
 		t = v.checkExpr(e.Expr)
 	}
 
+	// Atomic values can only be accessed through the atomic builtins
+	if types.IsAtomic(t) && !allowAtomicRef {
+		if _, ok := expr.(*ast.ParenExpr); !ok {
+			v.errorWithCode(v.exprLoc(expr), string(diagnostic.CodeAtomicDirectAccess),
+				"cannot load '%s' directly, use atomicLoad", t.String())
+		}
+	}
+
 	// Store type info
 	if t != nil && expr != nil {
+		v.typeInfo.Exprs[expr] = t
 		// Use expression location as key
 		switch e := expr.(type) {
 		case *ast.LiteralExpr:
@@ -1070,6 +1274,7 @@ func (v *Validator) checkBinary(e *ast.BinaryExpr) types.Type {
 }
 
 func (v *Validator) checkUnary(e *ast.UnaryExpr) types.Type {
+	v.allowAtomicRef = e.Op == ast.UnaryOpAddr
 	operandType := v.checkExpr(e.Operand)
 	if operandType == nil {
 		return nil
@@ -1116,9 +1321,9 @@ func (v *Validator) checkUnary(e *ast.UnaryExpr) types.Type {
 		return nil
 
 	case ast.UnaryOpAddr:
-		// Creates a pointer to the operand
-		// Simplified - actual address space detection is complex
-		return types.Ptr(types.AddressSpaceFunction, operandType, types.AccessModeReadWrite)
+		// Creates a pointer to the operand, in the memory of its root variable
+		space, access := v.referenceOrigin(e.Operand)
+		return types.Ptr(space, operandType, access)
 	}
 
 	return nil
@@ -1130,6 +1335,9 @@ func (v *Validator) checkCall(e *ast.CallExpr) types.Type {
 
 	// First check if it's a template type constructor
 	if e.TemplateType != nil {
+		for _, arg := range e.Args {
+			v.checkExpr(arg)
+		}
 		return v.resolveType(e.TemplateType)
 	}
 
@@ -1159,6 +1367,20 @@ func (v *Validator) checkCall(e *ast.CallExpr) types.Type {
 			// Will be checked in uniformity analysis phase
 		}
 
+		// Texture builtins resolve against the texture argument's type
+		if builtin.Kind == builtins.BuiltinTexture {
+			retType, err := builtins.ResolveTextureCall(calleeName, argTypes)
+			if err != nil {
+				if err.Arg < 0 {
+					v.errorWithCode(int(e.Loc.Start), string(diagnostic.CodeInvalidArgCount), "%s", err.Message)
+				} else {
+					v.errorWithCode(v.exprLoc(e.Args[err.Arg]), string(diagnostic.CodeInvalidArgType), "%s", err.Message)
+				}
+				return nil
+			}
+			return retType
+		}
+
 		// Resolve overload
 		retType, ok := builtins.ResolveOverload(builtin, argTypes)
 		if !ok {
@@ -1167,13 +1389,30 @@ func (v *Validator) checkCall(e *ast.CallExpr) types.Type {
 				calleeName, v.formatTypes(argTypes))
 			return nil
 		}
+
+		// Every atomic builtin except atomicLoad writes through its pointer
+		if builtin.Kind == builtins.BuiltinAtomic && calleeName != "atomicLoad" {
+			if ptr, ok := argTypes[0].(*types.Pointer); ok &&
+				effectiveAccessMode(ptr.AddressSpace, ptr.AccessMode) == types.AccessModeRead {
+				v.errorWithCode(int(e.Loc.Start), string(diagnostic.CodeAtomicReadOnly),
+					"'%s' requires a read_write pointer, got '%s'", calleeName, ptr.String())
+			}
+		}
 		return retType
 	}
 
 	// Check if it's a type constructor
 	if t := v.lookupType(calleeName); t != nil {
+		if inferred := inferConstructorType(calleeName, t, argTypes); inferred != nil {
+			t = inferred
+		}
 		return v.checkTypeConstructor(e, t, argTypes)
 	}
+	if calleeName == "array" && len(argTypes) > 0 {
+		if inferred := inferConstructorType(calleeName, nil, argTypes); inferred != nil {
+			return inferred
+		}
+	}
 
 	// Check if it's a user-defined function
 	if ident, ok := e.Func.(*ast.IdentExpr); ok && ident.Ref.IsValid() {
@@ -1204,6 +1443,76 @@ func (v *Validator) checkCall(e *ast.CallExpr) types.Type {
 	return nil
 }
 
+// inferConstructorType returns the type built by a vector, matrix or array
+// constructor that leaves its element type to inference, such as vec3(x)
+// or array(a, b), or nil. The element type is the common type of the
+// arguments' elements.
+func inferConstructorType(name string, t types.Type, argTypes []types.Type) types.Type {
+	if len(argTypes) == 0 {
+		return nil
+	}
+	elementOf := func(t types.Type) types.Type {
+		if ref, ok := t.(*types.Reference); ok {
+			t = ref.Element
+		}
+		switch t := t.(type) {
+		case *types.Vector:
+			return t.Element
+		case *types.Matrix:
+			return t.Element
+		}
+		return t
+	}
+	common := func(elem func(types.Type) types.Type) types.Type {
+		var result types.Type
+		for _, arg := range argTypes {
+			if arg == nil {
+				return nil
+			}
+			if result == nil {
+				result = elem(arg)
+			} else if result = types.CommonType(result, elem(arg)); result == nil {
+				return nil
+			}
+		}
+		return result
+	}
+
+	switch ty := t.(type) {
+	case *types.Vector:
+		if len(name) != len("vec3") {
+			return nil
+		}
+		if elem, ok := common(elementOf).(*types.Scalar); ok {
+			return &types.Vector{Width: ty.Width, Element: elem}
+		}
+	case *types.Matrix:
+		if len(name) != len("mat3x3") {
+			return nil
+		}
+		if elem, ok := common(elementOf).(*types.Scalar); ok {
+			if elem.Kind == types.ScalarAbstractInt {
+				elem = types.AbstractFloat
+			}
+			return &types.Matrix{Cols: ty.Cols, Rows: ty.Rows, Element: elem}
+		}
+	case nil:
+		if name != "array" {
+			return nil
+		}
+		value := func(t types.Type) types.Type {
+			if ref, ok := t.(*types.Reference); ok {
+				return ref.Element
+			}
+			return t
+		}
+		if elem := common(value); elem != nil {
+			return &types.Array{Element: elem, Count: len(argTypes)}
+		}
+	}
+	return nil
+}
+
 func (v *Validator) checkTypeConstructor(e *ast.CallExpr, t types.Type, argTypes []types.Type) types.Type {
 	// Type constructors create values of the type
 	switch ty := t.(type) {
@@ -1346,7 +1655,11 @@ func (v *Validator) checkMember(e *ast.MemberExpr) types.Type {
 
 func (v *Validator) analyzeUniformity() {
 	v.uniformityAnalyzer = NewUniformityAnalyzer(v.module, v.diags, v.options.DiagnosticFilters)
-	v.uniformityAnalyzer.Analyze()
+	v.eachDecl(phaseUniformity, func(decl ast.Decl) {
+		if fn, ok := decl.(*ast.FunctionDecl); ok {
+			v.uniformityAnalyzer.analyzeFunction(fn)
+		}
+	})
 }
 
 // ----------------------------------------------------------------------------
@@ -1449,6 +1762,83 @@ func (v *Validator) resolveType(t ast.Type) types.Type {
 	return nil
 }
 
+// checkAtomicElementTypes reports atomic<T> types, anywhere inside t, whose
+// element type is not i32 or u32.
+func (v *Validator) checkAtomicElementTypes(loc int, t ast.Type) {
+	switch ty := t.(type) {
+	case *ast.AtomicType:
+		if elem, ok := v.resolveType(ty.ElemType).(*types.Scalar); ok &&
+			(elem.Kind == types.ScalarI32 || elem.Kind == types.ScalarU32) {
+			return
+		}
+		elemName := "?"
+		if et := v.resolveType(ty.ElemType); et != nil {
+			elemName = et.String()
+		}
+		v.errorWithCode(loc, string(diagnostic.CodeInvalidAtomicType),
+			"atomic element type must be 'i32' or 'u32', got '%s'", elemName)
+	case *ast.ArrayType:
+		v.checkAtomicElementTypes(loc, ty.ElemType)
+	case *ast.PtrType:
+		v.checkAtomicElementTypes(loc, ty.ElemType)
+	}
+}
+
+// referenceOrigin returns the address space and access mode of the memory
+// a reference expression refers to, following it back to its root variable
+// or pointer.
+func (v *Validator) referenceOrigin(expr ast.Expr) (types.AddressSpace, types.AccessMode) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return v.referenceOrigin(e.Expr)
+	case *ast.MemberExpr:
+		return v.referenceOrigin(e.Base)
+	case *ast.IndexExpr:
+		return v.referenceOrigin(e.Base)
+	case *ast.UnaryExpr:
+		if e.Op == ast.UnaryOpDeref {
+			return v.referenceOrigin(e.Operand)
+		}
+	case *ast.IdentExpr:
+		if d, ok := v.varDecls[e.Ref]; ok {
+			space := types.AddressSpace(d.AddressSpace)
+			if d.AddressSpace == ast.AddressSpaceNone {
+				space = types.AddressSpaceFunction
+			}
+			return space, effectiveAccessMode(space, types.AccessMode(d.AccessMode))
+		}
+		if ptr, ok := v.symbolTypes[e.Ref].(*types.Pointer); ok {
+			return ptr.AddressSpace, effectiveAccessMode(ptr.AddressSpace, ptr.AccessMode)
+		}
+	}
+	return types.AddressSpaceFunction, types.AccessModeReadWrite
+}
+
+// effectiveAccessMode applies the default access mode of an address space
+// when none was written.
+func effectiveAccessMode(space types.AddressSpace, access types.AccessMode) types.AccessMode {
+	if access != types.AccessModeNone {
+		return access
+	}
+	switch space {
+	case types.AddressSpaceStorage, types.AddressSpaceUniform, types.AddressSpaceHandle:
+		return types.AccessModeRead
+	}
+	return types.AccessModeReadWrite
+}
+
+// atomicAddressSpaceAllowed reports whether atomics may be stored in memory
+// with the given address space and access mode.
+func atomicAddressSpaceAllowed(space types.AddressSpace, access types.AccessMode) bool {
+	switch space {
+	case types.AddressSpaceWorkgroup:
+		return true
+	case types.AddressSpaceStorage:
+		return access == types.AccessModeReadWrite
+	}
+	return false
+}
+
 func (v *Validator) lookupType(name string) types.Type {
 	// Check builtin types
 	switch name {
@@ -1466,6 +1856,18 @@ func (v *Validator) lookupType(name string) types.Type {
 		return &types.Sampler{Comparison: false}
 	case "sampler_comparison":
 		return &types.Sampler{Comparison: true}
+	case "texture_depth_2d":
+		return &types.Texture{Kind: types.TextureDepth, Dimension: types.Texture2D}
+	case "texture_depth_2d_array":
+		return &types.Texture{Kind: types.TextureDepth, Dimension: types.Texture2DArray}
+	case "texture_depth_cube":
+		return &types.Texture{Kind: types.TextureDepth, Dimension: types.TextureCube}
+	case "texture_depth_cube_array":
+		return &types.Texture{Kind: types.TextureDepth, Dimension: types.TextureCubeArray}
+	case "texture_depth_multisampled_2d":
+		return &types.Texture{Kind: types.TextureDepthMultisampled, Dimension: types.Texture2D}
+	case "texture_external":
+		return &types.Texture{Kind: types.TextureExternal, Dimension: types.Texture2D}
 	}
 
 	// Check for vector shorthand
@@ -1584,6 +1986,37 @@ func (v *Validator) symbolName(ref ast.Ref) string {
 	return ""
 }
 
+// symbolLoc returns the declaration offset of a symbol.
+func (v *Validator) symbolLoc(ref ast.Ref) int {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(v.module.Symbols) {
+		return 0
+	}
+	return int(v.module.Symbols[ref.InnerIndex].Loc.Start)
+}
+
+// exprLoc returns the start offset of an expression.
+func (v *Validator) exprLoc(expr ast.Expr) int {
+	switch e := expr.(type) {
+	case *ast.LiteralExpr:
+		return int(e.Loc.Start)
+	case *ast.IdentExpr:
+		return int(e.Loc.Start)
+	case *ast.BinaryExpr:
+		return int(e.Loc.Start)
+	case *ast.UnaryExpr:
+		return int(e.Loc.Start)
+	case *ast.CallExpr:
+		return int(e.Loc.Start)
+	case *ast.IndexExpr:
+		return int(e.Loc.Start)
+	case *ast.MemberExpr:
+		return int(e.Loc.Start)
+	case *ast.ParenExpr:
+		return int(e.Loc.Start)
+	}
+	return 0
+}
+
 func (v *Validator) formatTypes(types []types.Type) string {
 	var parts []string
 	for _, t := range types {
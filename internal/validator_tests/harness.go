@@ -20,6 +20,7 @@ type TestCase struct {
 	Source   string
 	Expected ExpectedResult
 	SpecRef  string
+	Lint     bool // run with lint warnings enabled
 }
 
 // ExpectedResult describes the expected validation outcome.
@@ -43,6 +44,7 @@ var (
 	expectWarningRe = regexp.MustCompile(`//\s*@expect-warning\s+(\w+)(?:\s+"([^"]*)")?`)
 	specRefRe       = regexp.MustCompile(`//\s*@spec-ref:\s*(.+)`)
 	testNameRe      = regexp.MustCompile(`//\s*@test:\s*(.+)`)
+	lintRe          = regexp.MustCompile(`//\s*@lint\b`)
 )
 
 // ParseTestFile parses a WGSL test file and extracts annotations.
@@ -71,6 +73,11 @@ func ParseTestFile(path string) (*TestCase, error) {
 			tc.Name = strings.TrimSpace(match[1])
 		}
 
+		// Check for @lint
+		if lintRe.MatchString(line) {
+			tc.Lint = true
+		}
+
 		// Check for @expect-valid
 		if expectValidRe.MatchString(line) {
 			tc.Expected.Valid = true
@@ -117,7 +124,7 @@ func ParseTestFile(path string) (*TestCase, error) {
 func RunTestCase(t *testing.T, tc *TestCase) {
 	t.Helper()
 
-	result := api.Validate(tc.Source)
+	result := api.ValidateWithOptions(tc.Source, api.ValidateOptions{Lint: tc.Lint})
 
 	if tc.Expected.Valid {
 		// Expect valid shader
@@ -247,7 +254,7 @@ func RunTestDirCollect(dir string) ([]TestResult, error) {
 			return nil
 		}
 
-		result := api.Validate(tc.Source)
+		result := api.ValidateWithOptions(tc.Source, api.ValidateOptions{Lint: tc.Lint})
 		tr := TestResult{
 			Name: tc.Name,
 			File: path,
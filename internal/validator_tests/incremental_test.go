@@ -0,0 +1,348 @@
+package validator_tests
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/types"
+	"github.com/HugoDaniel/miniray/internal/validator"
+)
+
+const incrementalShader = `struct Light { color: vec3f, intensity: f32 }
+@group(0) @binding(0) var<uniform> light: Light;
+const scale: f32 = 2.0;
+
+fn brightness() -> f32 {
+    return light.intensity * scale;
+}
+
+fn tint(c: vec3f) -> vec3f {
+    let k = 0.5;
+    return c * k;
+}
+
+@fragment fn main() -> @location(0) vec4f {
+    let b: f32 = 1.0;
+    return vec4f(light.color * b, 1.0);
+}
+`
+
+func TestIncrementalRechecksChangedDeclarations(t *testing.T) {
+	tests := []struct {
+		name      string
+		edit      func(string) string
+		rechecked int
+	}{
+		{"unchanged", func(s string) string { return s }, 0},
+		{"moved", func(s string) string { return "// header\n\n" + s }, 0},
+		{"function body", func(s string) string { return strings.Replace(s, "0.5", "0.25", 1) }, 1},
+		{"dependency", func(s string) string { return strings.Replace(s, "2.0", "3.0", 1) }, 2},
+		{"struct", func(s string) string { return strings.Replace(s, "intensity: f32", "intensity: f32, pad: f32", 1) }, 4},
+		{"new declaration", func(s string) string { return s + "const extra: u32 = 1u;\n" }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inc := validator.NewIncremental(validator.Options{})
+			first := inc.Validate(parse(t, incrementalShader))
+			if first.Reused != 0 || first.Rechecked != 6 {
+				t.Fatalf("first version: rechecked %d, reused %d", first.Rechecked, first.Reused)
+			}
+
+			module := parse(t, tt.edit(incrementalShader))
+			result := inc.Validate(module)
+			if result.Rechecked != tt.rechecked {
+				t.Errorf("rechecked %d declarations, want %d", result.Rechecked, tt.rechecked)
+			}
+			if result.Rechecked+result.Reused != len(module.Declarations) {
+				t.Errorf("rechecked %d and reused %d of %d declarations", result.Rechecked, result.Reused, len(module.Declarations))
+			}
+			compareResults(t, module, validator.Validate(module, validator.Options{}), result)
+		})
+	}
+}
+
+func TestIncrementalReplaysDiagnostics(t *testing.T) {
+	source := "fn f() -> i32 { return 1.5; }\nfn g() -> f32 { return 1.0; }\n"
+	inc := validator.NewIncremental(validator.Options{})
+	inc.Validate(parse(t, source))
+
+	module := parse(t, "\n\n"+strings.Replace(source, "1.0", "2.0", 1))
+	result := inc.Validate(module)
+	if result.Reused != 1 || result.Valid {
+		t.Fatalf("reused %d declarations, valid %v; want f replayed with its error", result.Reused, result.Valid)
+	}
+	if d := result.Diagnostics.Diagnostics()[0]; d.Range.Start.Line != 3 {
+		t.Errorf("error on line %d, want it moved to line 3", d.Range.Start.Line)
+	}
+	compareResults(t, module, validator.Validate(module, validator.Options{}), result)
+}
+
+func TestIncrementalRenamedDeclaration(t *testing.T) {
+	content, err := os.ReadFile("../../testdata/validation/lint/unused_declarations.wgsl")
+	if err != nil {
+		t.Skip("testdata not found")
+	}
+	source := string(content)
+
+	// The struct takes the name of the variable main returns
+	for _, options := range []validator.Options{{}, {Lint: true}} {
+		inc := validator.NewIncremental(options)
+		inc.Validate(parse(t, source))
+		module := parse(t, strings.Replace(source, "struct Light", "struct tint", 1))
+		result := inc.Validate(module)
+		if result.Valid {
+			t.Errorf("lint %v: renamed struct still valid", options.Lint)
+		}
+		compareResults(t, module, validator.Validate(module, options), result)
+
+		// and gives it back
+		module = parse(t, source)
+		compareResults(t, module, validator.Validate(module, options), inc.Validate(module))
+	}
+}
+
+func TestIncrementalDeclaredName(t *testing.T) {
+	// The parser binds S to the local, the validator resolves the type by
+	// name: declaring or removing the struct changes f without a
+	// dependency between them
+	without := "fn f() { let S = 1; var x: S; }\n"
+	with := without + "struct S { a: f32 }\n"
+	for _, edit := range [][2]string{{without, with}, {with, without}} {
+		inc := validator.NewIncremental(validator.Options{})
+		inc.Validate(parse(t, edit[0]))
+		module := parse(t, edit[1])
+		if !compareResults(t, module, validator.Validate(module, validator.Options{}), inc.Validate(module)) {
+			t.Errorf("%q after %q differs", edit[1], edit[0])
+		}
+	}
+}
+
+// TestIncrementalMatchesValidate validates edited versions of the test
+// shaders incrementally and compares every result with a full validation.
+func TestIncrementalMatchesValidate(t *testing.T) {
+	var files []string
+	for _, pattern := range []string{"../../testdata/*.wgsl", "../../testdata/validation/*/*.wgsl", "../../testdata/validation/*/*/*.wgsl"} {
+		matches, _ := filepath.Glob(pattern)
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		t.Skip("testdata not found")
+	}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		source := string(content)
+		module, errs := parser.New(source).Parse()
+		if len(errs) > 0 {
+			continue
+		}
+
+		// Edit declarations in turn, half a dozen per file: move everything
+		// after it, change its text, duplicate it and remove it, going
+		// back to the original source in between
+		versions := []string{source}
+		step := max(1, len(module.Declarations)/6)
+		for i := 0; i < len(module.Declarations); i += step {
+			start, end := declLoc(module.Declarations[i])
+			versions = append(versions,
+				source[:start]+"\n// moved\n"+source[start:],
+				source,
+				source[:end]+" "+source[end:],
+				source[:start]+source[start:end]+"\n"+source[start:end]+source[end:],
+				source[:start]+source[end:],
+				source,
+			)
+		}
+
+		// Rename declarations, to a new name and to the name of the next
+		// declaration, which may be of another kind
+		var names []string
+		for _, decl := range module.Declarations {
+			if name := declName(module, decl); name != "" {
+				names = append(names, name)
+			}
+		}
+		for i, name := range names {
+			other := names[(i+1)%len(names)]
+			for _, to := range []string{name + "_renamed", other} {
+				versions = append(versions, renameDecl(source, module, name, to), source)
+			}
+		}
+
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			for _, options := range []validator.Options{{}, {Lint: true}} {
+				inc := validator.NewIncremental(options)
+				for i, version := range versions {
+					module, errs := parser.New(version).Parse()
+					if len(errs) > 0 {
+						continue
+					}
+					want, ok := validateFull(module, options)
+					if !ok {
+						continue
+					}
+					got := inc.Validate(module)
+					if !compareResults(t, module, want, got) {
+						t.Fatalf("version %d (lint %v) differs:\n%s", i, options.Lint, version)
+					}
+				}
+			}
+		})
+	}
+}
+
+// validateFull validates module, failing on the versions the validator
+// crashes on, which some removals produce.
+func validateFull(module *ast.Module, options validator.Options) (result *validator.Result, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return validator.Validate(module, options), true
+}
+
+func parse(t *testing.T, source string) *ast.Module {
+	t.Helper()
+	module, errs := parser.New(source).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return module
+}
+
+// declName returns the name of a module-scope declaration, or "".
+func declName(module *ast.Module, decl ast.Decl) string {
+	var ref ast.Ref
+	switch d := decl.(type) {
+	case *ast.ConstDecl:
+		ref = d.Name
+	case *ast.OverrideDecl:
+		ref = d.Name
+	case *ast.VarDecl:
+		ref = d.Name
+	case *ast.LetDecl:
+		ref = d.Name
+	case *ast.FunctionDecl:
+		ref = d.Name
+	case *ast.StructDecl:
+		ref = d.Name
+	case *ast.AliasDecl:
+		ref = d.Name
+	}
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(module.Symbols) {
+		return ""
+	}
+	return module.Symbols[ref.InnerIndex].OriginalName
+}
+
+// renameDecl renames the declaration of name, leaving its uses alone.
+func renameDecl(source string, module *ast.Module, name, to string) string {
+	for _, decl := range module.Declarations {
+		if declName(module, decl) != name {
+			continue
+		}
+		start, end := declLoc(decl)
+		loc := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`).FindStringIndex(source[start:end])
+		if loc == nil {
+			break
+		}
+		return source[:start+loc[0]] + to + source[start+loc[1]:]
+	}
+	return source
+}
+
+func declLoc(decl ast.Decl) (int, int) {
+	var loc ast.Loc
+	switch d := decl.(type) {
+	case *ast.ConstDecl:
+		loc = d.Loc
+	case *ast.OverrideDecl:
+		loc = d.Loc
+	case *ast.VarDecl:
+		loc = d.Loc
+	case *ast.LetDecl:
+		loc = d.Loc
+	case *ast.FunctionDecl:
+		loc = d.Loc
+	case *ast.StructDecl:
+		loc = d.Loc
+	case *ast.AliasDecl:
+		loc = d.Loc
+	case *ast.ConstAssertDecl:
+		loc = d.Loc
+	}
+	return int(loc.Start), int(loc.End)
+}
+
+// compareResults reports the differences between a full and an
+// incremental result for module.
+func compareResults(t *testing.T, module *ast.Module, want, got *validator.Result) bool {
+	t.Helper()
+	var diffs []string
+	if got.Valid != want.Valid {
+		diffs = append(diffs, fmt.Sprintf("valid = %v, want %v", got.Valid, want.Valid))
+	}
+	if g, w := got.Diagnostics.Diagnostics(), want.Diagnostics.Diagnostics(); !reflect.DeepEqual(g, w) {
+		diffs = append(diffs, fmt.Sprintf("diagnostics = %+v\nwant %+v", g, w))
+	}
+	if g, w := entryPointStrings(got.EntryPoints), entryPointStrings(want.EntryPoints); !reflect.DeepEqual(g, w) {
+		diffs = append(diffs, fmt.Sprintf("entry points = %v\nwant %v", g, w))
+	}
+
+	g, w := got.TypeInfo, want.TypeInfo
+	if !reflect.DeepEqual(typeStrings(g.ExprTypes), typeStrings(w.ExprTypes)) {
+		diffs = append(diffs, "expression types by offset differ")
+	}
+	if !reflect.DeepEqual(typeStrings(g.Exprs), typeStrings(w.Exprs)) {
+		diffs = append(diffs, "expression types by node differ")
+	}
+	if !reflect.DeepEqual(typeStrings(g.SymbolTypes), typeStrings(w.SymbolTypes)) {
+		diffs = append(diffs, fmt.Sprintf("symbol types = %v\nwant %v", typeStrings(g.SymbolTypes), typeStrings(w.SymbolTypes)))
+	}
+	if !reflect.DeepEqual(g.Structs, w.Structs) {
+		diffs = append(diffs, "struct layouts differ")
+	}
+
+	for _, diff := range diffs {
+		t.Error(diff)
+	}
+	return len(diffs) == 0
+}
+
+func typeStrings[K comparable](m map[K]types.Type) map[K]string {
+	strs := make(map[K]string, len(m))
+	for k, t := range m {
+		strs[k] = typeString(t)
+	}
+	return strs
+}
+
+func typeString(t types.Type) string {
+	if t == nil || reflect.ValueOf(t).IsNil() {
+		return "<nil>"
+	}
+	return t.String()
+}
+
+func entryPointStrings(eps []*validator.EntryPointIO) []string {
+	var strs []string
+	for _, ep := range eps {
+		s := fmt.Sprintf("%s %s %d", ep.Name, ep.Stage, ep.Loc)
+		for _, io := range append(append([]validator.StageIO(nil), ep.Inputs...), ep.Outputs...) {
+			s += fmt.Sprintf(" [%s %d %s %s %s %s %d]", io.Name, io.Location, io.Builtin, typeString(io.Type), io.Interpolation, io.Sampling, io.Loc)
+		}
+		strs = append(strs, s)
+	}
+	return strs
+}
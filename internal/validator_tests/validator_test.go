@@ -4,6 +4,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/validator"
 )
 
 func getTestDataDir() string {
@@ -97,3 +100,28 @@ func TestAllValidation(t *testing.T) {
 	}
 	RunTestDir(t, dir)
 }
+
+// TestVarInitializerCheckedOnce checks that a var without a type reports
+// errors in its initializer once, not again when the initializer is
+// compared with the inferred type.
+func TestVarInitializerCheckedOnce(t *testing.T) {
+	source := `var<workgroup> c: atomic<u32>;
+@compute @workgroup_size(1) fn main() {
+    var z = c;
+}
+`
+	module, errs := parser.New(source).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	result := validator.Validate(module, validator.Options{})
+	loads := 0
+	for _, d := range result.Diagnostics.Diagnostics() {
+		if d.Code == "E0805" {
+			loads++
+		}
+	}
+	if loads != 1 {
+		t.Errorf("expected one E0805, got %v", result.Diagnostics.Diagnostics())
+	}
+}
@@ -175,6 +175,21 @@ export function validate(source, options) {
   return globalThis.__miniray.validate(source, options || {});
 }
 
+/**
+ * Create a compiler that minifies and validates with fixed options.
+ * The options are parsed once, and results and parsed modules of recent
+ * sources are cached, so calling it again with unchanged sources is cheap.
+ * @param {Object} [options] - Minification options
+ * @returns {Object} Compiler with minify(source), validate(source, options) and free()
+ */
+export function createCompiler(options) {
+  if (!_initialized) {
+    throw new Error('miniray not initialized. Call initialize() first.');
+  }
+
+  return globalThis.__miniray.createCompiler(options || {});
+}
+
 /**
  * Check if initialized.
  * @returns {boolean}
@@ -200,4 +215,4 @@ export const version = (() => {
 })();
 
 // Default export for convenience
-export default { initialize, minify, reflect, validate, isInitialized, version };
+export default { initialize, minify, reflect, validate, createCompiler, isInitialized, version };
@@ -155,6 +155,21 @@ export function reflect(source) {
   return globalThis.__miniray.reflect(source);
 }
 
+/**
+ * Create a compiler that minifies and validates with fixed options.
+ * The options are parsed once, and results and parsed modules of recent
+ * sources are cached, so calling it again with unchanged sources is cheap.
+ * @param {Object} [options] - Minification options
+ * @returns {Object} Compiler with minify(source), validate(source, options) and free()
+ */
+export function createCompiler(options) {
+  if (!_initialized) {
+    throw new Error('miniray not initialized. Call initialize() first.');
+  }
+
+  return globalThis.__miniray.createCompiler(options || {});
+}
+
 /**
  * Check if initialized.
  * @returns {boolean}
@@ -181,6 +196,7 @@ export default {
   initialize,
   minify,
   reflect,
+  createCompiler,
   isInitialized,
   version: getVersion
 };
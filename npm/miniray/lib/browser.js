@@ -186,6 +186,21 @@
     return globalThis.__miniray.validate(source, options || {});
   }
 
+  /**
+   * Create a compiler that minifies and validates with fixed options.
+   * The options are parsed once, and results and parsed modules of recent
+   * sources are cached, so calling it again with unchanged sources is cheap.
+   * @param {Object} [options] - Minification options
+   * @returns {Object} Compiler with minify(source), validate(source, options) and free()
+   */
+  function createCompiler(options) {
+    if (!_initialized) {
+      throw new Error('miniray not initialized. Call initialize() first.');
+    }
+
+    return globalThis.__miniray.createCompiler(options || {});
+  }
+
   /**
    * Check if initialized.
    * @returns {boolean}
@@ -210,6 +225,7 @@
     minify: minify,
     reflect: reflect,
     validate: validate,
+    createCompiler: createCompiler,
     isInitialized: isInitialized,
     get version() { return getVersion(); }
   };
@@ -167,6 +167,20 @@ export interface ValidateOptions {
    * Severities: "error", "warning", "info", "off"
    */
   diagnosticFilters?: Record<string, "error" | "warning" | "info" | "off">;
+
+  /**
+   * Report unused declarations and the other lint rules as warnings.
+   * Only supported by Compiler.validate.
+   * @default false
+   */
+  lint?: boolean;
+
+  /**
+   * Add the time spent in each phase and the number of declarations
+   * checked to the result. Only supported by Compiler.validate.
+   * @default false
+   */
+  stats?: boolean;
 }
 
 /**
@@ -203,6 +217,58 @@ export interface ValidateResult {
   errorCount: number;
   /** Number of warning-level diagnostics */
   warningCount: number;
+  /** Work done, when requested with the stats option */
+  stats?: ValidateStats;
+}
+
+/**
+ * The work of a validation. Times are in milliseconds.
+ */
+export interface ValidateStats {
+  /** Number of module-scope declarations */
+  declarations: number;
+  /** Number of declarations checked; the others were unchanged */
+  rechecked: number;
+  timings: {
+    parse: number;
+    typeDeclarations: number;
+    structLayouts: number;
+    declarations: number;
+    functions: number;
+    uniformity: number;
+    lint: number;
+    /** Time spent comparing the source with the previous one */
+    reuse: number;
+    total: number;
+  };
+}
+
+/**
+ * A compiler created by createCompiler. It minifies with the options it was
+ * created with and caches the results and parsed modules of recent sources
+ * by content hash, so calls with unchanged sources are cheap.
+ */
+export interface Compiler {
+  /**
+   * Minify WGSL source code with the compiler's options.
+   * @param source - WGSL source code to minify
+   */
+  minify(source: string): MinifyResult;
+
+  /**
+   * Validate WGSL source code, reusing the parsed module of an unchanged
+   * source. Only the declarations that changed since the previous source
+   * validated with the same options, or depend on one that did, are
+   * checked again.
+   * @param source - WGSL source code to validate
+   * @param options - Validation options
+   */
+  validate(source: string, options?: ValidateOptions): ValidateResult;
+
+  /**
+   * Release the compiler. It must not be used afterwards.
+   */
+  free(): void;
 }
 
 /**
@@ -252,6 +318,14 @@ export function reflect(source: string): ReflectResult;
  */
 export function validate(source: string, options?: ValidateOptions): ValidateResult;
 
+/**
+ * Create a compiler for repeated minification and validation, such as an
+ * editor validating on every keystroke.
+ * @param options - Minification options, parsed once
+ * @returns Compiler; call free() when done
+ */
+export function createCompiler(options?: MinifyOptions): Compiler;
+
 /**
  * Check if the WASM module is initialized.
  */
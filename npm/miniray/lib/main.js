@@ -178,6 +178,21 @@ function validate(source, options) {
   return global.__miniray.validate(source, options || {});
 }
 
+/**
+ * Create a compiler that minifies and validates with fixed options.
+ * The options are parsed once, and results and parsed modules of recent
+ * sources are cached, so calling it again with unchanged sources is cheap.
+ * @param {Object} [options] - Minification options
+ * @returns {Object} Compiler with minify(source), validate(source, options) and free()
+ */
+function createCompiler(options) {
+  if (!_initialized) {
+    throw new Error('miniray not initialized. Call initialize() first.');
+  }
+
+  return global.__miniray.createCompiler(options || {});
+}
+
 /**
  * Check if initialized.
  * @returns {boolean}
@@ -202,6 +217,7 @@ module.exports = {
   minify,
   reflect,
   validate,
+  createCompiler,
   isInitialized,
   get version() { return getVersion(); }
 };
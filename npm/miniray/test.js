@@ -263,6 +263,65 @@ fn main(@builtin(global_invocation_id) id: vec3u) {
 
     console.log(`\n${passed} passed, ${failed} failed`);
 
+    // Test compiler objects
+    console.log('\n--- Compiler API Tests ---');
+    const compilerShader = `fn unused() {}
+@fragment fn main() -> @location(0) vec4f { let c = vec4f(1.0); return c; }`;
+    const compiler = globalThis.__miniray.createCompiler({ minifyIdentifiers: false });
+    const compilerTests = [
+        {
+            name: 'Compiler minify matches minify',
+            check: () => {
+                const expected = globalThis.__miniray.minify(compilerShader, { minifyIdentifiers: false });
+                return compiler.minify(compilerShader).code === expected.code &&
+                       compiler.minify(compilerShader).code === expected.code;
+            }
+        },
+        {
+            name: 'Compiler validate with per-call options',
+            check: () => {
+                const plain = compiler.validate(compilerShader);
+                const linted = compiler.validate(compilerShader, { lint: true });
+                return plain.valid && plain.warningCount === 0 && linted.warningCount > 0;
+            }
+        },
+        {
+            name: 'Compiler validate reports errors',
+            check: () => {
+                const r = compiler.validate(`fn foo() -> f32 { return bar; }`);
+                return r.valid === false && r.errorCount > 0 && r.diagnostics[0].line === 1;
+            }
+        },
+        {
+            name: 'Compiler validate checks only changed declarations',
+            check: () => {
+                const first = compiler.validate(compilerShader, { stats: true });
+                const edited = compiler.validate(compilerShader.replace('1.0', '2.0'), { stats: true });
+                return first.stats.rechecked === 2 && edited.stats.rechecked === 1 &&
+                       edited.stats.declarations === 2 && edited.stats.timings.total >= 0;
+            }
+        }
+    ];
+
+    for (const test of compilerTests) {
+        try {
+            if (test.check()) {
+                console.log(`✓ ${test.name}`);
+                passed++;
+            } else {
+                console.log(`✗ ${test.name}`);
+                failed++;
+            }
+        } catch (err) {
+            console.log(`✗ ${test.name}`);
+            console.log(`  Error: ${err.message}`);
+            failed++;
+        }
+    }
+    compiler.free();
+
+    console.log(`\n${passed} passed, ${failed} failed`);
+
     // Show example output
     console.log('\n--- Example Output ---');
     const example = `@group(0) @binding(0) var<uniform> uniforms: f32;
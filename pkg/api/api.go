@@ -5,7 +5,15 @@
 package api
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
 	"github.com/HugoDaniel/miniray/internal/diagnostic"
+	"github.com/HugoDaniel/miniray/internal/lint"
 	"github.com/HugoDaniel/miniray/internal/minifier"
 	"github.com/HugoDaniel/miniray/internal/parser"
 	"github.com/HugoDaniel/miniray/internal/reflect"
@@ -38,6 +46,14 @@ type MinifyOptions struct {
 	// KeepNames specifies identifier names that should not be renamed.
 	KeepNames []string
 
+	// Overrides bakes values for override declarations, keyed by name or
+	// @id, into constants. Booleans are 0 or 1, as in WebGPU.
+	Overrides map[string]float64
+
+	// Defines sets preprocessor names for #define/#ifdef/#if feature
+	// variants. A name defined without a value should map to "".
+	Defines map[string]string
+
 	// SourceMap enables source map generation.
 	// If true, the result will include a source map.
 	SourceMap bool
@@ -58,6 +74,11 @@ type SourceMapOptions struct {
 	// IncludeSource embeds the original source code in "sourcesContent".
 	// This makes the source map self-contained but increases its size.
 	IncludeSource bool
+
+	// Scopes adds the "x_miniray_scopes" extension, which describes the
+	// function and block scopes of the source and the generated name of
+	// every declaration in them.
+	Scopes bool
 }
 
 // MinifyResult contains the minification output.
@@ -75,6 +96,10 @@ type MinifyResult struct {
 	// MinifiedSize is the size of the output in bytes.
 	MinifiedSize int
 
+	// Hash is the content hash of Code (see ContentHash).
+	// Empty if minification failed.
+	Hash string
+
 	// SourceMap is the generated source map as a JSON string.
 	// Empty if source map generation was not requested.
 	SourceMap string
@@ -103,11 +128,14 @@ func MinifyWithOptions(source string, opts MinifyOptions) MinifyResult {
 		MinifySyntax:           opts.MinifySyntax,
 		MangleExternalBindings: opts.MangleExternalBindings,
 		KeepNames:              opts.KeepNames,
+		Defines:                opts.Defines,
+		Overrides:              opts.Overrides,
 		GenerateSourceMap:      opts.SourceMap,
 		SourceMapOptions: minifier.SourceMapOptions{
 			File:          opts.SourceMapOptions.File,
 			SourceName:    opts.SourceMapOptions.SourceName,
 			IncludeSource: opts.SourceMapOptions.IncludeSource,
+			Scopes:        opts.SourceMapOptions.Scopes,
 		},
 	})
 
@@ -125,6 +153,9 @@ func MinifyWithOptions(source string, opts MinifyOptions) MinifyResult {
 		OriginalSize: result.Stats.OriginalSize,
 		MinifiedSize: result.Stats.MinifiedSize,
 	}
+	if len(errors) == 0 {
+		apiResult.Hash = ContentHash(result.Code)
+	}
 
 	// Include source map if generated
 	if result.SourceMap != nil {
@@ -143,6 +174,18 @@ func MinifyWhitespaceOnly(source string) MinifyResult {
 	})
 }
 
+// ContentHashLength is the number of characters in a content hash.
+const ContentHashLength = 8
+
+// ContentHash returns a short hash of code for cache-busting file names,
+// such as "shader.1a2b3c4d.wgsl". It is the first ContentHashLength hex
+// digits of the SHA-256 of code, so it is the same on every machine and
+// changes whenever the code does.
+func ContentHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])[:ContentHashLength]
+}
+
 // ----------------------------------------------------------------------------
 // Reflection API
 // ----------------------------------------------------------------------------
@@ -158,6 +201,10 @@ type ReflectResult struct {
 	// EntryPoints contains all shader entry point functions.
 	EntryPoints []EntryPointInfo `json:"entryPoints"`
 
+	// Overrides contains the pipeline-overridable constants, including
+	// the ones specialized by MinifyOptions.Overrides.
+	Overrides []OverrideInfo `json:"overrides,omitempty"`
+
 	// Errors contains any errors encountered during parsing.
 	Errors []string `json:"errors,omitempty"`
 }
@@ -276,6 +323,25 @@ type EntryPointInfo struct {
 	WorkgroupSize []int `json:"workgroupSize"`
 }
 
+// OverrideInfo describes an override declaration.
+type OverrideInfo struct {
+	// Name is the override name.
+	Name string `json:"name"`
+
+	// ID is the value of @id(n), nil if absent.
+	ID *int `json:"id,omitempty"`
+
+	// Type is the scalar type: "bool", "i32", "u32", "f32" or "f16".
+	// Empty if it is inferred from a non-literal initializer.
+	Type string `json:"type"`
+
+	// Specialized is true if the override was replaced by a constant.
+	Specialized bool `json:"specialized"`
+
+	// Value is the specialized value, nil if not specialized.
+	Value *float64 `json:"value,omitempty"`
+}
+
 // Reflect extracts binding, struct, and entry point information from WGSL source.
 // This is useful for shader introspection without minification.
 func Reflect(source string) ReflectResult {
@@ -286,6 +352,7 @@ func Reflect(source string) ReflectResult {
 		Bindings:    convertBindings(result.Bindings),
 		Structs:     convertStructs(result.Structs),
 		EntryPoints: convertEntryPoints(result.EntryPoints),
+		Overrides:   convertOverrides(result.Overrides),
 		Errors:      result.Errors,
 	}
 }
@@ -383,6 +450,24 @@ func convertEntryPoints(entryPoints []reflect.EntryPointInfo) []EntryPointInfo {
 	return result
 }
 
+// convertOverrides converts override info to API types.
+func convertOverrides(overrides []reflect.OverrideInfo) []OverrideInfo {
+	if len(overrides) == 0 {
+		return nil
+	}
+	result := make([]OverrideInfo, len(overrides))
+	for i, o := range overrides {
+		result[i] = OverrideInfo{
+			Name:        o.Name,
+			ID:          o.ID,
+			Type:        o.Type,
+			Specialized: o.Specialized,
+			Value:       o.Value,
+		}
+	}
+	return result
+}
+
 // ----------------------------------------------------------------------------
 // Combined Minify + Reflect API
 // ----------------------------------------------------------------------------
@@ -421,11 +506,14 @@ func MinifyAndReflectWithOptions(source string, opts MinifyOptions) MinifyAndRef
 		MinifySyntax:           opts.MinifySyntax,
 		MangleExternalBindings: opts.MangleExternalBindings,
 		KeepNames:              opts.KeepNames,
+		Defines:                opts.Defines,
+		Overrides:              opts.Overrides,
 		GenerateSourceMap:      opts.SourceMap,
 		SourceMapOptions: minifier.SourceMapOptions{
 			File:          opts.SourceMapOptions.File,
 			SourceName:    opts.SourceMapOptions.SourceName,
 			IncludeSource: opts.SourceMapOptions.IncludeSource,
+			Scopes:        opts.SourceMapOptions.Scopes,
 		},
 	})
 
@@ -448,10 +536,15 @@ func MinifyAndReflectWithOptions(source string, opts MinifyOptions) MinifyAndRef
 			Bindings:    convertBindings(result.Reflect.Bindings),
 			Structs:     convertStructs(result.Reflect.Structs),
 			EntryPoints: convertEntryPoints(result.Reflect.EntryPoints),
+			Overrides:   convertOverrides(result.Reflect.Overrides),
 			Errors:      result.Reflect.Errors,
 		},
 	}
 
+	if len(errors) == 0 {
+		apiResult.MinifyResult.Hash = ContentHash(result.Code)
+	}
+
 	// Include source map if generated
 	if result.SourceMap != nil {
 		apiResult.MinifyResult.SourceMap = result.SourceMap.ToJSON()
@@ -473,7 +566,17 @@ type ValidateOptions struct {
 	// DiagnosticFilters control which diagnostics are reported.
 	// The map key is the diagnostic rule name (e.g., "derivative_uniformity").
 	// The value is the severity: "error", "warning", "info", or "off".
+	// Lint rules (e.g., "unused_function") are filtered the same way.
 	DiagnosticFilters map[string]string
+
+	// Lint reports warnings for unused functions, structs, bindings,
+	// constants, parameters and locals, for discarded call results, and
+	// for the style and correctness rules of the lint package.
+	Lint bool
+
+	// Stats adds the time spent in each phase and the number of
+	// declarations checked to the result.
+	Stats bool
 }
 
 // DiagnosticInfo represents a single validation diagnostic.
@@ -501,6 +604,10 @@ type DiagnosticInfo struct {
 
 	// SpecRef is a reference to the WGSL spec section.
 	SpecRef string `json:"specRef,omitempty"`
+
+	// Stage is "vertex" or "fragment" when ValidateInterface checks two
+	// separate modules, telling which one the diagnostic belongs to.
+	Stage string `json:"stage,omitempty"`
 }
 
 // ValidateResult contains validation output.
@@ -516,6 +623,38 @@ type ValidateResult struct {
 
 	// WarningCount is the number of warning-level diagnostics.
 	WarningCount int `json:"warningCount"`
+
+	// Stats describes the work done, when ValidateOptions.Stats is set.
+	Stats *ValidateStats `json:"stats,omitempty"`
+}
+
+// ValidateStats describes the work of a validation.
+type ValidateStats struct {
+	// Declarations is the number of module-scope declarations.
+	Declarations int `json:"declarations"`
+
+	// Rechecked is the number of declarations checked. An
+	// IncrementalValidator reuses the results of the others.
+	Rechecked int `json:"rechecked"`
+
+	// Timings are the times spent in each phase, in milliseconds.
+	Timings ValidateTimings `json:"timings"`
+}
+
+// ValidateTimings are the times spent in each validation phase, in
+// milliseconds.
+type ValidateTimings struct {
+	Parse            float64 `json:"parse"`
+	TypeDeclarations float64 `json:"typeDeclarations"`
+	StructLayouts    float64 `json:"structLayouts"`
+	Declarations     float64 `json:"declarations"`
+	Functions        float64 `json:"functions"`
+	Uniformity       float64 `json:"uniformity"`
+	Lint             float64 `json:"lint"`
+	// Reuse is the time spent comparing the module with the previous
+	// version validated by an IncrementalValidator.
+	Reuse float64 `json:"reuse"`
+	Total float64 `json:"total"`
 }
 
 // Validate validates WGSL source code and returns diagnostics.
@@ -526,10 +665,49 @@ func Validate(source string) ValidateResult {
 
 // ValidateWithOptions validates WGSL source code with custom options.
 func ValidateWithOptions(source string, opts ValidateOptions) ValidateResult {
-	// Parse the source
-	p := parser.New(source)
-	module, parseErrors := p.Parse()
+	return Parse(source).Validate(opts)
+}
+
+// Module is a parsed WGSL module. Validating it does not change it, so a
+// Module can be validated any number of times, with different options and
+// from several goroutines at once.
+type Module struct {
+	module      *ast.Module
+	parseErrors []parser.ParseError
+	parseTime   time.Duration
+}
+
+// Parse parses WGSL source code for validation. Parse errors are reported
+// by Validate.
+func Parse(source string) *Module {
+	start := time.Now()
+	module, parseErrors := parser.New(source).Parse()
+	return &Module{module: module, parseErrors: parseErrors, parseTime: time.Since(start)}
+}
 
+// Validate validates the module with the given options.
+func (m *Module) Validate(opts ValidateOptions) ValidateResult {
+	result, _ := m.validate(opts, "")
+	return result
+}
+
+// validateSource parses and validates a module. The validator result is nil
+// when parsing failed. Diagnostics are tagged with stage, if given.
+func validateSource(source string, opts ValidateOptions, stage string) (ValidateResult, *validator.Result) {
+	return Parse(source).validate(opts, stage)
+}
+
+// validate validates the module. The validator result is nil when parsing
+// failed. Diagnostics are tagged with stage, if given.
+func (m *Module) validate(opts ValidateOptions, stage string) (ValidateResult, *validator.Result) {
+	options := opts.validatorOptions()
+	return m.check(opts, options, stage, func(module *ast.Module) *validator.Result {
+		return validator.Validate(module, options)
+	})
+}
+
+// validatorOptions converts the options for the validator.
+func (opts ValidateOptions) validatorOptions() validator.Options {
 	// Convert diagnostic filters
 	var filters *diagnostic.DiagnosticFilter
 	if len(opts.DiagnosticFilters) > 0 {
@@ -547,7 +725,16 @@ func ValidateWithOptions(source string, opts ValidateOptions) ValidateResult {
 			}
 		}
 	}
+	return validator.Options{
+		StrictMode:        opts.StrictMode,
+		DiagnosticFilters: filters,
+		Lint:              opts.Lint,
+	}
+}
 
+// check reports the parse errors of the module or, when there are none,
+// runs validate and the lint rules.
+func (m *Module) check(opts ValidateOptions, options validator.Options, stage string, validate func(*ast.Module) *validator.Result) (ValidateResult, *validator.Result) {
 	// Initialize result
 	result := ValidateResult{
 		Valid:       true,
@@ -555,55 +742,235 @@ func ValidateWithOptions(source string, opts ValidateOptions) ValidateResult {
 	}
 
 	// Add parse errors
-	for _, e := range parseErrors {
+	for _, e := range m.parseErrors {
 		result.Diagnostics = append(result.Diagnostics, DiagnosticInfo{
-			Severity: "error",
-			Code:     "E0001",
-			Message:  e.Message,
-			Line:     e.Line,
-			Column:   e.Column,
+			Severity:  "error",
+			Code:      "E0001",
+			Message:   e.Message,
+			Line:      e.Line,
+			Column:    e.Column,
+			EndLine:   e.EndLine,
+			EndColumn: e.EndColumn,
+			Stage:     stage,
 		})
 		result.ErrorCount++
 		result.Valid = false
 	}
 
-	// If parsing succeeded, run semantic validation
-	if len(parseErrors) == 0 {
-		validatorResult := validator.Validate(module, validator.Options{
-			StrictMode:        opts.StrictMode,
-			DiagnosticFilters: filters,
+	if len(m.parseErrors) > 0 {
+		if opts.Stats {
+			result.Stats = &ValidateStats{Timings: ValidateTimings{
+				Parse: milliseconds(m.parseTime),
+				Total: milliseconds(m.parseTime),
+			}}
+		}
+		return result, nil
+	}
+
+	// Parsing succeeded, run semantic validation
+	validatorResult := validate(m.module)
+	var lintTime time.Duration
+	if opts.Lint {
+		start := time.Now()
+		lint.Run(m.module, validatorResult.TypeInfo, validatorResult.Diagnostics, lint.Options{
+			Filter:     options.DiagnosticFilters,
+			StrictMode: opts.StrictMode,
 		})
+		lintTime = time.Since(start)
+	}
+	result.addDiagnostics(validatorResult.Diagnostics, stage)
+
+	if opts.Stats {
+		t := validatorResult.Timings
+		result.Stats = &ValidateStats{
+			Declarations: len(m.module.Declarations),
+			Rechecked:    validatorResult.Rechecked,
+			Timings: ValidateTimings{
+				Parse:            milliseconds(m.parseTime),
+				TypeDeclarations: milliseconds(t.TypeDeclarations),
+				StructLayouts:    milliseconds(t.StructLayouts),
+				Declarations:     milliseconds(t.Declarations),
+				Functions:        milliseconds(t.Functions),
+				Uniformity:       milliseconds(t.Uniformity),
+				Lint:             milliseconds(t.Lint + lintTime),
+				Reuse:            milliseconds(t.Reuse),
+				Total:            milliseconds(m.parseTime + t.Total() + lintTime),
+			},
+		}
+	}
 
-		// Convert diagnostics
-		for _, d := range validatorResult.Diagnostics.Diagnostics() {
-			severity := "error"
-			switch d.Severity {
-			case diagnostic.Error:
-				severity = "error"
-				result.ErrorCount++
-			case diagnostic.Warning:
-				severity = "warning"
-				result.WarningCount++
-			case diagnostic.Info:
-				severity = "info"
-			case diagnostic.Note:
-				severity = "note"
-			}
+	return result, validatorResult
+}
+
+// add adds the work of another validation.
+func (s *ValidateStats) add(o *ValidateStats) {
+	s.Declarations += o.Declarations
+	s.Rechecked += o.Rechecked
+	t, u := &s.Timings, o.Timings
+	t.Parse += u.Parse
+	t.TypeDeclarations += u.TypeDeclarations
+	t.StructLayouts += u.StructLayouts
+	t.Declarations += u.Declarations
+	t.Functions += u.Functions
+	t.Uniformity += u.Uniformity
+	t.Lint += u.Lint
+	t.Reuse += u.Reuse
+	t.Total += u.Total
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
 
-			result.Diagnostics = append(result.Diagnostics, DiagnosticInfo{
-				Severity:  severity,
-				Code:      d.Code,
-				Message:   d.Message,
-				Line:      d.Range.Start.Line,
-				Column:    d.Range.Start.Column,
-				EndLine:   d.Range.End.Line,
-				EndColumn: d.Range.End.Column,
-				SpecRef:   d.SpecRef,
-			})
+// IncrementalValidator validates successive versions of a shader, such as
+// the text of an editor buffer, with fixed options. Each version is
+// compared with the previous one, and only the declarations that changed,
+// or depend on one that changed, are checked again; the results are the
+// ones Validate reports. It is safe for concurrent use, but validations
+// run one at a time, so use one per document.
+type IncrementalValidator struct {
+	mu          sync.Mutex
+	opts        ValidateOptions
+	options     validator.Options
+	incremental *validator.Incremental
+}
+
+// NewIncrementalValidator creates an incremental validator with the given
+// options.
+func NewIncrementalValidator(opts ValidateOptions) *IncrementalValidator {
+	options := opts.validatorOptions()
+	return &IncrementalValidator{
+		opts:        opts,
+		options:     options,
+		incremental: validator.NewIncremental(options),
+	}
+}
+
+// Validate validates the module, reusing the results of the declarations
+// it shares with the previously validated one. A module that does not
+// parse is reported without affecting the next validation.
+func (iv *IncrementalValidator) Validate(m *Module) ValidateResult {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	result, _ := m.check(iv.opts, iv.options, "", iv.incremental.Validate)
+	return result
+}
+
+// addDiagnostics converts and appends a diagnostic list to the result.
+func (r *ValidateResult) addDiagnostics(diags *diagnostic.DiagnosticList, stage string) {
+	for _, d := range diags.Diagnostics() {
+		severity := "error"
+		switch d.Severity {
+		case diagnostic.Error:
+			severity = "error"
+			r.ErrorCount++
+			r.Valid = false
+		case diagnostic.Warning:
+			severity = "warning"
+			r.WarningCount++
+		case diagnostic.Info:
+			severity = "info"
+		case diagnostic.Note:
+			severity = "note"
 		}
 
-		result.Valid = !validatorResult.Diagnostics.HasErrors()
+		r.Diagnostics = append(r.Diagnostics, DiagnosticInfo{
+			Severity:  severity,
+			Code:      d.Code,
+			Message:   d.Message,
+			Line:      d.Range.Start.Line,
+			Column:    d.Range.Start.Column,
+			EndLine:   d.Range.End.Line,
+			EndColumn: d.Range.End.Column,
+			SpecRef:   d.SpecRef,
+			Stage:     stage,
+		})
 	}
+}
+
+// InterfaceOptions selects the entry points checked by ValidateInterface.
+type InterfaceOptions struct {
+	ValidateOptions
+
+	// VertexEntry is the name of the vertex entry point.
+	VertexEntry string
+
+	// FragmentEntry is the name of the fragment entry point.
+	FragmentEntry string
 
+	// FragmentSource is the module containing the fragment entry point.
+	// If empty, both entry points are looked up in the same source.
+	FragmentSource string
+}
+
+// ValidateInterface validates one or two modules and checks that the
+// outputs of the vertex entry point match the inputs of the fragment entry
+// point: every fragment @location must be written by the vertex stage with
+// the same type and a compatible @interpolate.
+//
+// When FragmentSource is set, each diagnostic's Stage tells which module
+// it belongs to ("vertex" or "fragment").
+func ValidateInterface(source string, opts InterfaceOptions) ValidateResult {
+	if opts.FragmentSource == "" {
+		result, vr := validateSource(source, opts.ValidateOptions, "")
+		if vr != nil {
+			linkStages(&result, vr, vr, source, opts, "", "")
+		}
+		return result
+	}
+
+	result, vertexResult := validateSource(source, opts.ValidateOptions, "vertex")
+	fragment, fragmentResult := validateSource(opts.FragmentSource, opts.ValidateOptions, "fragment")
+	result.Diagnostics = append(result.Diagnostics, fragment.Diagnostics...)
+	result.ErrorCount += fragment.ErrorCount
+	result.WarningCount += fragment.WarningCount
+	result.Valid = result.Valid && fragment.Valid
+	if result.Stats != nil && fragment.Stats != nil {
+		result.Stats.add(fragment.Stats)
+	}
+	if vertexResult != nil && fragmentResult != nil {
+		linkStages(&result, vertexResult, fragmentResult, opts.FragmentSource, opts, "vertex", "fragment")
+	}
 	return result
 }
+
+// linkStages looks up both entry points and reports interface mismatches.
+func linkStages(result *ValidateResult, vertexResult, fragmentResult *validator.Result, fragmentSource string, opts InterfaceOptions, vertexStage, fragmentStage string) {
+	vertex := findEntryPoint(vertexResult, opts.VertexEntry, validator.StageVertex)
+	fragment := findEntryPoint(fragmentResult, opts.FragmentEntry, validator.StageFragment)
+
+	missing := func(stage, name, tag string) {
+		result.Diagnostics = append(result.Diagnostics, DiagnosticInfo{
+			Severity: "error",
+			Code:     string(diagnostic.CodeMissingEntryPoint),
+			Message:  fmt.Sprintf("%s entry point '%s' not found", stage, name),
+			Line:     1,
+			Column:   1,
+			Stage:    tag,
+		})
+		result.ErrorCount++
+		result.Valid = false
+	}
+	if vertex == nil {
+		missing("vertex", opts.VertexEntry, vertexStage)
+	}
+	if fragment == nil {
+		missing("fragment", opts.FragmentEntry, fragmentStage)
+	}
+	if vertex == nil || fragment == nil {
+		return
+	}
+
+	diags := diagnostic.NewDiagnosticList(fragmentSource)
+	validator.ValidateStageInterface(vertex, fragment, diags)
+	result.addDiagnostics(diags, fragmentStage)
+}
+
+func findEntryPoint(r *validator.Result, name string, stage validator.ShaderStage) *validator.EntryPointIO {
+	for _, ep := range r.EntryPoints {
+		if ep.Name == name && ep.Stage == stage {
+			return ep
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -624,3 +625,245 @@ struct Outer { inner: Inner }
 		t.Error("expected nested layout for struct field")
 	}
 }
+
+// Tests for vertex/fragment interface validation
+
+const interfaceVertex = `
+struct VertexOutput {
+    @builtin(position) position: vec4f,
+    @location(0) uv: vec2f,
+    @location(1) @interpolate(flat) id: u32,
+}
+
+@vertex
+fn vs_main(@builtin(vertex_index) index: u32) -> VertexOutput {
+    var out: VertexOutput;
+    out.position = vec4f(0.0, 0.0, 0.0, 1.0);
+    out.uv = vec2f(0.0, 0.0);
+    out.id = index;
+    return out;
+}
+`
+
+func TestValidateInterface(t *testing.T) {
+	source := interfaceVertex + `
+@fragment
+fn fs_main(@location(0) uv: vec2f, @location(1) @interpolate(flat) id: u32) -> @location(0) vec4f {
+    return vec4f(uv, 0.0, 1.0);
+}
+`
+	result := ValidateInterface(source, InterfaceOptions{VertexEntry: "vs_main", FragmentEntry: "fs_main"})
+	if !result.Valid {
+		t.Fatalf("expected valid interface, got %v", result.Diagnostics)
+	}
+}
+
+func TestValidateInterfaceMismatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		code     string
+	}{
+		{
+			name:     "type mismatch",
+			fragment: `@fragment fn fs_main(@location(0) uv: vec3f) -> @location(0) vec4f { return vec4f(uv, 1.0); }`,
+			code:     "E0605",
+		},
+		{
+			name:     "missing location",
+			fragment: `@fragment fn fs_main(@location(2) color: vec4f) -> @location(0) vec4f { return color; }`,
+			code:     "E0606",
+		},
+		{
+			name:     "interpolation mismatch",
+			fragment: `@fragment fn fs_main(@location(0) @interpolate(linear) uv: vec2f) -> @location(0) vec4f { return vec4f(uv, 0.0, 1.0); }`,
+			code:     "E0607",
+		},
+		{
+			name:     "missing entry point",
+			fragment: `@fragment fn other() -> @location(0) vec4f { return vec4f(1.0); }`,
+			code:     "E0601",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ValidateInterface(interfaceVertex, InterfaceOptions{
+				VertexEntry:    "vs_main",
+				FragmentEntry:  "fs_main",
+				FragmentSource: tt.fragment,
+			})
+			if result.Valid {
+				t.Fatal("expected interface errors")
+			}
+			found := false
+			for _, d := range result.Diagnostics {
+				if d.Code == tt.code {
+					found = true
+					if d.Stage != "fragment" {
+						t.Errorf("expected diagnostic on fragment module, got stage %q", d.Stage)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("expected %s, got %v", tt.code, result.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestValidateLint(t *testing.T) {
+	source := `
+fn scale(x: f32, factor: f32) -> f32 {
+    return x;
+}
+
+@fragment
+fn main() -> @location(0) vec4f {
+    return vec4f(scale(1.0, 2.0));
+}
+`
+	hasWarning := func(result ValidateResult, code string) bool {
+		for _, d := range result.Diagnostics {
+			if d.Code == code && d.Severity == "warning" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if hasWarning(Validate(source), "unused_parameter") {
+		t.Error("lint warnings should only be reported with Lint enabled")
+	}
+
+	result := ValidateWithOptions(source, ValidateOptions{Lint: true})
+	if !hasWarning(result, "unused_parameter") {
+		t.Errorf("expected unused_parameter warning, got %v", result.Diagnostics)
+	}
+
+	result = ValidateWithOptions(source, ValidateOptions{
+		Lint:              true,
+		DiagnosticFilters: map[string]string{"unused_parameter": "off"},
+	})
+	if hasWarning(result, "unused_parameter") {
+		t.Error("expected unused_parameter to be disabled by filter")
+	}
+}
+
+func TestValidateReportsAllParseErrors(t *testing.T) {
+	source := "fn f() -> f32 {\n    let a = 1.0 +;\n    let b = a * ;\n    return b foo;\n}\n"
+
+	result := Validate(source)
+	if result.Valid {
+		t.Fatal("expected invalid result")
+	}
+	if result.ErrorCount != 3 {
+		t.Fatalf("expected 3 parse errors, got %d: %+v", result.ErrorCount, result.Diagnostics)
+	}
+
+	last := result.Diagnostics[2]
+	if last.Line != 4 || last.Column != 14 || last.EndLine != 4 || last.EndColumn != 17 {
+		t.Errorf("expected range 4:14-4:17, got %d:%d-%d:%d", last.Line, last.Column, last.EndLine, last.EndColumn)
+	}
+}
+
+func TestIncrementalValidator(t *testing.T) {
+	source := `
+const gain: f32 = 2.0;
+
+fn scale(x: f32) -> f32 {
+    return x * gain;
+}
+
+fn bias(x: f32) -> f32 {
+    return x + 1.0;
+}
+
+@fragment
+fn main() -> @location(0) vec4f {
+    return vec4f(1.0);
+}
+`
+	iv := NewIncrementalValidator(ValidateOptions{Stats: true})
+	if r := iv.Validate(Parse(source)); !r.Valid || r.Stats.Rechecked != 4 {
+		t.Fatalf("first version: valid %v, stats %+v", r.Valid, r.Stats)
+	}
+
+	// A broken version is reported and leaves the previous one in place
+	if r := iv.Validate(Parse(strings.Replace(source, "x + 1.0;", "x +;", 1))); r.Valid || r.ErrorCount != 1 {
+		t.Errorf("expected one parse error, got %+v", r.Diagnostics)
+	}
+
+	edited := strings.Replace(source, "2.0", "true", 1)
+	r := iv.Validate(Parse(edited))
+	if r.Stats.Rechecked != 2 {
+		t.Errorf("rechecked %d declarations, want the constant and its user", r.Stats.Rechecked)
+	}
+	if want := ValidateWithOptions(edited, ValidateOptions{}); !reflect.DeepEqual(r.Diagnostics, want.Diagnostics) {
+		t.Errorf("diagnostics = %+v, want %+v", r.Diagnostics, want.Diagnostics)
+	}
+	if r.Valid {
+		t.Error("expected a type error for the boolean constant")
+	}
+	if r.Stats.Timings.Total <= 0 || r.Stats.Timings.Parse <= 0 {
+		t.Errorf("missing timings: %+v", r.Stats.Timings)
+	}
+}
+
+func TestMinifyAndReflectOverrides(t *testing.T) {
+	source := `
+@id(3) override threshold: f32 = 0.5;
+override blockSize: u32 = 64u;
+@group(0) @binding(0) var<storage, read_write> data: array<f32>;
+
+@compute @workgroup_size(blockSize)
+fn main(@builtin(global_invocation_id) id: vec3u) {
+    data[id.x] = max(data[id.x], threshold);
+}
+`
+	result := MinifyAndReflectWithOptions(source, MinifyOptions{
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		Overrides:         map[string]float64{"blockSize": 256},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+
+	overrides := result.Reflect.Overrides
+	if len(overrides) != 2 {
+		t.Fatalf("expected 2 overrides, got %d", len(overrides))
+	}
+	if o := overrides[0]; o.Name != "threshold" || o.ID == nil || *o.ID != 3 || o.Specialized {
+		t.Errorf("unexpected threshold info: %+v", o)
+	}
+	if o := overrides[1]; !o.Specialized || o.Value == nil || *o.Value != 256 {
+		t.Errorf("unexpected blockSize info: %+v", o)
+	}
+	if ws := result.Reflect.EntryPoints[0].WorkgroupSize; ws[0] != 256 {
+		t.Errorf("expected workgroup size 256, got %v", ws)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	// Fixed value: the hash must not change across machines or releases
+	if got := ContentHash("fn f(){}"); got != "745dc9de" {
+		t.Errorf("ContentHash = %q, want 745dc9de", got)
+	}
+	if ContentHash("fn f(){}") == ContentHash("fn g(){}") {
+		t.Error("different code should hash differently")
+	}
+
+	result := Minify("fn helper() -> f32 { return 1.0; }\n@fragment fn main() -> @location(0) vec4f { return vec4f(helper()); }")
+	if len(result.Hash) != ContentHashLength || result.Hash != ContentHash(result.Code) {
+		t.Errorf("Hash = %q, want ContentHash of the code %q", result.Hash, ContentHash(result.Code))
+	}
+	reflected := MinifyAndReflect("@compute @workgroup_size(1) fn main() {}")
+	if reflected.Hash != ContentHash(reflected.Code) {
+		t.Errorf("MinifyAndReflect Hash = %q, want %q", reflected.Hash, ContentHash(reflected.Code))
+	}
+
+	if failed := Minify("fn broken( {"); failed.Hash != "" {
+		t.Errorf("failed minification should have no hash, got %q", failed.Hash)
+	}
+}
@@ -0,0 +1,206 @@
+package wgsl
+
+import (
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/lexer"
+)
+
+// The syntax tree types are aliases of the ones used by the minifier, so
+// a module built or rewritten here can be handed to the pipeline as is.
+
+// Tree structure.
+type (
+	Module = ast.Module
+	Node   = ast.Node
+	Loc    = ast.Loc
+
+	Directive           = ast.Directive
+	EnableDirective     = ast.EnableDirective
+	RequiresDirective   = ast.RequiresDirective
+	DiagnosticDirective = ast.DiagnosticDirective
+
+	Decl            = ast.Decl
+	ConstDecl       = ast.ConstDecl
+	OverrideDecl    = ast.OverrideDecl
+	VarDecl         = ast.VarDecl
+	LetDecl         = ast.LetDecl
+	FunctionDecl    = ast.FunctionDecl
+	Parameter       = ast.Parameter
+	StructDecl      = ast.StructDecl
+	StructMember    = ast.StructMember
+	AliasDecl       = ast.AliasDecl
+	ConstAssertDecl = ast.ConstAssertDecl
+	Attribute       = ast.Attribute
+
+	Type        = ast.Type
+	IdentType   = ast.IdentType
+	VecType     = ast.VecType
+	MatType     = ast.MatType
+	ArrayType   = ast.ArrayType
+	PtrType     = ast.PtrType
+	AtomicType  = ast.AtomicType
+	SamplerType = ast.SamplerType
+	TextureType = ast.TextureType
+
+	Expr        = ast.Expr
+	IdentExpr   = ast.IdentExpr
+	LiteralExpr = ast.LiteralExpr
+	BinaryExpr  = ast.BinaryExpr
+	UnaryExpr   = ast.UnaryExpr
+	CallExpr    = ast.CallExpr
+	IndexExpr   = ast.IndexExpr
+	MemberExpr  = ast.MemberExpr
+	ParenExpr   = ast.ParenExpr
+	ErrorExpr   = ast.ErrorExpr
+
+	Stmt         = ast.Stmt
+	CompoundStmt = ast.CompoundStmt
+	ReturnStmt   = ast.ReturnStmt
+	IfStmt       = ast.IfStmt
+	SwitchStmt   = ast.SwitchStmt
+	SwitchCase   = ast.SwitchCase
+	ForStmt      = ast.ForStmt
+	WhileStmt    = ast.WhileStmt
+	LoopStmt     = ast.LoopStmt
+	BreakStmt    = ast.BreakStmt
+	BreakIfStmt  = ast.BreakIfStmt
+	ContinueStmt = ast.ContinueStmt
+	DiscardStmt  = ast.DiscardStmt
+	AssignStmt   = ast.AssignStmt
+	IncrDecrStmt = ast.IncrDecrStmt
+	CallStmt     = ast.CallStmt
+	DeclStmt     = ast.DeclStmt
+	ErrorStmt    = ast.ErrorStmt
+)
+
+// Symbols.
+type (
+	Ref         = ast.Ref
+	Symbol      = ast.Symbol
+	SymbolKind  = ast.SymbolKind
+	SymbolFlags = ast.SymbolFlags
+)
+
+// Operators and enumerations.
+type (
+	BinaryOp         = ast.BinaryOp
+	UnaryOp          = ast.UnaryOp
+	AssignOp         = ast.AssignOp
+	AddressSpace     = ast.AddressSpace
+	AccessMode       = ast.AccessMode
+	TextureKind      = ast.TextureKind
+	TextureDimension = ast.TextureDimension
+	LiteralKind      = lexer.TokenKind
+)
+
+// Symbol kinds.
+const (
+	SymbolUnbound   = ast.SymbolUnbound
+	SymbolConst     = ast.SymbolConst
+	SymbolOverride  = ast.SymbolOverride
+	SymbolLet       = ast.SymbolLet
+	SymbolVar       = ast.SymbolVar
+	SymbolFunction  = ast.SymbolFunction
+	SymbolStruct    = ast.SymbolStruct
+	SymbolAlias     = ast.SymbolAlias
+	SymbolParameter = ast.SymbolParameter
+	SymbolBuiltin   = ast.SymbolBuiltin
+	SymbolMember    = ast.SymbolMember
+)
+
+// Symbol flags.
+const (
+	MustNotBeRenamed  = ast.MustNotBeRenamed
+	IsEntryPoint      = ast.IsEntryPoint
+	IsAPIFacing       = ast.IsAPIFacing
+	IsBuiltin         = ast.IsBuiltin
+	IsExternalBinding = ast.IsExternalBinding
+	IsLive            = ast.IsLive
+)
+
+// Literal kinds.
+const (
+	LiteralInt   = lexer.TokIntLiteral
+	LiteralFloat = lexer.TokFloatLiteral
+	LiteralTrue  = lexer.TokTrue
+	LiteralFalse = lexer.TokFalse
+)
+
+// Binary operators.
+const (
+	BinOpAdd        = ast.BinOpAdd
+	BinOpSub        = ast.BinOpSub
+	BinOpMul        = ast.BinOpMul
+	BinOpDiv        = ast.BinOpDiv
+	BinOpMod        = ast.BinOpMod
+	BinOpAnd        = ast.BinOpAnd
+	BinOpOr         = ast.BinOpOr
+	BinOpXor        = ast.BinOpXor
+	BinOpShl        = ast.BinOpShl
+	BinOpShr        = ast.BinOpShr
+	BinOpLogicalAnd = ast.BinOpLogicalAnd
+	BinOpLogicalOr  = ast.BinOpLogicalOr
+	BinOpEq         = ast.BinOpEq
+	BinOpNe         = ast.BinOpNe
+	BinOpLt         = ast.BinOpLt
+	BinOpLe         = ast.BinOpLe
+	BinOpGt         = ast.BinOpGt
+	BinOpGe         = ast.BinOpGe
+)
+
+// Unary operators.
+const (
+	UnaryOpNeg    = ast.UnaryOpNeg
+	UnaryOpNot    = ast.UnaryOpNot
+	UnaryOpBitNot = ast.UnaryOpBitNot
+	UnaryOpDeref  = ast.UnaryOpDeref
+	UnaryOpAddr   = ast.UnaryOpAddr
+)
+
+// Assignment operators.
+const (
+	AssignOpSimple = ast.AssignOpSimple
+	AssignOpAdd    = ast.AssignOpAdd
+	AssignOpSub    = ast.AssignOpSub
+	AssignOpMul    = ast.AssignOpMul
+	AssignOpDiv    = ast.AssignOpDiv
+	AssignOpMod    = ast.AssignOpMod
+	AssignOpAnd    = ast.AssignOpAnd
+	AssignOpOr     = ast.AssignOpOr
+	AssignOpXor    = ast.AssignOpXor
+	AssignOpShl    = ast.AssignOpShl
+	AssignOpShr    = ast.AssignOpShr
+)
+
+// Address spaces and access modes.
+const (
+	AddressSpaceNone      = ast.AddressSpaceNone
+	AddressSpaceFunction  = ast.AddressSpaceFunction
+	AddressSpacePrivate   = ast.AddressSpacePrivate
+	AddressSpaceWorkgroup = ast.AddressSpaceWorkgroup
+	AddressSpaceUniform   = ast.AddressSpaceUniform
+	AddressSpaceStorage   = ast.AddressSpaceStorage
+	AddressSpaceHandle    = ast.AddressSpaceHandle
+
+	AccessModeNone      = ast.AccessModeNone
+	AccessModeRead      = ast.AccessModeRead
+	AccessModeWrite     = ast.AccessModeWrite
+	AccessModeReadWrite = ast.AccessModeReadWrite
+)
+
+// Texture kinds and dimensions.
+const (
+	TextureSampled           = ast.TextureSampled
+	TextureMultisampled      = ast.TextureMultisampled
+	TextureStorage           = ast.TextureStorage
+	TextureDepth             = ast.TextureDepth
+	TextureDepthMultisampled = ast.TextureDepthMultisampled
+	TextureExternal          = ast.TextureExternal
+
+	Texture1D        = ast.Texture1D
+	Texture2D        = ast.Texture2D
+	Texture2DArray   = ast.Texture2DArray
+	Texture3D        = ast.Texture3D
+	TextureCube      = ast.TextureCube
+	TextureCubeArray = ast.TextureCubeArray
+)
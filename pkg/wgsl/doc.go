@@ -0,0 +1,39 @@
+// Package wgsl exposes the WGSL syntax tree used by miniray, so that Go
+// tools can parse shaders, inspect and rewrite them, print them back and
+// run their own passes inside the minifier.
+//
+//	module, errs := wgsl.Parse(source)
+//	wgsl.Inspect(module, func(n wgsl.Node) bool {
+//		if call, ok := n.(*wgsl.CallExpr); ok {
+//			...
+//		}
+//		return true
+//	})
+//	fmt.Println(wgsl.Print(module, wgsl.PrintOptions{}))
+//
+// Custom passes are Transform functions. Minify runs them after dead code
+// elimination and before identifiers are counted and renamed. A transform
+// sees every declaration under its original name; with tree shaking on,
+// the symbols of unreachable ones lack the IsLive flag and are dropped when
+// printing. Anything a transform leaves in the tree is minified like the
+// rest of the shader.
+//
+// # Compatibility
+//
+// This package follows the Go 1 compatibility guidelines within a major
+// version of miniray. Exported functions, types, fields and constants are
+// not removed or changed in incompatible ways, with these exceptions:
+//
+//   - New node types, fields, constants and options may be added. Type
+//     switches over nodes should have a default case, and struct literals
+//     of options and nodes should use field names.
+//   - A node's concrete type may become more precise when WGSL grows, for
+//     example a new Type implementation for a new builtin type.
+//   - Symbol.NestedScopeSlot and Symbol.UseCount are minifier bookkeeping;
+//     their values are not part of the API.
+//   - The exact output of Print and Minify may change between releases,
+//     but it is always valid WGSL equivalent to the input.
+//
+// The packages under internal/ carry no such promise; this package is the
+// supported way to reach them.
+package wgsl
@@ -0,0 +1,190 @@
+package wgsl
+
+import (
+	"fmt"
+
+	"github.com/HugoDaniel/miniray/internal/ast"
+	"github.com/HugoDaniel/miniray/internal/minifier"
+	"github.com/HugoDaniel/miniray/internal/parser"
+	"github.com/HugoDaniel/miniray/internal/printer"
+)
+
+// Error is a problem found while parsing or minifying. Positions are
+// 1-based; the end position is zero when only the start is known.
+type Error struct {
+	Message   string
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+}
+
+func (e Error) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Parse parses WGSL source into a module with resolved symbols. The parser
+// recovers from syntax errors, so the module is returned even when errors
+// are reported; failed parts are represented by *ErrorExpr and *ErrorStmt.
+func Parse(source string) (*Module, []Error) {
+	module, errs := parser.New(source).Parse()
+	var result []Error
+	for _, e := range errs {
+		result = append(result, Error{
+			Message:   e.Message,
+			Line:      e.Line,
+			Column:    e.Column,
+			EndLine:   e.EndLine,
+			EndColumn: e.EndColumn,
+		})
+	}
+	return module, result
+}
+
+// Visitor is called by Walk for every node. If Visit returns a non-nil
+// visitor w, the children of the node are visited with w, followed by a
+// call of w.Visit(nil).
+type Visitor = ast.Visitor
+
+// Walk traverses the tree rooted at node in depth-first source order.
+func Walk(v Visitor, node Node) {
+	ast.Walk(v, node)
+}
+
+// Inspect traverses the tree rooted at node, calling f for every node and
+// skipping the children of nodes for which f returns false.
+func Inspect(node Node, f func(Node) bool) {
+	ast.Inspect(node, f)
+}
+
+// RewriteExprs replaces every expression below node with the result of f.
+// Operands are rewritten before the expression that contains them.
+func RewriteExprs(node Node, f func(Expr) Expr) {
+	ast.RewriteExprs(node, f)
+}
+
+// SymbolOf returns the symbol a reference resolves to, or nil for an
+// invalid or unresolved reference.
+func SymbolOf(module *Module, ref Ref) *Symbol {
+	if !ref.IsValid() || int(ref.InnerIndex) >= len(module.Symbols) {
+		return nil
+	}
+	return &module.Symbols[ref.InnerIndex]
+}
+
+// DeclareSymbol adds a symbol for a declaration created by a transform and
+// returns a reference to it. The symbol is live and keeps its name, so it
+// survives tree shaking and renaming.
+func DeclareSymbol(module *Module, name string, kind SymbolKind) Ref {
+	ref := Ref{InnerIndex: uint32(len(module.Symbols))}
+	module.Symbols = append(module.Symbols, Symbol{
+		OriginalName: name,
+		Kind:         kind,
+		Flags:        IsLive | MustNotBeRenamed,
+	})
+	return ref
+}
+
+// PrintOptions controls Print.
+type PrintOptions struct {
+	// MinifyWhitespace prints the module without optional whitespace.
+	MinifyWhitespace bool
+}
+
+// Print prints a module as WGSL using the original symbol names. All
+// declarations are printed, live or not.
+func Print(module *Module, opts PrintOptions) string {
+	p := printer.New(printer.Options{
+		MinifyWhitespace: opts.MinifyWhitespace,
+	}, module.Symbols)
+	return p.Print(module)
+}
+
+// Transform is a custom pass over a module. Returning an error stops
+// minification; the error is reported in MinifyResult.Errors.
+type Transform func(module *Module) error
+
+// MinifyOptions controls Minify.
+type MinifyOptions struct {
+	// MinifyWhitespace removes unnecessary whitespace and newlines.
+	MinifyWhitespace bool
+
+	// MinifyIdentifiers renames identifiers to shorter names.
+	MinifyIdentifiers bool
+
+	// MinifySyntax applies syntax-level optimizations.
+	MinifySyntax bool
+
+	// MangleExternalBindings renames uniform and storage variables
+	// directly instead of aliasing them.
+	MangleExternalBindings bool
+
+	// TreeShaking removes declarations not reachable from entry points.
+	TreeShaking bool
+
+	// KeepNames lists identifiers that must not be renamed.
+	KeepNames []string
+
+	// Transforms run in order after tree shaking and before renaming.
+	Transforms []Transform
+
+	// SourceMap enables source map generation.
+	SourceMap bool
+}
+
+// MinifyResult is the output of Minify.
+type MinifyResult struct {
+	// Code is the minified WGSL, or the input when there are errors.
+	Code string
+
+	// Errors contains parse and transform errors.
+	Errors []Error
+
+	// SourceMap is the source map JSON, empty unless requested.
+	SourceMap string
+}
+
+// Minify parses and minifies source, running opts.Transforms inside the
+// pipeline.
+func Minify(source string, opts MinifyOptions) MinifyResult {
+	module, errs := Parse(source)
+	if len(errs) > 0 {
+		return MinifyResult{Code: source, Errors: errs}
+	}
+	return MinifyModule(module, opts)
+}
+
+// MinifyModule minifies a parsed module. Source maps refer to
+// module.Source. The module is modified in place.
+func MinifyModule(module *Module, opts MinifyOptions) MinifyResult {
+	// Transforms run as unnamed plugins so that their errors are reported
+	// as returned
+	var plugins []minifier.Plugin
+	for _, transform := range opts.Transforms {
+		plugins = append(plugins, minifier.Plugin{PreRename: transform})
+	}
+
+	m := minifier.New(minifier.Options{
+		MinifyWhitespace:       opts.MinifyWhitespace,
+		MinifyIdentifiers:      opts.MinifyIdentifiers,
+		MinifySyntax:           opts.MinifySyntax,
+		MangleExternalBindings: opts.MangleExternalBindings,
+		TreeShaking:            opts.TreeShaking,
+		KeepNames:              opts.KeepNames,
+		GenerateSourceMap:      opts.SourceMap,
+		Plugins:                plugins,
+	})
+
+	result := m.MinifyModuleWithSource(module, module.Source)
+	out := MinifyResult{Code: result.Code}
+	for _, e := range result.Errors {
+		out.Errors = append(out.Errors, Error{Message: e.Message, Line: e.Line, Column: e.Column})
+	}
+	if result.SourceMap != nil {
+		out.SourceMap = result.SourceMap.ToJSON()
+	}
+	return out
+}
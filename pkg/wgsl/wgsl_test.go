@@ -0,0 +1,221 @@
+package wgsl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const shader = `const scale = 2.0;
+
+fn debugOnly() -> f32 {
+    return 1.0;
+}
+
+fn double(x: f32) -> f32 {
+    return ENGINE_SCALE(x) * scale;
+}
+
+@fragment
+fn main() -> @location(0) vec4f {
+    let v = double(0.5);
+    return vec4f(v, v, v, 1.0);
+}
+`
+
+func mustParse(t *testing.T, source string) *Module {
+	t.Helper()
+	module, errs := Parse(source)
+	if len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return module
+}
+
+type nodeCounter struct {
+	kinds []string
+}
+
+func (c *nodeCounter) Visit(node Node) Visitor {
+	if node != nil {
+		c.kinds = append(c.kinds, fmt.Sprintf("%T", node))
+	}
+	return c
+}
+
+func TestWalk(t *testing.T) {
+	module := mustParse(t, "fn f(a: f32) -> f32 { return a + 1.0; }")
+
+	c := &nodeCounter{}
+	Walk(c, module)
+	expected := []string{
+		"*ast.Module",
+		"*ast.FunctionDecl",
+		"*ast.Parameter",
+		"*ast.IdentType",
+		"*ast.IdentType",
+		"*ast.CompoundStmt",
+		"*ast.ReturnStmt",
+		"*ast.BinaryExpr",
+		"*ast.IdentExpr",
+		"*ast.LiteralExpr",
+	}
+	if strings.Join(c.kinds, " ") != strings.Join(expected, " ") {
+		t.Errorf("visit order:\nexpected %v\ngot      %v", expected, c.kinds)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	module := mustParse(t, shader)
+
+	var calls []string
+	Inspect(module, func(n Node) bool {
+		if fn, ok := n.(*FunctionDecl); ok && SymbolOf(module, fn.Name).OriginalName == "main" {
+			return false
+		}
+		if call, ok := n.(*CallExpr); ok {
+			if ident, ok := call.Func.(*IdentExpr); ok {
+				calls = append(calls, ident.Name)
+			}
+		}
+		return true
+	})
+	if strings.Join(calls, ",") != "ENGINE_SCALE" {
+		t.Errorf("expected only the call outside main, got %v", calls)
+	}
+}
+
+func TestRewriteAndPrint(t *testing.T) {
+	module := mustParse(t, "fn f(x: f32) -> f32 { return (x + 0.0) * 2.0; }")
+
+	RewriteExprs(module, func(e Expr) Expr {
+		if bin, ok := e.(*BinaryExpr); ok && bin.Op == BinOpAdd {
+			if lit, ok := bin.Right.(*LiteralExpr); ok && lit.Value == "0.0" {
+				return bin.Left
+			}
+		}
+		return e
+	})
+
+	got := Print(module, PrintOptions{MinifyWhitespace: true})
+	if got != "fn f(x:f32)->f32{return (x)*2.0;}" {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	module, errs := Parse("fn f() {\n    let a = ;\n}")
+	if module == nil || len(errs) != 1 {
+		t.Fatalf("expected a module and one error, got %v", errs)
+	}
+	if errs[0].Line != 2 || errs[0].EndLine != 2 {
+		t.Errorf("unexpected error position %+v", errs[0])
+	}
+	if !strings.HasPrefix(errs[0].Error(), "2:") {
+		t.Errorf("expected position in error string, got %q", errs[0].Error())
+	}
+}
+
+// expandMacro replaces calls of ENGINE_SCALE(x) with (x * 0.5).
+func expandMacro(module *Module) error {
+	RewriteExprs(module, func(e Expr) Expr {
+		call, ok := e.(*CallExpr)
+		if !ok {
+			return e
+		}
+		ident, ok := call.Func.(*IdentExpr)
+		if !ok || ident.Name != "ENGINE_SCALE" {
+			return e
+		}
+		if len(call.Args) != 1 {
+			return e
+		}
+		half := &LiteralExpr{Kind: LiteralFloat, Value: "0.5"}
+		return &ParenExpr{Expr: &BinaryExpr{Op: BinOpMul, Left: call.Args[0], Right: half}}
+	})
+	return nil
+}
+
+func TestMinifyTransform(t *testing.T) {
+	var sawDead bool
+	checkLiveness := func(module *Module) error {
+		for _, decl := range module.Declarations {
+			if fn, ok := decl.(*FunctionDecl); ok {
+				sym := SymbolOf(module, fn.Name)
+				if sym.OriginalName == "debugOnly" && !sym.Flags.Has(IsLive) {
+					sawDead = true
+				}
+			}
+		}
+		return nil
+	}
+
+	result := Minify(shader, MinifyOptions{
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		TreeShaking:       true,
+		Transforms:        []Transform{checkLiveness, expandMacro},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !sawDead {
+		t.Error("expected the transform to run after tree shaking")
+	}
+	if strings.Contains(result.Code, "ENGINE_SCALE") || !strings.Contains(result.Code, "*0.5)") {
+		t.Errorf("expected the macro to be expanded, got %s", result.Code)
+	}
+	if strings.Contains(result.Code, "double") || strings.Contains(result.Code, "scale") {
+		t.Errorf("expected identifiers to be renamed after the transform, got %s", result.Code)
+	}
+}
+
+func TestMinifyTransformDeclaresSymbol(t *testing.T) {
+	// Replace the body of double() with a call to an injected helper
+	inject := func(module *Module) error {
+		ref := DeclareSymbol(module, "instrument", SymbolFunction)
+		module.Declarations = append(module.Declarations, &FunctionDecl{
+			Name:       ref,
+			Parameters: nil,
+			Body:       &CompoundStmt{},
+		})
+		for _, decl := range module.Declarations {
+			fn, ok := decl.(*FunctionDecl)
+			if !ok || SymbolOf(module, fn.Name).OriginalName != "main" {
+				continue
+			}
+			call := &CallExpr{Func: &IdentExpr{Name: "instrument", Ref: ref}}
+			fn.Body.Stmts = append([]Stmt{&CallStmt{Call: call}}, fn.Body.Stmts...)
+		}
+		return expandMacro(module)
+	}
+
+	result := Minify(shader, MinifyOptions{
+		MinifyWhitespace:  true,
+		MinifyIdentifiers: true,
+		TreeShaking:       true,
+		Transforms:        []Transform{inject},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	if !strings.Contains(result.Code, "fn instrument(){}") || !strings.Contains(result.Code, "instrument();") {
+		t.Errorf("expected injected function and call, got %s", result.Code)
+	}
+}
+
+func TestMinifyTransformError(t *testing.T) {
+	result := Minify(shader, MinifyOptions{
+		MinifyWhitespace: true,
+		Transforms: []Transform{func(*Module) error {
+			return errors.New("unsupported macro")
+		}},
+	})
+	if len(result.Errors) != 1 || result.Errors[0].Message != "unsupported macro" {
+		t.Fatalf("expected transform error, got %v", result.Errors)
+	}
+	if result.Code != shader {
+		t.Error("expected the input to be returned on error")
+	}
+}
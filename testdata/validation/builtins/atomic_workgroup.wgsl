@@ -0,0 +1,20 @@
+// @test: builtins/atomic-workgroup
+// @expect-valid
+// @spec-ref: 17.8 "Atomic Built-in Functions"
+// Atomics in workgroup memory and through read_write storage pointers
+
+var<workgroup> histogram : array<atomic<u32>, 16>;
+
+@group(0) @binding(0) var<storage, read_write> total : atomic<i32>;
+
+fn bump(p : ptr<storage, atomic<i32>, read_write>) {
+    atomicAdd(p, 1);
+}
+
+@compute @workgroup_size(16)
+fn main(@builtin(local_invocation_index) idx : u32) {
+    atomicStore(&histogram[idx], 0u);
+    workgroupBarrier();
+    let prev = atomicOr(&histogram[idx % 4u], 1u);
+    let current = atomicLoad(&total);
+}
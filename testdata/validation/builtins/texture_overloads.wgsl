@@ -0,0 +1,42 @@
+// @test: builtins/texture-overloads
+// @expect-valid
+// @spec-ref: 17.7 "Texture Built-in Functions"
+// Texture builtins resolved against texture kind, dimension and format
+
+@group(0) @binding(0) var color : texture_2d<f32>;
+@group(0) @binding(1) var layers : texture_2d_array<f32>;
+@group(0) @binding(2) var env : texture_cube<f32>;
+@group(0) @binding(3) var shadow : texture_depth_2d;
+@group(0) @binding(4) var ids : texture_2d<u32>;
+@group(0) @binding(5) var msaa : texture_multisampled_2d<f32>;
+@group(0) @binding(6) var video : texture_external;
+@group(0) @binding(7) var samp : sampler;
+@group(0) @binding(8) var cmp : sampler_comparison;
+@group(1) @binding(0) var out_rgba : texture_storage_2d<rgba8unorm, write>;
+@group(1) @binding(1) var out_ids : texture_storage_2d<r32uint, read_write>;
+
+@fragment
+fn main(@location(0) uv : vec2<f32>) -> @location(0) vec4<f32> {
+    let a = textureSample(color, samp, uv);
+    let b = textureSample(color, samp, uv, vec2<i32>(1, 1));
+    let c = textureSample(layers, samp, uv, 2u);
+    let d = textureSample(env, samp, vec3<f32>(uv, 1.0));
+    let e : f32 = textureSample(shadow, samp, uv);
+    let f : f32 = textureSampleCompare(shadow, cmp, uv, 0.5);
+    let g = textureSampleLevel(color, samp, uv, 0.0);
+    let h = textureSampleBias(color, samp, uv, 1.0);
+    let i = textureSampleGrad(color, samp, uv, vec2<f32>(0.0), vec2<f32>(0.0));
+    let j : vec4<u32> = textureLoad(ids, vec2<i32>(0, 0), 0);
+    let k = textureLoad(msaa, vec2<u32>(0u, 0u), 1);
+    let l = textureSampleBaseClampToEdge(video, samp, uv);
+    let m = textureGather(0, color, samp, uv);
+    let n = textureGather(shadow, samp, uv);
+    let size : vec2<u32> = textureDimensions(color, 0);
+    let count : u32 = textureNumLayers(layers);
+    let levels : u32 = textureNumLevels(env);
+    let samples : u32 = textureNumSamples(msaa);
+    textureStore(out_rgba, vec2<i32>(0, 0), a);
+    textureStore(out_ids, vec2<u32>(0u, 0u), vec4<u32>(j.x));
+    let prev : vec4<u32> = textureLoad(out_ids, vec2<u32>(1u, 1u));
+    return a + b + c + d;
+}
@@ -0,0 +1,10 @@
+// @test: errors/atomics/atomic-direct-load
+// @expect-error E0805 "use atomicLoad"
+// Atomic values cannot be read without atomicLoad
+
+var<workgroup> counter : atomic<u32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    let c = counter;
+}
@@ -0,0 +1,14 @@
+// @test: errors/atomics/atomic-direct-store
+// @expect-error E0805 "use atomicStore"
+// Atomic values cannot be written without atomicStore
+
+struct Counter {
+    count : atomic<u32>,
+}
+
+@group(0) @binding(0) var<storage, read_write> counter : Counter;
+
+@compute @workgroup_size(1)
+fn main() {
+    counter.count = 0u;
+}
@@ -0,0 +1,10 @@
+// @test: errors/atomics/atomic-in-private
+// @expect-error E0804 "must be in address space"
+// Atomics may only live in workgroup or read_write storage memory
+
+var<private> counter : atomic<u32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    atomicAdd(&counter, 1u);
+}
@@ -0,0 +1,14 @@
+// @test: errors/atomics/atomic-in-read-storage
+// @expect-error E0804 "access mode 'read'"
+// Atomics in storage memory require read_write access
+
+struct Counter {
+    count : atomic<u32>,
+}
+
+@group(0) @binding(0) var<storage, read> counter : Counter;
+
+@compute @workgroup_size(1)
+fn main() {
+    let c = atomicLoad(&counter.count);
+}
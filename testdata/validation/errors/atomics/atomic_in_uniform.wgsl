@@ -0,0 +1,14 @@
+// @test: errors/atomics/atomic-in-uniform
+// @expect-error E0804 "got 'uniform'"
+// Atomics cannot live in uniform memory
+
+struct Counter {
+    count : atomic<u32>,
+}
+
+@group(0) @binding(0) var<uniform> counter : Counter;
+
+@compute @workgroup_size(1)
+fn main() {
+    let c = atomicLoad(&counter.count);
+}
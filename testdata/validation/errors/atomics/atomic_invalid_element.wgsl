@@ -0,0 +1,10 @@
+// @test: errors/atomics/atomic-invalid-element
+// @expect-error E0807 "got 'f32'"
+// Atomic element types are limited to i32 and u32
+
+var<workgroup> total : atomic<f32>;
+
+@compute @workgroup_size(1)
+fn main() {
+    atomicAdd(&total, 1.0);
+}
@@ -0,0 +1,11 @@
+// @test: errors/atomics/atomic-store-read-only
+// @expect-error E0806 "atomicStore"
+// atomicStore needs a pointer with read_write access
+
+fn reset(p : ptr<storage, atomic<u32>, read>) {
+    atomicStore(p, 0u);
+}
+
+@compute @workgroup_size(1)
+fn main() {
+}
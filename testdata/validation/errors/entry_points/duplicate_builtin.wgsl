@@ -0,0 +1,13 @@
+// @test: errors/entry-points/duplicate-builtin
+// @expect-error E0604 "@builtin(position) is used by both"
+// A builtin may only appear once per entry point input or output
+
+struct FragmentInput {
+    @builtin(position) coord : vec4<f32>,
+    @location(0) color : vec4<f32>,
+}
+
+@fragment
+fn main(@builtin(position) pos : vec4<f32>, input : FragmentInput) -> @location(0) vec4<f32> {
+    return input.color;
+}
@@ -0,0 +1,18 @@
+// @test: errors/entry-points/duplicate-location
+// @expect-error E0603 "@location(1) is used by both"
+// Each @location may only be used once per entry point input or output
+
+struct VertexOutput {
+    @builtin(position) position : vec4<f32>,
+    @location(1) color : vec4<f32>,
+    @location(1) uv : vec2<f32>,
+}
+
+@vertex
+fn main() -> VertexOutput {
+    var out : VertexOutput;
+    out.position = vec4<f32>(0.0, 0.0, 0.0, 1.0);
+    out.color = vec4<f32>(1.0, 1.0, 1.0, 1.0);
+    out.uv = vec2<f32>(0.0, 0.0);
+    return out;
+}
@@ -0,0 +1,10 @@
+// @test: errors/entry-points/duplicate-location-const
+// @expect-error E0603 "@location(1) is used by both 'color' and 'uv'"
+// Locations are const-expressions, not only decimal literals
+
+const SLOT : u32 = 1u;
+
+@fragment
+fn main(@location(0x1) color : vec4<f32>, @location(SLOT) uv : vec2<f32>) -> @location(0) vec4<f32> {
+    return color;
+}
@@ -0,0 +1,12 @@
+// @test: errors/entry-points/duplicate-location-params
+// @expect-error E0603 "@location(0) is used by both 'color' and 'uv'"
+// Locations are checked across parameters and struct members together
+
+struct FragmentInput {
+    @location(0) uv : vec2<f32>,
+}
+
+@fragment
+fn main(@location(0) color : vec4<f32>, input : FragmentInput) -> @location(0) vec4<f32> {
+    return color;
+}
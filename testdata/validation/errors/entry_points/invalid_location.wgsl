@@ -0,0 +1,10 @@
+// @test: errors/entry-points/invalid-location
+// @expect-error E0404 "@location of 'color' must be a non-negative integer constant"
+// An override is not a constant, so the input has no location
+
+override slot : u32 = 1u;
+
+@fragment
+fn main(@location(slot) color : vec4<f32>) -> @location(0) vec4<f32> {
+    return color;
+}
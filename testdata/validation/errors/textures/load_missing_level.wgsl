@@ -0,0 +1,10 @@
+// @test: errors/textures/load-missing-level
+// @expect-error E0202 "expects 3 arguments, got 2"
+// textureLoad on sampled textures requires a mip level
+
+@group(0) @binding(0) var tex : texture_2d<f32>;
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    return textureLoad(tex, vec2<i32>(0, 0));
+}
@@ -0,0 +1,12 @@
+// @test: errors/textures/sample-compare-non-comparison
+// @expect-error E0203 "must be 'sampler_comparison', got 'sampler'"
+// textureSampleCompare needs a comparison sampler
+
+@group(0) @binding(0) var shadow : texture_depth_2d;
+@group(0) @binding(1) var samp : sampler;
+
+@fragment
+fn main(@location(0) uv : vec2<f32>) -> @location(0) vec4<f32> {
+    let d = textureSampleCompare(shadow, samp, uv, 0.5);
+    return vec4<f32>(d);
+}
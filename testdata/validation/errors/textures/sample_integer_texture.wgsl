@@ -0,0 +1,12 @@
+// @test: errors/textures/sample-integer-texture
+// @expect-error E0203 "sampled type 'f32'"
+// Integer textures cannot be filtered
+
+@group(0) @binding(0) var tex : texture_2d<u32>;
+@group(0) @binding(1) var samp : sampler;
+
+@fragment
+fn main(@location(0) uv : vec2<f32>) -> @location(0) vec4<f32> {
+    let c = textureSample(tex, samp, uv);
+    return c;
+}
@@ -0,0 +1,11 @@
+// @test: errors/textures/sample-storage-texture
+// @expect-error E0203 "not defined for texture type 'texture_storage_2d"
+// Storage textures cannot be sampled
+
+@group(0) @binding(0) var tex : texture_storage_2d<rgba8unorm, write>;
+@group(0) @binding(1) var samp : sampler;
+
+@fragment
+fn main(@location(0) uv : vec2<f32>) -> @location(0) vec4<f32> {
+    return textureSample(tex, samp, uv);
+}
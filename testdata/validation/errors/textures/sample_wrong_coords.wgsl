@@ -0,0 +1,11 @@
+// @test: errors/textures/sample-wrong-coords
+// @expect-error E0203 "argument 3 of 'textureSample' must be 'vec3<f32>'"
+// Cube textures are sampled with 3D coordinates
+
+@group(0) @binding(0) var env : texture_cube<f32>;
+@group(0) @binding(1) var samp : sampler;
+
+@fragment
+fn main(@location(0) uv : vec2<f32>) -> @location(0) vec4<f32> {
+    return textureSample(env, samp, uv);
+}
@@ -0,0 +1,10 @@
+// @test: errors/textures/store-read-only
+// @expect-error E0203 "'write' or 'read_write' access"
+// textureStore needs a writable storage texture
+
+@group(0) @binding(0) var tex : texture_storage_2d<r32float, read>;
+
+@compute @workgroup_size(1)
+fn main() {
+    textureStore(tex, vec2<i32>(0, 0), vec4<f32>(1.0));
+}
@@ -0,0 +1,10 @@
+// @test: errors/textures/store-wrong-texel-type
+// @expect-error E0203 "texel format 'rgba8unorm'"
+// textureStore values must match the channel type of the texel format
+
+@group(0) @binding(0) var tex : texture_storage_2d<rgba8unorm, write>;
+
+@compute @workgroup_size(1)
+fn main() {
+    textureStore(tex, vec2<i32>(0, 0), vec4<u32>(1u, 2u, 3u, 4u));
+}
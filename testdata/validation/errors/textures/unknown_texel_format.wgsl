@@ -0,0 +1,9 @@
+// @test: errors/textures/unknown-texel-format
+// @expect-error E0211 "unknown texel format 'rgb8unorm'"
+// Storage textures must use a valid texel format
+
+@group(0) @binding(0) var tex : texture_storage_2d<rgb8unorm, write>;
+
+@compute @workgroup_size(1)
+fn main() {
+}
@@ -0,0 +1,25 @@
+// @test: lint/unused-declarations
+// @lint
+// @expect-valid
+// @expect-warning unused_function "function 'helper' is never used"
+// @expect-warning unused_struct "struct 'Light' is never used"
+// @expect-warning unused_binding "binding 'shadowMap' is never used"
+// @expect-warning unused_variable "variable 'scratch' is never used"
+// Declarations unreachable from any entry point are reported
+
+struct Light {
+    color : vec4<f32>,
+}
+
+@group(0) @binding(0) var<uniform> tint : vec4<f32>;
+@group(0) @binding(1) var shadowMap : texture_2d<f32>;
+
+var<private> scratch : f32;
+
+fn helper() {
+}
+
+@fragment
+fn main() -> @location(0) vec4<f32> {
+    return tint;
+}
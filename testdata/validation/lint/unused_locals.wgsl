@@ -0,0 +1,17 @@
+// @test: lint/unused-locals
+// @lint
+// @expect-valid
+// @expect-warning unused_variable "local 'unused' is never used"
+// @expect-warning discarded_result "result of 'normalize' is discarded"
+// Unused locals of live functions and pure calls used as statements.
+// Entry point IO parameters are part of the interface and never reported.
+
+@group(0) @binding(0) var<storage, read_write> data : array<f32>;
+
+@compute @workgroup_size(64)
+fn main(@builtin(global_invocation_id) id : vec3<u32>, @builtin(local_invocation_index) index : u32) {
+    let unused = 2.0;
+    let _ignored = 3.0;
+    normalize(vec3<f32>(1.0, 0.0, 0.0));
+    data[id.x] = 1.0;
+}
@@ -0,0 +1,19 @@
+// @test: types/constructor-inferred
+// @expect-valid
+// @spec-ref: 8.7.2 "Value Constructor Built-in Functions"
+// Constructors without a template infer their element type from the arguments
+
+@group(0) @binding(0) var<storage, read_write> out : array<vec4<u32>, 4>;
+
+@compute @workgroup_size(1)
+fn main() {
+    let u = vec4<u32>(1u, 2u, 3u, 4u);
+    let a : vec4<u32> = vec4(u.x);
+    let b : vec2<i32> = vec2(1i, 2);
+    let c : vec2<i32> = b + vec2(0, 1);
+    let m : mat2x2<f32> = mat2x2(1, 0, 0, 1);
+    let arr : array<u32, 3> = array(1u, 2u, 3u);
+    out[0] = vec4(a.x, u.yzw);
+    out[1] = vec4(arr[0], arr[1], u.x, 0);
+    out[2] = vec4(select(0u, 1u, m[0].x > 0.5));
+}